@@ -3,17 +3,40 @@
 // @description API REST de exemplo para CRUD de usuários usando Go e MongoDB
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 
+	"user-api/docs"
+	"user-api/internal/build"
+	"user-api/internal/config"
+	"user-api/internal/domain"
 	httphandler "user-api/internal/handler/http"
+	"user-api/internal/infra/audit"
+	"user-api/internal/infra/event"
+	"user-api/internal/infra/mail"
 	"user-api/internal/infra/mongo"
+	"user-api/internal/infra/postgres"
+	"user-api/internal/infra/tracing"
+	"user-api/internal/job"
+	"user-api/internal/logging"
 	"user-api/internal/repository"
 	"user-api/internal/usecase"
 )
@@ -22,16 +45,60 @@ func main() {
 	// ============================================
 	// CONFIGURAÇÃO INICIAL
 	// ============================================
-	// Lê variáveis de ambiente ou usa valores padrão
-	// os.Getenv() retorna uma string vazia se a variável não existir
-	mongoURI := os.Getenv("MONGO_URI")
-	if mongoURI == "" {
-		mongoURI = "mongodb://localhost:27017"
+	// config.Load() lê todas as variáveis de ambiente de uma vez,
+	// aplica valores padrão e valida o que é obrigatório
+	// Se algo estiver errado, a aplicação nem chega a iniciar
+	cfg, err := config.Load()
+	if err != nil {
+		// O logger ainda não existe (depende da config): usa o logger padrão do slog
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(1)
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8082"
+	// ============================================
+	// LOGGER ESTRUTURADO
+	// ============================================
+	// LOG_LEVEL controla o nível mínimo e LOG_FORMAT escolhe json (produção) ou text (local)
+	// O logger é injetado nas camadas (usecase, handler) em vez de usar uma variável global
+	logger, err := logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		slog.Error("invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+	// SetDefault faz o pacote log padrão (usado por bibliotecas) também sair em slog
+	slog.SetDefault(logger)
+
+	// ============================================
+	// SINAIS DE ENCERRAMENTO
+	// ============================================
+	// signal.NotifyContext cria um context que é CANCELADO quando o processo
+	// recebe SIGINT (Ctrl+C) ou SIGTERM (docker stop, Kubernetes)
+	//
+	// Esse context é passado para tudo que roda em segundo plano (rate limiter,
+	// job de purge): quando ele é cancelado, as goroutines terminam sozinhas
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// ============================================
+	// TRACING (OPENTELEMETRY)
+	// ============================================
+	// Com OTEL_EXPORTER_OTLP_ENDPOINT definido, cada requisição e cada comando
+	// do MongoDB geram spans enviados ao coletor. Sem ele, tracing é no-op
+	shutdownTracing, err := tracing.Setup(ctx, cfg.OTLPEndpoint, cfg.ServiceName)
+	if err != nil {
+		logger.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+	// O flush dos spans pendentes acontece quando main termina, depois do Shutdown do servidor
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Error("failed to flush traces", "error", err)
+		}
+	}()
+	if cfg.OTLPEndpoint != "" {
+		logger.Info("tracing enabled", "endpoint", cfg.OTLPEndpoint)
 	}
 
 	// ============================================
@@ -56,20 +123,50 @@ func main() {
 	//   var x int = 10        // x é um valor
 	//   var p *int = &x      // p é um ponteiro para x (armazena o endereço de x)
 	//   *p = 20              // modifica x através do ponteiro (x agora é 20)
-	client := mongo.NewClient(mongoURI)
+	//
+	// ConnectWithRetry chama NewClient várias vezes com backoff exponencial
+	// Útil quando a API sobe antes do MongoDB (docker compose, Kubernetes)
+	// Só depois de esgotar as tentativas decidimos encerrar a aplicação
+	//
+	// clientOpts ajusta o pool de conexões (MONGO_MAX_POOL_SIZE, MONGO_MIN_POOL_SIZE...)
+	// e a consistência em replica sets (MONGO_READ_PREFERENCE, MONGO_WRITE_CONCERN)
+	clientOpts := mongo.ClientOptions{
+		MaxPoolSize:     cfg.MongoMaxPoolSize,
+		MinPoolSize:     cfg.MongoMinPoolSize,
+		MaxConnIdleTime: cfg.MongoMaxConnIdleTime,
+		ReadPreference:  cfg.MongoReadPreference,
+		WriteConcern:    cfg.MongoWriteConcern,
+		Tracing:         cfg.OTLPEndpoint != "",
+	}
+	client, err := mongo.ConnectWithRetry(cfg.MongoURI, clientOpts, cfg.MongoConnectMaxAttempts, cfg.MongoConnectBaseDelay, logger)
+	if err != nil {
+		// slog não tem Fatal: registramos o erro e encerramos com código 1
+		logger.Error("failed to connect to MongoDB", "error", err)
+		os.Exit(1)
+	}
 
 	// defer garante que esta função seja executada quando main() terminar
 	// Mesmo se houver um panic ou return antecipado, o defer sempre executa
 	// Isso é essencial para limpar recursos (fechar conexões, arquivos, etc.)
+	//
+	// ORDEM NO ENCERRAMENTO:
+	// - defers rodam na ordem inversa (LIFO) e só quando main termina, ou seja,
+	//   DEPOIS do srv.Shutdown: nenhuma requisição em andamento perde a conexão com o banco
+	// - Disconnect espera as operações em uso voltarem ao pool; o prazo de 10s evita
+	//   que uma conexão travada segure a saída do processo (e o rolling restart)
 	defer func() {
-		if err := client.Disconnect(nil); err != nil {
-			log.Printf("Error disconnecting from MongoDB: %v", err)
+		disconnectCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := client.Disconnect(disconnectCtx); err != nil {
+			logger.Error("error disconnecting from MongoDB", "error", err)
+			return
 		}
+		logger.Info("disconnected from MongoDB")
 	}()
 
 	// Database() retorna um ponteiro (*mongo.Database)
 	// Todas as operações no banco usam este mesmo objeto compartilhado
-	db := client.Database("userdb")
+	db := client.Database(cfg.MongoDB)
 
 	// ============================================
 	// INJEÇÃO DE DEPENDÊNCIAS
@@ -83,9 +180,98 @@ func main() {
 	// 3. Desacoplamento: cada camada não conhece detalhes da implementação da outra
 	//
 	// O fluxo é: Handler usa UseCase, UseCase usa Repository, Repository usa MongoDB
-	repo := repository.NewUserMongoRepository(db)
-	uc := usecase.NewUserUseCase(repo)
-	handler := httphandler.NewUserHandler(uc)
+	// MONGO_POOL_WAIT_TIMEOUT: com o pool cheio, a operação espera no máximo isso por uma conexão (senão 503)
+	// READ_FROM_SECONDARY: listagens, buscas e contagens leem de secundários; GetByID e escritas, do primário
+	//
+	// STORAGE=postgres troca SÓ o repositório de usuários: o resto da cadeia é o mesmo
+	// (idempotência, tokens de verificação e audit log continuam no MongoDB)
+	// storagePing é a checagem do PostgreSQL no preflight e no /healthz (nil com STORAGE=mongo)
+	var repo domain.UserRepository
+	var storagePing func(ctx context.Context) error
+	switch cfg.Storage {
+	case config.StoragePostgres:
+		pg, err := postgres.ConnectWithRetry(ctx, cfg.PostgresDSN, cfg.PostgresMaxOpenConns, cfg.PostgresConnectMaxAttempts, cfg.PostgresConnectBaseDelay, logger)
+		if err != nil {
+			logger.Error("failed to connect to PostgreSQL", "error", err)
+			os.Exit(1)
+		}
+		// Como o Disconnect do MongoDB: só fecha quando main termina, depois do Shutdown do servidor
+		defer pg.Close()
+		repo = repository.NewUserPostgresRepository(pg, cfg.PostgresTable, cfg.PostgresOpTimeout)
+		storagePing = pg.PingContext
+		logger.Info("users stored in PostgreSQL", "table", cfg.PostgresTable)
+	default:
+		repo = repository.NewUserMongoRepository(db, cfg.MongoCollection, cfg.MongoOpTimeout, repository.RetryPolicy{
+			MaxAttempts: cfg.MongoRetryMaxAttempts,
+			BaseDelay:   cfg.MongoRetryBaseDelay,
+		}, repository.NewPoolGate(cfg.MongoMaxPoolSize, cfg.MongoPoolWaitTimeout), cfg.ReadFromSecondary)
+	}
+	// Decorator: operações acima de SLOW_QUERY_THRESHOLD vão para o log em WARN
+	// Implementa a mesma interface, então o usecase recebe repo sem saber que ele foi envolvido
+	if cfg.SlowQueryThreshold > 0 {
+		repo = repository.NewSlowQueryRepository(repo, cfg.SlowQueryThreshold, logger)
+	}
+	// Decorator opcional: cache em memória do GetByID (USER_CACHE_SIZE > 0)
+	// Fica por fora do de consultas lentas: um acerto no cache não é uma consulta
+	// USER_CACHE_STALE_TTL: com o MongoDB fora, leituras respondem do cache expirado (header Warning)
+	if cfg.UserCacheSize > 0 {
+		repo = repository.NewCachedUserRepository(repo, cfg.UserCacheSize, cfg.UserCacheTTL, cfg.UserCacheStaleTTL)
+		logger.Info("user cache enabled", "size", cfg.UserCacheSize, "ttl", cfg.UserCacheTTL.String(),
+			"stale_ttl", cfg.UserCacheStaleTTL.String())
+	}
+	// Decorator opcional: total de usuários em cache (COUNT_CACHE_INTERVAL > 0)
+	// Run reconta no banco a cada intervalo e para quando ctx é cancelado (encerramento)
+	if cfg.CountCacheInterval > 0 {
+		countCache := repository.NewCountCacheRepository(repo, cfg.CountCacheInterval, logger)
+		repo = countCache
+		go countCache.Run(ctx)
+	}
+	// O publisher recebe os eventos de domínio (criação, atualização, remoção)
+	// Com WEBHOOK_URL definido, cada evento vira um POST assinado para essa URL
+	// Sem ele, NoopPublisher descarta os eventos
+	var publisher domain.EventPublisher = event.NewNoopPublisher()
+	if cfg.WebhookURL != "" {
+		publisher = event.NewWebhookPublisher(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookTimeout, cfg.WebhookMaxRetries, logger)
+		logger.Info("webhook notifications enabled")
+	}
+	// Verificação de email: tokens em uma collection com índice TTL
+	// LogMailer só escreve o email no log - troque por um provedor real em produção
+	verificationTokens, err := repository.NewVerificationMongoStore(ctx, db, cfg.VerificationCollection, cfg.MongoOpTimeout)
+	if err != nil {
+		logger.Error("failed to set up verification token store", "error", err)
+		os.Exit(1)
+	}
+	verification := usecase.VerificationOptions{
+		Tokens:   verificationTokens,
+		Mailer:   mail.NewLogMailer(logger),
+		TokenTTL: cfg.VerificationTokenTTL,
+	}
+	// Audit log: com AUDIT_ENABLED, cada alteração vai para a collection AUDIT_COLLECTION
+	// Sem ele, NoopLogger descarta as entradas e o histórico fica vazio
+	var auditLogger domain.AuditLogger = audit.NewNoopLogger()
+	if cfg.AuditEnabled {
+		auditStore, err := repository.NewAuditMongoStore(ctx, db, cfg.AuditCollection, cfg.MongoOpTimeout)
+		if err != nil {
+			logger.Error("failed to set up audit log", "error", err)
+			os.Exit(1)
+		}
+		auditLogger = auditStore
+		logger.Info("audit log enabled", "collection", cfg.AuditCollection)
+	}
+	uc := usecase.NewUserUseCase(repo, publisher, auditLogger, verification, logger)
+	// Chaves do header Idempotency-Key ficam em uma collection com índice TTL
+	idempotency, err := repository.NewIdempotencyMongoStore(ctx, db, cfg.IdempotencyCollection, cfg.IdempotencyTTL, cfg.MongoOpTimeout)
+	if err != nil {
+		logger.Error("failed to set up idempotency store", "error", err)
+		os.Exit(1)
+	}
+	handler := httphandler.NewUserHandler(uc, cfg.MaxBodyBytes, cfg.PageDefault, cfg.PageMax, idempotency, cfg.EnableAdmin, cfg.BasePath, cfg.UserStreamMaxClients, logger)
+
+	// Job de purge: apaga de vez os usuários removidos há mais de PURGE_RETENTION
+	// Roda em uma goroutine própria e para quando ctx é cancelado (encerramento)
+	if cfg.PurgeInterval > 0 {
+		go job.NewPurgeJob(uc, cfg.PurgeInterval, cfg.PurgeRetention, logger).Run(ctx)
+	}
 
 	// ============================================
 	// CONFIGURAÇÃO DE ROTAS HTTP
@@ -94,32 +280,246 @@ func main() {
 	// Router mapeia URLs para funções (handlers)
 	r := chi.NewRouter()
 
+	// RequestID gera um ID único por requisição (ou reaproveita o header X-Request-Id)
+	// O ID aparece nos logs e permite juntar todas as linhas de uma mesma requisição
+	// r.Use deve ser chamado antes de registrar as rotas
+	r.Use(middleware.RequestID)
+
+	// Headers de segurança (nosniff, X-Frame-Options, Referrer-Policy, CSP) em todas as respostas
+	// Cada um pode ser desligado com "off" (ex: SECURITY_FRAME_OPTIONS=off)
+	r.Use(httphandler.NewSecurityHeaders(httphandler.SecurityHeaders{
+		ContentTypeOptions:    cfg.ContentTypeOptions,
+		FrameOptions:          cfg.FrameOptions,
+		ReferrerPolicy:        cfg.ReferrerPolicy,
+		ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+		SwaggerCSP:            cfg.SwaggerCSP,
+	}))
+
+	// CORS (CORS_ALLOWED_ORIGINS): responde o preflight antes do roteamento e da autenticação
+	// Access-Control-Max-Age (CORS_MAX_AGE) faz o navegador reaproveitar o preflight
+	r.Use(httphandler.NewCORS(httphandler.ParseCORSOrigins(cfg.CORSAllowedOrigins), cfg.CORSMaxAge))
+
+	// Headers que nunca aparecem nos logs: Authorization, Cookie, X-API-Key... mais LOG_REDACT_HEADERS
+	// O mesmo redactor vai para todo middleware que registra headers
+	redactor := httphandler.NewHeaderRedactor(httphandler.ParseHeaderNames(cfg.LogRedactHeaders))
+
+	// Middleware de recuperação: um panic em qualquer handler vira 500 JSON
+	// em vez de derrubar a conexão. Fica no início para envolver todos os outros
+	r.Use(httphandler.NewRecoveryMiddleware(logger, redactor))
+
+	// URLs longas demais (MAX_URL_PATH_LENGTH, MAX_QUERY_LENGTH) → 414 antes de qualquer outro trabalho
+	r.Use(httphandler.NewURLLengthLimit(cfg.MaxURLPathLength, cfg.MaxQueryLength))
+
+	// Caminhos com barra final ("/api/v1/users/") → 308 para a forma sem barra
+	r.Use(httphandler.RedirectTrailingSlash)
+
+	// Nenhuma rota lê mais que MAX_REQUEST_BODY_BYTES do corpo (413), mesmo sem usar o decodeJSON
+	// Uma rota pode trocar o limite com httphandler.WithBodyLimit
+	r.Use(httphandler.NewBodyLimit(cfg.MaxRequestBodyBytes))
+
+	// DEBUG_BODIES: corpos de requisição e resposta no log (DEBUG), cortados e com segredos escondidos
+	// Desligado, o middleware nem entra na cadeia
+	if cfg.DebugBodies {
+		r.Use(httphandler.NewDebugBodies(logger, cfg.DebugBodiesMaxBytes, redactor))
+	}
+
+	// Middleware de métricas: registra contagem e latência de TODAS as requisições
+	r.Use(httphandler.MetricsMiddleware)
+
+	// Middleware de tracing: um span por requisição (no-op sem OTEL_EXPORTER_OTLP_ENDPOINT)
+	r.Use(httphandler.TracingMiddleware)
+
+	// Load shedding: no máximo MAX_CONCURRENT_REQUESTS requisições em andamento
+	// Acima disso, 503 com Retry-After (protege o processo e o MongoDB da sobrecarga)
+	// MAX_CONCURRENT_REQUESTS=0 desabilita
+	if cfg.MaxConcurrentRequests > 0 {
+		r.Use(httphandler.NewConcurrencyLimiter(cfg.MaxConcurrentRequests, cfg.MaxConcurrentWait).Middleware)
+	}
+
+	// Middleware de timeout: cada requisição tem um prazo máximo (REQUEST_TIMEOUT)
+	// O prazo viaja no context até o MongoDB; se estourar, a resposta é 503
+	r.Use(httphandler.NewTimeoutMiddleware(cfg.RequestTimeout))
+
+	// Com TRUST_PROXY, o IP do cliente sai do X-Forwarded-For, contando TRUST_PROXY_HOPS a partir da direita
+	trustedHops := 0
+	if cfg.TrustProxy {
+		trustedHops = cfg.TrustProxyHops
+	}
+
+	// Rate limiting por IP (token bucket): acima do limite a resposta é 429
+	// RATE_LIMIT_RPS=0 desabilita; /healthz, /readyz e /metrics ficam de fora
+	if cfg.RateLimitRPS > 0 {
+		limiter := httphandler.NewRateLimiter(ctx, cfg.RateLimitRPS, cfg.RateLimitBurst, trustedHops)
+		r.Use(limiter.Middleware)
+	}
+
+	// Multi-tenancy (MULTI_TENANT): as rotas /api/... exigem o header X-Tenant-ID
+	// e cada tenant lê e grava na sua própria collection ("users_<tenant>")
+	if cfg.MultiTenant {
+		r.Use(httphandler.RequireTenant)
+	}
+
+	// Método não suportado em uma rota existente → 405 JSON com o header Allow
+	r.MethodNotAllowed(httphandler.NewMethodNotAllowedHandler(r))
+
 	// Registra rota de healthcheck
-	httphandler.RegisterHealth(r)
+	// As checagens só rodam em /healthz?verbose=true (os probes usam a forma simples)
+	healthChecks := []httphandler.HealthCheck{{
+		Name:    "mongo",
+		Timeout: 2 * time.Second,
+		Check: func(ctx context.Context) error {
+			return client.Ping(ctx, nil)
+		},
+	}}
+	if storagePing != nil {
+		healthChecks = append(healthChecks, httphandler.HealthCheck{Name: "postgres", Timeout: 2 * time.Second, Check: storagePing})
+	}
+	httphandler.RegisterHealth(r, healthChecks...)
+
+	// Prontidão: /readyz responde 200 até o SIGTERM e 503 durante o SHUTDOWN_DELAY
+	// (só passa a true quando o servidor começa a atender, logo abaixo)
+	var ready atomic.Bool
+	httphandler.RegisterReadiness(r, &ready)
+
+	// Registra rota de versão (commit, data de build, versão semântica)
+	httphandler.RegisterVersion(r)
 
 	// Registra rotas de usuários (CRUD)
-	handler.RegisterRoutes(r)
+	// O middleware de autenticação protege as rotas de escrita
+	// Aceita JWT sempre e, com API_KEYS definido, também o header X-API-Key (serviço a serviço)
+	authenticators := []httphandler.Authenticator{httphandler.NewJWTAuthenticator([]byte(cfg.JWTSecret))}
+	apiKeys, err := httphandler.ParseAPIKeys(cfg.APIKeys)
+	if err != nil {
+		logger.Error("invalid API_KEYS", "error", err)
+		os.Exit(1)
+	}
+	if len(apiKeys) > 0 {
+		authenticators = append(authenticators, httphandler.NewAPIKeyAuthenticator(apiKeys))
+		logger.Info("API key authentication enabled", "keys", len(apiKeys))
+	}
+	auth := httphandler.RequireAuth(authenticators...)
+	// O validador usa o spec gerado pelo swag (pacote docs): rode "swag init" após mudar as anotações
+	validator, err := httphandler.NewOpenAPIValidator(docs.SwaggerInfo.ReadDoc(), cfg.MaxBodyBytes)
+	if err != nil {
+		logger.Error("failed to load OpenAPI spec for request validation", "error", err)
+		os.Exit(1)
+	}
+	// GET /api/v1/users/email-available tem um limite por IP mais baixo que o geral:
+	// é público e permite descobrir quem tem conta (EMAIL_CHECK_RATE_LIMIT_RPS=0 desabilita)
+	limitEmailCheck := func(next http.Handler) http.Handler { return next }
+	if cfg.EmailCheckRateLimitRPS > 0 {
+		limitEmailCheck = httphandler.NewRateLimiter(ctx, cfg.EmailCheckRateLimitRPS, cfg.EmailCheckRateLimitBurst, trustedHops).Middleware
+	}
+	handler.RegisterRoutes(r, auth, validator.Middleware, limitEmailCheck)
+
+	// Rotas de administração (reset da collection e PATCH /api/v1/users/bulk) só existem com ENABLE_ADMIN=true
+	// Desabilitadas, respondem 404 como qualquer rota inexistente (o preflight avisa quando estão ligadas)
+	if cfg.EnableAdmin {
+		// GET /api/v1/admin/mongo: versão e topologia do MongoDB (hello + buildInfo)
+		dbInfo := func(ctx context.Context) (any, error) {
+			return mongo.GetServerInfo(ctx, client)
+		}
+		httphandler.RegisterAdmin(r, uc, dbInfo, auth, logger)
+	}
+
+	// Registra a rota /metrics (formato Prometheus)
+	httphandler.RegisterMetrics(r, uc, logger)
 
 	// Registra rotas do Swagger UI (documentação interativa)
 	// Acesse: http://localhost:8080/swagger/index.html
-	httphandler.RegisterSwagger(r)
+	// Com ENABLE_SWAGGER=false (padrão em produção) as rotas não existem e /swagger/ responde 404
+	// O spec (pacote docs) continua carregado: a validação dos corpos (OpenAPIValidator) depende dele
+	if cfg.EnableSwagger {
+		httphandler.RegisterSwagger(r)
+	}
+
+	// ============================================
+	// PREFLIGHT
+	// ============================================
+	// Antes de aceitar requisições: MongoDB no ar, índices criados, certificado TLS e porta livre
+	// Uma falha crítica encerra a aplicação aqui, com todas as falhas no log (ver preflight.go)
+	// O listener devolvido já está com a porta aberta: o servidor usa ele
+	ln, err := preflight(ctx, cfg, func(ctx context.Context) error {
+		return client.Ping(ctx, nil)
+	}, storagePing, repo, logger)
+	if err != nil {
+		logger.Error("preflight failed", "error", err)
+		os.Exit(1)
+	}
 
 	// ============================================
 	// INICIALIZAÇÃO DO SERVIDOR
 	// ============================================
-	// ListenAndServe inicia um servidor HTTP que escuta na porta especificada
-	// O segundo parâmetro é o handler (router) que processa as requisições
+	// http.Server permite configurar timeouts, ao contrário de http.ListenAndServe
+	// Sem timeouts, um cliente lento pode segurar uma conexão indefinidamente
 	//
-	// IMPORTANTE: Esta função é BLOQUEANTE
-	// Ela fica rodando indefinidamente até que o servidor seja encerrado
-	// Por isso não há código depois dela - ela nunca retorna normalmente
+	// IMPORTANTE: Serve é BLOQUEANTE
+	// Por isso ela roda em uma goroutine, enquanto main espera o sinal de encerramento
+	srv := &http.Server{
+		Addr:         ":" + cfg.Port,
+		Handler:      r,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+
+	// HTTPS opcional: com TLS_CERT_FILE e TLS_KEY_FILE a própria API termina o TLS
+	// Atrás de um proxy/load balancer que já faz isso, deixe as duas vazias (HTTP puro)
+	if cfg.TLSEnabled() {
+		srv.TLSConfig = newTLSConfig()
+	}
+
+	logger.Info("server starting", "port", cfg.Port, "tls", cfg.TLSEnabled(), "version", build.Version, "commit", build.Commit)
+
+	// O canal recebe o erro de Serve (ex: o listener falhou)
+	// Buffer de 1: a goroutine consegue enviar mesmo que ninguém esteja lendo
 	//
-	// Em produção, considere:
-	// - Adicionar timeouts (ReadTimeout, WriteTimeout)
-	// - Configurar TLS/HTTPS
-	// - Usar graceful shutdown (permitir requisições em andamento terminarem)
-	log.Printf("Server starting on port %s", port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	// O encerramento é o mesmo nos dois modos: srv.Shutdown também espera as conexões TLS
+	serverErr := make(chan error, 1)
+	go func() {
+		if cfg.TLSEnabled() {
+			serverErr <- srv.ServeTLS(ln, cfg.TLSCertFile, cfg.TLSKeyFile)
+			return
+		}
+		serverErr <- srv.Serve(ln)
+	}()
+	ready.Store(true)
+
+	// Espera o que acontecer primeiro: falha do servidor ou sinal de encerramento
+	select {
+	case err := <-serverErr:
+		logger.Error("failed to start server", "error", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	// ============================================
+	// ENCERRAMENTO GRACIOSO (GRACEFUL SHUTDOWN)
+	// ============================================
+	// Primeiro, /readyz passa a responder 503 e esperamos SHUTDOWN_DELAY
+	//
+	// POR QUE ESPERAR?
+	// - No Kubernetes, o SIGTERM e a retirada do pod do load balancer acontecem AO MESMO TEMPO
+	// - O load balancer só percebe na próxima sonda do /readyz (ou quando os endpoints se propagam)
+	// - Sem a espera, requisições que ainda chegam nesse intervalo encontrariam a porta fechada
+	// - Durante a espera o servidor continua atendendo normalmente
+	//
+	// Padrão 0 (imediato): em desenvolvimento não há load balancer para esperar
+	ready.Store(false)
+	if cfg.ShutdownDelay > 0 {
+		// stop devolve os sinais ao comportamento padrão: um segundo Ctrl+C encerra na hora, sem esperar
+		stop()
+		logger.Info("draining before shutdown", "delay", cfg.ShutdownDelay.String())
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	// Shutdown para de aceitar conexões novas e ESPERA as requisições em
+	// andamento terminarem (até SHUTDOWN_TIMEOUT), em vez de cortá-las no meio
+	logger.Info("shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("graceful shutdown failed", "error", err)
 	}
+	logger.Info("server stopped")
 }
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"time"
+
+	"user-api/internal/config"
+	"user-api/internal/domain"
+)
+
+// preflightTimeout é o prazo de cada checagem que fala com o MongoDB
+const preflightTimeout = 10 * time.Second
+
+// preflightCheck é uma checagem da inicialização
+type preflightCheck struct {
+	name     string
+	critical bool   // Falha crítica impede a aplicação de subir; as demais só geram WARN
+	after    string // Checagem da qual esta depende: se aquela falhou, esta nem roda
+	run      func(ctx context.Context) error
+}
+
+// ============================================
+// PREFLIGHT (AUTOVERIFICAÇÃO NA INICIALIZAÇÃO)
+// ============================================
+// preflight roda as checagens de inicialização ANTES de o servidor aceitar requisições
+//
+// AS CHECAGENS (na ordem):
+// - config: certificado e chave TLS legíveis e compatíveis, quando configurados (crítica)
+// - jwt_secret, admin_routes, debug_bodies, tls: configurações arriscadas, como o secret de desenvolvimento (não críticas)
+// - mongo: o MongoDB responde ao ping (crítica)
+// - postgres: com STORAGE=postgres, o PostgreSQL responde ao ping (crítica)
+// - indexes: os índices da collection de usuários existem; os que faltam são criados (crítica)
+// Com STORAGE=postgres, indexes cria as tabelas (CREATE TABLE IF NOT EXISTS) e depende do postgres
+// - port: a porta PORT está livre (crítica)
+//
+// POR QUE ABRIR A PORTA AQUI?
+// - "A porta está livre" só é verdade enquanto ninguém a ocupar: testar e fechar abriria uma janela
+// - O listener aberto é devolvido e o servidor usa ELE (srv.Serve), sem abrir a porta de novo
+//
+// Cada checagem vai para o log com o resultado; todas rodam, mesmo depois de uma falha,
+// e o erro devolvido junta as falhas críticas (errors.Join): um único deploy mostra todos os problemas
+// Em caso de erro, o listener (se abriu) já foi fechado
+func preflight(ctx context.Context, cfg *config.Config, ping, storagePing func(ctx context.Context) error, repo domain.UserRepository, logger *slog.Logger) (net.Listener, error) {
+	var ln net.Listener
+
+	checks := []preflightCheck{
+		{name: "config", critical: true, run: func(ctx context.Context) error {
+			if cfg.TLSEnabled() {
+				if _, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+					return fmt.Errorf("TLS certificate: %w", err)
+				}
+			}
+			return nil
+		}},
+		// Configurações válidas, mas arriscadas: o que é inválido de fato já foi recusado por config.Load
+		{name: "jwt_secret", run: func(ctx context.Context) error {
+			if !cfg.IsProduction() && os.Getenv("JWT_SECRET") == "" {
+				return errors.New("JWT_SECRET not set, using insecure development secret")
+			}
+			return nil
+		}},
+		{name: "admin_routes", run: func(ctx context.Context) error {
+			if cfg.EnableAdmin {
+				return errors.New("ENABLE_ADMIN is on: POST /api/v1/admin/reset can drop all users, PATCH /api/v1/users/bulk can update many")
+			}
+			return nil
+		}},
+		{name: "debug_bodies", run: func(ctx context.Context) error {
+			if !cfg.DebugBodies {
+				return nil
+			}
+			if !logger.Enabled(ctx, slog.LevelDebug) {
+				return errors.New("DEBUG_BODIES is on but LOG_LEVEL is not debug: no bodies will be logged")
+			}
+			return errors.New("DEBUG_BODIES is on: request and response bodies go to the log")
+		}},
+		{name: "tls", run: func(ctx context.Context) error {
+			if cfg.IsProduction() && !cfg.TLSEnabled() {
+				return errors.New("serving plain HTTP in production: TLS must be terminated by a proxy in front of the API")
+			}
+			return nil
+		}},
+		{name: "mongo", critical: true, run: ping},
+	}
+	// Onde ficam os usuários: o MongoDB ou, com storagePing, o PostgreSQL
+	storage := "mongo"
+	if storagePing != nil {
+		storage = "postgres"
+		checks = append(checks, preflightCheck{name: storage, critical: true, run: storagePing})
+	}
+	checks = append(checks,
+		preflightCheck{name: "indexes", critical: true, after: storage, run: repo.EnsureIndexes},
+		preflightCheck{name: "port", critical: true, run: func(ctx context.Context) error {
+			var err error
+			ln, err = net.Listen("tcp", ":"+cfg.Port)
+			return err
+		}},
+	)
+
+	var errs []error
+	failed := map[string]bool{}
+	for _, check := range checks {
+		if check.after != "" && failed[check.after] {
+			failed[check.name] = true
+			logger.Error("preflight check skipped", "check", check.name, "reason", check.after+" failed")
+			continue
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, preflightTimeout)
+		start := time.Now()
+		err := check.run(checkCtx)
+		cancel()
+
+		switch {
+		case err == nil:
+			logger.Info("preflight check passed", "check", check.name, "duration", time.Since(start).String())
+		case check.critical:
+			failed[check.name] = true
+			errs = append(errs, fmt.Errorf("%s: %w", check.name, err))
+			logger.Error("preflight check failed", "check", check.name, "error", err)
+		default:
+			logger.Warn("preflight check warning", "check", check.name, "warning", err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		if ln != nil {
+			ln.Close()
+		}
+		return nil, err
+	}
+	return ln, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import "crypto/tls"
+
+// ============================================
+// CONFIGURAÇÃO TLS (HTTPS)
+// ============================================
+// newTLSConfig define as versões e cifras aceitas quando a API serve HTTPS
+// (TLS_CERT_FILE e TLS_KEY_FILE definidos)
+//
+// POR QUE NO MÍNIMO TLS 1.2?
+// - TLS 1.0 e 1.1 têm fraquezas conhecidas e foram descontinuados (RFC 8996)
+// - Todo cliente atual suporta 1.2 e 1.3
+//
+// SOBRE AS CIFRAS:
+// - A lista vale só para TLS 1.2: no TLS 1.3 o Go escolhe as cifras e elas já são todas seguras
+// - Só ECDHE (forward secrecy: uma chave vazada não decifra o tráfego antigo)
+// - Só AEAD (GCM e ChaCha20-Poly1305), sem CBC
+func newTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CurvePreferences: []tls.CurveID{
+			tls.X25519,
+			tls.CurveP256,
+		},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+	}
+}
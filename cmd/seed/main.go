@@ -0,0 +1,131 @@
+// Comando seed: popula o MongoDB com usuários falsos para demos e testes de carga
+//
+// Uso:
+//
+//	go run ./cmd/seed -n 500
+//
+// Lê as mesmas variáveis de ambiente da API (MONGO_URI, MONGO_DB, MONGO_COLLECTION...)
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"strings"
+
+	"user-api/internal/config"
+	"user-api/internal/domain"
+	"user-api/internal/infra/audit"
+	"user-api/internal/infra/event"
+	"user-api/internal/infra/mongo"
+	"user-api/internal/logging"
+	"user-api/internal/repository"
+	"user-api/internal/usecase"
+)
+
+// Listas usadas para gerar nomes realistas (nome + sobrenome)
+var (
+	firstNames = []string{
+		"Ana", "Bruno", "Carla", "Daniel", "Eduarda", "Felipe", "Gabriela", "Henrique",
+		"Isabela", "João", "Larissa", "Marcos", "Natália", "Otávio", "Paula", "Rafael",
+		"Sofia", "Thiago", "Vitória", "Lucas",
+	}
+	lastNames = []string{
+		"Silva", "Santos", "Oliveira", "Souza", "Rodrigues", "Ferreira", "Alves", "Pereira",
+		"Lima", "Gomes", "Costa", "Ribeiro", "Martins", "Carvalho", "Almeida", "Lopes",
+	}
+)
+
+func main() {
+	// flag.Int define o parâmetro -n; o valor só é lido depois de flag.Parse()
+	n := flag.Int("n", 100, "number of users to insert")
+	flag.Parse()
+
+	if *n < 1 {
+		fmt.Fprintln(os.Stderr, "seed: -n must be at least 1")
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "seed:", err)
+		os.Exit(1)
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "seed:", err)
+		os.Exit(1)
+	}
+
+	clientOpts := mongo.ClientOptions{
+		MaxPoolSize:     cfg.MongoMaxPoolSize,
+		MinPoolSize:     cfg.MongoMinPoolSize,
+		MaxConnIdleTime: cfg.MongoMaxConnIdleTime,
+		ReadPreference:  cfg.MongoReadPreference,
+		WriteConcern:    cfg.MongoWriteConcern,
+	}
+	client, err := mongo.ConnectWithRetry(cfg.MongoURI, clientOpts, cfg.MongoConnectMaxAttempts, cfg.MongoConnectBaseDelay, logger)
+	if err != nil {
+		logger.Error("failed to connect to MongoDB", "error", err)
+		os.Exit(1)
+	}
+	defer client.Disconnect(context.Background())
+
+	// Usamos o MESMO caminho da API (usecase → repository): as validações
+	// de nome e email valem também para os dados gerados
+	// Eventos são descartados (NoopPublisher) para não disparar webhooks em massa
+	// O audit log também (NoopLogger): dados falsos não são alterações de verdade
+	// Sem verificação (VerificationOptions{}): nenhum email é enviado aos usuários falsos
+	repo := repository.NewUserMongoRepository(client.Database(cfg.MongoDB), cfg.MongoCollection, cfg.MongoOpTimeout, repository.RetryPolicy{
+		MaxAttempts: cfg.MongoRetryMaxAttempts,
+		BaseDelay:   cfg.MongoRetryBaseDelay,
+	}, nil, false)
+	uc := usecase.NewUserUseCase(repo, event.NewNoopPublisher(), audit.NewNoopLogger(), usecase.VerificationOptions{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx := context.Background()
+
+	// Os emails são numerados a partir do total atual: rodar o seed
+	// várias vezes não gera emails repetidos
+	start, err := uc.CountUsers(ctx, domain.UserFilter{})
+	if err != nil {
+		logger.Error("failed to count users", "error", err)
+		os.Exit(1)
+	}
+
+	// Mostra o progresso a cada 10% (ou a cada usuário, para N pequeno)
+	step := max(*n/10, 1)
+	for i := 1; i <= *n; i++ {
+		first := firstNames[rand.Intn(len(firstNames))]
+		last := lastNames[rand.Intn(len(lastNames))]
+		name := first + " " + last
+		email := fmt.Sprintf("%s.%s.%d@example.com", emailPart(first), emailPart(last), start+int64(i))
+
+		if _, err := uc.CreateUser(ctx, name, email, "", nil); err != nil {
+			logger.Error("failed to create user", "inserted", i-1, "error", err)
+			os.Exit(1)
+		}
+		if i%step == 0 || i == *n {
+			fmt.Printf("inserted %d/%d users\n", i, *n)
+		}
+	}
+
+	total, err := uc.CountUsers(ctx, domain.UserFilter{})
+	if err != nil {
+		logger.Error("failed to count users", "error", err)
+		os.Exit(1)
+	}
+	fmt.Printf("done: %d users inserted, %d users in collection %q\n", *n, total, cfg.MongoCollection)
+}
+
+// accents troca letras acentuadas pela versão sem acento (emails ficam em ASCII)
+var accents = strings.NewReplacer("á", "a", "ã", "a", "é", "e", "í", "i", "ó", "o", "ô", "o", "ú", "u", "ç", "c")
+
+// emailPart converte um nome para o formato usado no email (ex: "João" → "joao")
+func emailPart(s string) string {
+	return accents.Replace(strings.ToLower(s))
+}
@@ -0,0 +1,17 @@
+// Package build guarda os metadados da versão compilada
+//
+// Os valores são injetados em tempo de compilação com -ldflags -X:
+//
+//	go build -ldflags "-X user-api/internal/build.Version=1.2.0 \
+//	  -X user-api/internal/build.Commit=$(git rev-parse --short HEAD) \
+//	  -X user-api/internal/build.Time=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/api
+//
+// Sem -ldflags (ex: go run), ficam os valores padrão abaixo
+package build
+
+// IMPORTANTE: -X só funciona com variáveis string (não constantes)
+var (
+	Version = "dev"     // Versão semântica (ex: "1.2.0")
+	Commit  = "unknown" // Hash do commit git
+	Time    = "unknown" // Data/hora da compilação (RFC3339, UTC)
+)
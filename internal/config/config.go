@@ -0,0 +1,704 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================
+// CONFIGURAÇÃO DA APLICAÇÃO
+// ============================================
+// Config reúne TODAS as configurações lidas de variáveis de ambiente
+// Em vez de espalhar os.Getenv() pelo código, lemos tudo uma única vez
+// no início (Load) e passamos os campos tipados para quem precisa
+//
+// VANTAGENS:
+// - Um único lugar para ver quais variáveis a aplicação usa
+// - Tipos corretos (time.Duration, int) em vez de strings soltas
+// - Erros de configuração aparecem na inicialização, não no meio de uma requisição
+type Config struct {
+	Env string // Ambiente de execução: "development" ou "production"
+
+	LogLevel  string // Nível mínimo de log: debug, info, warn, error
+	LogFormat string // Formato do log: json ou text
+
+	Port         string        // Porta HTTP
+	ReadTimeout  time.Duration // Tempo máximo para ler a requisição inteira
+	WriteTimeout time.Duration // Tempo máximo para escrever a resposta
+	IdleTimeout  time.Duration // Tempo máximo de conexões keep-alive ociosas
+
+	TLSCertFile string // Certificado (PEM) para servir HTTPS (vazio = HTTP puro)
+	TLSKeyFile  string // Chave privada (PEM) do certificado
+
+	RequestTimeout time.Duration // Prazo máximo de processamento de cada requisição
+	MaxBodyBytes   int64         // Tamanho máximo do corpo JSON em create/update
+	// Tamanho máximo do corpo de QUALQUER requisição (rede de segurança para rotas que não usam o decodeJSON)
+	MaxRequestBodyBytes int64
+
+	MaxURLPathLength int // Tamanho máximo do caminho da URL, em bytes (0 = sem limite)
+	MaxQueryLength   int // Tamanho máximo da query string, em bytes (0 = sem limite)
+
+	PageDefault int // Tamanho da página quando ?limit= não é informado
+	PageMax     int // Maior ?limit= aceito (valores acima são reduzidos a ele)
+
+	BasePath string // Prefixo sob o qual a API é publicada (ex: "/users-service"), usado nos links de ?hateoas=true
+
+	RateLimitRPS   float64 // Requisições por segundo permitidas por IP (0 = desabilitado)
+	RateLimitBurst int     // Rajada máxima por IP
+	TrustProxy     bool    // Confiar em X-Forwarded-For para descobrir o IP do cliente
+	TrustProxyHops int     // Quantos proxies confiáveis acrescentam o IP ao X-Forwarded-For (com TrustProxy)
+
+	CORSAllowedOrigins string        // Origens liberadas para CORS, separadas por vírgula ("*" = todas; vazio = CORS desabilitado)
+	CORSMaxAge         time.Duration // Por quanto tempo o navegador reaproveita o resultado do preflight
+
+	// Headers de segurança de todas as respostas ("off" desabilita cada um)
+	ContentTypeOptions    string // X-Content-Type-Options
+	FrameOptions          string // X-Frame-Options
+	ReferrerPolicy        string // Referrer-Policy
+	ContentSecurityPolicy string // Content-Security-Policy das rotas da API
+	SwaggerCSP            string // Content-Security-Policy do Swagger UI (precisa de scripts e estilos)
+
+	EmailCheckRateLimitRPS   float64 // Limite próprio (por IP) de GET /api/v1/users/email-available (0 = desabilitado)
+	EmailCheckRateLimitBurst int     // Rajada máxima por IP nesse endpoint
+
+	MaxConcurrentRequests int           // Requisições processadas ao mesmo tempo (0 = sem limite)
+	MaxConcurrentWait     time.Duration // Quanto uma requisição espera por uma vaga antes do 503
+	UserStreamMaxClients  int           // Conexões simultâneas em GET /api/v1/users/stream (SSE)
+
+	// Storage escolhe onde ficam os usuários: "mongo" (padrão) ou "postgres"
+	// Com "postgres", o MongoDB continua guardando idempotência, tokens de verificação e audit log
+	Storage string
+
+	PostgresDSN          string        // DSN do PostgreSQL (obrigatório com STORAGE=postgres)
+	PostgresTable        string        // Tabela de usuários (os emails ficam em "<tabela>$emails")
+	PostgresMaxOpenConns int           // Máximo de conexões abertas no pool do database/sql
+	PostgresOpTimeout    time.Duration // Prazo de cada operação no PostgreSQL
+
+	PostgresConnectMaxAttempts int           // Tentativas de conexão ao PostgreSQL na inicialização
+	PostgresConnectBaseDelay   time.Duration // Espera inicial do backoff exponencial (como MongoConnectBaseDelay)
+
+	MongoURI        string // URI de conexão do MongoDB
+	MongoDB         string // Nome do database
+	MongoCollection string // Nome da collection de usuários
+
+	MongoMaxPoolSize     uint64        // Máximo de conexões no pool (0 = sem limite)
+	MongoMinPoolSize     uint64        // Conexões mantidas abertas mesmo ociosas
+	MongoMaxConnIdleTime time.Duration // Tempo máximo de uma conexão ociosa (0 = sem limite)
+	MongoPoolWaitTimeout time.Duration // Espera máxima por uma conexão livre do pool (0 = até o MONGO_OP_TIMEOUT)
+
+	MongoReadPreference string // primary, primaryPreferred, secondary, secondaryPreferred ou nearest
+	MongoWriteConcern   string // "majority" ou número de membros que confirmam (vazio = padrão do servidor)
+	ReadFromSecondary   bool   // Listagens, buscas e contagens leem de secundários (secondaryPreferred)
+
+	MongoConnectMaxAttempts int           // Tentativas de conexão na inicialização
+	MongoConnectBaseDelay   time.Duration // Espera inicial do backoff exponencial
+	MongoOpTimeout          time.Duration // Prazo de cada operação no MongoDB (limitado também pelo prazo da requisição)
+	MongoRetryMaxAttempts   int           // Tentativas de cada operação em erros passageiros (1 = sem retry)
+	MongoRetryBaseDelay     time.Duration // Espera antes da segunda tentativa (dobra a cada falha)
+	SlowQueryThreshold      time.Duration // Operações do repositório acima disso são registradas em WARN (0 = desabilitado)
+
+	UserCacheSize int           // Usuários guardados no cache em memória do GetByID (0 = cache desabilitado)
+	UserCacheTTL  time.Duration // Por quanto tempo um usuário em cache é usado sem consultar o banco
+	// Com o MongoDB inalcançável, leituras usam o cache expirado há até este tempo (0 = desabilitado)
+	UserCacheStaleTTL time.Duration
+	// Intervalo em que o total de usuários em cache é recontado no banco (0 = GET /users/count sempre conta)
+	CountCacheInterval time.Duration
+
+	JWTSecret string // Secret HS256 usado para validar tokens JWT
+	APIKeys   string // Chaves aceitas no header X-API-Key ("label:chave" separados por vírgula; vazio = desabilitado)
+
+	WebhookURL        string        // URL que recebe os eventos (vazio = desabilitado)
+	WebhookSecret     string        // Secret compartilhado para assinar os eventos
+	WebhookTimeout    time.Duration // Timeout de cada tentativa de entrega
+	WebhookMaxRetries int           // Novas tentativas após falha (5xx ou rede)
+
+	IdempotencyCollection string        // Collection das chaves do header Idempotency-Key
+	IdempotencyTTL        time.Duration // Por quanto tempo uma chave continua válida
+
+	VerificationCollection string        // Collection dos tokens de verificação de email
+	VerificationTokenTTL   time.Duration // Validade de um token de verificação
+
+	AuditEnabled    bool   // Registra criações/atualizações/remoções no audit log
+	AuditCollection string // Collection do audit log
+
+	PurgeInterval  time.Duration // Intervalo do job que apaga usuários removidos (0 = desabilitado)
+	PurgeRetention time.Duration // Tempo que um usuário removido fica guardado antes do purge
+
+	ShutdownTimeout time.Duration // Tempo máximo para terminar as requisições em andamento no encerramento
+	ShutdownDelay   time.Duration // Espera entre o sinal (/readyz passa a 503) e o início do encerramento (0 = imediato)
+
+	EnableAdmin bool // Habilita as rotas /api/v1/admin (somente fora de produção)
+
+	EnableSwagger bool // Registra o Swagger UI em /swagger/ (padrão: ligado fora de produção, desligado em produção)
+
+	DebugBodies         bool // Loga os corpos de requisição e resposta em DEBUG (somente fora de produção)
+	DebugBodiesMaxBytes int  // Quanto de cada corpo vai para o log; o resto é cortado
+	// Headers escondidos nos logs além dos padrão (Authorization, Cookie, X-API-Key...), separados por vírgula
+	LogRedactHeaders string
+
+	MultiTenant bool // Exige o header X-Tenant-ID nas rotas da API; cada tenant tem a sua collection
+
+	OTLPEndpoint string // Coletor OTLP que recebe os traces (vazio = tracing desabilitado)
+	ServiceName  string // Nome do serviço exibido nos traces
+}
+
+// validReadPreferences lista os modos aceitos em MONGO_READ_PREFERENCE (em minúsculas)
+// Validar aqui faz um valor errado derrubar a aplicação na inicialização,
+// antes mesmo das tentativas de conexão
+var validReadPreferences = map[string]bool{
+	"primary":            true,
+	"primarypreferred":   true,
+	"secondary":          true,
+	"secondarypreferred": true,
+	"nearest":            true,
+}
+
+// devJWTSecret é usado apenas fora de produção quando JWT_SECRET não é definido
+const devJWTSecret = "dev-secret"
+
+// Load lê as variáveis de ambiente, aplica valores padrão e valida o resultado
+// Retorna erro quando algum valor é inválido ou obrigatório e está ausente
+func Load() (*Config, error) {
+	cfg := &Config{
+		Env:             getEnv("APP_ENV", "development"),
+		LogLevel:        getEnv("LOG_LEVEL", "info"),
+		LogFormat:       getEnv("LOG_FORMAT", "json"),
+		Port:            getEnv("PORT", "8082"),
+		TLSCertFile:     os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:      os.Getenv("TLS_KEY_FILE"),
+		Storage:         strings.ToLower(getEnv("STORAGE", StorageMongo)),
+		PostgresDSN:     os.Getenv("POSTGRES_DSN"),
+		PostgresTable:   getEnv("POSTGRES_TABLE", "users"),
+		MongoURI:        getEnv("MONGO_URI", "mongodb://localhost:27017"),
+		MongoDB:         getEnv("MONGO_DB", "userdb"),
+		MongoCollection: getEnv("MONGO_COLLECTION", "users"),
+		JWTSecret:       os.Getenv("JWT_SECRET"),
+		APIKeys:         os.Getenv("API_KEYS"),
+		WebhookURL:      os.Getenv("WEBHOOK_URL"),
+		WebhookSecret:   os.Getenv("WEBHOOK_SECRET"),
+		BasePath:        os.Getenv("BASE_PATH"),
+
+		MongoReadPreference: getEnv("MONGO_READ_PREFERENCE", "primary"),
+		MongoWriteConcern:   os.Getenv("MONGO_WRITE_CONCERN"),
+
+		IdempotencyCollection:  getEnv("IDEMPOTENCY_COLLECTION", "idempotency_keys"),
+		VerificationCollection: getEnv("VERIFICATION_COLLECTION", "verification_tokens"),
+		AuditCollection:        getEnv("AUDIT_COLLECTION", "audit"),
+
+		CORSAllowedOrigins: os.Getenv("CORS_ALLOWED_ORIGINS"),
+		LogRedactHeaders:   os.Getenv("LOG_REDACT_HEADERS"),
+
+		ContentTypeOptions:    getHeaderValue("SECURITY_CONTENT_TYPE_OPTIONS", "nosniff"),
+		FrameOptions:          getHeaderValue("SECURITY_FRAME_OPTIONS", "DENY"),
+		ReferrerPolicy:        getHeaderValue("SECURITY_REFERRER_POLICY", "no-referrer"),
+		ContentSecurityPolicy: getHeaderValue("SECURITY_CSP", "default-src 'none'; frame-ancestors 'none'"),
+		SwaggerCSP:            getHeaderValue("SECURITY_SWAGGER_CSP", "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; frame-ancestors 'none'"),
+
+		// Nomes padrão do OpenTelemetry: as mesmas variáveis funcionam em qualquer SDK
+		OTLPEndpoint: os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		ServiceName:  getEnv("OTEL_SERVICE_NAME", "user-api"),
+	}
+
+	var err error
+	if cfg.ReadTimeout, err = getDuration("READ_TIMEOUT", 10*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.WriteTimeout, err = getDuration("WRITE_TIMEOUT", 10*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.IdleTimeout, err = getDuration("IDLE_TIMEOUT", 60*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.RequestTimeout, err = getDuration("REQUEST_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.MaxBodyBytes, err = getInt64("MAX_BODY_BYTES", 1<<20); err != nil {
+		return nil, err
+	}
+	// Sem a variável, o limite global é o mesmo do corpo JSON
+	if cfg.MaxRequestBodyBytes, err = getInt64("MAX_REQUEST_BODY_BYTES", cfg.MaxBodyBytes); err != nil {
+		return nil, err
+	}
+	if cfg.MaxURLPathLength, err = getInt("MAX_URL_PATH_LENGTH", 1024); err != nil {
+		return nil, err
+	}
+	if cfg.MaxQueryLength, err = getInt("MAX_QUERY_LENGTH", 4096); err != nil {
+		return nil, err
+	}
+	if cfg.PageDefault, err = getInt("PAGE_DEFAULT", 20); err != nil {
+		return nil, err
+	}
+	if cfg.PageMax, err = getInt("PAGE_MAX", 100); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimitRPS, err = getFloat("RATE_LIMIT_RPS", 10); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimitBurst, err = getInt("RATE_LIMIT_BURST", 20); err != nil {
+		return nil, err
+	}
+	if cfg.EmailCheckRateLimitRPS, err = getFloat("EMAIL_CHECK_RATE_LIMIT_RPS", 1); err != nil {
+		return nil, err
+	}
+	if cfg.EmailCheckRateLimitBurst, err = getInt("EMAIL_CHECK_RATE_LIMIT_BURST", 5); err != nil {
+		return nil, err
+	}
+	if cfg.MaxConcurrentRequests, err = getInt("MAX_CONCURRENT_REQUESTS", 200); err != nil {
+		return nil, err
+	}
+	if cfg.MaxConcurrentWait, err = getDuration("MAX_CONCURRENT_WAIT", 100*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if cfg.UserStreamMaxClients, err = getInt("USER_STREAM_MAX_CLIENTS", 100); err != nil {
+		return nil, err
+	}
+	if cfg.TrustProxy, err = getBool("TRUST_PROXY", false); err != nil {
+		return nil, err
+	}
+	if cfg.TrustProxyHops, err = getInt("TRUST_PROXY_HOPS", 1); err != nil {
+		return nil, err
+	}
+	if cfg.PostgresMaxOpenConns, err = getInt("POSTGRES_MAX_OPEN_CONNS", 25); err != nil {
+		return nil, err
+	}
+	if cfg.PostgresOpTimeout, err = getDuration("POSTGRES_OP_TIMEOUT", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.PostgresConnectMaxAttempts, err = getInt("POSTGRES_CONNECT_MAX_ATTEMPTS", 5); err != nil {
+		return nil, err
+	}
+	if cfg.PostgresConnectBaseDelay, err = getDuration("POSTGRES_CONNECT_BASE_DELAY", time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.MongoConnectMaxAttempts, err = getInt("MONGO_CONNECT_MAX_ATTEMPTS", 5); err != nil {
+		return nil, err
+	}
+	if cfg.MongoConnectBaseDelay, err = getDuration("MONGO_CONNECT_BASE_DELAY", time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.MongoOpTimeout, err = getDuration("MONGO_OP_TIMEOUT", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.MongoRetryMaxAttempts, err = getInt("MONGO_RETRY_MAX_ATTEMPTS", 3); err != nil {
+		return nil, err
+	}
+	if cfg.MongoRetryBaseDelay, err = getDuration("MONGO_RETRY_BASE_DELAY", 100*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if cfg.CORSMaxAge, err = getDuration("CORS_MAX_AGE", 600*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.SlowQueryThreshold, err = getDuration("SLOW_QUERY_THRESHOLD", 200*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if cfg.UserCacheSize, err = getInt("USER_CACHE_SIZE", 0); err != nil {
+		return nil, err
+	}
+	if cfg.UserCacheTTL, err = getDuration("USER_CACHE_TTL", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.UserCacheStaleTTL, err = getDuration("USER_CACHE_STALE_TTL", 0); err != nil {
+		return nil, err
+	}
+	if cfg.CountCacheInterval, err = getDuration("COUNT_CACHE_INTERVAL", 0); err != nil {
+		return nil, err
+	}
+
+	// Padrões iguais aos do driver: sem as variáveis, nada muda
+	if cfg.MongoMaxPoolSize, err = getUint64("MONGO_MAX_POOL_SIZE", 100); err != nil {
+		return nil, err
+	}
+	if cfg.MongoMinPoolSize, err = getUint64("MONGO_MIN_POOL_SIZE", 0); err != nil {
+		return nil, err
+	}
+	if cfg.MongoMaxConnIdleTime, err = getDuration("MONGO_MAX_CONN_IDLE_TIME", 0); err != nil {
+		return nil, err
+	}
+	if cfg.MongoPoolWaitTimeout, err = getDuration("MONGO_POOL_WAIT_TIMEOUT", 0); err != nil {
+		return nil, err
+	}
+
+	if cfg.WebhookTimeout, err = getDuration("WEBHOOK_TIMEOUT", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.WebhookMaxRetries, err = getInt("WEBHOOK_MAX_RETRIES", 3); err != nil {
+		return nil, err
+	}
+
+	if cfg.IdempotencyTTL, err = getDuration("IDEMPOTENCY_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.VerificationTokenTTL, err = getDuration("VERIFICATION_TOKEN_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.AuditEnabled, err = getBool("AUDIT_ENABLED", false); err != nil {
+		return nil, err
+	}
+
+	if cfg.PurgeInterval, err = getDuration("PURGE_INTERVAL", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.PurgeRetention, err = getDuration("PURGE_RETENTION", 30*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.ShutdownTimeout, err = getDuration("SHUTDOWN_TIMEOUT", 10*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.ShutdownDelay, err = getDuration("SHUTDOWN_DELAY", 0); err != nil {
+		return nil, err
+	}
+	if cfg.EnableAdmin, err = getBool("ENABLE_ADMIN", false); err != nil {
+		return nil, err
+	}
+	// O padrão depende do ambiente: em produção a documentação interativa só expõe a API
+	if cfg.EnableSwagger, err = getBool("ENABLE_SWAGGER", !cfg.IsProduction()); err != nil {
+		return nil, err
+	}
+	if cfg.MultiTenant, err = getBool("MULTI_TENANT", false); err != nil {
+		return nil, err
+	}
+	if cfg.DebugBodies, err = getBool("DEBUG_BODIES", false); err != nil {
+		return nil, err
+	}
+	if cfg.DebugBodiesMaxBytes, err = getInt("DEBUG_BODIES_MAX_BYTES", 4096); err != nil {
+		return nil, err
+	}
+	if cfg.ReadFromSecondary, err = getBool("READ_FROM_SECONDARY", false); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// IsProduction indica se a aplicação está rodando em produção
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+// TLSEnabled indica se a API deve servir HTTPS (certificado e chave configurados)
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// validate verifica valores obrigatórios e aplica regras por ambiente
+// Em produção não aceitamos o secret de desenvolvimento: o erro é explícito
+func (c *Config) validate() error {
+	if c.Port == "" {
+		return errors.New("config: PORT must not be empty")
+	}
+	// Só um dos dois quase sempre é engano: sem este erro, a API subiria em HTTP sem avisar
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("config: TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	switch c.Storage {
+	case StorageMongo:
+	case StoragePostgres:
+		if c.PostgresDSN == "" {
+			return errors.New("config: POSTGRES_DSN is required when STORAGE=postgres")
+		}
+		if c.PostgresTable == "" {
+			return errors.New("config: POSTGRES_TABLE must not be empty")
+		}
+		if c.PostgresMaxOpenConns < 1 {
+			return errors.New("config: POSTGRES_MAX_OPEN_CONNS must be at least 1")
+		}
+		if c.PostgresOpTimeout <= 0 {
+			return errors.New("config: POSTGRES_OP_TIMEOUT must be positive")
+		}
+		if c.PostgresConnectMaxAttempts < 1 {
+			return errors.New("config: POSTGRES_CONNECT_MAX_ATTEMPTS must be at least 1")
+		}
+		if c.PostgresConnectBaseDelay <= 0 {
+			return errors.New("config: POSTGRES_CONNECT_BASE_DELAY must be positive")
+		}
+		// Listagens nos secundários é um recurso do replica set do MongoDB
+		if c.ReadFromSecondary {
+			return errors.New("config: READ_FROM_SECONDARY requires STORAGE=mongo")
+		}
+	default:
+		return fmt.Errorf("config: invalid STORAGE %q (use mongo or postgres)", c.Storage)
+	}
+	if c.MongoURI == "" {
+		return errors.New("config: MONGO_URI must not be empty")
+	}
+	if c.MongoDB == "" {
+		return errors.New("config: MONGO_DB must not be empty")
+	}
+	if c.MongoCollection == "" {
+		return errors.New("config: MONGO_COLLECTION must not be empty")
+	}
+	if c.RequestTimeout <= 0 {
+		return errors.New("config: REQUEST_TIMEOUT must be positive")
+	}
+	if c.MaxBodyBytes <= 0 {
+		return errors.New("config: MAX_BODY_BYTES must be positive")
+	}
+	// Um limite global menor reduziria o MAX_BODY_BYTES sem avisar
+	if c.MaxRequestBodyBytes < c.MaxBodyBytes {
+		return errors.New("config: MAX_REQUEST_BODY_BYTES must be at least MAX_BODY_BYTES")
+	}
+	if c.MaxURLPathLength < 0 {
+		return errors.New("config: MAX_URL_PATH_LENGTH must not be negative")
+	}
+	if c.MaxQueryLength < 0 {
+		return errors.New("config: MAX_QUERY_LENGTH must not be negative")
+	}
+	if c.PageDefault < 1 {
+		return errors.New("config: PAGE_DEFAULT must be at least 1")
+	}
+	if c.PageDefault > c.PageMax {
+		return errors.New("config: PAGE_DEFAULT must not be greater than PAGE_MAX")
+	}
+	if c.BasePath != "" && (!strings.HasPrefix(c.BasePath, "/") || strings.HasSuffix(c.BasePath, "/")) {
+		return errors.New("config: BASE_PATH must start with / and must not end with /")
+	}
+	if c.TrustProxy && c.TrustProxyHops < 1 {
+		return errors.New("config: TRUST_PROXY_HOPS must be at least 1 when TRUST_PROXY is set")
+	}
+	if c.RateLimitRPS < 0 {
+		return errors.New("config: RATE_LIMIT_RPS must not be negative")
+	}
+	if c.RateLimitRPS > 0 && c.RateLimitBurst < 1 {
+		return errors.New("config: RATE_LIMIT_BURST must be at least 1")
+	}
+	if c.EmailCheckRateLimitRPS < 0 {
+		return errors.New("config: EMAIL_CHECK_RATE_LIMIT_RPS must not be negative")
+	}
+	if c.EmailCheckRateLimitRPS > 0 && c.EmailCheckRateLimitBurst < 1 {
+		return errors.New("config: EMAIL_CHECK_RATE_LIMIT_BURST must be at least 1")
+	}
+	if c.MaxConcurrentRequests < 0 {
+		return errors.New("config: MAX_CONCURRENT_REQUESTS must not be negative")
+	}
+	if c.MaxConcurrentWait < 0 {
+		return errors.New("config: MAX_CONCURRENT_WAIT must not be negative")
+	}
+	if c.UserStreamMaxClients < 1 {
+		return errors.New("config: USER_STREAM_MAX_CLIENTS must be at least 1")
+	}
+	if c.MongoConnectMaxAttempts < 1 {
+		return errors.New("config: MONGO_CONNECT_MAX_ATTEMPTS must be at least 1")
+	}
+	if c.MongoConnectBaseDelay <= 0 {
+		return errors.New("config: MONGO_CONNECT_BASE_DELAY must be positive")
+	}
+	if c.MongoOpTimeout <= 0 {
+		return errors.New("config: MONGO_OP_TIMEOUT must be positive")
+	}
+	if c.MongoRetryMaxAttempts < 1 {
+		return errors.New("config: MONGO_RETRY_MAX_ATTEMPTS must be at least 1")
+	}
+	if c.MongoRetryBaseDelay < 0 {
+		return errors.New("config: MONGO_RETRY_BASE_DELAY must not be negative")
+	}
+	if c.CORSMaxAge < 0 {
+		return errors.New("config: CORS_MAX_AGE must not be negative")
+	}
+	if c.SlowQueryThreshold < 0 {
+		return errors.New("config: SLOW_QUERY_THRESHOLD must not be negative")
+	}
+	if c.UserCacheSize < 0 {
+		return errors.New("config: USER_CACHE_SIZE must not be negative")
+	}
+	if c.UserCacheSize > 0 && c.UserCacheTTL <= 0 {
+		return errors.New("config: USER_CACHE_TTL must be positive")
+	}
+	if c.UserCacheStaleTTL < 0 {
+		return errors.New("config: USER_CACHE_STALE_TTL must not be negative")
+	}
+	if c.UserCacheStaleTTL > 0 && c.UserCacheSize == 0 {
+		return errors.New("config: USER_CACHE_STALE_TTL requires USER_CACHE_SIZE > 0")
+	}
+	if c.CountCacheInterval < 0 {
+		return errors.New("config: COUNT_CACHE_INTERVAL must not be negative")
+	}
+	// O total em cache é o da collection padrão: com um tenant por collection, não haveria o que guardar
+	if c.CountCacheInterval > 0 && c.MultiTenant {
+		return errors.New("config: COUNT_CACHE_INTERVAL can't be used with MULTI_TENANT")
+	}
+	if c.MongoMaxPoolSize > 0 && c.MongoMinPoolSize > c.MongoMaxPoolSize {
+		return errors.New("config: MONGO_MIN_POOL_SIZE must not exceed MONGO_MAX_POOL_SIZE")
+	}
+	if c.MongoMaxConnIdleTime < 0 {
+		return errors.New("config: MONGO_MAX_CONN_IDLE_TIME must not be negative")
+	}
+	if c.MongoPoolWaitTimeout < 0 {
+		return errors.New("config: MONGO_POOL_WAIT_TIMEOUT must not be negative")
+	}
+	if !validReadPreferences[strings.ToLower(c.MongoReadPreference)] {
+		return fmt.Errorf("config: invalid MONGO_READ_PREFERENCE %q (use primary, primaryPreferred, secondary, secondaryPreferred or nearest)", c.MongoReadPreference)
+	}
+	// READ_FROM_SECONDARY separa as leituras: listagens nos secundários, o resto no primário
+	// Com MONGO_READ_PREFERENCE já mandando tudo para secundários, não haveria o que separar
+	if c.ReadFromSecondary {
+		if mode := strings.ToLower(c.MongoReadPreference); mode != "primary" && mode != "primarypreferred" {
+			return fmt.Errorf("config: READ_FROM_SECONDARY requires MONGO_READ_PREFERENCE primary or primaryPreferred, got %q", c.MongoReadPreference)
+		}
+	}
+	if c.MongoWriteConcern != "" && c.MongoWriteConcern != "majority" {
+		if n, err := strconv.Atoi(c.MongoWriteConcern); err != nil || n < 0 {
+			return fmt.Errorf("config: invalid MONGO_WRITE_CONCERN %q (use majority or a number)", c.MongoWriteConcern)
+		}
+	}
+
+	if c.WebhookURL != "" && c.WebhookSecret == "" {
+		return errors.New("config: WEBHOOK_SECRET is required when WEBHOOK_URL is set")
+	}
+	if c.WebhookMaxRetries < 0 {
+		return errors.New("config: WEBHOOK_MAX_RETRIES must not be negative")
+	}
+
+	// O índice TTL do MongoDB trabalha em segundos inteiros
+	if c.IdempotencyTTL < time.Second {
+		return errors.New("config: IDEMPOTENCY_TTL must be at least 1s")
+	}
+	if c.VerificationTokenTTL < time.Minute {
+		return errors.New("config: VERIFICATION_TOKEN_TTL must be at least 1m")
+	}
+	if c.AuditEnabled && c.AuditCollection == "" {
+		return errors.New("config: AUDIT_COLLECTION must not be empty when AUDIT_ENABLED is set")
+	}
+
+	if c.PurgeInterval < 0 {
+		return errors.New("config: PURGE_INTERVAL must not be negative")
+	}
+	if c.PurgeRetention < 0 {
+		return errors.New("config: PURGE_RETENTION must not be negative")
+	}
+	if c.ShutdownTimeout <= 0 {
+		return errors.New("config: SHUTDOWN_TIMEOUT must be positive")
+	}
+	if c.ShutdownDelay < 0 {
+		return errors.New("config: SHUTDOWN_DELAY must not be negative")
+	}
+
+	// As rotas de admin apagam dados: nunca em produção, nem por engano
+	if c.EnableAdmin && c.IsProduction() {
+		return errors.New("config: ENABLE_ADMIN must not be enabled in production")
+	}
+	// Corpos têm dados pessoais (emails, telefones): nunca no log de produção
+	if c.DebugBodies && c.IsProduction() {
+		return errors.New("config: DEBUG_BODIES must not be enabled in production")
+	}
+	if c.DebugBodiesMaxBytes < 1 {
+		return errors.New("config: DEBUG_BODIES_MAX_BYTES must be at least 1")
+	}
+
+	if c.JWTSecret == "" {
+		if c.IsProduction() {
+			return errors.New("config: JWT_SECRET is required in production")
+		}
+		c.JWTSecret = devJWTSecret
+	}
+
+	return nil
+}
+
+// Valores aceitos em STORAGE
+const (
+	StorageMongo    = "mongo"
+	StoragePostgres = "postgres"
+)
+
+// getEnv retorna o valor da variável ou o padrão quando ela não existe
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// getHeaderValue lê o valor de um header de resposta
+// Variável vazia usa o padrão (como getEnv); "off" desabilita o header (devolve "")
+func getHeaderValue(key, def string) string {
+	if v := getEnv(key, def); !strings.EqualFold(v, "off") {
+		return v
+	}
+	return ""
+}
+
+// getDuration lê uma duração no formato do Go (ex: "5s", "250ms", "1m")
+func getDuration(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("config: invalid %s %q: %w", key, v, err)
+	}
+	return d, nil
+}
+
+// getInt lê um número inteiro (ex: "5")
+func getInt(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("config: invalid %s %q: %w", key, v, err)
+	}
+	return n, nil
+}
+
+// getInt64 lê um número inteiro de 64 bits (ex: tamanhos em bytes)
+func getInt64(key string, def int64) (int64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("config: invalid %s %q: %w", key, v, err)
+	}
+	return n, nil
+}
+
+// getUint64 lê um número inteiro não negativo (ex: tamanhos de pool)
+func getUint64(key string, def uint64) (uint64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("config: invalid %s %q: %w", key, v, err)
+	}
+	return n, nil
+}
+
+// getFloat lê um número decimal (ex: "2.5")
+func getFloat(key string, def float64) (float64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("config: invalid %s %q: %w", key, v, err)
+	}
+	return f, nil
+}
+
+// getBool lê um booleano ("true", "false", "1", "0")
+func getBool(key string, def bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("config: invalid %s %q: %w", key, v, err)
+	}
+	return b, nil
+}
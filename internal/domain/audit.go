@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ============================================
+// AUDITORIA
+// ============================================
+// O audit log registra QUEM alterou QUAL usuário, COMO e QUANDO
+//
+// DIFERENÇA PARA OS EVENTOS (event.go):
+// - Eventos avisam outros sistemas e podem ser descartados (NoopPublisher)
+// - O audit log é um histórico consultável (GET /api/v1/users/{id}/audit), guardado para compliance
+// - Por isso cada entrada traz o autor (actor) e, nas atualizações, o que mudou
+
+// AuditOperation identifica o tipo de alteração registrada
+type AuditOperation string
+
+// Operações registradas no audit log
+const (
+	AuditCreate AuditOperation = "create"
+	AuditUpdate AuditOperation = "update"
+	AuditDelete AuditOperation = "delete"
+)
+
+// AuditEntry é uma alteração registrada no audit log
+type AuditEntry struct {
+	ID         string         `json:"id"`
+	UserID     string         `json:"user_id"`           // Usuário alterado
+	Operation  AuditOperation `json:"operation"`         // create, update ou delete
+	Actor      string         `json:"actor,omitempty"`   // Quem fez a alteração (claim "sub" do JWT); vazio sem autenticação
+	Changes    UserChanges    `json:"changes,omitempty"` // Só em updates: valor antigo e novo de cada campo
+	OccurredAt time.Time      `json:"occurred_at"`       // Quando aconteceu (UTC)
+}
+
+// AuditLogger define o contrato para gravar e consultar o audit log
+// Implementações: MongoDB (repository.AuditMongoStore) e no-op (audit desabilitado)
+type AuditLogger interface {
+	// Record grava uma entrada; entry.ID é preenchido pela implementação
+	Record(ctx context.Context, entry *AuditEntry) error
+
+	// ListByUser retorna as limit entradas mais recentes do usuário, da mais nova para a mais antiga
+	ListByUser(ctx context.Context, userID string, limit int) ([]*AuditEntry, error)
+}
+
+// ============================================
+// AUTOR DA REQUISIÇÃO NO CONTEXT
+// ============================================
+// O middleware de autenticação (camada HTTP) guarda no context quem fez a requisição
+// O usecase lê daqui, sem depender do pacote HTTP nem do JWT
+
+// actorKey é a chave do autor no context (tipo não exportado evita colisões)
+type actorKey struct{}
+
+// ContextWithActor devolve um context que carrega o ID de quem fez a requisição
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext retorna o autor guardado por ContextWithActor
+// O segundo retorno é false quando a requisição não foi autenticada
+func ActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// ============================================
+// CONTAGEM EM CACHE NO CONTEXT
+// ============================================
+// Com COUNT_CACHE_INTERVAL, o total de usuários pode vir de um valor guardado em memória,
+// recontado periodicamente, em vez de um CountDocuments a cada chamada
+// Quem sabe disso é o repositório; quem informa o cliente ("cached" e "as_of") é o handler HTTP
+// Mesmo arranjo da marca de resposta desatualizada (ver stale.go):
+// - O handler cria a marca (ContextWithCountTracking) antes de chamar o usecase
+// - O repositório a preenche (MarkCountCached) quando responde do cache
+// - O handler consulta (CountCachedAt) antes de escrever a resposta
+//
+// Sem ContextWithCountTracking (jobs, listagens com envelope), MarkCountCached não faz nada
+
+// countKey é a chave da marca no context (tipo não exportado evita colisões)
+type countKey struct{}
+
+// ContextWithCountTracking devolve um context com uma marca de "contagem em cache", ainda vazia
+func ContextWithCountTracking(ctx context.Context) context.Context {
+	return context.WithValue(ctx, countKey{}, new(atomic.Pointer[time.Time]))
+}
+
+// MarkCountCached registra que a contagem veio do cache, recontada no banco em asOf
+func MarkCountCached(ctx context.Context, asOf time.Time) {
+	if mark, ok := ctx.Value(countKey{}).(*atomic.Pointer[time.Time]); ok {
+		mark.Store(&asOf)
+	}
+}
+
+// CountCachedAt informa se MarkCountCached foi chamado neste context e com qual asOf
+func CountCachedAt(ctx context.Context) (time.Time, bool) {
+	mark, ok := ctx.Value(countKey{}).(*atomic.Pointer[time.Time])
+	if !ok {
+		return time.Time{}, false
+	}
+	asOf := mark.Load()
+	if asOf == nil {
+		return time.Time{}, false
+	}
+	return *asOf, true
+}
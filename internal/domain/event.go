@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ============================================
+// EVENTOS DE DOMÍNIO
+// ============================================
+// Eventos avisam o "resto do mundo" que algo aconteceu com um usuário
+// O usecase publica o evento depois que a operação foi persistida
+//
+// POR QUE EVENTOS?
+// - Outros sistemas (Kafka, NATS, webhooks) reagem sem o usecase conhecê-los
+// - Adicionar um novo consumidor não exige mudar a lógica de negócio
+
+// EventType identifica o tipo do evento
+type EventType string
+
+// Tipos de evento do ciclo de vida do usuário
+const (
+	UserCreated EventType = "user.created"
+	UserUpdated EventType = "user.updated"
+	UserDeleted EventType = "user.deleted"
+)
+
+// Event é a mensagem publicada para cada mudança de um usuário
+type Event struct {
+	Type       EventType `json:"type"`        // O que aconteceu
+	UserID     string    `json:"user_id"`     // Usuário afetado
+	OccurredAt time.Time `json:"occurred_at"` // Quando aconteceu (UTC)
+}
+
+// EventPublisher define o contrato para publicar eventos
+// Implementações possíveis: no-op (padrão), webhook, Kafka, NATS...
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// ============================================
+// ALTERAÇÕES EM TEMPO REAL (CHANGE STREAM)
+// ============================================
+// Os eventos acima são publicados pelo usecase DESTE processo
+// UserChangeStream vem do próprio banco: vê as alterações de qualquer instância (e de scripts)
+// É o que alimenta o GET /api/v1/users/stream (Server-Sent Events)
+
+// UserChange é uma alteração de usuário vista no banco
+// Type reaproveita os tipos de evento: UserCreated, UserUpdated ou UserDeleted (soft delete)
+type UserChange struct {
+	Type EventType
+	User *User // O usuário como ficou depois da alteração
+}
+
+// UserChangeStream entrega as alterações uma a uma, na ordem em que aconteceram
+// Mesmo uso de um cursor: for s.Next(ctx) { s.Change() }; depois, s.Err() e s.Close(ctx)
+type UserChangeStream interface {
+	// Next espera a próxima alteração; false quando o stream acabou (ctx cancelado ou erro)
+	Next(ctx context.Context) bool
+	// Change é a alteração trazida pelo último Next
+	Change() UserChange
+	// Err é o erro que encerrou o stream (com ctx cancelado, o próprio erro do context)
+	Err() error
+	// Close libera o stream no banco
+	Close(ctx context.Context) error
+}
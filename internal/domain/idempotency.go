@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ============================================
+// IDEMPOTÊNCIA
+// ============================================
+// Uma operação idempotente pode ser repetida sem efeito extra
+// POST não é idempotente: se a rede cair depois que o servidor criou o usuário,
+// o cliente tenta de novo e cria um DUPLICADO
+//
+// A SOLUÇÃO:
+// - O cliente envia um header Idempotency-Key (ex: um UUID) em cada POST
+// - Na primeira vez, guardamos a chave junto com o ID do usuário criado
+// - Numa repetição com a mesma chave, devolvemos o usuário original
+
+// IdempotencyRecord é o que guardamos para cada chave
+type IdempotencyRecord struct {
+	Key         string    // Valor do header Idempotency-Key
+	RequestHash string    // Hash do corpo: detecta a mesma chave com corpo diferente
+	UserID      string    // Usuário criado (vazio enquanto a requisição ainda está em andamento)
+	CreatedAt   time.Time // Quando a chave foi usada pela primeira vez
+}
+
+// IdempotencyStore define o contrato para guardar as chaves
+// Implementações possíveis: MongoDB com TTL (padrão), Redis, memória...
+type IdempotencyStore interface {
+	// Reserve tenta registrar a chave como "em andamento"
+	// Retorna nil quando a chave é nova (a reserva foi feita)
+	// Retorna o registro existente quando a chave já foi usada
+	Reserve(ctx context.Context, key, requestHash string) (*IdempotencyRecord, error)
+
+	// Complete associa o usuário criado à chave reservada
+	Complete(ctx context.Context, key, userID string) error
+
+	// Release desfaz a reserva (ex: a criação falhou e o cliente pode tentar de novo)
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// ============================================
+// RESPOSTA DESATUALIZADA (STALE) NO CONTEXT
+// ============================================
+// Com USER_CACHE_STALE_TTL, o cache pode responder uma leitura com dados antigos quando o MongoDB está fora
+// Quem decide isso é o repositório; quem precisa avisar o cliente (header Warning) é o handler HTTP
+// O context liga os dois sem que o repositório conheça o pacote HTTP:
+// - O handler cria a marca (ContextWithStaleTracking) antes de chamar o usecase
+// - O repositório a liga (MarkStale) quando responde do cache desatualizado
+// - O handler consulta (IsStale) antes de escrever a resposta
+//
+// Sem ContextWithStaleTracking (jobs, startup), MarkStale não faz nada
+
+// staleKey é a chave da marca no context (tipo não exportado evita colisões)
+type staleKey struct{}
+
+// ContextWithStaleTracking devolve um context com uma marca de "resposta desatualizada", ainda desligada
+func ContextWithStaleTracking(ctx context.Context) context.Context {
+	return context.WithValue(ctx, staleKey{}, new(atomic.Bool))
+}
+
+// MarkStale liga a marca: algum dado desta requisição veio de um cache desatualizado
+func MarkStale(ctx context.Context) {
+	if stale, ok := ctx.Value(staleKey{}).(*atomic.Bool); ok {
+		stale.Store(true)
+	}
+}
+
+// IsStale informa se MarkStale foi chamado neste context
+func IsStale(ctx context.Context) bool {
+	stale, ok := ctx.Value(staleKey{}).(*atomic.Bool)
+	return ok && stale.Load()
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "context"
+
+// ============================================
+// TENANT NO CONTEXT (MULTI-TENANCY)
+// ============================================
+// Com MULTI_TENANT=true, cada requisição da API pertence a um tenant (cliente isolado)
+// O middleware HTTP lê o tenant do header X-Tenant-ID e o guarda no context
+// O repositório lê daqui para escolher a collection, sem depender do pacote HTTP
+//
+// Sem tenant no context (MULTI_TENANT=false, jobs, startup) vale a collection padrão
+
+// tenantKey é a chave do tenant no context (tipo não exportado evita colisões)
+type tenantKey struct{}
+
+// ContextWithTenant devolve um context que carrega o ID do tenant
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext retorna o tenant guardado por ContextWithTenant
+// O segundo retorno é false quando a requisição não tem tenant
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok && tenant != ""
+}
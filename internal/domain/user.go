@@ -1,5 +1,14 @@
 package domain
 
+import (
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // ============================================
 // ENTIDADE DE DOMÍNIO
 // ============================================
@@ -17,7 +26,257 @@ package domain
 type User struct {
 	ID    string `json:"id"`    // Identificador único (hex do ObjectID do MongoDB)
 	Name  string `json:"name"`  // Nome completo do usuário
-	Email string `json:"email"`  // Email (deve conter '@')
+	Email string `json:"email"` // Email principal (deve conter '@')
+
+	// Emails lista todos os endereços do usuário; exatamente um é o principal
+	// Email (acima) continua existindo e é sempre igual ao principal:
+	// clientes antigos seguem funcionando sem conhecer a lista
+	Emails []EmailAddress `json:"emails,omitempty"`
+
+	// PendingEmail é o novo email principal pedido em POST /api/v1/users/{id}/change-email
+	// Só vira o principal (e verificado) quando o token enviado a ele for confirmado;
+	// até lá o email atual continua valendo. Vazio = nenhuma troca pendente
+	PendingEmail string `json:"pending_email,omitempty"`
+
+	// Phone é opcional, no formato E.164 (ex: +5511987654321)
+	// omitempty: quando vazio, o campo nem aparece no JSON
+	Phone string `json:"phone,omitempty"`
+
+	// Verified indica se o email principal foi confirmado (POST /api/v1/users/verify)
+	// Usuários não verificados aparecem normalmente nas listagens, com verified=false
+	Verified bool `json:"verified"`
+
+	// Metadata guarda atributos livres definidos por cada consumidor da API
+	// Ex: {"crm_id": "123", "plan": "premium"} - sem mudar o schema a cada novo atributo
+	// Os limites (quantidade de chaves, tamanho) são validados no usecase
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Version é incrementado a cada atualização (optimistic locking)
+	// O cliente envia a versão que leu; se outro cliente atualizou antes,
+	// a versão não bate mais e a atualização é rejeitada com conflito
+	Version int `json:"version"`
+
+	// LoginCount conta os logins do usuário (RecordLogin)
+	// É um contador: muda por incremento atômico (IncrementField), nunca pelo PUT
+	LoginCount int64 `json:"login_count"`
+
+	CreatedAt time.Time `json:"created_at"` // Data de criação (UTC)
+
+	// UpdatedAt é a data da última escrita (UTC); na criação, igual a CreatedAt
+	// Usado na sincronização incremental (GET /api/v1/users?updatedSince=...)
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EmailAddress é um dos endereços de email do usuário
+type EmailAddress struct {
+	Address string `json:"address"`
+	Primary bool   `json:"primary"` // true no endereço principal (espelhado em User.Email)
+}
+
+// HasEmail informa se o endereço já está na lista do usuário
+func (u *User) HasEmail(address string) bool {
+	for _, e := range u.Emails {
+		if e.Address == address {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPrimaryEmail define o email principal, mantendo Email e Emails consistentes
+// - Endereço já na lista: ele passa a ser o principal
+// - Endereço novo: SUBSTITUI o principal atual (é o que "alterar o email" sempre fez)
+func (u *User) SetPrimaryEmail(address string) {
+	if !u.HasEmail(address) {
+		replaced := false
+		for i := range u.Emails {
+			if u.Emails[i].Primary {
+				u.Emails[i].Address = address
+				replaced = true
+			}
+		}
+		if !replaced {
+			u.Emails = append(u.Emails, EmailAddress{Address: address})
+		}
+	}
+
+	for i := range u.Emails {
+		u.Emails[i].Primary = u.Emails[i].Address == address
+	}
+	u.Email = address
+}
+
+// ============================================
+// DIFERENÇA ENTRE VERSÕES
+// ============================================
+// FieldChange guarda o valor de um campo antes e depois de uma atualização
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// UserChanges mapeia o nome do campo (o mesmo do JSON) para o que mudou nele
+// Campos que não mudaram não aparecem
+type UserChanges map[string]FieldChange
+
+// Clone devolve uma cópia do usuário que não compartilha slices nem maps com o original
+// Necessária para guardar o estado "antes": SetPrimaryEmail altera a lista Emails no lugar
+func (u *User) Clone() *User {
+	c := *u
+	c.Emails = slices.Clone(u.Emails)
+	c.Metadata = maps.Clone(u.Metadata)
+	return &c
+}
+
+// Diff compara u (antes) com after (depois) e devolve os campos de dados que mudaram
+//
+// SOBRE OS CAMPOS COMPARADOS:
+// - Os que o cliente altera (name, email, emails, phone, metadata)
+// - verified, que volta a false quando o email principal muda
+// - pending_email, a troca de email aguardando confirmação
+// - version, created_at e updated_at ficam de fora: version e updated_at mudam em TODA atualização e created_at nunca muda
+// - login_count também: é um contador, não um dado que o cliente altera
+//
+// metadata nil e vazio são equivalentes (nenhum metadado)
+func (u *User) Diff(after *User) UserChanges {
+	changes := UserChanges{}
+	if u.Name != after.Name {
+		changes["name"] = FieldChange{Old: u.Name, New: after.Name}
+	}
+	if u.Email != after.Email {
+		changes["email"] = FieldChange{Old: u.Email, New: after.Email}
+	}
+	if !slices.Equal(u.Emails, after.Emails) {
+		changes["emails"] = FieldChange{Old: u.Emails, New: after.Emails}
+	}
+	if u.Phone != after.Phone {
+		changes["phone"] = FieldChange{Old: u.Phone, New: after.Phone}
+	}
+	if u.Verified != after.Verified {
+		changes["verified"] = FieldChange{Old: u.Verified, New: after.Verified}
+	}
+	if u.PendingEmail != after.PendingEmail {
+		changes["pending_email"] = FieldChange{Old: u.PendingEmail, New: after.PendingEmail}
+	}
+	if !maps.Equal(u.Metadata, after.Metadata) {
+		changes["metadata"] = FieldChange{Old: u.Metadata, New: after.Metadata}
+	}
+	return changes
+}
+
+// ============================================
+// FILTRO DE LISTAGEM
+// ============================================
+// UserFilter agrupa os critérios opcionais usados para listar e contar usuários
+// Campos vazios significam "sem filtro" para aquele campo
+//
+// POR QUE UMA STRUCT E NÃO PARÂMETROS SOLTOS?
+// - Podemos adicionar novos filtros sem mudar a assinatura dos métodos
+// - List e Count recebem o MESMO filtro, garantindo resultados consistentes
+type UserFilter struct {
+	Name string // Busca parcial (case-insensitive) pelo nome
+
+	// UpdatedSince restringe aos usuários alterados a partir desta data (inclusive)
+	// Com ela, a ordem deixa de ser a de criação e passa a ser a de alteração (ver UpdatedCursor)
+	UpdatedSince time.Time
+
+	// Fields não filtra QUAIS usuários voltam, e sim QUAIS CAMPOS de cada um (projeção)
+	// Vazio = todos os campos. Usa os nomes do JSON (ver UserFieldNames)
+	Fields []string
+}
+
+// UserFieldNames lista os campos de User que podem ser pedidos em ?fields=
+// São os nomes do JSON - cada camada traduz para o seu formato (ex: id → _id no MongoDB)
+var UserFieldNames = []string{"id", "name", "email", "emails", "phone", "verified", "metadata", "version", "login_count", "created_at", "updated_at"}
+
+// Contadores do usuário aceitos por UserRepository.IncrementField (nomes do JSON)
+// Qualquer outro campo é recusado: o incremento não serve para alterar dados arbitrários
+const (
+	CounterLoginCount = "login_count"
+)
+
+// ============================================
+// CURSOR DA SINCRONIZAÇÃO INCREMENTAL
+// ============================================
+// Com UpdatedSince, a listagem é ordenada por (updatedAt, id) e o cursor precisa das DUAS partes
+//
+// POR QUE O ID SOZINHO NÃO BASTA?
+// - Um usuário alterado entre duas páginas vai para o fim da ordem
+// - Se o cursor fosse só o ID, a posição dele seria lida de novo no banco (já com a data nova)
+// e a página seguinte pularia todos os usuários entre a data antiga e a nova
+// - Com a data no cursor, a próxima página começa exatamente onde a anterior terminou
+//
+// Formato: "<updatedAt em milissegundos Unix>_<id>" (ex: "1704067200000_507f1f77bcf86cd799439011")
+// O cliente não precisa interpretá-lo: basta devolver o "next" recebido em ?after=
+
+// UpdatedCursor monta o cursor da paginação por alteração a partir do último usuário da página
+func UpdatedCursor(u *User) string {
+	return strconv.FormatInt(u.UpdatedAt.UnixMilli(), 10) + "_" + u.ID
+}
+
+// ParseUpdatedCursor separa o cursor de UpdatedCursor em data e ID
+// ok é false quando o cursor não está no formato esperado
+func ParseUpdatedCursor(cursor string) (updatedAt time.Time, id string, ok bool) {
+	ms, id, found := strings.Cut(cursor, "_")
+	if !found || id == "" {
+		return time.Time{}, "", false
+	}
+	n, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return time.UnixMilli(n).UTC(), id, true
+}
+
+// ============================================
+// CRITÉRIOS DE BUSCA
+// ============================================
+// SearchCriteria agrupa os critérios da busca (GET /api/v1/users/search)
+// Todos os filtros são opcionais e se COMBINAM (E lógico): name E email E intervalo de datas
+//
+// DIFERENÇA PARA UserFilter:
+// - UserFilter é o filtro simples da listagem, compartilhado por List, Count e Stream
+// - SearchCriteria traz também ordenação e paginação: a busca devolve a página e o total de uma vez
+type SearchCriteria struct {
+	Name  string // Busca parcial (case-insensitive) pelo nome
+	Email string // Busca parcial (case-insensitive) pelo email principal
+
+	// Intervalo da data de criação: CreatedAfter é inclusivo, CreatedBefore é exclusivo
+	// Valor zero = sem limite daquele lado
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	Sort  string // Campo de ordenação (ver SearchSortFields); vazio = created_at
+	Order string // "asc" ou "desc"; vazio = asc
+
+	Offset int
+	Limit  int
+}
+
+// SearchSortFields lista os campos aceitos em SearchCriteria.Sort (nomes do JSON)
+var SearchSortFields = []string{"name", "email", "created_at"}
+
+// ============================================
+// ATUALIZAÇÃO EM MASSA
+// ============================================
+// BulkUpdateFilter escolhe os usuários de uma atualização em massa (PATCH /api/v1/users/bulk)
+// Os filtros se combinam (E lógico); todos vazios = TODOS os usuários
+type BulkUpdateFilter struct {
+	Name       string // Busca parcial (case-insensitive) pelo nome, como na listagem
+	NamePrefix string // Nome começando com (case-insensitive)
+	Verified   *bool  // nil = verificados ou não
+}
+
+// IsEmpty diz se o filtro casa com todos os usuários
+func (f BulkUpdateFilter) IsEmpty() bool {
+	return f.Name == "" && f.NamePrefix == "" && f.Verified == nil
+}
+
+// BulkUpdateChanges são as alterações aplicadas a todos os usuários do filtro
+type BulkUpdateChanges struct {
+	// Metadata altera chaves avulsas: valor grava a chave, nil a remove
+	// As chaves que não aparecem aqui continuam como estão em cada usuário
+	Metadata map[string]*string
 }
 
 // ============================================
@@ -37,6 +296,11 @@ type User struct {
 // - List retorna ([]*User, error): slice de ponteiros - mais eficiente para muitos itens
 // - Update(user *User): recebe ponteiro para modificar os campos
 //
+// SOBRE context.Context (primeiro parâmetro de todos os métodos):
+// - Carrega o prazo (deadline) e o cancelamento da requisição HTTP
+// - Se o cliente desistir ou o tempo acabar, a consulta ao MongoDB é cancelada
+// - Por convenção em Go, ctx é sempre o primeiro parâmetro
+//
 // Quando usar ponteiro vs valor?
 // - Use ponteiro (*T) quando: struct é grande, precisa modificar, quer compartilhar
 // - Use valor (T) quando: struct é pequena, não precisa modificar, quer cópia independente
@@ -44,26 +308,111 @@ type UserRepository interface {
 	// Create persiste um novo usuário
 	// Recebe *User (ponteiro) para poder popular o campo ID após salvar
 	// O repositório modifica o user.ID diretamente na mesma instância
-	Create(user *User) error
-	
+	Create(ctx context.Context, user *User) error
+
+	// Upsert atualiza o usuário cujo email principal é user.Email ou, se não houver, cria um novo
+	// Em qualquer caso, user passa a refletir o que ficou gravado (ID, versão, data de criação...)
+	// before é o estado anterior à atualização; nil significa que o usuário foi criado
+	// Phone vazio e Metadata nil mantêm os valores atuais (mesma regra do Update)
+	Upsert(ctx context.Context, user *User) (before *User, err error)
+
 	// GetByID busca um usuário pelo ID
 	// Retorna *User (ponteiro) para evitar copiar a struct
-	// Se não encontrar, retorna erro (não retorna nil sem erro)
-	GetByID(id string) (*User, error)
-	
-	// List retorna todos os usuários
+	// Se não encontrar, retorna erro (não retorna nil sem erro):
+	// ErrNotFound se o ID nunca existiu, ErrGone se o usuário foi removido (soft delete)
+	GetByID(ctx context.Context, id string) (*User, error)
+
+	// List retorna os usuários que atendem ao filtro
 	// Retorna []*User (slice de ponteiros) - mais eficiente que []User
 	// Cada elemento do slice é um ponteiro para uma struct User
-	List() ([]*User, error)
-	
+	List(ctx context.Context, filter UserFilter) ([]*User, error)
+
+	// ListAfter retorna até limit usuários com ID maior que after (ordenados por ID)
+	// after vazio = primeira página. Usado na paginação por cursor
+	// Com filter.UpdatedSince, a ordem é (UpdatedAt, ID) e after é um UpdatedCursor
+	ListAfter(ctx context.Context, filter UserFilter, after string, limit int) ([]*User, error)
+
+	// ListOffset retorna até limit usuários pulando os offset primeiros (ordenados por ID; por UpdatedAt com filter.UpdatedSince)
+	// Usado na paginação por offset, que permite voltar páginas
+	ListOffset(ctx context.Context, filter UserFilter, offset, limit int) ([]*User, error)
+
+	// Search retorna a página de usuários que atendem aos critérios e o total de resultados
+	// total conta TODOS os que casam, não só os da página
+	Search(ctx context.Context, criteria SearchCriteria) (users []*User, total int64, err error)
+
+	// Stream percorre os usuários que atendem ao filtro chamando fn para cada um
+	// Os documentos são lidos um a um do banco - a memória não cresce com o total
+	// Se fn retornar erro, a iteração para e o erro é retornado
+	Stream(ctx context.Context, filter UserFilter, fn func(*User) error) error
+
+	// ListIDs chama fn com o ID de cada usuário, em ordem, a partir do cursor after ("" = início)
+	// Só o _id é lido do banco; limit 0 = todos (lidos um a um, sem acumular em memória)
+	ListIDs(ctx context.Context, after string, limit int, fn func(id string) error) error
+
+	// Count retorna quantos usuários atendem ao filtro
+	// Não carrega os documentos - apenas conta no banco
+	Count(ctx context.Context, filter UserFilter) (int64, error)
+
+	// EmailExists informa se algum usuário tem email na sua lista de emails
+	// Usuários removidos (soft delete) contam: o endereço continua ocupado até o purge
+	EmailExists(ctx context.Context, email string) (bool, error)
+
 	// Update atualiza um usuário existente
 	// Recebe *User (ponteiro) com os campos já modificados
-	// O repositório apenas persiste as alterações
-	Update(user *User) error
-	
+	// Só atualiza se user.Version ainda for a versão salva no banco;
+	// em caso de sucesso, incrementa user.Version
+	// Usuário removido → ErrGone (como no GetByID); inexistente → ErrNotFound
+	Update(ctx context.Context, user *User) error
+
 	// Delete remove um usuário pelo ID
 	// Retorna apenas error (não precisa retornar o usuário deletado)
-	Delete(id string) error
+	// Um usuário já removido resulta em ErrGone
+	Delete(ctx context.Context, id string) error
+
+	// GetByIDs busca vários usuários em uma única consulta
+	// IDs inexistentes simplesmente não aparecem no resultado
+	// IDs em formato inválido são devolvidos em invalid
+	GetByIDs(ctx context.Context, ids []string) (users []*User, invalid []string, err error)
+
+	// EnsureIndexes cria os índices da collection (operação idempotente)
+	EnsureIndexes(ctx context.Context) error
+
+	// DropAll apaga TODOS os usuários e recria a collection com seus índices
+	// Uso exclusivo de ambientes de teste
+	DropAll(ctx context.Context) error
+
+	// PurgeDeletedBefore apaga definitivamente os usuários removidos antes de t
+	// Retorna quantos documentos foram apagados
+	PurgeDeletedBefore(ctx context.Context, t time.Time) (int64, error)
+
+	// MarkVerified marca o usuário como verificado se email ainda for o seu email principal
+	// Retorna ErrNotFound se o usuário não existe ou o email principal mudou
+	MarkVerified(ctx context.Context, id, email string) error
+
+	// IncrementField soma delta ao contador field (ex: CounterLoginCount) em uma única operação atômica
+	// Sem ler o usuário antes: dois incrementos simultâneos nunca se perdem
+	// Campo fora da lista de contadores → usecase.ErrUnknownCounter; usuário inexistente → usecase.ErrNotFound
+	// (removido → usecase.ErrGone)
+	IncrementField(ctx context.Context, id, field string, delta int) error
+
+	// DeleteMany remove vários usuários em uma única operação
+	// IDs em formato inválido são ignorados e devolvidos em invalid
+	// deleted é quantos usuários foram de fato removidos
+	DeleteMany(ctx context.Context, ids []string) (deleted int64, invalid []string, err error)
+
+	// UpdateMany aplica changes a todos os usuários que casam com filter, em uma operação
+	// maxMatched > 0 é uma trava: se mais usuários casarem, nada é alterado (ErrBulkConfirmRequired)
+	// matched é quantos casaram com o filtro; modified, quantos foram de fato alterados
+	UpdateMany(ctx context.Context, filter BulkUpdateFilter, changes BulkUpdateChanges, maxMatched int64) (matched, modified int64, err error)
+
+	// Watch abre um stream com as criações, atualizações e remoções de usuários a partir de agora
+	// Exige replica set ou cluster shardeado: em um servidor standalone → usecase.ErrChangeStreamsUnsupported
+	Watch(ctx context.Context) (UserChangeStream, error)
+
+	// WithTransaction executa fn dentro de uma transação
+	// Todas as chamadas ao repositório feitas com o ctx recebido por fn
+	// participam da transação: se fn retornar erro, tudo é desfeito (abort)
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 // ============================================
@@ -80,22 +429,97 @@ type UserRepository interface {
 type UserUseCase interface {
 	// CreateUser valida os dados e cria um novo usuário
 	// Retorna *User (ponteiro) com o usuário criado (incluindo o ID gerado)
-	CreateUser(name, email string) (*User, error)
-	
+	// phone é opcional ("" = sem telefone); metadata também (nil = sem metadados)
+	CreateUser(ctx context.Context, name, email, phone string, metadata map[string]string) (*User, error)
+
+	// UpsertUser cria o usuário ou, se o email já for o principal de alguém, atualiza esse usuário
+	// created indica qual dos dois aconteceu; changes traz os campos alterados (vazio na criação)
+	UpsertUser(ctx context.Context, name, email, phone string, metadata map[string]string) (user *User, created bool, changes UserChanges, err error)
+
+	// IsEmailAvailable informa se email ainda pode ser usado em um cadastro
+	// Valida o formato como o CreateUser (ErrInvalidEmail, ErrEmailTooLong)
+	IsEmailAvailable(ctx context.Context, email string) (bool, error)
+
 	// GetUser busca um usuário pelo ID
 	// Retorna *User (ponteiro) ou erro se não encontrar
-	GetUser(id string) (*User, error)
-	
-	// ListUsers retorna todos os usuários cadastrados
+	GetUser(ctx context.Context, id string) (*User, error)
+
+	// ListUsers retorna os usuários cadastrados que atendem ao filtro
 	// Retorna []*User (slice de ponteiros)
-	ListUsers() ([]*User, error)
-	
+	ListUsers(ctx context.Context, filter UserFilter) ([]*User, error)
+
+	// ListUsersPage retorna uma página de usuários a partir do cursor after
+	// next é o cursor da próxima página ("" quando não há mais páginas)
+	ListUsersPage(ctx context.Context, filter UserFilter, after string, limit int) (users []*User, next string, err error)
+
+	// ListUsersOffset retorna uma página por offset e se existe página seguinte
+	ListUsersOffset(ctx context.Context, filter UserFilter, offset, limit int) (users []*User, hasNext bool, err error)
+
+	// SearchUsers valida os critérios e retorna a página de resultados e o total de resultados
+	SearchUsers(ctx context.Context, criteria SearchCriteria) (users []*User, total int64, err error)
+
+	// StreamUsers chama fn para cada usuário que atende ao filtro, sem carregar todos em memória
+	StreamUsers(ctx context.Context, filter UserFilter, fn func(*User) error) error
+
+	// ListUserIDsPage retorna uma página de IDs a partir do cursor after e o cursor da próxima
+	ListUserIDsPage(ctx context.Context, after string, limit int) (ids []string, next string, err error)
+
+	// StreamUserIDs chama fn com o ID de cada usuário, sem carregar todos em memória
+	StreamUserIDs(ctx context.Context, fn func(id string) error) error
+
+	// CountUsers retorna o total de usuários que atendem ao filtro
+	CountUsers(ctx context.Context, filter UserFilter) (int64, error)
+
+	// WatchUsers abre um stream com as alterações de usuários a partir de agora (ver UserChangeStream)
+	WatchUsers(ctx context.Context) (UserChangeStream, error)
+
 	// UpdateUser atualiza os campos de um usuário existente
-	// Recebe id e os novos valores (name e email podem ser vazios)
-	// Retorna *User (ponteiro) com os dados atualizados
-	UpdateUser(id, name, email string) (*User, error)
-	
+	// Recebe id e os novos valores (name, email e phone podem ser vazios); um email diferente do principal é recusado (a troca é pelo ChangeEmail)
+	// metadata nil mantém os metadados atuais; um map (mesmo vazio) os SUBSTITUI por inteiro
+	// version é a versão que o cliente leu (0 = não verificar)
+	// Retorna *User (ponteiro) com os dados atualizados e os campos que mudaram (valor antigo e novo)
+	UpdateUser(ctx context.Context, id, name, email, phone string, metadata map[string]string, version int) (*User, UserChanges, error)
+
 	// DeleteUser remove um usuário pelo ID
 	// Retorna apenas error (não precisa retornar o usuário deletado)
-	DeleteUser(id string) error
+	DeleteUser(ctx context.Context, id string) error
+
+	// GetUsers busca vários usuários de uma vez
+	// Retorna os encontrados e quais IDs eram inválidos
+	GetUsers(ctx context.Context, ids []string) (users []*User, invalid []string, err error)
+
+	// ResetUsers apaga todos os usuários (ambientes de teste)
+	ResetUsers(ctx context.Context) error
+
+	// PurgeDeletedUsers apaga definitivamente os usuários removidos há mais de retention
+	PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int64, error)
+
+	// AddEmail adiciona um endereço (não principal) à lista de emails do usuário
+	AddEmail(ctx context.Context, id, email string) (*User, error)
+
+	// ChangeEmail pede a troca do email principal: o novo fica pendente até ser verificado
+	ChangeEmail(ctx context.Context, id, email string) (*User, error)
+
+	// VerifyEmail consome o token de verificação e marca o usuário como verificado
+	// Se o token é do email pendente, ele passa a ser o principal
+	VerifyEmail(ctx context.Context, token string) (*User, error)
+
+	// RecordLogin soma um ao contador de logins do usuário (login_count)
+	RecordLogin(ctx context.Context, id string) error
+
+	// DeleteUsers remove vários usuários de uma vez
+	// Retorna quantos foram removidos, quais IDs não existiam e quais eram inválidos
+	DeleteUsers(ctx context.Context, ids []string) (deleted int64, notFound, invalid []string, err error)
+
+	// BulkUpdateUsers aplica changes a todos os usuários que casam com filter
+	// Sem confirm, um filtro vazio ou que case com muitos usuários é recusado (ErrBulkConfirmRequired)
+	BulkUpdateUsers(ctx context.Context, filter BulkUpdateFilter, changes BulkUpdateChanges, confirm bool) (matched, modified int64, err error)
+
+	// MergeUsers funde o usuário duplicado secondaryID em primaryID (em uma transação) e remove o duplicado
+	// Com copyMetadata, as chaves de metadata do secundário que o principal não tem são copiadas
+	MergeUsers(ctx context.Context, primaryID, secondaryID string, copyMetadata bool) (*User, error)
+
+	// GetUserAudit retorna as limit alterações mais recentes do usuário (audit log)
+	// Usuários removidos continuam com histórico; um ID sem alterações retorna lista vazia
+	GetUserAudit(ctx context.Context, id string, limit int) ([]*AuditEntry, error)
 }
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ============================================
+// VERIFICAÇÃO DE EMAIL
+// ============================================
+// Todo usuário nasce com Verified=false
+// Na criação geramos um token aleatório e o enviamos por email (via Mailer)
+// O usuário prova que o endereço é dele devolvendo o token em POST /api/v1/users/verify
+//
+// REGRAS DO TOKEN:
+// - Aleatório criptográfico (crypto/rand): não dá para adivinhar nem derivar de outro token
+// - Uso único: o store o REMOVE na mesma operação que o lê
+// - Expira: depois de ExpiresAt ele é recusado com um erro específico
+
+// VerificationToken é um token de verificação guardado no store
+type VerificationToken struct {
+	Token     string    // Valor enviado ao usuário
+	UserID    string    // Usuário que será marcado como verificado
+	Email     string    // Endereço para o qual o token foi enviado
+	ExpiresAt time.Time // Depois disso o token é recusado
+}
+
+// VerificationTokenStore define o contrato para guardar os tokens
+// Implementações possíveis: MongoDB com TTL (padrão), Redis, memória...
+type VerificationTokenStore interface {
+	// Save guarda um token novo
+	Save(ctx context.Context, token VerificationToken) error
+
+	// Consume busca e REMOVE o token em uma única operação atômica
+	// Retorna nil (sem erro) quando o token não existe ou já foi usado
+	// Tokens expirados também são retornados: quem chama decide o que fazer
+	Consume(ctx context.Context, token string) (*VerificationToken, error)
+}
+
+// Mailer define o contrato para enviar emails
+// Implementações possíveis: log (padrão, para desenvolvimento), SMTP, SES, SendGrid...
+type Mailer interface {
+	// SendVerification envia o token de verificação para o endereço to
+	SendVerification(ctx context.Context, to, token string) error
+}
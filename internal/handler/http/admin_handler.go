@@ -0,0 +1,121 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// ROTAS DE ADMINISTRAÇÃO (SOMENTE TESTES)
+// ============================================
+// Rotas que existem para facilitar testes de integração
+// (ex: começar cada suíte com a collection vazia)
+//
+// POR QUE 404 QUANDO DESABILITADO?
+// - main só chama RegisterAdmin com ENABLE_ADMIN=true
+// - Sem a rota registrada, o chi responde 404 como para qualquer rota inexistente
+// - Em produção ela fica invisível: ninguém consegue nem descobrir que existe
+
+// dbInfoTimeout é o prazo da consulta de GET /api/v1/admin/mongo
+// São comandos leves: se demorarem mais que isso, o banco já está com problemas
+const dbInfoTimeout = 2 * time.Second
+
+// DatabaseInfoFunc consulta a versão e a topologia do banco para diagnóstico
+// O resultado é devolvido como JSON sem alterações (main liga a infra/mongo.GetServerInfo)
+type DatabaseInfoFunc func(ctx context.Context) (any, error)
+
+// AdminHandler agrupa as rotas de administração
+type AdminHandler struct {
+	uc     domain.UserUseCase
+	dbInfo DatabaseInfoFunc
+	logger *slog.Logger
+}
+
+// RegisterAdmin registra as rotas /api/v1/admin (exigem autenticação)
+func RegisterAdmin(r chi.Router, uc domain.UserUseCase, dbInfo DatabaseInfoFunc, auth func(http.Handler) http.Handler, logger *slog.Logger) {
+	h := &AdminHandler{uc: uc, dbInfo: dbInfo, logger: logger.With("component", "admin")}
+
+	r.Route("/api/v1/admin", func(r chi.Router) {
+		r.Use(auth)
+		r.Post("/reset", h.reset)
+		r.Get("/mongo", h.mongoInfo)
+	})
+}
+
+// reset trata requisições POST /api/v1/admin/reset
+// Apaga TODOS os usuários e recria a collection com os índices
+//
+// @Summary Reset users collection (test environments only)
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/reset [post]
+func (h *AdminHandler) reset(w http.ResponseWriter, r *http.Request) {
+	// Log "barulhento" (nível WARN) antes e depois: apagar tudo nunca deve passar despercebido
+	userID, _ := UserIDFromContext(r.Context())
+	h.logger.Warn("ADMIN RESET requested: dropping users collection",
+		"request_id", middleware.GetReqID(r.Context()),
+		"user_id", userID,
+		"remote_addr", r.RemoteAddr,
+	)
+
+	if err := h.uc.ResetUsers(r.Context()); err != nil {
+		h.logger.Error("ADMIN RESET failed", "request_id", middleware.GetReqID(r.Context()), "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to reset users")
+		return
+	}
+
+	h.logger.Warn("ADMIN RESET done: users collection dropped and recreated",
+		"request_id", middleware.GetReqID(r.Context()),
+		"user_id", userID,
+	)
+	writeJSON(w, r, http.StatusOK, map[string]string{"status": "reset"})
+}
+
+// mongoInfo trata requisições GET /api/v1/admin/mongo
+// Mostra a versão do MongoDB, a topologia (standalone, replica set, sharded) e o primário atual
+//
+// PARA QUE SERVE?
+// - Diagnosticar de relance por que transações (merge) ou o stream de alterações respondem 501
+// - Conferir para qual membro do replica set a API está apontando depois de um failover
+//
+// POR QUE SÓ COM ENABLE_ADMIN?
+// - Versão e hosts internos do banco ajudam quem procura vulnerabilidades: não ficam expostos em produção
+// - Por isso não entra no /healthz?verbose=true, que é público
+//
+// Um comando negado (ex: buildInfo sem permissão) não derruba a resposta: vira um aviso em "warnings"
+//
+// @Summary MongoDB server info (test environments only)
+// @Description Server version, topology (standalone, replica_set or sharded), replica set name and current primary, from the hello and buildInfo commands. Commands the database user may not run are reported in warnings
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/mongo [get]
+func (h *AdminHandler) mongoInfo(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), dbInfoTimeout)
+	defer cancel()
+
+	info, err := h.dbInfo(ctx)
+	if err != nil {
+		h.logger.Error("failed to get MongoDB server info", "request_id", middleware.GetReqID(r.Context()), "error", err)
+		writeError(w, r, http.StatusServiceUnavailable, "MongoDB server info unavailable")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, info)
+}
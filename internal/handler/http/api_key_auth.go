@@ -0,0 +1,124 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"user-api/internal/domain"
+)
+
+// minAPIKeyLength evita chaves curtas demais para resistir a tentativa e erro
+const minAPIKeyLength = 16
+
+// ============================================
+// API KEYS (SERVIÇO A SERVIÇO)
+// ============================================
+// APIKey é uma chave aceita no header X-API-Key
+//
+// POR QUE UM LABEL?
+// - Identifica QUEM usa a chave (ex: "billing") sem expor a chave em logs e no audit log
+// - O autor da requisição vira "apikey:<label>"
+// - Permissões por chave (scopes) podem entrar aqui como novos campos, sem mudar o formato do resto
+type APIKey struct {
+	Label string
+	Key   string
+}
+
+// apiKeyContextKey guarda no context a APIKey que autenticou a requisição
+type apiKeyContextKey struct{}
+
+// APIKeyAuthenticator valida o header X-API-Key contra as chaves configuradas
+type APIKeyAuthenticator struct {
+	keys []APIKey
+}
+
+// NewAPIKeyAuthenticator cria o authenticator com as chaves aceitas
+func NewAPIKeyAuthenticator(keys []APIKey) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{keys: keys}
+}
+
+// Credential descreve o header esperado
+func (a *APIKeyAuthenticator) Credential() string {
+	return "X-API-Key"
+}
+
+// Authenticate procura a chave recebida entre as configuradas
+//
+// COMPARAÇÃO EM TEMPO CONSTANTE:
+// - Com ==, a comparação para no primeiro byte diferente
+// - Medindo o tempo de resposta, um atacante descobriria a chave byte a byte (timing attack)
+// - subtle.ConstantTimeCompare leva o mesmo tempo acerte ou erre
+// - Comparamos os hashes SHA-256: têm sempre 32 bytes, então nem o TAMANHO da chave vaza
+// - O loop percorre TODAS as chaves, sem parar na primeira que casa
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (context.Context, error) {
+	provided := r.Header.Get("X-API-Key")
+	if provided == "" {
+		return nil, errNoCredentials
+	}
+
+	providedHash := sha256.Sum256([]byte(provided))
+	var match *APIKey
+	for i := range a.keys {
+		keyHash := sha256.Sum256([]byte(a.keys[i].Key))
+		if subtle.ConstantTimeCompare(providedHash[:], keyHash[:]) == 1 {
+			match = &a.keys[i]
+		}
+	}
+	if match == nil {
+		return nil, errors.New("Invalid API key")
+	}
+
+	ctx := context.WithValue(r.Context(), apiKeyContextKey{}, *match)
+	return domain.ContextWithActor(ctx, "apikey:"+match.Label), nil
+}
+
+// APIKeyFromContext retorna a APIKey que autenticou a requisição
+// O segundo retorno é false quando a requisição não usou API key (ex: JWT)
+func APIKeyFromContext(ctx context.Context) (APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(APIKey)
+	return key, ok
+}
+
+// ParseAPIKeys lê a lista de chaves no formato da variável API_KEYS
+//
+// FORMATO: entradas separadas por vírgula, cada uma "label:chave" ou só "chave"
+// - API_KEYS=billing:3f9a...,reports:81cc...
+// - Sem label, a chave recebe "key1", "key2"... pela posição
+//
+// Recusa chaves curtas (menos de minAPIKeyLength caracteres) e labels repetidos
+// As mensagens de erro nunca incluem a chave
+func ParseAPIKeys(raw string) ([]APIKey, error) {
+	var keys []APIKey
+	labels := map[string]bool{}
+
+	for i, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		label, key, found := strings.Cut(entry, ":")
+		if !found {
+			label, key = fmt.Sprintf("key%d", i+1), entry
+		}
+		label, key = strings.TrimSpace(label), strings.TrimSpace(key)
+
+		if label == "" {
+			return nil, fmt.Errorf("API key #%d has an empty label", i+1)
+		}
+		if len(key) < minAPIKeyLength {
+			return nil, fmt.Errorf("API key %q must have at least %d characters", label, minAPIKeyLength)
+		}
+		if labels[label] {
+			return nil, fmt.Errorf("API key label %q is used more than once", label)
+		}
+		labels[label] = true
+		keys = append(keys, APIKey{Label: label, Key: key})
+	}
+	return keys, nil
+}
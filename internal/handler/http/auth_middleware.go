@@ -0,0 +1,138 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// MIDDLEWARE DE AUTENTICAÇÃO
+// ============================================
+// Um middleware é uma função que "envolve" um handler HTTP
+// Ele executa ANTES do handler e decide se a requisição pode continuar
+//
+// FLUXO:
+// 1. Cada Authenticator procura a SUA credencial (JWT no Authorization, chave no X-API-Key...)
+// 2. O primeiro que encontrar uma credencial decide: válida → segue; inválida → 401
+// 3. Nenhuma credencial na requisição → 401
+// 4. Se válida → coloca o autor (actor) no context e chama o próximo handler
+
+// errNoCredentials indica que a requisição não traz a credencial de um Authenticator
+// Não é um 401 por si só: outro Authenticator pode aceitar a requisição
+var errNoCredentials = errors.New("no credentials")
+
+// Authenticator verifica UM tipo de credencial
+//
+// POR QUE UMA INTERFACE?
+// - RequireAuth combina vários tipos: a rota aceita JWT OU API key
+// - Um novo tipo (ex: mTLS) é só mais uma implementação, sem mexer nas rotas
+type Authenticator interface {
+	// Authenticate retorna o context com o autor da requisição
+	// errNoCredentials: a requisição não traz esta credencial
+	// Outro erro: a credencial existe, mas é inválida (a mensagem vai para o 401)
+	Authenticate(r *http.Request) (context.Context, error)
+
+	// Credential descreve a credencial esperada (usado na mensagem do 401)
+	Credential() string
+}
+
+// RequireAuth cria um middleware chi que exige uma credencial válida de algum dos authenticators
+//
+// ORDEM:
+// - Os authenticators são consultados na ordem recebida
+// - O primeiro que ENCONTRA uma credencial decide; os seguintes não são consultados
+// - Assim, um JWT inválido é recusado mesmo que a requisição também traga uma API key
+func RequireAuth(authenticators ...Authenticator) func(http.Handler) http.Handler {
+	credentials := make([]string, 0, len(authenticators))
+	for _, a := range authenticators {
+		credentials = append(credentials, a.Credential())
+	}
+	missing := "Missing credentials: send " + strings.Join(credentials, " or ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, a := range authenticators {
+				ctx, err := a.Authenticate(r)
+				if err == errNoCredentials {
+					continue
+				}
+				if err != nil {
+					writeError(w, r, http.StatusUnauthorized, err.Error())
+					return
+				}
+				// r.WithContext retorna uma cópia da requisição usando o context com o autor
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+			writeError(w, r, http.StatusUnauthorized, missing)
+		})
+	}
+}
+
+// NewAuthMiddleware cria um middleware chi que exige um JWT válido
+// Atalho para RequireAuth(NewJWTAuthenticator(secret))
+func NewAuthMiddleware(secret []byte) func(http.Handler) http.Handler {
+	return RequireAuth(NewJWTAuthenticator(secret))
+}
+
+// ============================================
+// JWT
+// ============================================
+// JWTAuthenticator valida o header "Authorization: Bearer <token>"
+// O token deve ser assinado com HMAC-SHA256 (HS256) usando o secret informado
+// e conter as claims "sub" (ID do usuário) e "exp" (expiração)
+type JWTAuthenticator struct {
+	secret []byte
+}
+
+// NewJWTAuthenticator cria o authenticator de JWT com o secret HS256
+func NewJWTAuthenticator(secret []byte) *JWTAuthenticator {
+	return &JWTAuthenticator{secret: secret}
+}
+
+// Credential descreve o header esperado
+func (a *JWTAuthenticator) Credential() string {
+	return "Authorization: Bearer"
+}
+
+// Authenticate valida o token e coloca a claim "sub" no context como autor
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (context.Context, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, errNoCredentials
+	}
+
+	// O header deve ter o formato "Bearer <token>"
+	tokenString, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || tokenString == "" {
+		return nil, errors.New("Missing or malformed Authorization header")
+	}
+
+	// jwt.ParseWithClaims valida a assinatura e as claims registradas
+	// - WithValidMethods: aceita apenas HS256 (evita ataques de troca de algoritmo)
+	// - WithExpirationRequired: tokens sem "exp" são rejeitados
+	claims := &jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid || claims.Subject == "" {
+		return nil, errors.New("Invalid or expired token")
+	}
+
+	// ContextWithActor (context.WithValue) cria um NOVO context com o valor adicionado
+	// A chave fica no domínio: o usecase também lê o autor (audit log)
+	return domain.ContextWithActor(r.Context(), claims.Subject), nil
+}
+
+// UserIDFromContext retorna o ID do usuário autenticado guardado pelo middleware
+// O segundo retorno é false quando a requisição não passou pela autenticação
+// Com API key, o "usuário" é o serviço: "apikey:<label>"
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	return domain.ActorFromContext(ctx)
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testJWTSecret = []byte("test-secret-with-at-least-32-bytes!!")
+
+// signToken assina um JWT HS256 com as claims informadas
+func signToken(t *testing.T, secret []byte, claims jwt.RegisteredClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	valid := jwt.RegisteredClaims{Subject: "user-42", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+
+	tampered := signToken(t, testJWTSecret, valid)
+	tampered = tampered[:len(tampered)-2] + "xx"
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantUserID    string
+	}{
+		{name: "valid token", authorization: "Bearer " + signToken(t, testJWTSecret, valid), wantStatus: http.StatusOK, wantUserID: "user-42"},
+		{name: "missing header", authorization: "", wantStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", authorization: "Basic dXNlcjpwYXNz", wantStatus: http.StatusUnauthorized},
+		{name: "empty bearer token", authorization: "Bearer ", wantStatus: http.StatusUnauthorized},
+		{name: "expired token", authorization: "Bearer " + signToken(t, testJWTSecret, jwt.RegisteredClaims{Subject: "user-42", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}), wantStatus: http.StatusUnauthorized},
+		{name: "tampered signature", authorization: "Bearer " + tampered, wantStatus: http.StatusUnauthorized},
+		{name: "signed with another secret", authorization: "Bearer " + signToken(t, []byte("another-secret-with-32-bytes-or-more"), valid), wantStatus: http.StatusUnauthorized},
+		{name: "without exp", authorization: "Bearer " + signToken(t, testJWTSecret, jwt.RegisteredClaims{Subject: "user-42"}), wantStatus: http.StatusUnauthorized},
+		{name: "without sub", authorization: "Bearer " + signToken(t, testJWTSecret, jwt.RegisteredClaims{ExpiresAt: valid.ExpiresAt}), wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID, _ = UserIDFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/users", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			NewAuthMiddleware(testJWTSecret)(next).ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusUnauthorized {
+				if code := decodeErrorCode(t, rec); code != CodeUnauthorized {
+					t.Errorf("code = %q, want %q", code, CodeUnauthorized)
+				}
+				return
+			}
+			if gotUserID != tt.wantUserID {
+				t.Errorf("UserIDFromContext = %q, want %q", gotUserID, tt.wantUserID)
+			}
+		})
+	}
+}
+
+// TestRequireAuthOrder confere que o primeiro authenticator que encontra uma credencial decide
+func TestRequireAuthOrder(t *testing.T) {
+	keys := []APIKey{{Label: "billing", Key: "0123456789abcdef0123456789abcdef"}}
+	middleware := RequireAuth(NewJWTAuthenticator(testJWTSecret), NewAPIKeyAuthenticator(keys))
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+		wantActor  string
+	}{
+		{name: "api key alone", headers: map[string]string{"X-API-Key": keys[0].Key}, wantStatus: http.StatusOK, wantActor: "apikey:billing"},
+		{name: "invalid jwt is not rescued by a valid api key", headers: map[string]string{"Authorization": "Bearer invalid", "X-API-Key": keys[0].Key}, wantStatus: http.StatusUnauthorized},
+		{name: "wrong api key", headers: map[string]string{"X-API-Key": "wrong"}, wantStatus: http.StatusUnauthorized},
+		{name: "no credentials", headers: nil, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotActor string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotActor, _ = UserIDFromContext(r.Context())
+			})
+			r := httptest.NewRequest(http.MethodDelete, "/api/v1/users/"+testUserID, nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			middleware(next).ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if gotActor != tt.wantActor {
+				t.Errorf("actor = %q, want %q", gotActor, tt.wantActor)
+			}
+		})
+	}
+}
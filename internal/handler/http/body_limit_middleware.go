@@ -0,0 +1,79 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ============================================
+// LIMITE GLOBAL DO CORPO DA REQUISIÇÃO
+// ============================================
+// NewBodyLimit aplica http.MaxBytesReader ao corpo de TODAS as requisições (MAX_REQUEST_BODY_BYTES)
+//
+// POR QUE, SE O decodeJSON JÁ LIMITA?
+// - O limite do decodeJSON (MAX_BODY_BYTES) só vale para quem lê o corpo por ele
+// - Uma rota nova que leia r.Body de outro jeito (io.ReadAll, outro decoder) ficaria sem limite
+// - Aqui a proteção vem por padrão: nenhuma rota lê mais que o limite, sem código no handler
+//
+// COMO FUNCIONA:
+// - Requisições sem corpo (GET, DELETE...) passam sem alteração
+// - O corpo é lido normalmente até o limite; passar dele faz a leitura falhar com *http.MaxBytesError
+// - Quem lê responde 413 BODY_TOO_LARGE (writeBodyTooLarge, usado pelo decodeJSON e pelo validador OpenAPI)
+// - O MaxBytesReader também avisa o servidor para fechar a conexão: o resto do corpo não é lido
+//
+// LIMITE POR ROTA:
+// - WithBodyLimit troca o limite de uma rota (maior ou menor), ex: r.With(WithBodyLimit(10<<20)).Post(...)
+// - O limite global vale até o handler ler o corpo: a rota decide antes disso
+//
+// Deve rodar antes do roteamento (r.Use no router principal)
+func NewBodyLimit(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limitBody(w, r, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithBodyLimit substitui o limite global do corpo em uma rota (r.With)
+// Sem o middleware global na cadeia, aplica o limite do mesmo jeito
+func WithBodyLimit(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limitBody(w, r, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitedBody é o corpo com limite; guarda o original para WithBodyLimit poder trocar o limite
+// (envolver de novo só deixaria o limite MENOR valer: o MaxBytesReader de fora continuaria lá)
+type limitedBody struct {
+	io.ReadCloser               // http.MaxBytesReader sobre orig
+	orig          io.ReadCloser // Corpo original, sem limite
+}
+
+// limitBody troca r.Body pelo corpo original limitado a maxBytes
+func limitBody(w http.ResponseWriter, r *http.Request, maxBytes int64) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return
+	}
+	orig := r.Body
+	if limited, ok := orig.(*limitedBody); ok {
+		orig = limited.orig
+	}
+	r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(w, orig, maxBytes), orig: orig}
+}
+
+// writeBodyTooLarge responde 413 se err veio de um corpo acima do limite
+// Retorna false (sem escrever nada) para qualquer outro erro
+func writeBodyTooLarge(w http.ResponseWriter, r *http.Request, err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return false
+	}
+	writeErrorCode(w, r, http.StatusRequestEntityTooLarge, CodeBodyTooLarge, fmt.Sprintf("Request body too large (max %d bytes)", maxBytesErr.Limit))
+	return true
+}
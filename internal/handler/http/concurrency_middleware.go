@@ -0,0 +1,122 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ============================================
+// LIMITE DE REQUISIÇÕES SIMULTÂNEAS (LOAD SHEDDING)
+// ============================================
+// ConcurrencyLimiter limita quantas requisições são processadas AO MESMO TEMPO
+//
+// DIFERENÇA PARA O RATE LIMITER:
+// - Rate limiter: quantas requisições POR SEGUNDO cada IP pode fazer (justiça entre clientes)
+// - Este limite: quantas requisições em andamento o SERVIDOR aguenta (proteção do processo e do MongoDB)
+//
+// SEMÁFORO COM CHANNEL:
+// - Um channel com buffer de tamanho max funciona como um semáforo
+// - Enviar (sem <- struct{}{}) ocupa uma vaga; receber (<-sem) libera
+// - Com o buffer cheio, o envio bloqueia até alguém liberar uma vaga
+//
+// SEM FILA INFINITA:
+// - Uma requisição espera por uma vaga no máximo "wait"; depois recebe 503 com Retry-After
+// - Avisar logo que está sobrecarregado (load shedding) é melhor que acumular requisições
+// - Se o cliente desistir durante a espera (context cancelado), a requisição sai da fila na hora
+type ConcurrencyLimiter struct {
+	sem  chan struct{}
+	wait time.Duration
+}
+
+var (
+	httpRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of HTTP requests currently being processed.",
+	})
+
+	httpRequestsShedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "http_requests_shed_total",
+		Help: "Total number of HTTP requests rejected because the concurrency limit was reached.",
+	})
+)
+
+// concurrencyExemptPaths não passam pelo limite
+// Sob carga, o health check precisa continuar respondendo (senão o orquestrador
+// reinicia o processo justamente quando ele está ocupado) e as métricas mostram o problema
+// O stream de alterações tem limite próprio (USER_STREAM_MAX_CLIENTS): cada conexão ocuparia uma vaga por horas
+var concurrencyExemptPaths = map[string]bool{
+	"/healthz":     true,
+	"/readyz":      true,
+	"/metrics":     true,
+	UserStreamPath: true,
+}
+
+// NewConcurrencyLimiter cria o limitador com max vagas
+// wait é quanto uma requisição pode esperar por uma vaga (0 = rejeita na hora)
+func NewConcurrencyLimiter(max int, wait time.Duration) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		sem:  make(chan struct{}, max),
+		wait: wait,
+	}
+}
+
+// Middleware ocupa uma vaga durante a requisição ou responde 503 quando não há vagas
+func (cl *ConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if concurrencyExemptPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !cl.acquire(r) {
+			// Cliente já foi embora: não há para quem responder
+			if r.Context().Err() != nil {
+				return
+			}
+			httpRequestsShedTotal.Inc()
+			w.Header().Set("Retry-After", "1")
+			writeErrorCode(w, r, http.StatusServiceUnavailable, CodeOverloaded, "Server is overloaded, try again later")
+			return
+		}
+		defer cl.release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acquire tenta ocupar uma vaga
+// Retorna false se o prazo de espera acabou ou o context da requisição foi cancelado
+func (cl *ConcurrencyLimiter) acquire(r *http.Request) bool {
+	// Caminho rápido: há vaga livre, sem criar timer
+	select {
+	case cl.sem <- struct{}{}:
+		httpRequestsInFlight.Inc()
+		return true
+	default:
+	}
+	if cl.wait <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(cl.wait)
+	defer timer.Stop()
+
+	select {
+	case cl.sem <- struct{}{}:
+		httpRequestsInFlight.Inc()
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// release libera a vaga ocupada por acquire
+func (cl *ConcurrencyLimiter) release() {
+	<-cl.sem
+	httpRequestsInFlight.Dec()
+}
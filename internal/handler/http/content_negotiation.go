@@ -0,0 +1,262 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Formatos de resposta suportados
+const (
+	mediaJSON = "application/json"
+	mediaXML  = "application/xml"
+)
+
+// formatContextKey guarda no context o formato escolhido pela negociação
+type formatContextKey struct{}
+
+// ============================================
+// NEGOCIAÇÃO DE CONTEÚDO (HEADER Accept)
+// ============================================
+// NegotiateContentType escolhe o formato da resposta a partir do header Accept
+//
+// REGRAS:
+// - Sem Accept, ou com "*/*" / "application/*": JSON (o padrão de sempre)
+// - "application/xml" (ou "text/xml") com preferência maior que a do JSON: XML
+// - Empate de preferência (q): JSON
+// - Nenhum tipo que sabemos produzir (ex: "Accept: text/csv"): 406 Not Acceptable
+//
+// SOBRE q (QUALITY):
+// - "Accept: application/xml;q=0.9, application/json;q=0.5" = "prefiro XML, aceito JSON"
+// - Sem q, o peso é 1; q=0 significa "NÃO aceito este tipo"
+//
+// POR QUE UM MIDDLEWARE?
+// - A decisão acontece UMA vez, antes do handler: um 406 não deixa um POST criar nada
+// - Os handlers só chamam writeResponse; o formato já está no context
+//
+// Vary: Accept avisa caches que a mesma URL pode ter corpos em formatos diferentes
+func NegotiateContentType(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+
+		format, ok := negotiateFormat(r.Header.Get("Accept"))
+		if !ok {
+			writeError(w, r, http.StatusNotAcceptable, "Not Acceptable: supported types are application/json and application/xml")
+			return
+		}
+		ctx := context.WithValue(r.Context(), formatContextKey{}, format)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// negotiateFormat devolve o formato preferido entre os suportados
+// ok é false quando o cliente enviou um Accept e nenhum tipo suportado é aceito
+func negotiateFormat(accept string) (format string, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return mediaJSON, true
+	}
+
+	// -1 = o tipo não aparece no Accept
+	jsonQ, xmlQ, anyQ := -1.0, -1.0, -1.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, found := params["q"]; found {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case mediaJSON:
+			jsonQ = max(jsonQ, q)
+		case mediaXML, "text/xml":
+			xmlQ = max(xmlQ, q)
+		case "*/*", "application/*":
+			anyQ = max(anyQ, q)
+		}
+	}
+
+	// O tipo citado explicitamente vale sobre o curinga:
+	// "*/*, application/xml;q=0" aceita qualquer coisa, MENOS XML
+	if jsonQ < 0 {
+		jsonQ = anyQ
+	}
+	if xmlQ < 0 {
+		xmlQ = anyQ
+	}
+
+	switch {
+	case jsonQ <= 0 && xmlQ <= 0:
+		return "", false
+	case xmlQ > jsonQ:
+		return mediaXML, true
+	default:
+		return mediaJSON, true
+	}
+}
+
+// responseFormat devolve o formato negociado (JSON quando a rota não passa pela negociação)
+func responseFormat(r *http.Request) string {
+	if format, ok := r.Context().Value(formatContextKey{}).(string); ok {
+		return format
+	}
+	return mediaJSON
+}
+
+// writeResponse escreve data no formato negociado (JSON ou XML)
+// Respostas de sucesso montadas com dados do cache desatualizado levam o header Warning (ver stale.go)
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	if status < http.StatusBadRequest {
+		warnIfStale(w, r)
+	}
+	if responseFormat(r) == mediaXML {
+		writeXML(w, r, status, data)
+		return
+	}
+	writeJSON(w, r, status, data)
+}
+
+// ============================================
+// JSON INDENTADO (DEPURAÇÃO)
+// ============================================
+// wantsPrettyJSON informa se o cliente pediu o JSON indentado, mais fácil de ler no curl:
+// - ?pretty=true na URL
+// - ou o parâmetro pretty=true no Accept: "Accept: application/json; pretty=true"
+//
+// POR QUE NÃO É O PADRÃO?
+// - A indentação aumenta o corpo (espaços e quebras de linha) e o tempo de serialização
+// - Programas não precisam dela: o padrão continua compacto
+//
+// Vale para tudo que passa por writeJSON (respostas e erros); o XML não muda
+// Os streams (NDJSON, exportação, lista de IDs sem paginação, SSE) escrevem direto na conexão
+// e continuam compactos: no NDJSON, cada usuário PRECISA caber em uma linha
+func wantsPrettyJSON(r *http.Request) bool {
+	if pretty, _ := strconv.ParseBool(r.URL.Query().Get("pretty")); pretty {
+		return true
+	}
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		if _, params, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil {
+			if pretty, _ := strconv.ParseBool(params["pretty"]); pretty {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ============================================
+// XML
+// ============================================
+// writeXML escreve data como XML, com a MESMA estrutura do JSON:
+//
+//	{"id": "1", "emails": [{"address": "a@b.com"}], "metadata": {"plan": "pro"}}
+//
+// vira
+//
+//	<response><id>1</id><emails><item><address>a@b.com</address></item></emails><metadata><plan>pro</plan></metadata></response>
+//
+// POR QUE CONVERTER A PARTIR DO JSON?
+// - Os tipos já têm tags json (nomes, omitempty, campos promovidos); o XML herda tudo isso
+// - encoding/xml não serializa maps (metadata, respostas de listas): a conversão resolve todos de uma vez
+// - Um tipo novo de resposta sai em XML sem precisar de tags xml próprias
+//
+// Itens de arrays viram <item>; chaves que não são nomes XML válidos (ex: chave de metadata "1st")
+// viram <entry key="1st">
+func writeXML(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	body, err := encodeXML(data)
+	if err != nil {
+		writeJSON(w, r, http.StatusInternalServerError, map[string]string{"error": "internal server error", "code": CodeInternal})
+		return
+	}
+	w.Header().Set("Content-Type", mediaXML+"; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// encodeXML serializa data em JSON e reescreve os tokens como elementos XML, na mesma ordem
+func encodeXML(data interface{}) ([]byte, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber() // Mantém os números como vieram (sem passar por float64)
+
+	if err := jsonValueToXML(enc, dec, "response"); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonValueToXML lê o próximo valor JSON de dec e o escreve como o elemento name
+// Objetos e arrays são percorridos recursivamente; null vira um elemento vazio
+func jsonValueToXML(enc *xml.Encoder, dec *json.Decoder, name string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	start := xmlElement(name)
+
+	delim, isDelim := tok.(json.Delim)
+	if !isDelim {
+		text := ""
+		if tok != nil {
+			text = fmt.Sprint(tok)
+		}
+		return enc.EncodeElement(text, start)
+	}
+
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for dec.More() {
+		child := "item"
+		if delim == '{' {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			child = key.(string)
+		}
+		if err := jsonValueToXML(enc, dec, child); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil { // Consome o "}" ou "]"
+		return err
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// xmlNamePattern é um subconjunto seguro dos nomes de elemento XML
+var xmlNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// xmlElement cria o elemento para a chave JSON name
+// Nomes inválidos e os começados por "xml" (reservados) viram <entry key="...">
+func xmlElement(name string) xml.StartElement {
+	if xmlNamePattern.MatchString(name) && !strings.HasPrefix(strings.ToLower(name), "xml") {
+		return xml.StartElement{Name: xml.Name{Local: name}}
+	}
+	return xml.StartElement{
+		Name: xml.Name{Local: "entry"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}},
+	}
+}
@@ -0,0 +1,36 @@
+package http
+
+import (
+	"mime"
+	"net/http"
+)
+
+// ============================================
+// MIDDLEWARE DE CONTENT-TYPE
+// ============================================
+// RequireJSON exige "Content-Type: application/json" em requisições com corpo
+//
+// POR QUE?
+// - Sem a verificação, um formulário (application/x-www-form-urlencoded) chega ao decodeJSON
+// - O cliente recebe um "Invalid JSON" confuso, sem saber que o problema é o Content-Type
+// - 415 Unsupported Media Type diz exatamente o que está errado
+//
+// QUAIS MÉTODOS?
+// - Só POST, PUT e PATCH (os que enviam corpo)
+// - GET, DELETE, HEAD e OPTIONS passam direto: não têm corpo para validar
+//
+// mime.ParseMediaType separa o tipo dos parâmetros, então
+// "application/json; charset=utf-8" também é aceito
+func RequireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != "application/json" {
+				writeError(w, r, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers que o navegador pode enviar e ler em requisições de outras origens
+const (
+	corsAllowMethods  = "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsAllowHeaders  = "Authorization, Content-Type, If-Match, If-None-Match, Idempotency-Key, X-API-Key, X-Envelope, X-Request-Id, X-Tenant-ID"
+	corsExposeHeaders = "ETag, Link, Location, Retry-After, X-Request-Id"
+)
+
+// ============================================
+// MIDDLEWARE DE CORS
+// ============================================
+// NewCORS libera o acesso à API a páginas de outras origens (ex: um front-end em https://app.exemplo.com)
+//
+// COMO O NAVEGADOR FAZ:
+// - Requisições "simples" vão direto; a resposta só é entregue à página se trouxer Access-Control-Allow-Origin
+// - As demais (PUT, DELETE, JSON, header Authorization...) são precedidas de um PREFLIGHT:
+// um OPTIONS com Access-Control-Request-Method perguntando se a requisição de verdade é permitida
+//
+// REGRAS:
+// - allowedOrigins vazio: o middleware não faz nada (CORS desabilitado)
+// - "*" libera qualquer origem; senão, só as origens da lista (comparação exata, ex: "https://app.exemplo.com")
+// - Origem fora da lista: a resposta sai sem os headers de CORS e o navegador bloqueia a leitura
+// - O preflight é respondido aqui mesmo (204), antes do roteamento e da autenticação
+//
+// SOBRE Access-Control-Max-Age:
+// - Sem ele, o navegador guarda o resultado do preflight por poucos segundos e repete o OPTIONS
+// - maxAge (CORS_MAX_AGE) diz por quanto tempo o resultado pode ser reaproveitado; zero omite o header
+//
+// SOBRE Vary: Origin:
+// - Com uma lista de origens, Access-Control-Allow-Origin ecoa a origem de CADA requisição
+// - Vary: Origin avisa caches que a mesma URL tem respostas diferentes por origem
+// - Com "*" a resposta é a mesma para todos e o header não é necessário
+//
+// Não libera cookies (Access-Control-Allow-Credentials): a API autentica por headers
+func NewCORS(allowedOrigins []string, maxAge time.Duration) func(http.Handler) http.Handler {
+	allowAll := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[origin] = true
+	}
+	maxAgeSeconds := strconv.Itoa(int(maxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		if len(allowed) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			if !allowAll {
+				h.Add("Vary", "Origin")
+			}
+
+			origin := r.Header.Get("Origin")
+			if origin == "" || !(allowAll || allowed[origin]) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if allowAll {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+
+			// Preflight: OPTIONS com Access-Control-Request-Method (um OPTIONS comum segue para a rota)
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				h.Set("Access-Control-Allow-Methods", corsAllowMethods)
+				h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+				if maxAge > 0 {
+					h.Set("Access-Control-Max-Age", maxAgeSeconds)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			h.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ParseCORSOrigins lê a lista de origens de CORS_ALLOWED_ORIGINS ("https://a.com,https://b.com")
+// Barras finais são removidas: o header Origin nunca as tem
+func ParseCORSOrigins(raw string) []string {
+	var origins []string
+	for _, origin := range strings.Split(raw, ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// ============================================
+// LOG DOS CORPOS (DEBUG_BODIES)
+// ============================================
+// Em staging às vezes precisamos ver exatamente o que entrou e o que saiu, sem montar um proxy
+// Com DEBUG_BODIES=true (e LOG_LEVEL=debug), cada requisição gera uma linha DEBUG com os dois corpos e os headers:
+//
+//	{"level":"DEBUG","msg":"http bodies","request_id":"...","method":"POST","path":"/api/v1/users",
+//	 "status":201,"request_body":"{\"name\":\"Maria\",...}","response_body":"{\"id\":\"507f...\",...}",
+//	 "request_headers":{"Authorization":"[REDACTED]","Content-Type":"application/json"},"response_headers":{...}}
+//
+// CUIDADOS:
+// - Só os primeiros maxBytes de cada corpo vão para o log ("..." marca o corte)
+// - Campos JSON com nomes sensíveis (password, token, secret...) têm o valor trocado por "[REDACTED]"
+// - Headers sensíveis (Authorization, Cookie, X-API-Key... e LOG_REDACT_HEADERS) também, pelo HeaderRedactor
+// - O corpo da requisição é lido só até maxBytes e "recolocado" na frente do resto: o handler lê o corpo inteiro,
+// sem que o middleware guarde mais do que o trecho que vai para o log
+//
+// DESLIGADO É INERTE:
+// - Sem DEBUG_BODIES o middleware nem é registrado (ver cmd/api/main.go)
+// - Com ele, mas com o nível DEBUG desligado, a requisição passa direto, sem buffer nenhum
+
+// sensitiveJSONField casa um par "chave": "valor" cuja chave parece guardar um segredo
+// Funciona também em um corpo cortado no meio (não precisa de um JSON completo):
+// um valor sem a aspa final, cortado em maxBytes, é escondido até o fim
+var sensitiveJSONField = regexp.MustCompile(`(?i)("[^"]*(?:password|passwd|secret|token|api_?key|authorization)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"?`)
+
+// NewDebugBodies cria o middleware que registra os corpos em DEBUG
+// maxBytes é quanto de cada corpo vai para o log; redactor esconde os headers sensíveis
+func NewDebugBodies(logger *slog.Logger, maxBytes int, redactor *HeaderRedactor) func(http.Handler) http.Handler {
+	logger = logger.With("component", "debug_bodies")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !logger.Enabled(r.Context(), slog.LevelDebug) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Lê até maxBytes+1: o byte a mais diz se o corpo foi cortado
+			var reqBody []byte
+			if r.Body != nil && r.Body != http.NoBody {
+				reqBody, _ = io.ReadAll(io.LimitReader(r.Body, int64(maxBytes)+1))
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(reqBody), r.Body), r.Body}
+			}
+
+			// Tee copia o que o handler escreve para resp, que guarda só o começo
+			// WrapResponseWriter mantém http.Flusher (o stream ndjson continua funcionando)
+			resp := &headBuffer{max: maxBytes}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(resp)
+
+			next.ServeHTTP(ww, r)
+
+			logger.Debug("http bodies",
+				"request_id", middleware.GetReqID(r.Context()),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", ww.Status(),
+				"request_body", debugBody(reqBody, maxBytes),
+				"response_body", debugBody(resp.buf.Bytes(), maxBytes),
+				"response_bytes", ww.BytesWritten(),
+				"request_headers", redactor.LogValue(r.Header),
+				"response_headers", redactor.LogValue(ww.Header()),
+			)
+		})
+	}
+}
+
+// headBuffer guarda só os primeiros max bytes escritos (e um a mais, para saber se houve corte)
+// Write nunca falha: um erro aqui interromperia a resposta do cliente
+type headBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if room := b.max + 1 - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// debugBody prepara o corpo para o log: corta em maxBytes e esconde os campos sensíveis
+func debugBody(body []byte, maxBytes int) string {
+	truncated := len(body) > maxBytes
+	if truncated {
+		body = body[:maxBytes]
+	}
+	out := sensitiveJSONField.ReplaceAllString(string(body), `${1}"[REDACTED]"`)
+	if truncated {
+		out += "..."
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// envelopeHeader é o header que pede o envelope (alternativa ao ?envelope=true)
+const envelopeHeader = "X-Envelope"
+
+// ============================================
+// ENVELOPE DE RESPOSTA
+// ============================================
+// Por padrão, as respostas continuam como sempre foram:
+// - GET /api/v1/users → array puro: [{...}, {...}]
+// - GET /api/v1/users/{id} → o usuário: {...}
+//
+// Com ?envelope=true (ou o header "X-Envelope: true"), todas têm o MESMO formato:
+//
+//	{"data": [...], "meta": {"total": 57, "limit": 20, "offset": 40}}
+//	{"data": {...}}
+//
+// POR QUE OPCIONAL?
+// - Clientes existentes esperam o array puro: mudar o padrão quebraria todos eles
+// - Clientes gerados (OpenAPI generators) preferem um formato só, com os metadados em um lugar fixo
+//
+// POR QUE CENTRALIZADO AQUI?
+// - Os handlers só informam os dados e os metadados; o formato é decidido em um lugar
+// - Um endpoint novo entra no envelope chamando writeResource ou writeList
+
+// envelope é o corpo de uma resposta com envelope
+type envelope struct {
+	Data interface{} `json:"data"`
+	Meta *listMeta   `json:"meta,omitempty"` // Só em listas
+}
+
+// listMeta são os metadados de uma lista
+// Ponteiros distinguem "zero" de "não se aplica": offset 0 aparece, offset ausente não
+type listMeta struct {
+	Total  *int64 `json:"total,omitempty"`  // Itens que casam com o filtro (todas as páginas)
+	Limit  int    `json:"limit,omitempty"`  // Tamanho de página aplicado
+	Offset *int   `json:"offset,omitempty"` // Paginação por offset
+	Next   string `json:"next,omitempty"`   // Paginação por cursor (ausente na última página)
+}
+
+// wantsEnvelope diz se o cliente pediu o envelope (?envelope=true ou X-Envelope: true)
+// Valores que não são booleanos contam como "não pediu", como no ?diff=
+//
+// Vary: X-Envelope avisa caches (proxies, navegador) que a MESMA URL
+// tem corpos diferentes conforme o header - por isso vai em toda resposta que consulta o envelope
+func wantsEnvelope(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Add("Vary", envelopeHeader)
+	if v, _ := strconv.ParseBool(r.URL.Query().Get("envelope")); v {
+		return true
+	}
+	v, _ := strconv.ParseBool(r.Header.Get(envelopeHeader))
+	return v
+}
+
+// writeResource escreve um recurso único: {"data": {...}} com envelope, o próprio recurso sem ele
+func writeResource(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	if wantsEnvelope(w, r) {
+		writeResponse(w, r, status, envelope{Data: data})
+		return
+	}
+	writeResponse(w, r, status, data)
+}
+
+// writeList escreve uma lista com envelope: {"data": [...], "meta": {...}}
+// Quem chama já verificou wantsEnvelope: sem envelope, cada lista mantém o seu formato antigo
+func writeList(w http.ResponseWriter, r *http.Request, status int, data interface{}, meta listMeta) {
+	writeResponse(w, r, status, envelope{Data: data, Meta: &meta})
+}
@@ -0,0 +1,183 @@
+package http
+
+import (
+	"net/http"
+
+	"user-api/internal/usecase"
+)
+
+// ============================================
+// CÓDIGOS DE ERRO
+// ============================================
+// Toda resposta de erro tem dois campos:
+//
+//	{"error": "user not found", "code": "USER_NOT_FOUND"}
+//
+// - error: mensagem para HUMANOS (pode mudar de texto a qualquer momento)
+// - code: identificador ESTÁVEL para programas (SDKs fazem switch nele, sem comparar strings)
+//
+// POR QUE OS CÓDIGOS FICAM NO HANDLER?
+// - Eles fazem parte do contrato HTTP, assim como o status
+// - O usecase continua expondo só os erros Go (ErrNotFound, ErrEmailTaken...)
+// - Aqui traduzimos cada erro do usecase para o seu código (ver usecaseErrorCodes)
+//
+// Um código publicado nunca muda de significado: novos casos ganham códigos novos
+
+// Códigos específicos (erros do usecase e da leitura do corpo)
+const (
+	CodeUserNotFound                    = "USER_NOT_FOUND"
+	CodeUserGone                        = "USER_GONE"
+	CodeInvalidEmail                    = "INVALID_EMAIL"
+	CodeInvalidPhone                    = "INVALID_PHONE"
+	CodeInvalidMetadata                 = "INVALID_METADATA"
+	CodeValidationFailed                = "VALIDATION_FAILED"
+	CodeEmailTaken                      = "EMAIL_TAKEN"
+	CodeTooManyEmails                   = "TOO_MANY_EMAILS"
+	CodeEmailUnchanged                  = "EMAIL_UNCHANGED"
+	CodeEmailChangeRequiresVerification = "EMAIL_CHANGE_REQUIRES_VERIFICATION"
+	CodeVersionConflict                 = "VERSION_CONFLICT"
+	CodeInvalidPagination               = "INVALID_PAGINATION"
+	CodeInvalidSearch                   = "INVALID_SEARCH"
+	CodeInvalidFilter                   = "INVALID_FILTER"
+	CodeInvalidIDs                      = "INVALID_IDS"
+	CodeInvalidID                       = "INVALID_ID"
+	CodeInvalidFields                   = "INVALID_FIELDS"
+	CodeInvalidToken                    = "INVALID_TOKEN"
+	CodeTokenExpired                    = "TOKEN_EXPIRED"
+	CodeTransactionsUnsupported         = "TRANSACTIONS_UNSUPPORTED"
+	CodeChangeStreamsUnsupported        = "CHANGE_STREAMS_UNSUPPORTED"
+	CodeTooManyStreams                  = "TOO_MANY_STREAMS"
+	CodeInvalidJSON                     = "INVALID_JSON"
+	CodeUnknownField                    = "UNKNOWN_FIELD"
+	CodeInvalidFieldType                = "INVALID_FIELD_TYPE"
+	CodeBodyTooLarge                    = "BODY_TOO_LARGE"
+	CodeSchemaViolation                 = "SCHEMA_VIOLATION"
+	CodeIdempotencyKeyReused            = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyKeyInProgress        = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	CodeTimeout                         = "TIMEOUT"
+	CodeOverloaded                      = "OVERLOADED"
+	CodeDatabaseBusy                    = "DATABASE_BUSY"
+	CodeDocumentTooLarge                = "DOCUMENT_TOO_LARGE"
+	CodeConfirmationRequired            = "CONFIRMATION_REQUIRED"
+	CodeMergeSameUser                   = "MERGE_SAME_USER"
+	CodeTenantRequired                  = "TENANT_REQUIRED"
+	CodeInvalidTenant                   = "INVALID_TENANT"
+)
+
+// Códigos genéricos, usados quando não há um código específico para o erro
+const (
+	CodeBadRequest           = "BAD_REQUEST"
+	CodeUnauthorized         = "UNAUTHORIZED"
+	CodeNotFound             = "NOT_FOUND"
+	CodeMethodNotAllowed     = "METHOD_NOT_ALLOWED"
+	CodeNotAcceptable        = "NOT_ACCEPTABLE"
+	CodeConflict             = "CONFLICT"
+	CodeGone                 = "GONE"
+	CodePreconditionFailed   = "PRECONDITION_FAILED"
+	CodeURITooLong           = "URI_TOO_LONG"
+	CodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	CodeUnprocessableEntity  = "UNPROCESSABLE_ENTITY"
+	CodeRateLimited          = "RATE_LIMITED"
+	CodeInternal             = "INTERNAL_ERROR"
+	CodeServiceUnavailable   = "SERVICE_UNAVAILABLE"
+)
+
+// usecaseErrorCodes traduz os erros do usecase para os códigos da API
+var usecaseErrorCodes = map[error]string{
+	usecase.ErrNotFound:             CodeUserNotFound,
+	usecase.ErrGone:                 CodeUserGone,
+	usecase.ErrInvalidEmail:         CodeInvalidEmail,
+	usecase.ErrEmailTooLong:         CodeInvalidEmail,
+	usecase.ErrInvalidPhone:         CodeInvalidPhone,
+	usecase.ErrTooManyMetadataKeys:  CodeInvalidMetadata,
+	usecase.ErrInvalidMetadataKey:   CodeInvalidMetadata,
+	usecase.ErrMetadataValueTooLong: CodeInvalidMetadata,
+	usecase.ErrNameTooLong:          CodeValidationFailed,
+	usecase.ErrNameRequired:         CodeValidationFailed,
+	usecase.ErrNameTooShort:         CodeValidationFailed,
+	usecase.ErrInvalidName:          CodeValidationFailed,
+	usecase.ErrEmailTaken:           CodeEmailTaken,
+	usecase.ErrTooManyEmails:        CodeTooManyEmails,
+	usecase.ErrEmailUnchanged:       CodeEmailUnchanged,
+	// PUT com outro email principal: a troca é pelo POST /{id}/change-email
+	usecase.ErrEmailChangeRequiresVerification: CodeEmailChangeRequiresVerification,
+	usecase.ErrVersionConflict:                 CodeVersionConflict,
+	usecase.ErrInvalidCursor:                   CodeInvalidPagination,
+	usecase.ErrInvalidLimit:                    CodeInvalidPagination,
+	usecase.ErrInvalidOffset:                   CodeInvalidPagination,
+	usecase.ErrInvalidUpdatedSince:             CodeInvalidFilter,
+	usecase.ErrOffsetTooLarge:                  CodeInvalidPagination,
+	usecase.ErrSearchTermTooLong:               CodeInvalidSearch,
+	usecase.ErrInvalidSort:                     CodeInvalidSearch,
+	usecase.ErrInvalidOrder:                    CodeInvalidSearch,
+	usecase.ErrInvalidDate:                     CodeInvalidSearch,
+	usecase.ErrInvalidDateRange:                CodeInvalidSearch,
+	usecase.ErrNoIDs:                           CodeInvalidIDs,
+	usecase.ErrTooManyIDs:                      CodeInvalidIDs,
+	usecase.ErrInvalidToken:                    CodeInvalidToken,
+	usecase.ErrTokenExpired:                    CodeTokenExpired,
+	usecase.ErrTransactionsUnsupported:         CodeTransactionsUnsupported,
+	usecase.ErrNoChanges:                       CodeValidationFailed,
+	usecase.ErrBulkConfirmRequired:             CodeConfirmationRequired,
+	usecase.ErrMergeSameUser:                   CodeMergeSameUser,
+	// Exige replica set, como as transações (501 no GET /stream)
+	usecase.ErrChangeStreamsUnsupported: CodeChangeStreamsUnsupported,
+}
+
+// statusCodes é o código genérico de cada status HTTP
+var statusCodes = map[int]string{
+	http.StatusBadRequest:           CodeBadRequest,
+	http.StatusUnauthorized:         CodeUnauthorized,
+	http.StatusNotFound:             CodeNotFound,
+	http.StatusMethodNotAllowed:     CodeMethodNotAllowed,
+	http.StatusNotAcceptable:        CodeNotAcceptable,
+	http.StatusConflict:             CodeConflict,
+	http.StatusGone:                 CodeGone,
+	http.StatusPreconditionFailed:   CodePreconditionFailed,
+	http.StatusRequestURITooLong:    CodeURITooLong,
+	http.StatusUnsupportedMediaType: CodeUnsupportedMediaType,
+	http.StatusUnprocessableEntity:  CodeUnprocessableEntity,
+	http.StatusTooManyRequests:      CodeRateLimited,
+	http.StatusInternalServerError:  CodeInternal,
+	http.StatusServiceUnavailable:   CodeServiceUnavailable,
+}
+
+// writeError escreve uma resposta de erro com o código genérico do status
+// r é usado para escolher o idioma da mensagem (Accept-Language, ver i18n.go)
+func writeError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	writeErrorCode(w, r, status, statusCode(status), msg)
+}
+
+// writeUsecaseError escreve um erro do usecase: a mensagem é err.Error()
+// e o código vem de usecaseErrorCodes (ou do status, se o erro não estiver lá)
+func writeUsecaseError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	code, ok := usecaseErrorCodes[err]
+	if !ok {
+		code = statusCode(status)
+	}
+	writeErrorCode(w, r, status, code, err.Error())
+}
+
+// writeErrorCode escreve {"error": msg, "code": code} com o status informado
+// Se o cliente pediu outro idioma e o catálogo tem o código, a mensagem é traduzida;
+// senão msg (em inglês) é usada como está. O code nunca muda com o idioma
+func writeErrorCode(w http.ResponseWriter, r *http.Request, status int, code, msg string) {
+	lang := negotiateLanguage(r.Header.Get("Accept-Language"))
+	if translated, ok := translate(lang, code); ok {
+		msg = translated
+	}
+
+	w.Header().Set("Content-Language", lang)
+	// Vary avisa caches (CDN, proxies) que a resposta muda conforme o Accept-Language
+	w.Header().Add("Vary", "Accept-Language")
+	// Erros seguem o formato negociado pelo Accept (JSON ou XML), como as demais respostas
+	writeResponse(w, r, status, map[string]string{"error": msg, "code": code})
+}
+
+// statusCode devolve o código genérico do status (INTERNAL_ERROR para status sem código)
+func statusCode(status int) string {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	return CodeInternal
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// ETAG
+// ============================================
+// ETag é uma "impressão digital" da representação de um recurso
+//
+// USOS:
+// - Cache: If-None-Match com o ETag atual → 304 Not Modified (sem corpo)
+// - Concorrência: If-Match com ETag desatualizado → 412 Precondition Failed
+//
+// Toda a API calcula o ETag aqui, para que GET, PUT e DELETE concordem
+
+// computeETag calcula o ETag de um usuário a partir do hash dos seus campos
+// O valor vem entre aspas, como exige a especificação HTTP (ex: "a1b2c3...")
+func computeETag(user *domain.User) string {
+	// json.Marshal de uma struct é determinístico (campos na ordem da declaração)
+	data, _ := json.Marshal(user)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches verifica se o header (If-Match ou If-None-Match) contém o ETag
+// O header pode ter uma lista separada por vírgulas, o curinga "*"
+// ou ETags fracos (prefixo W/), que comparamos pelo valor
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"user-api/internal/domain"
+)
+
+// flushEvery define de quantas em quantas linhas enviamos os dados ao cliente
+const flushEvery = 100
+
+// ============================================
+// EXPORTAÇÃO DE USUÁRIOS
+// ============================================
+// exportUsers trata requisições GET /api/v1/users/export?format=csv|json
+//
+// STREAMING:
+// - Os usuários são lidos um a um do cursor do MongoDB e escritos na resposta
+// - Nada é acumulado em memória: funciona para collections de qualquer tamanho
+// - http.Flusher envia periodicamente o que já foi escrito para o cliente
+//
+// LIMITAÇÃO:
+// - Depois que a primeira linha foi enviada, o status 200 já saiu
+// - Se o banco falhar no meio, só podemos interromper a resposta (e registrar em log)
+//
+// PRAZOS:
+// - Nem REQUEST_TIMEOUT nem WRITE_TIMEOUT valem aqui (ver isLongLived): cortariam uma exportação grande
+// no meio, com o 200 já enviado
+// - A leitura termina quando acaba ou quando o cliente desconecta
+//
+// @Summary Export users
+// @Tags users
+// @Produce text/csv
+// @Produce json
+// @Param format query string false "Export format: csv (default) or json"
+// @Param name query string false "Filter by name (partial, case-insensitive)"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/v1/users/export [get]
+func (h *UserHandler) exportUsers(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+
+	if format != "csv" && format != "json" {
+		writeError(w, r, http.StatusBadRequest, `Invalid format (use "csv" or "json")`)
+		return
+	}
+	clearWriteDeadline(w, h.logger)
+
+	var err error
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="users.csv"`)
+		err = h.exportCSV(w, r)
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="users.json"`)
+		err = h.exportJSON(w, r)
+	}
+
+	if err != nil {
+		h.logger.Error("export interrupted", "format", format, "error", err)
+	}
+}
+
+// exportCSV escreve o cabeçalho e uma linha por usuário
+// encoding/csv cuida de aspas e vírgulas dentro dos valores
+func (h *UserHandler) exportCSV(w http.ResponseWriter, r *http.Request) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "name", "email", "created_at"}); err != nil {
+		return err
+	}
+
+	rows := 0
+	err := h.uc.StreamUsers(r.Context(), parseFilter(r), func(u *domain.User) error {
+		if err := cw.Write([]string{u.ID, u.Name, u.Email, u.CreatedAt.Format(time.RFC3339)}); err != nil {
+			return err
+		}
+		rows++
+		if rows%flushEvery == 0 {
+			cw.Flush()
+			flush(w)
+		}
+		return cw.Error()
+	})
+
+	// Flush final: garante que as últimas linhas (buffer do csv.Writer) sejam enviadas
+	cw.Flush()
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// exportJSON escreve um array JSON item por item: [ {...}, {...} ]
+// Diferente de writeJSON, não monta o slice inteiro antes de codificar
+func (h *UserHandler) exportJSON(w http.ResponseWriter, r *http.Request) error {
+	if _, err := w.Write([]byte("[")); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	rows := 0
+	err := h.uc.StreamUsers(r.Context(), parseFilter(r), func(u *domain.User) error {
+		if rows > 0 {
+			if _, err := w.Write([]byte(",")); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(u); err != nil {
+			return err
+		}
+		rows++
+		if rows%flushEvery == 0 {
+			flush(w)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte("]\n"))
+	return err
+}
+
+// ============================================
+// STREAMING NDJSON DA LISTAGEM
+// ============================================
+// ndjsonMediaType é o Content-Type do NDJSON (newline-delimited JSON): um objeto JSON por linha
+const ndjsonMediaType = "application/x-ndjson"
+
+// listOrStream despacha GET /api/v1/users: com ?stream= a resposta é o streaming NDJSON,
+// sem ele segue para list (a listagem de sempre)
+//
+// POR QUE NÃO DENTRO DE listUsers?
+// - A listagem passa pela negociação de conteúdo (JSON ou XML); o NDJSON é escolhido pela query,
+// como o ?format= do /export, e um "Accept: application/x-ndjson" não pode virar 406
+func (h *UserHandler) listOrStream(list http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("stream") {
+			h.streamUsersNDJSON(w, r)
+			return
+		}
+		list.ServeHTTP(w, r)
+	}
+}
+
+// streamUsersNDJSON trata GET /api/v1/users?stream=ndjson
+// Escreve um usuário por linha, lidos um a um do cursor do MongoDB:
+//
+//	{"id":"507f...","name":"Maria Silva",...}
+//	{"id":"507f...","name":"João Souza",...}
+//
+// POR QUE NDJSON, SE JÁ EXISTE O /export?format=json?
+// - O cliente processa cada linha assim que ela chega, sem esperar (nem parsear) um array gigante
+// - Ferramentas de linha de comando (jq, grep) e pipelines de dados leem NDJSON nativamente
+// - Segue os filtros e o ?fields= da listagem (e é pública como ela)
+//
+// Devolve TODOS os usuários que casam com o filtro: after, limit e offset não se combinam com o streaming
+// Como no /export, sem REQUEST_TIMEOUT nem WRITE_TIMEOUT (ver isLongLived), e com as mesmas limitações
+func (h *UserHandler) streamUsersNDJSON(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("stream") != "ndjson" {
+		writeError(w, r, http.StatusBadRequest, `Invalid stream (use "ndjson")`)
+		return
+	}
+	if query.Has("after") || query.Has("limit") || query.Has("offset") {
+		writeError(w, r, http.StatusBadRequest, "stream=ndjson returns every matching user and cannot be combined with after, limit or offset")
+		return
+	}
+	fields, err := parseFields(r)
+	if err != nil {
+		writeErrorCode(w, r, http.StatusBadRequest, CodeInvalidFields, err.Error())
+		return
+	}
+	filter, err := parseListFilter(r)
+	if err != nil {
+		writeUsecaseError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	filter.Fields = fields
+
+	clearWriteDeadline(w, h.logger)
+	w.Header().Set("Content-Type", ndjsonMediaType)
+
+	// Encode termina cada valor com "\n": exatamente uma linha por usuário
+	enc := json.NewEncoder(w)
+	rows := 0
+	err = h.uc.StreamUsers(r.Context(), filter, func(u *domain.User) error {
+		if err := enc.Encode(h.userResource(r, u, fields)); err != nil {
+			return err
+		}
+		rows++
+		if rows%flushEvery == 0 {
+			flush(w)
+		}
+		return nil
+	})
+	if err == nil {
+		return
+	}
+
+	// Antes da primeira linha nada foi enviado: ainda dá tempo de responder um erro de verdade
+	if rows == 0 {
+		h.writeServerError(w, r, err, "Failed to stream users")
+		return
+	}
+	h.logger.Error("ndjson stream interrupted", "rows", rows, "error", err)
+}
+
+// flush envia ao cliente o que já foi escrito, se o ResponseWriter suportar
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// SELEÇÃO DE CAMPOS (SPARSE FIELDSETS)
+// ============================================
+// ?fields=id,name faz a resposta trazer apenas esses campos de cada usuário
+// Útil para clientes móveis: menos bytes trafegando pela rede
+//
+// COMO FUNCIONA:
+// - Na listagem, os campos viram uma projeção no MongoDB (o banco envia menos dados)
+// - Na resposta, cada usuário vira um objeto JSON só com os campos pedidos
+// - Nomes desconhecidos retornam 400: um erro de digitação (ex: "emial")
+//   não deve passar despercebido devolvendo um objeto incompleto
+
+// parseFields lê ?fields= e valida cada nome contra domain.UserFieldNames
+// Retorna nil quando o parâmetro não foi informado (todos os campos)
+func parseFields(r *http.Request) ([]string, error) {
+	raw := r.URL.Query().Get("fields")
+	if raw == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" || slices.Contains(fields, f) {
+			continue
+		}
+		if !slices.Contains(domain.UserFieldNames, f) {
+			return nil, fmt.Errorf("unknown field %q in fields (allowed: %s)", f, strings.Join(domain.UserFieldNames, ", "))
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// selectFields monta a representação JSON do usuário só com os campos pedidos
+func selectFields(user *domain.User, fields []string) map[string]interface{} {
+	out := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		switch f {
+		case "id":
+			out["id"] = user.ID
+		case "name":
+			out["name"] = user.Name
+		case "email":
+			out["email"] = user.Email
+		case "emails":
+			out["emails"] = user.Emails
+		case "phone":
+			out["phone"] = user.Phone
+		case "verified":
+			out["verified"] = user.Verified
+		case "metadata":
+			out["metadata"] = user.Metadata
+		case "version":
+			out["version"] = user.Version
+		case "login_count":
+			out["login_count"] = user.LoginCount
+		case "created_at":
+			out["created_at"] = user.CreatedAt
+		case "updated_at":
+			out["updated_at"] = user.UpdatedAt
+		}
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// LINKS HATEOAS (?hateoas=true)
+// ============================================
+// Com ?hateoas=true, cada usuário da resposta ganha um objeto "_links" com o que dá para fazer com ele:
+//
+//	{"id": "507f...", "name": "Maria", ...,
+//	 "_links": {
+//	   "self":   {"href": "http://host/api/v1/users/507f...", "method": "GET"},
+//	   "update": {"href": "http://host/api/v1/users/507f...", "method": "PUT"},
+//	   "delete": {"href": "http://host/api/v1/users/507f...", "method": "DELETE"}
+//	 }}
+//
+// POR QUE OPCIONAL?
+// - Clientes hipermídia genéricos navegam pelos links sem montar URLs
+// - Os demais só pagariam bytes a mais em toda resposta: o padrão continua enxuto
+//
+// POR QUE CENTRALIZADO AQUI?
+// - Respostas de um usuário e de listas usam userResource/userResources: os links são sempre os mesmos
+// - As URLs são absolutas (esquema e host da requisição, como no header Link) e levam o BASE_PATH,
+// o prefixo sob o qual um proxy publica a API (ex: "/users-service")
+
+// hrefLink é um link do objeto _links
+type hrefLink struct {
+	Href   string `json:"href"`
+	Method string `json:"method"`
+}
+
+// userLinks é o objeto _links de um usuário
+type userLinks struct {
+	Self   hrefLink `json:"self"`
+	Update hrefLink `json:"update"`
+	Delete hrefLink `json:"delete"`
+}
+
+// userWithLinks é o usuário com "_links" a mais (mesmo embedding de userWithChanges)
+type userWithLinks struct {
+	*domain.User
+	Links *userLinks `json:"_links"`
+}
+
+// wantsLinks diz se o cliente pediu os links (?hateoas=true)
+// Valores que não são booleanos contam como "não pediu", como no ?envelope=
+func wantsLinks(r *http.Request) bool {
+	v, _ := strconv.ParseBool(r.URL.Query().Get("hateoas"))
+	return v
+}
+
+// userLinks monta os links do usuário, ou nil quando o cliente não os pediu
+func (h *UserHandler) userLinks(r *http.Request, id string) *userLinks {
+	if !wantsLinks(r) {
+		return nil
+	}
+	href := requestScheme(r) + "://" + r.Host + h.basePath + "/api/v1/users/" + id
+	return &userLinks{
+		Self:   hrefLink{Href: href, Method: http.MethodGet},
+		Update: hrefLink{Href: href, Method: http.MethodPut},
+		Delete: hrefLink{Href: href, Method: http.MethodDelete},
+	}
+}
+
+// userResource monta a representação de um usuário: só os campos pedidos (?fields=) e os links (?hateoas=)
+// Sem nenhum dos dois, é o próprio usuário
+func (h *UserHandler) userResource(r *http.Request, user *domain.User, fields []string) interface{} {
+	links := h.userLinks(r, user.ID)
+	if len(fields) > 0 {
+		out := selectFields(user, fields)
+		if links != nil {
+			out["_links"] = links
+		}
+		return out
+	}
+	if links == nil {
+		return user
+	}
+	return userWithLinks{User: user, Links: links}
+}
+
+// userResources aplica userResource a uma lista inteira
+// Sem campos nem links pedidos, a lista original é devolvida sem alteração
+func (h *UserHandler) userResources(r *http.Request, users []*domain.User, fields []string) interface{} {
+	if len(fields) == 0 && !wantsLinks(r) {
+		return users
+	}
+	out := make([]interface{}, 0, len(users))
+	for _, u := range users {
+		out = append(out, h.userResource(r, u, fields))
+	}
+	return out
+}
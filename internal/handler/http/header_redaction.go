@@ -0,0 +1,106 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"net/textproto"
+	"slices"
+	"strings"
+)
+
+// DefaultSensitiveHeaders são os headers sempre escondidos nos logs (credenciais e sessões)
+// LOG_REDACT_HEADERS acrescenta outros; não há como tirar um destes da lista
+var DefaultSensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-API-Key",
+	"X-Auth-Token",
+	"X-CSRF-Token",
+}
+
+// redactedValue substitui o valor de um header sensível (o mesmo marcador dos corpos no DEBUG_BODIES)
+const redactedValue = "[REDACTED]"
+
+// ============================================
+// REDAÇÃO DE HEADERS NOS LOGS
+// ============================================
+// HeaderRedactor esconde os valores dos headers sensíveis antes de irem para o log
+//
+// POR QUE UM TIPO COMPARTILHADO?
+// - Todo caminho que registra headers (DEBUG_BODIES, panic recuperado...) usa o MESMO redactor, criado em main
+// - Um log novo que registre headers não escolhe a lista sozinho: herda a de sempre, com as extensões da configuração
+// - Um "Authorization: Bearer ..." no log é um token vazado: logs vão para agregadores, tickets e prints de tela
+//
+// O QUE APARECE NO LOG:
+// - O NOME do header continua lá, com "[REDACTED]" no valor: dá para saber que a credencial veio, sem vê-la
+// - Os demais headers aparecem como chegaram
+//
+// A comparação ignora maiúsculas (a forma canônica do Go: "x-api-key" e "X-Api-Key" são o mesmo header)
+type HeaderRedactor struct {
+	sensitive map[string]bool // Nomes na forma canônica (textproto.CanonicalMIMEHeaderKey)
+}
+
+// NewHeaderRedactor cria o redactor com DefaultSensitiveHeaders mais os headers de extra
+func NewHeaderRedactor(extra []string) *HeaderRedactor {
+	hr := &HeaderRedactor{sensitive: map[string]bool{}}
+	for _, name := range slices.Concat(DefaultSensitiveHeaders, extra) {
+		hr.sensitive[textproto.CanonicalMIMEHeaderKey(name)] = true
+	}
+	return hr
+}
+
+// ParseHeaderNames lê a lista de LOG_REDACT_HEADERS ("X-Session-Id, X-Signature")
+func ParseHeaderNames(raw string) []string {
+	var names []string
+	for _, name := range strings.Split(raw, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// IsSensitive informa se o valor do header deve ser escondido
+func (hr *HeaderRedactor) IsSensitive(name string) bool {
+	return hr.sensitive[textproto.CanonicalMIMEHeaderKey(name)]
+}
+
+// Redact devolve uma CÓPIA de h com os valores sensíveis trocados por "[REDACTED]"
+// h não é alterado: os headers originais continuam valendo para a requisição (ex: a autenticação)
+func (hr *HeaderRedactor) Redact(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for name, values := range h {
+		if hr.IsSensitive(name) {
+			masked := make([]string, len(values))
+			for i := range masked {
+				masked[i] = redactedValue
+			}
+			out[name] = masked
+			continue
+		}
+		out[name] = slices.Clone(values)
+	}
+	return out
+}
+
+// LogValue devolve os headers já redigidos como um grupo do slog, em ordem alfabética:
+//
+//	"request_headers": {"Authorization": "[REDACTED]", "Content-Type": "application/json"}
+//
+// Um header com vários valores vira uma string com os valores separados por ", " (como no HTTP)
+func (hr *HeaderRedactor) LogValue(h http.Header) slog.Value {
+	redacted := hr.Redact(h)
+	names := make([]string, 0, len(redacted))
+	for name := range redacted {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	attrs := make([]slog.Attr, 0, len(names))
+	for _, name := range names {
+		attrs = append(attrs, slog.String(name, strings.Join(redacted[name], ", ")))
+	}
+	return slog.GroupValue(attrs...)
+}
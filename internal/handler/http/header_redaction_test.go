@@ -0,0 +1,136 @@
+package http
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseHeaderNames(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{raw: "", want: nil},
+		{raw: "X-Session-Id", want: []string{"X-Session-Id"}},
+		{raw: " X-Session-Id , ,x-signature ", want: []string{"X-Session-Id", "x-signature"}},
+	}
+	for _, tt := range tests {
+		if got := ParseHeaderNames(tt.raw); !slices.Equal(got, tt.want) {
+			t.Errorf("ParseHeaderNames(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestHeaderRedactor(t *testing.T) {
+	redactor := NewHeaderRedactor(ParseHeaderNames("X-Session-Id, x-signature"))
+
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "Authorization", want: true},
+		{header: "authorization", want: true},
+		{header: "Cookie", want: true},
+		{header: "Set-Cookie", want: true},
+		{header: "X-API-Key", want: true},
+		{header: "x-api-key", want: true},
+		{header: "X-Session-Id", want: true},
+		{header: "X-Signature", want: true},
+		{header: "Content-Type", want: false},
+		{header: "X-Request-Id", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := redactor.IsSensitive(tt.header); got != tt.want {
+				t.Errorf("IsSensitive(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+
+			h := http.Header{}
+			h.Add(tt.header, "value-1")
+			h.Add(tt.header, "value-2")
+			redacted := redactor.Redact(h)
+
+			want := []string{"value-1", "value-2"}
+			if tt.want {
+				want = []string{redactedValue, redactedValue}
+			}
+			if got := redacted.Values(tt.header); !slices.Equal(got, want) {
+				t.Errorf("Redact()[%s] = %q, want %q", tt.header, got, want)
+			}
+			// O original continua valendo para a requisição
+			if got := h.Values(tt.header); !slices.Equal(got, []string{"value-1", "value-2"}) {
+				t.Errorf("Redact changed the original header: %q", got)
+			}
+		})
+	}
+}
+
+// TestHeaderRedactionInLogs confere que nenhum caminho que registra headers deixa uma credencial no log
+func TestHeaderRedactionInLogs(t *testing.T) {
+	secrets := map[string]string{
+		"Authorization": "Bearer secret-token",
+		"Cookie":        "session=secret-cookie",
+		"X-API-Key":     "secret-api-key",
+		"X-Session-Id":  "secret-session", // Extra, via LOG_REDACT_HEADERS
+	}
+	redactor := NewHeaderRedactor(ParseHeaderNames("X-Session-Id"))
+
+	tests := []struct {
+		name       string
+		middleware func(logger *slog.Logger) func(http.Handler) http.Handler
+		handler    http.HandlerFunc
+	}{
+		{
+			name: "debug bodies",
+			middleware: func(logger *slog.Logger) func(http.Handler) http.Handler {
+				return NewDebugBodies(logger, 1024, redactor)
+			},
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret-set-cookie"})
+				w.WriteHeader(http.StatusNoContent)
+			},
+		},
+		{
+			name: "recovered panic",
+			middleware: func(logger *slog.Logger) func(http.Handler) http.Handler {
+				return NewRecoveryMiddleware(logger, redactor)
+			},
+			handler: func(http.ResponseWriter, *http.Request) { panic("boom") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			r.Header.Set("Content-Type", "application/json")
+			for name, value := range secrets {
+				r.Header.Set(name, value)
+			}
+			tt.middleware(logger)(tt.handler).ServeHTTP(httptest.NewRecorder(), r)
+
+			out := logs.String()
+			if out == "" {
+				t.Fatal("nothing was logged")
+			}
+			if strings.Contains(out, "secret-") {
+				t.Errorf("log leaks a sensitive header:\n%s", out)
+			}
+			// O log usa o nome na forma canônica do Go ("X-Api-Key")
+			for name := range secrets {
+				if !strings.Contains(out, `"`+http.CanonicalHeaderKey(name)+`":"`+redactedValue+`"`) {
+					t.Errorf("log does not show %s as %s:\n%s", name, redactedValue, out)
+				}
+			}
+			if !strings.Contains(out, `"Content-Type":"application/json"`) {
+				t.Errorf("log hides a header that is not sensitive:\n%s", out)
+			}
+		})
+	}
+}
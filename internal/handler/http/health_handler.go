@@ -1,33 +1,186 @@
 package http
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// processStart marca quando o pacote foi carregado (praticamente o início do processo)
+// É a base do "uptime" do health check detalhado
+var processStart = time.Now()
+
+// Status de um componente (e do health check como um todo)
+const (
+	healthOK       = "ok"
+	healthDown     = "down"
+	healthDraining = "draining" // /readyz durante o encerramento
+)
+
+// defaultHealthCheckTimeout é o prazo de uma checagem sem Timeout próprio
+const defaultHealthCheckTimeout = 2 * time.Second
+
+// HealthCheck é uma checagem de um componente (ex: ping no MongoDB)
+// Check retorna nil quando o componente está saudável
+type HealthCheck struct {
+	Name    string
+	Timeout time.Duration // Prazo da checagem (zero usa defaultHealthCheckTimeout)
+	Check   func(ctx context.Context) error
+}
+
+// componentHealth é o resultado de uma checagem no JSON do modo detalhado
+type componentHealth struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
 // RegisterHealth registra a rota de healthcheck
 // Útil para monitoramento e verificar se a aplicação está respondendo
-func RegisterHealth(r chi.Router) {
-	r.Get("/healthz", healthz)
+//
+// checks só rodam no modo detalhado (?verbose=true); a forma simples continua instantânea
+func RegisterHealth(r chi.Router, checks ...HealthCheck) {
+	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		healthz(w, r, checks)
+	})
+}
+
+// ============================================
+// PRONTIDÃO (READINESS)
+// ============================================
+// RegisterReadiness registra GET /readyz: 200 enquanto ready for true, 503 depois
+//
+// POR QUE SEPARADO DO /healthz?
+// - /healthz diz se o processo está VIVO: falhar nele faz o orquestrador reiniciar o pod
+// - /readyz diz se ele deve RECEBER TRÁFEGO: falhar nele só tira o pod do load balancer
+// - No encerramento queremos o segundo, não o primeiro (ver SHUTDOWN_DELAY em main)
+//
+// ready é compartilhado com main, que o desliga ao receber SIGTERM
+// atomic.Bool porque é escrito pela goroutine de main e lido por todas as requisições, sem mutex
+// Como o /healthz simples, não consulta o banco: um MongoDB fora tiraria todas as réplicas do ar ao mesmo tempo
+func RegisterReadiness(r chi.Router, ready *atomic.Bool) {
+	r.Get("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		readyz(w, r, ready)
+	})
+}
+
+// readyz responde {"status": "ok"} ou, durante o encerramento, 503 {"status": "draining"}
+//
+// @Summary Readiness check
+// @Description Responds 503 once the server received SIGTERM and is draining (SHUTDOWN_DELAY), so load balancers stop routing to it
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /readyz [get]
+func readyz(w http.ResponseWriter, r *http.Request, ready *atomic.Bool) {
+	if !ready.Load() {
+		writeJSON(w, r, http.StatusServiceUnavailable, map[string]interface{}{"status": healthDraining})
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{"status": healthOK})
 }
 
 // healthz retorna um JSON simples indicando que a aplicação está funcionando
 // Este endpoint deve ser rápido - não faça consultas pesadas aqui
-// Em produção, você pode adicionar checagens (ex: ping ao banco), mas mantenha rápido
+//
+// DUAS FORMAS:
+// - /healthz: só confirma que o processo responde (liveness). É o que os probes usam
+// - /healthz?verbose=true: checa cada componente (ex: MongoDB) e mostra uptime e versão do Go
+//
+// POR QUE O PROBE NÃO CHECA O MONGODB?
+// - Se o banco cair, reiniciar a API não resolve nada
+// - Com a checagem no probe, TODAS as réplicas seriam reiniciadas em cascata
+// - O modo detalhado serve para quem investiga (staging, dashboards), não para o orquestrador
 //
 // @Summary Health check
+// @Description With verbose=true, checks each component (MongoDB...) and reports uptime and Go version. Responds 503 when any component is down
 // @Tags health
 // @Produce json
+// @Param verbose query bool false "Include per-component health"
 // @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
 // @Router /healthz [get]
-func healthz(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status": "ok",
+func healthz(w http.ResponseWriter, r *http.Request, checks []HealthCheck) {
+	body := map[string]interface{}{
+		"status": healthOK,
 		"time":   time.Now().UTC().Format(time.RFC3339),
-	})
+	}
+	status := http.StatusOK
+
+	if verbose, _ := strconv.ParseBool(r.URL.Query().Get("verbose")); verbose {
+		components := runHealthChecks(r.Context(), checks)
+
+		// O status geral é o PIOR entre os componentes
+		for _, c := range components {
+			if c.Status == healthDown {
+				body["status"] = healthDown
+				status = http.StatusServiceUnavailable
+			}
+		}
+
+		uptime := time.Since(processStart)
+		body["components"] = components
+		body["uptime"] = uptime.Truncate(time.Second).String()
+		body["uptime_seconds"] = int64(uptime.Seconds())
+		body["go_version"] = runtime.Version()
+	}
+
+	writeJSON(w, r, status, body)
+}
+
+// runHealthChecks executa todas as checagens AO MESMO TEMPO
+//
+// POR QUE CONCORRENTE E COM PRAZO INDIVIDUAL?
+// - Em sequência, o tempo de resposta seria a SOMA das checagens
+// - Em paralelo, é o da mais lenta - e cada uma tem seu próprio timeout
+// - Um componente travado vira "down" no seu prazo, sem segurar os outros
+//
+// Cada goroutine escreve só no seu índice de results: não há disputa pelo mesmo dado
+func runHealthChecks(ctx context.Context, checks []HealthCheck) map[string]componentHealth {
+	results := make([]componentHealth, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			results[i] = runHealthCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	components := make(map[string]componentHealth, len(checks))
+	for i, check := range checks {
+		components[check.Name] = results[i]
+	}
+	return components
+}
+
+// runHealthCheck executa uma checagem com o seu prazo e mede quanto ela levou
+func runHealthCheck(ctx context.Context, check HealthCheck) componentHealth {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	result := componentHealth{
+		Status:    healthOK,
+		LatencyMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = healthDown
+		result.Error = err.Error()
+	}
+	return result
 }
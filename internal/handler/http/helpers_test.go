@@ -0,0 +1,131 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// FAKES DOS TESTES DO HANDLER
+// ============================================
+// fakeUseCase embute a interface domain.UserUseCase: só os métodos que o teste
+// define (os campos func) têm implementação; chamar qualquer outro causa panic,
+// o que deixa claro que o handler usou algo que o teste não esperava
+type fakeUseCase struct {
+	domain.UserUseCase
+
+	createUser  func(ctx context.Context, name, email, phone string, metadata map[string]string) (*domain.User, error)
+	getUser     func(ctx context.Context, id string) (*domain.User, error)
+	updateUser  func(ctx context.Context, id, name, email, phone string, metadata map[string]string, version int) (*domain.User, domain.UserChanges, error)
+	deleteUser  func(ctx context.Context, id string) error
+	deleteUsers func(ctx context.Context, ids []string) (int64, []string, []string, error)
+	mergeUsers  func(ctx context.Context, primaryID, secondaryID string, copyMetadata bool) (*domain.User, error)
+}
+
+func (f *fakeUseCase) CreateUser(ctx context.Context, name, email, phone string, metadata map[string]string) (*domain.User, error) {
+	return f.createUser(ctx, name, email, phone, metadata)
+}
+
+func (f *fakeUseCase) GetUser(ctx context.Context, id string) (*domain.User, error) {
+	return f.getUser(ctx, id)
+}
+
+func (f *fakeUseCase) UpdateUser(ctx context.Context, id, name, email, phone string, metadata map[string]string, version int) (*domain.User, domain.UserChanges, error) {
+	return f.updateUser(ctx, id, name, email, phone, metadata, version)
+}
+
+func (f *fakeUseCase) DeleteUser(ctx context.Context, id string) error {
+	return f.deleteUser(ctx, id)
+}
+
+func (f *fakeUseCase) DeleteUsers(ctx context.Context, ids []string) (int64, []string, []string, error) {
+	return f.deleteUsers(ctx, ids)
+}
+
+func (f *fakeUseCase) MergeUsers(ctx context.Context, primaryID, secondaryID string, copyMetadata bool) (*domain.User, error) {
+	return f.mergeUsers(ctx, primaryID, secondaryID, copyMetadata)
+}
+
+// memoryIdempotencyStore guarda as chaves em um map (o suficiente para os testes)
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*domain.IdempotencyRecord
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: map[string]*domain.IdempotencyRecord{}}
+}
+
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, key, requestHash string) (*domain.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rec, ok := s.records[key]; ok {
+		copied := *rec
+		return &copied, nil
+	}
+	s.records[key] = &domain.IdempotencyRecord{Key: key, RequestHash: requestHash}
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(_ context.Context, key, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key].UserID = userID
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// newTestHandler cria o handler com limites pequenos e um logger que descarta tudo
+func newTestHandler(uc domain.UserUseCase, idempotency domain.IdempotencyStore) *UserHandler {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewUserHandler(uc, 1<<20, 20, 100, idempotency, false, "", 1, logger)
+}
+
+// newTestRouter registra as rotas do handler com middlewares que só repassam a requisição
+// (os testes aqui exercitam só o handler, sem autenticação, validação OpenAPI ou rate limit)
+func newTestRouter(h *UserHandler) http.Handler {
+	pass := func(next http.Handler) http.Handler { return next }
+	r := chi.NewRouter()
+	h.RegisterRoutes(r, pass, pass, pass)
+	return r
+}
+
+// newJSONRequest monta uma requisição com corpo JSON
+func newJSONRequest(method, target, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+// decodeErrorCode lê o campo "code" de uma resposta de erro
+func decodeErrorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	return decodeErrorBody(t, rec)["code"]
+}
+
+// decodeErrorBody lê o corpo de uma resposta de erro ({"error": ..., "code": ...})
+func decodeErrorBody(t *testing.T, rec *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("error body is not JSON: %v (%s)", err, rec.Body.String())
+	}
+	return body
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ============================================
+// MENSAGENS DE ERRO TRADUZIDAS
+// ============================================
+// O header Accept-Language diz em quais idiomas o cliente prefere ler
+// Exemplo: "pt-BR,pt;q=0.9,en;q=0.8" → português primeiro, inglês como alternativa
+//
+// COMO FUNCIONA:
+// - negotiateLanguage escolhe o melhor idioma SUPORTADO (padrão: inglês)
+// - translate busca a mensagem no catálogo pelo CÓDIGO do erro (ver errors.go)
+// - Sem tradução para o código, a mensagem original (em inglês) é mantida
+//
+// POR QUE O CATÁLOGO É POR CÓDIGO E NÃO POR MENSAGEM?
+// - O código é estável; o texto em inglês pode mudar sem quebrar as traduções
+// - Mensagens com partes dinâmicas (ex: nome do campo desconhecido) ficam em inglês,
+//   pois o texto do catálogo é fixo
+
+// defaultLanguage é usado quando o cliente não pede nenhum idioma suportado
+const defaultLanguage = "en"
+
+// messageCatalog guarda as traduções: idioma → código → mensagem
+// O inglês não está aqui: as mensagens originais já são em inglês
+var messageCatalog = map[string]map[string]string{
+	"pt": {
+		CodeUserNotFound:                    "Usuário não encontrado",
+		CodeUserGone:                        "Usuário removido",
+		CodeInvalidEmail:                    "Email inválido: deve conter '@' e ter no máximo 320 caracteres",
+		CodeInvalidPhone:                    "O telefone deve estar no formato E.164 (ex: +5511987654321)",
+		CodeInvalidMetadata:                 "Metadata inválido: até 20 chaves (1 a 64 caracteres, sem '.' e '$') e valores de até 512 caracteres",
+		CodeValidationFailed:                "Os dados enviados não passaram na validação",
+		CodeEmailTaken:                      "Este email já está em uso",
+		CodeTooManyEmails:                   "Um usuário pode ter no máximo 10 emails",
+		CodeEmailUnchanged:                  "Este já é o email principal do usuário",
+		CodeEmailChangeRequiresVerification: "O email principal só pode ser trocado por POST /api/v1/users/{id}/change-email",
+		CodeVersionConflict:                 "O usuário foi alterado por outra requisição",
+		CodeInvalidPagination:               "Parâmetros de paginação inválidos (limit, offset ou after)",
+		CodeInvalidSearch:                   "Critérios de busca inválidos (name, email, sort, order ou datas)",
+		CodeInvalidFilter:                   "updatedSince deve ser uma data RFC 3339 (ex: 2024-01-01T00:00:00Z)",
+		CodeInvalidIDs:                      "A lista de IDs deve ter entre 1 e 1000 itens",
+		CodeInvalidID:                       "O id deve ser um ObjectID de 24 caracteres hexadecimais",
+		CodeInvalidToken:                    "Token de verificação inválido ou já utilizado",
+		CodeTokenExpired:                    "O token de verificação expirou",
+		CodeTransactionsUnsupported:         "Transações exigem um replica set ou cluster shardeado do MongoDB",
+		CodeChangeStreamsUnsupported:        "O stream de alterações exige um replica set ou cluster shardeado do MongoDB",
+		CodeTooManyStreams:                  "Muitos streams de alterações abertos, tente novamente em instantes",
+		CodeInvalidJSON:                     "JSON inválido no corpo da requisição",
+		CodeIdempotencyKeyReused:            "Este Idempotency-Key já foi usado com um corpo diferente",
+		CodeIdempotencyKeyInProgress:        "Uma requisição com este Idempotency-Key ainda está em andamento",
+		CodeTimeout:                         "A requisição excedeu o tempo limite",
+		CodeOverloaded:                      "Servidor sobrecarregado, tente novamente em instantes",
+		CodeDatabaseBusy:                    "Banco de dados ocupado, tente novamente em instantes",
+		CodeDocumentTooLarge:                "O usuário passaria do limite de 16MB do MongoDB: envie menos dados (ex: metadata menor)",
+		CodeConfirmationRequired:            "A atualização alcança todos os usuários ou mais de 1000: repita com confirm=true",
+		CodeMergeSameUser:                   "Um usuário não pode ser fundido nele mesmo",
+		CodeTenantRequired:                  "O header X-Tenant-ID é obrigatório",
+		CodeInvalidTenant:                   "X-Tenant-ID inválido: de 1 a 64 letras minúsculas, dígitos, '-' ou '_'",
+		CodeUnauthorized:                    "Autenticação ausente, inválida ou expirada",
+		CodeMethodNotAllowed:                "Método não permitido",
+		CodePreconditionFailed:              "O usuário foi alterado desde a última leitura",
+		CodeNotAcceptable:                   "Formato não suportado: use application/json ou application/xml no Accept",
+		CodeURITooLong:                      "URL longa demais (caminho ou query string)",
+		CodeUnsupportedMediaType:            "O Content-Type deve ser application/json",
+		CodeRateLimited:                     "Limite de requisições excedido",
+		CodeInternal:                        "Erro interno do servidor",
+	},
+}
+
+// translate devolve a mensagem do código no idioma lang, se existir
+func translate(lang, code string) (string, bool) {
+	msg, ok := messageCatalog[lang][code]
+	return msg, ok
+}
+
+// negotiateLanguage escolhe o idioma da resposta a partir do Accept-Language
+//
+// REGRAS (simplificação da RFC 9110):
+// - Cada item pode ter um peso q (0 a 1, padrão 1); itens com q=0 são ignorados
+// - Vale o item de MAIOR peso cujo idioma principal é suportado ("pt-BR" → "pt")
+// - "*" ou nenhum idioma suportado → defaultLanguage
+func negotiateLanguage(header string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag == "" || q <= 0 {
+			continue
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		candidates = append(candidates, candidate{lang: primary, q: q})
+	}
+
+	// SliceStable: com pesos iguais, vale a ordem em que o cliente listou
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, c := range candidates {
+		if c.lang == defaultLanguage {
+			return c.lang
+		}
+		if _, ok := messageCatalog[c.lang]; ok {
+			return c.lang
+		}
+	}
+	return defaultLanguage
+}
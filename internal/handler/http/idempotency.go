@@ -0,0 +1,130 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// IDEMPOTENCY-KEY
+// ============================================
+// O header Idempotency-Key torna o POST seguro para repetir
+//
+// FLUXO EM createUser:
+// 1. Sem o header (ou sem store configurado) → cria normalmente
+// 2. Chave nova → reserva, cria o usuário e associa o ID à chave
+// 3. Chave repetida com o MESMO corpo → devolve o 201 original, sem criar de novo
+// 4. Chave repetida com corpo DIFERENTE → 422 (provável bug no cliente)
+// 5. Chave cuja primeira requisição ainda não terminou → 409
+//
+// POR QUE A CHAVE INCLUI QUEM CHAMOU?
+// - A chave vem do cliente: dois clientes podem gerar a mesma (ex: "1", "pedido-42")
+// - Sem o autor, o segundo cliente receberia o 201 com o usuário criado pelo primeiro
+// - Com "autor + chave", cada API key (ou subject do JWT) tem o seu próprio espaço de chaves
+
+// idempotencyKeyHeader é o nome do header enviado pelo cliente
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength limita o tamanho da chave (um UUID tem 36 caracteres)
+const maxIdempotencyKeyLength = 255
+
+// idempotencyScope guarda a chave junto com o autor da requisição (ver domain.ActorFromContext)
+// API key vira "apikey:<label>"; JWT, o subject do token
+// Sem autenticação não há autor e a chave fica como veio
+// O separador \x00 não aparece em headers, então "autor + chave" não colide com outra chave
+func idempotencyScope(ctx context.Context, key string) string {
+	actor, ok := domain.ActorFromContext(ctx)
+	if !ok || actor == "" {
+		return key
+	}
+	return actor + "\x00" + key
+}
+
+// requestHash calcula a "impressão digital" do corpo de criação
+// O separador \x00 evita que ("ab", "c") e ("a", "bc") gerem o mesmo hash
+// metadata entra como JSON: json.Marshal ordena as chaves do map, então o resultado é estável
+// Sem metadata, o hash é o mesmo de antes (chaves já guardadas continuam valendo)
+func requestHash(name, email, phone string, metadata map[string]string) string {
+	data := name + "\x00" + email + "\x00" + phone
+	if len(metadata) > 0 {
+		encoded, _ := json.Marshal(metadata)
+		data += "\x00" + string(encoded)
+	}
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// reserveIdempotencyKey trata a chave antes da criação
+// Retorna reserved=true quando a chave é nova e a criação deve continuar
+// Retorna done=true quando a resposta já foi escrita (replay ou erro)
+func (h *UserHandler) reserveIdempotencyKey(w http.ResponseWriter, r *http.Request, key, hash string) (reserved, done bool) {
+	if len(key) > maxIdempotencyKeyLength {
+		writeError(w, r, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+		return false, true
+	}
+
+	existing, err := h.idempotency.Reserve(r.Context(), idempotencyScope(r.Context(), key), hash)
+	if err != nil {
+		h.writeServerError(w, r, err, "Failed to check idempotency key")
+		return false, true
+	}
+	if existing == nil {
+		return true, false
+	}
+
+	h.replayCreate(w, r, existing, hash)
+	return false, true
+}
+
+// replayCreate responde a uma repetição de uma chave já usada
+func (h *UserHandler) replayCreate(w http.ResponseWriter, r *http.Request, rec *domain.IdempotencyRecord, hash string) {
+	if rec.RequestHash != hash {
+		writeErrorCode(w, r, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, "Idempotency-Key was already used with a different request body")
+		return
+	}
+	if rec.UserID == "" {
+		writeErrorCode(w, r, http.StatusConflict, CodeIdempotencyKeyInProgress, "A request with this Idempotency-Key is still in progress")
+		return
+	}
+
+	user, err := h.uc.GetUser(r.Context(), rec.UserID)
+	if err != nil {
+		// O usuário foi criado por esta chave, mas removido depois
+		if writeMissingUser(w, r, err) {
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to get user")
+		return
+	}
+
+	// Idempotent-Replayed avisa o cliente que nada foi criado desta vez
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.Header().Set("Location", "/api/v1/users/"+user.ID)
+	writeResource(w, r, http.StatusCreated, h.userResource(r, user, nil))
+}
+
+// finishIdempotencyKey conclui ou desfaz a reserva depois da criação
+// Usa context.WithoutCancel: mesmo que o cliente tenha desconectado,
+// a chave precisa refletir o que aconteceu com a criação
+func (h *UserHandler) finishIdempotencyKey(r *http.Request, key string, user *domain.User) {
+	ctx := context.WithoutCancel(r.Context())
+	key = idempotencyScope(ctx, key)
+
+	if user == nil {
+		// A criação falhou: libera a chave para o cliente tentar de novo
+		if err := h.idempotency.Release(ctx, key); err != nil {
+			h.logger.Warn("failed to release idempotency key", "error", err)
+		}
+		return
+	}
+
+	// Se falhar, a chave fica "em andamento" até expirar e repetições recebem 409
+	if err := h.idempotency.Complete(ctx, key, user.ID); err != nil {
+		h.logger.Warn("failed to complete idempotency key", "user_id", user.ID, "error", err)
+	}
+}
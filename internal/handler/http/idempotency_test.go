@@ -0,0 +1,94 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"user-api/internal/domain"
+)
+
+func TestIdempotencyScope(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor string
+		want  string
+	}{
+		{name: "without authentication", actor: "", want: "key-1"},
+		{name: "api key", actor: "apikey:billing", want: "apikey:billing\x00key-1"},
+		{name: "jwt subject", actor: "user-42", want: "user-42\x00key-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.actor != "" {
+				ctx = domain.ContextWithActor(ctx, tt.actor)
+			}
+			if got := idempotencyScope(ctx, "key-1"); got != tt.want {
+				t.Errorf("idempotencyScope() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateUserIdempotencyKey(t *testing.T) {
+	const body = `{"name":"Ana","email":"ana@example.com"}`
+
+	tests := []struct {
+		name       string
+		firstActor string
+		secondBody string
+		secondAs   string
+		wantStatus int
+		wantCode   string
+		wantCreate int // quantas vezes o usecase criou um usuário no total
+	}{
+		{name: "same caller, same body replays", firstActor: "apikey:billing", secondAs: "apikey:billing", secondBody: body, wantStatus: http.StatusCreated, wantCreate: 1},
+		{name: "same caller, different body is rejected", firstActor: "apikey:billing", secondAs: "apikey:billing", secondBody: `{"name":"Bia","email":"bia@example.com"}`, wantStatus: http.StatusUnprocessableEntity, wantCode: CodeIdempotencyKeyReused, wantCreate: 1},
+		{name: "other caller gets its own key", firstActor: "apikey:billing", secondAs: "apikey:reports", secondBody: body, wantStatus: http.StatusCreated, wantCreate: 2},
+		{name: "other jwt subject gets its own key", firstActor: "user-1", secondAs: "user-2", secondBody: body, wantStatus: http.StatusCreated, wantCreate: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := map[string]*domain.User{}
+			uc := &fakeUseCase{
+				createUser: func(_ context.Context, name, email, _ string, _ map[string]string) (*domain.User, error) {
+					id := fmt.Sprintf("65a1b2c3d4e5f6a7b8c9d0e%d", len(users)+1)
+					users[id] = &domain.User{ID: id, Name: name, Email: email, Version: 1}
+					return users[id], nil
+				},
+				getUser: func(_ context.Context, id string) (*domain.User, error) {
+					return users[id], nil
+				},
+			}
+			h := newTestHandler(uc, newMemoryIdempotencyStore())
+
+			send := func(actor, body string) *httptest.ResponseRecorder {
+				r := newJSONRequest(http.MethodPost, "/api/v1/users", body)
+				r.Header.Set(idempotencyKeyHeader, "order-42")
+				r = r.WithContext(domain.ContextWithActor(r.Context(), actor))
+				rec := httptest.NewRecorder()
+				h.createUser(rec, r)
+				return rec
+			}
+
+			if rec := send(tt.firstActor, body); rec.Code != http.StatusCreated {
+				t.Fatalf("first request: status = %d, want 201 (%s)", rec.Code, rec.Body.String())
+			}
+			rec := send(tt.secondAs, tt.secondBody)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("second request: status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode != "" {
+				if code := decodeErrorCode(t, rec); code != tt.wantCode {
+					t.Errorf("code = %q, want %q", code, tt.wantCode)
+				}
+			}
+			if len(users) != tt.wantCreate {
+				t.Errorf("users created = %d, want %d", len(users), tt.wantCreate)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"user-api/internal/usecase"
+)
+
+// ============================================
+// LISTAGEM DE IDS
+// ============================================
+// listUserIDs trata requisições GET /api/v1/users/ids
+// Pensado para jobs de sincronização que só comparam conjuntos de IDs
+//
+// DOIS MODOS:
+// - Sem ?after= nem ?limit=: TODOS os IDs em um array JSON ["...", "..."], escrito em streaming
+// (como o /export: a memória não cresce com o tamanho da collection)
+// - Com ?after= ou ?limit=: paginação por cursor, como a listagem:
+// {"data": ["...", "..."], "next": "507f1f77bcf86cd799439011", "limit": 1000}
+//
+// O limite de página aqui é idsPageMax, bem acima do PAGE_MAX da listagem: um ID tem 24 caracteres
+//
+// Sempre JSON: fica fora da negociação de conteúdo, como o /export
+//
+// @Summary List user IDs
+// @Description Only the IDs, in creation order. Without after/limit, every ID is streamed as one JSON array; with them, the response is a cursor page
+// @Tags users
+// @Produce json
+// @Param after query string false "Cursor: return IDs after this one (enables pagination)"
+// @Param limit query int false "Page size (enables pagination; default PAGE_DEFAULT, at most 10000)"
+// @Param envelope query bool false "Wrap a page as {\"data\": ..., \"meta\": ...}"
+// @Success 200 {array} string
+// @Header 200 {string} Link "Pagination links (rel=first, next) when paginating"
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/users/ids [get]
+func (h *UserHandler) listUserIDs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Has("after") || query.Has("limit") {
+		h.listUserIDsPage(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := h.streamUserIDs(w, r); err != nil {
+		h.logger.Error("ids stream interrupted", "error", err)
+	}
+}
+
+// idsPageMax é o maior ?limit= aceito em /ids (valores acima são reduzidos a ele)
+const idsPageMax = 10000
+
+// listUserIDsPage responde uma página de IDs da paginação por cursor
+func (h *UserHandler) listUserIDsPage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := h.pageDefault
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeUsecaseError(w, r, http.StatusBadRequest, usecase.ErrInvalidLimit)
+			return
+		}
+		limit = min(n, idsPageMax)
+	}
+
+	ids, next, err := h.uc.ListUserIDsPage(r.Context(), query.Get("after"), limit)
+	if err != nil {
+		if err == usecase.ErrInvalidCursor || err == usecase.ErrInvalidLimit {
+			writeUsecaseError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to list user IDs")
+		return
+	}
+
+	links := []string{link(r, "first", map[string]string{"after": "", "limit": strconv.Itoa(limit)})}
+	if next != "" {
+		links = append(links, link(r, "next", map[string]string{"after": next, "limit": strconv.Itoa(limit)}))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+
+	if wantsEnvelope(w, r) {
+		writeList(w, r, http.StatusOK, ids, listMeta{Limit: limit, Next: next})
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"data":  ids,
+		"next":  next,
+		"limit": limit,
+	})
+}
+
+// streamUserIDs escreve o array de IDs item por item: ["...", "..."]
+// Se o banco falhar antes do primeiro ID, ainda dá tempo de responder um erro de verdade
+func (h *UserHandler) streamUserIDs(w http.ResponseWriter, r *http.Request) error {
+	rows := 0
+	err := h.uc.StreamUserIDs(r.Context(), func(id string) error {
+		sep := ","
+		if rows == 0 {
+			sep = "["
+		}
+		// Um ObjectID em hexadecimal não precisa de escape: as aspas bastam
+		if _, err := w.Write([]byte(sep + strconv.Quote(id))); err != nil {
+			return err
+		}
+		rows++
+		if rows%flushEvery == 0 {
+			flush(w)
+		}
+		return nil
+	})
+	if err != nil {
+		if rows == 0 {
+			h.writeServerError(w, r, err, "Failed to list user IDs")
+			return nil
+		}
+		return err
+	}
+
+	if rows == 0 {
+		_, err = w.Write([]byte("[]\n"))
+		return err
+	}
+	_, err = w.Write([]byte("]\n"))
+	return err
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// ============================================
+// HEADER LINK (RFC 8288)
+// ============================================
+// O header Link descreve a navegação entre páginas de um jeito padronizado:
+//
+//	Link: <http://host/api/v1/users?offset=0&limit=20>; rel="first",
+//	      <http://host/api/v1/users?offset=40&limit=20>; rel="next"
+//
+// Bibliotecas HTTP genéricas (e a API do GitHub, por exemplo) seguem
+// rel="next" automaticamente, sem conhecer o formato do corpo
+//
+// As URLs são absolutas e mantêm os outros parâmetros da requisição (name, fields...)
+
+// link monta uma entrada do header Link a partir da requisição atual
+// params substitui parâmetros da query; valor "" remove o parâmetro
+func link(r *http.Request, rel string, params map[string]string) string {
+	query := r.URL.Query()
+	for k, v := range params {
+		if v == "" {
+			query.Del(k)
+			continue
+		}
+		query.Set(k, v)
+	}
+
+	u := *r.URL
+	u.Scheme = requestScheme(r)
+	u.Host = r.Host
+	u.RawQuery = query.Encode()
+	return "<" + u.String() + `>; rel="` + rel + `"`
+}
+
+// requestScheme é o esquema das URLs absolutas montadas a partir da requisição
+// Sem TLS na própria conexão, assumimos http (atrás de um proxy HTTPS
+// o host e o esquema vistos aqui são os do proxy para a API)
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// offsetLinks monta os links first, prev e next da paginação por offset
+// - prev é omitido na primeira página (offset 0)
+// - next é omitido na última página (hasNext false)
+func offsetLinks(r *http.Request, offset, limit int, hasNext bool) []string {
+	page := func(rel string, off int) string {
+		return link(r, rel, map[string]string{"offset": strconv.Itoa(off), "limit": strconv.Itoa(limit)})
+	}
+
+	links := []string{page("first", 0)}
+	if offset > 0 {
+		// Com offset menor que limit, a página anterior começa em 0 (nunca negativo)
+		links = append(links, page("prev", max(offset-limit, 0)))
+	}
+	if hasNext {
+		links = append(links, page("next", offset+limit))
+	}
+	return links
+}
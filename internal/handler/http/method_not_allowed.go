@@ -0,0 +1,62 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ============================================
+// 405 METHOD NOT ALLOWED
+// ============================================
+// Quando a URL existe mas o método não (ex: PATCH /api/v1/users),
+// o correto é 405 com o header Allow listando os métodos aceitos
+// - 404 daria a entender que o recurso não existe
+// - O header Allow ajuda o cliente a descobrir o que pode fazer
+//
+// O handler padrão do chi já responde 405 com Allow, mas em texto puro
+// Aqui mantemos o mesmo formato JSON de erro do resto da API
+
+// probeMethods são os métodos testados para montar o header Allow
+var probeMethods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// NewMethodNotAllowedHandler cria o handler 405 usado em router.MethodNotAllowed
+// routes é o router onde o handler é registrado: perguntamos a ele quais métodos casam
+//
+// IMPORTANTE: cada sub-router (r.Route) precisa registrar o SEU handler
+// Dentro de um sub-router o caminho é relativo (ex: "/" em vez de "/api/v1/users"),
+// então só o próprio sub-router sabe responder quais métodos ele aceita
+func NewMethodNotAllowedHandler(routes chi.Routes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(allowedMethods(routes, r), ", "))
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// allowedMethods lista os métodos de probeMethods que routes aceita no caminho de r
+func allowedMethods(routes chi.Routes, r *http.Request) []string {
+	// RoutePath é o caminho "restante" dentro do router atual
+	// No router principal ele fica vazio: usamos o caminho completo
+	path := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+		path = rctx.RoutePath
+	}
+
+	// Match "simula" o roteamento sem executar nenhum handler
+	// Cada consulta precisa de um RouteContext novo (ele guarda o resultado)
+	var allowed []string
+	for _, method := range probeMethods {
+		if routes.Match(chi.NewRouteContext(), method, path) {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
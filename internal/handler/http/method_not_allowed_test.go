@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMethodNotAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		target    string
+		wantAllow string
+	}{
+		{name: "patch on the collection", method: http.MethodPatch, target: "/api/v1/users", wantAllow: "GET, POST, OPTIONS"},
+		{name: "delete on the collection", method: http.MethodDelete, target: "/api/v1/users", wantAllow: "GET, POST, OPTIONS"},
+		{name: "post on a user", method: http.MethodPost, target: "/api/v1/users/" + testUserID, wantAllow: "GET, PUT, DELETE"},
+		{name: "patch on a user", method: http.MethodPatch, target: "/api/v1/users/" + testUserID, wantAllow: "GET, PUT, DELETE"},
+	}
+	router := newTestRouter(newTestHandler(&fakeUseCase{}, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if rec.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("status = %d, want 405 (%s)", rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+			if code := decodeErrorCode(t, rec); code != CodeMethodNotAllowed {
+				t.Errorf("code = %q, want %q", code, CodeMethodNotAllowed)
+			}
+		})
+	}
+}
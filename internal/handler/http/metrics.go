@@ -0,0 +1,96 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// MÉTRICAS PROMETHEUS
+// ============================================
+// Prometheus coleta métricas fazendo GET periódico em /metrics
+//
+// TIPOS DE MÉTRICA USADOS:
+// - Counter: só aumenta (ex: total de requisições)
+// - Histogram: distribui valores em faixas (ex: latência)
+// - Gauge: sobe e desce (ex: total de usuários)
+//
+// SOBRE OS LABELS:
+// - method, route e status permitem filtrar/agrupar no Prometheus
+// - route usa o PADRÃO do chi (/api/v1/users/{id}), não o caminho real
+// - Com o caminho real, cada ID viraria uma série (alta cardinalidade)
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// MetricsMiddleware registra contagem e latência de cada requisição
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		// WrapResponseWriter "espia" o status escrito pelo handler
+		// O http.ResponseWriter padrão não permite ler o status depois
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		// O padrão da rota só é conhecido DEPOIS que o chi fez o roteamento
+		route := chi.RouteContext(r.Context()).RoutePattern()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		labels := prometheus.Labels{
+			"method": r.Method,
+			"route":  route,
+			"status": strconv.Itoa(status),
+		}
+		httpRequestsTotal.With(labels).Inc()
+		httpRequestDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// RegisterMetrics registra o gauge de total de usuários e a rota /metrics
+// O gauge é calculado no momento da coleta (GaugeFunc), consultando o usecase
+func RegisterMetrics(r chi.Router, uc domain.UserUseCase, logger *slog.Logger) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "users_total",
+		Help: "Total number of users stored.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		count, err := uc.CountUsers(ctx, domain.UserFilter{})
+		if err != nil {
+			logger.Error("failed to count users for metrics", "component", "metrics", "error", err)
+			return 0
+		}
+		return float64(count)
+	}))
+
+	r.Handle("/metrics", promhttp.Handler())
+}
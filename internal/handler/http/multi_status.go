@@ -0,0 +1,64 @@
+package http
+
+import "net/http"
+
+// ============================================
+// RESPOSTAS DE OPERAÇÕES EM LOTE (207 MULTI-STATUS)
+// ============================================
+// Em uma operação em lote, cada item pode dar certo ou errado independentemente
+// Um único 200 ou 500 esconderia quais itens falharam; por isso a resposta traz
+// o resultado de CADA item e o status geral resume o conjunto:
+//
+//	{"results": [
+//	  {"index": 0, "id": "507f...", "status": 200},
+//	  {"index": 1, "id": "abc", "status": 400, "code": "INVALID_ID", "error": "..."}
+//	], ...}
+//
+// STATUS GERAL (ver multiStatus):
+// - Todos deram certo: o status de sucesso da operação (200 ou 201)
+// - Todos falharam: 400
+// - Misturado: 207 Multi-Status - o cliente repete só os itens com status de erro
+//
+// index é a posição do item na requisição: identifica o item mesmo quando o ID se repete
+
+// itemResult é o resultado de um item da operação em lote
+type itemResult struct {
+	Index  int    `json:"index"`
+	ID     string `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Code   string `json:"code,omitempty"`  // Só em falhas: o mesmo código das respostas de erro
+	Error  string `json:"error,omitempty"` // Só em falhas
+}
+
+// failed diz se o item falhou (status fora da faixa 2xx)
+func (res itemResult) failed() bool {
+	return res.Status < 200 || res.Status > 299
+}
+
+// multiStatus escolhe o status geral a partir dos resultados dos itens
+func multiStatus(results []itemResult, success int) int {
+	failures := 0
+	for _, res := range results {
+		if res.failed() {
+			failures++
+		}
+	}
+	switch {
+	case failures == 0:
+		return success
+	case failures == len(results):
+		return http.StatusBadRequest
+	default:
+		return http.StatusMultiStatus
+	}
+}
+
+// writeMultiStatus escreve body com os resultados em "results" e o status geral de multiStatus
+// body traz os campos próprios da operação (ex: "deleted"); pode ser nil
+func writeMultiStatus(w http.ResponseWriter, r *http.Request, success int, results []itemResult, body map[string]interface{}) {
+	if body == nil {
+		body = make(map[string]interface{}, 1)
+	}
+	body["results"] = results
+	writeResponse(w, r, multiStatus(results, success), body)
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMultiStatus(t *testing.T) {
+	ok := itemResult{Status: http.StatusOK}
+	notFound := itemResult{Status: http.StatusNotFound}
+	tests := []struct {
+		name    string
+		results []itemResult
+		success int
+		want    int
+	}{
+		{name: "all succeeded", results: []itemResult{ok, ok}, success: http.StatusOK, want: http.StatusOK},
+		{name: "all succeeded, created", results: []itemResult{{Status: http.StatusCreated}}, success: http.StatusCreated, want: http.StatusCreated},
+		{name: "all failed", results: []itemResult{notFound, notFound}, success: http.StatusOK, want: http.StatusBadRequest},
+		{name: "mixed", results: []itemResult{ok, notFound}, success: http.StatusOK, want: http.StatusMultiStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := multiStatus(tt.results, tt.success); got != tt.want {
+				t.Errorf("multiStatus() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBulkDeleteUsers(t *testing.T) {
+	const (
+		deletedID = "65a1b2c3d4e5f6a7b8c9d0e1"
+		missingID = "65a1b2c3d4e5f6a7b8c9d0e2"
+		invalidID = "not-an-id"
+	)
+	tests := []struct {
+		name         string
+		ids          []string
+		wantStatus   int
+		wantStatuses []int
+	}{
+		{name: "all deleted", ids: []string{deletedID}, wantStatus: http.StatusOK, wantStatuses: []int{200}},
+		{name: "none deleted", ids: []string{missingID, invalidID}, wantStatus: http.StatusBadRequest, wantStatuses: []int{404, 400}},
+		{name: "mixed", ids: []string{deletedID, missingID, invalidID}, wantStatus: http.StatusMultiStatus, wantStatuses: []int{200, 404, 400}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &fakeUseCase{
+				deleteUsers: func(_ context.Context, ids []string) (int64, []string, []string, error) {
+					var deleted int64
+					var notFound, invalid []string
+					for _, id := range ids {
+						switch id {
+						case deletedID:
+							deleted++
+						case missingID:
+							notFound = append(notFound, id)
+						default:
+							invalid = append(invalid, id)
+						}
+					}
+					return deleted, notFound, invalid, nil
+				},
+			}
+			body, _ := json.Marshal(map[string][]string{"ids": tt.ids})
+			rec := httptest.NewRecorder()
+			newTestRouter(newTestHandler(uc, nil)).ServeHTTP(rec, newJSONRequest(http.MethodPost, "/api/v1/users/bulk-delete", string(body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			var resp struct {
+				Results []itemResult `json:"results"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if len(resp.Results) != len(tt.ids) {
+				t.Fatalf("results = %d entries, want %d", len(resp.Results), len(tt.ids))
+			}
+			for i, res := range resp.Results {
+				if res.Index != i || res.ID != tt.ids[i] || res.Status != tt.wantStatuses[i] {
+					t.Errorf("results[%d] = %+v, want index %d, id %q, status %d", i, res, i, tt.ids[i], tt.wantStatuses[i])
+				}
+				if res.failed() && res.Code == "" {
+					t.Errorf("results[%d] failed without a code", i)
+				}
+			}
+		})
+	}
+}
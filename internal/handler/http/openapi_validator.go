@@ -0,0 +1,141 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+// ============================================
+// VALIDAÇÃO DE REQUISIÇÕES PELO OPENAPI
+// ============================================
+// OpenAPIValidator confere o corpo das requisições contra o schema da documentação Swagger
+//
+// POR QUE VALIDAR PELO SPEC?
+// - A documentação vira contrato: o que o Swagger descreve é o que a API aceita
+// - Tipos errados ({"name": 123}) são recusados com o campo e o motivo, antes do handler
+// - Documentação e validação vêm da MESMA fonte (os tipos em requests.go), sem ficar fora de sincronia
+//
+// DE ONDE VEM O SPEC?
+// - Do pacote docs gerado pelo swag init (docs.SwaggerInfo.ReadDoc()), no formato Swagger 2.0
+// - O kin-openapi valida OpenAPI 3: convertemos com openapi2conv.ToV3 na inicialização
+//
+// O QUE FICA DE FORA:
+// - Autenticação (o middleware de auth já cuida) e regras de negócio (usecase)
+// - Só as rotas que usam o middleware são validadas (hoje: criar e atualizar usuário)
+type OpenAPIValidator struct {
+	router       routers.Router
+	maxBodyBytes int64
+}
+
+// NewOpenAPIValidator carrega o spec Swagger 2.0 (JSON) e prepara o validador
+// maxBodyBytes limita o corpo lido aqui, como o decodeJSON faz no handler
+func NewOpenAPIValidator(spec string, maxBodyBytes int64) (*OpenAPIValidator, error) {
+	var doc2 openapi2.T
+	if err := json.Unmarshal([]byte(spec), &doc2); err != nil {
+		return nil, fmt.Errorf("openapi: parse spec: %w", err)
+	}
+	doc3, err := openapi2conv.ToV3(&doc2)
+	if err != nil {
+		return nil, fmt.Errorf("openapi: convert spec to v3: %w", err)
+	}
+
+	// O spec aponta para o host da documentação (ex: https://localhost:8080/)
+	// Sem servers, as rotas casam só pelo caminho, em qualquer host em que a API estiver rodando
+	doc3.Servers = nil
+
+	router, err := legacy.NewRouter(doc3)
+	if err != nil {
+		return nil, fmt.Errorf("openapi: build router: %w", err)
+	}
+	return &OpenAPIValidator{router: router, maxBodyBytes: maxBodyBytes}, nil
+}
+
+// Middleware valida a requisição contra a operação correspondente do spec
+// Violação do schema → 400 com código SCHEMA_VIOLATION e os detalhes de cada campo
+// Rotas que não estão no spec passam direto
+func (v *OpenAPIValidator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := v.router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// O validador lê o corpo inteiro (e depois o recoloca para o handler):
+		// o limite evita ler um corpo gigante antes do decodeJSON
+		r.Body = http.MaxBytesReader(w, r.Body, v.maxBodyBytes)
+
+		err = openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				MultiError:         true,
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			},
+		})
+		if err != nil {
+			if writeBodyTooLarge(w, r, err) {
+				return
+			}
+			writeErrorCode(w, r, http.StatusBadRequest, CodeSchemaViolation,
+				"Request does not match the API schema: "+strings.Join(schemaViolations(err), "; "))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// schemaViolations transforma o erro do kin-openapi em mensagens curtas, uma por problema
+// Exemplo: `body.name: value must be a string`
+//
+// O erro pode ser uma lista (MultiError) com erros de parâmetros e do corpo;
+// no corpo, o SchemaError indica o caminho do campo (JSONPointer) e o motivo
+func schemaViolations(err error) []string {
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		var out []string
+		for _, e := range multi {
+			out = append(out, schemaViolations(e)...)
+		}
+		return out
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		path := strings.Join(schemaErr.JSONPointer(), ".")
+		if path == "" {
+			return []string{"body: " + schemaErr.Reason}
+		}
+		return []string{"body." + path + ": " + schemaErr.Reason}
+	}
+
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		if reqErr.Parameter != nil {
+			// Valor que nem chega a ser convertido (ex: "xx" em um bool) vem sem Reason, só com Err
+			reason := reqErr.Reason
+			if reason == "" && reqErr.Err != nil {
+				reason = reqErr.Err.Error()
+			}
+			return []string{reqErr.Parameter.In + " " + reqErr.Parameter.Name + ": " + reason}
+		}
+		if reqErr.Err != nil {
+			return []string{reqErr.Err.Error()}
+		}
+		return []string{reqErr.Reason}
+	}
+
+	return []string{err.Error()}
+}
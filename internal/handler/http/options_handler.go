@@ -0,0 +1,132 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// operationBodies são os corpos aceitos por cada rota do sub-router de usuários ("MÉTODO padrão")
+// Os tipos nomeados vêm de requests.go; os demais repetem a struct anônima do handler
+// Rota ausente daqui = sem corpo
+var operationBodies = map[string]any{
+	"POST /":           CreateUserRequest{},
+	"PUT /{id}":        UpdateUserRequest{},
+	"PATCH /bulk":      BulkUpdateRequest{},
+	"POST /{id}/merge": MergeUsersRequest{},
+	"POST /batch-get": struct {
+		IDs []string `json:"ids" validate:"required"`
+	}{},
+	"POST /bulk-delete": struct {
+		IDs []string `json:"ids" validate:"required"`
+	}{},
+	"POST /verify": struct {
+		Token string `json:"token" validate:"required"`
+	}{},
+	"POST /{id}/emails": struct {
+		Email string `json:"email" validate:"required"`
+	}{},
+	"POST /{id}/change-email": struct {
+		Email string `json:"email" validate:"required"`
+	}{},
+}
+
+// discoveryResponse é o corpo de OPTIONS /api/v1/users
+type discoveryResponse struct {
+	Path       string      `json:"path"`
+	Operations []operation `json:"operations"`
+}
+
+// operation é uma rota registrada: método, caminho completo e, se tiver, os campos do corpo
+type operation struct {
+	Method string         `json:"method"`
+	Path   string         `json:"path"`
+	Body   *operationBody `json:"body,omitempty"`
+}
+
+// operationBody separa os campos do corpo em obrigatórios e opcionais (nomes do JSON)
+type operationBody struct {
+	Required []string `json:"required"`
+	Optional []string `json:"optional"`
+}
+
+// ============================================
+// OPTIONS (DESCOBERTA DAS OPERAÇÕES)
+// ============================================
+// NewOptionsHandler responde OPTIONS na raiz de um sub-router (ex: OPTIONS /api/v1/users)
+// com o header Allow e a lista das operações registradas nele:
+//
+//	{"path": "/api/v1/users", "operations": [
+//	  {"method": "POST", "path": "/api/v1/users", "body": {"required": ["email"], "optional": ["name", "phone", "metadata"]}},
+//	  {"method": "GET", "path": "/api/v1/users/{id}"}, ...]}
+//
+// POR QUE, SE JÁ EXISTE O SWAGGER?
+// - É uma sonda leve: o cliente descobre o que pode fazer sem baixar e interpretar o spec inteiro
+// - Reflete a instância que responde: PATCH /bulk só aparece com ENABLE_ADMIN
+//
+// DE ONDE VEM A LISTA?
+// - Das próprias rotas (chi.Walk em routes): uma rota nova aparece sem mudar nada aqui
+// - Os campos do corpo vêm de operationBodies: obrigatórios são os com validate:"required" (como no Swagger)
+//
+// E O PREFLIGHT DE CORS?
+// - O preflight (OPTIONS com Access-Control-Request-Method) é respondido pelo NewCORS, antes do roteamento
+// - Só chega aqui se o CORS estiver desabilitado ou a origem não for permitida: sem os headers de CORS,
+// o navegador recusa do mesmo jeito
+//
+// routes é o sub-router onde o handler é registrado (mesmo arranjo do NewMethodNotAllowedHandler)
+func NewOptionsHandler(routes chi.Routes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// O handler está na raiz do sub-router: o caminho da requisição é o prefixo das rotas
+		prefix := strings.TrimSuffix(r.URL.Path, "/")
+
+		var ops []operation
+		_ = chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			op := operation{Method: method, Path: prefix + strings.TrimSuffix(route, "/")}
+			if body, ok := operationBodies[method+" "+route]; ok {
+				op.Body = describeBody(body)
+			}
+			ops = append(ops, op)
+			return nil
+		})
+		// Ordem estável: por caminho e, no mesmo caminho, na ordem de probeMethods
+		slices.SortStableFunc(ops, func(a, b operation) int {
+			if c := strings.Compare(a.Path, b.Path); c != 0 {
+				return c
+			}
+			return methodRank(a.Method) - methodRank(b.Method)
+		})
+
+		w.Header().Set("Allow", strings.Join(allowedMethods(routes, r), ", "))
+		writeJSON(w, r, http.StatusOK, discoveryResponse{Path: prefix, Operations: ops})
+	}
+}
+
+// methodRank é a posição do método em probeMethods (métodos fora dela vão para o fim)
+func methodRank(method string) int {
+	if i := slices.Index(probeMethods, method); i >= 0 {
+		return i
+	}
+	return len(probeMethods)
+}
+
+// describeBody lista os campos JSON de uma struct de corpo
+func describeBody(body any) *operationBody {
+	desc := &operationBody{Required: []string{}, Optional: []string{}}
+	t := reflect.TypeOf(body)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if strings.Contains(field.Tag.Get("validate"), "required") {
+			desc.Required = append(desc.Required, name)
+		} else {
+			desc.Optional = append(desc.Optional, name)
+		}
+	}
+	return desc
+}
@@ -0,0 +1,164 @@
+package http
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ============================================
+// RATE LIMITING POR IP
+// ============================================
+// RateLimiter limita quantas requisições cada IP pode fazer por segundo
+//
+// TOKEN BUCKET ("balde de fichas"):
+// - Cada IP tem um balde com capacidade "burst" fichas
+// - O balde é reabastecido com "rps" fichas por segundo
+// - Cada requisição consome uma ficha; balde vazio → 429 Too Many Requests
+// - Permite rajadas curtas (burst) mas limita a taxa média (rps)
+//
+// MEMÓRIA:
+// - Cada IP novo cria um balde no map
+// - Uma goroutine de limpeza remove baldes sem uso há algum tempo
+type RateLimiter struct {
+	rps         rate.Limit
+	burst       int
+	trustedHops int // Proxies confiáveis na frente da API (0 = ignora X-Forwarded-For)
+
+	// mu protege o map: o middleware é chamado por várias goroutines ao mesmo tempo
+	mu      sync.Mutex
+	clients map[string]*client
+}
+
+// client guarda o balde de um IP e quando ele foi usado pela última vez
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Parâmetros da limpeza de baldes ociosos
+const (
+	rateLimitSweepInterval = time.Minute
+	rateLimitIdleTimeout   = 3 * time.Minute
+)
+
+// rateLimitExemptPaths não passam pelo limite
+// Probes do orquestrador e o scraper do Prometheus vêm sempre dos mesmos IPs e em intervalos fixos:
+// com o limite, um 429 no /healthz ou no /readyz tiraria a instância do ar sem motivo
+var rateLimitExemptPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// NewRateLimiter cria o limitador e inicia a goroutine de limpeza
+// A limpeza para quando ctx for cancelado
+// trustedHops: quantos proxies confiáveis ficam na frente da API; com 0, X-Forwarded-For é ignorado
+// (só use > 0 atrás de proxies que de fato acrescentam o IP ao header!)
+func NewRateLimiter(ctx context.Context, rps float64, burst int, trustedHops int) *RateLimiter {
+	rl := &RateLimiter{
+		rps:         rate.Limit(rps),
+		burst:       burst,
+		trustedHops: trustedHops,
+		clients:     make(map[string]*client),
+	}
+	go rl.sweep(ctx)
+	return rl
+}
+
+// Middleware rejeita com 429 as requisições acima do limite do IP
+// O header Retry-After informa em quantos segundos tentar de novo
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rateLimitExemptPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !rl.limiterFor(rl.clientIP(r)).Allow() {
+			// Tempo para o balde ganhar uma nova ficha (mínimo 1 segundo)
+			retryAfter := int(math.Ceil(1 / float64(rl.rps)))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeError(w, r, http.StatusTooManyRequests, "Rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limiterFor retorna o balde do IP, criando um novo se necessário
+func (rl *RateLimiter) limiterFor(ip string) *rate.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	c, ok := rl.clients[ip]
+	if !ok {
+		c = &client{limiter: rate.NewLimiter(rl.rps, rl.burst)}
+		rl.clients[ip] = c
+	}
+	c.lastSeen = time.Now()
+	return c.limiter
+}
+
+// clientIP descobre o IP do cliente
+//
+// SOBRE X-Forwarded-For:
+// - Cada proxy ACRESCENTA ao fim o IP de quem falou com ele: "forjado, cliente, proxy1"
+// - O cliente controla o começo do header: mandando "X-Forwarded-For: 1.2.3.4", o primeiro valor é o que ele quiser
+// - Só o fim é confiável: com trustedHops proxies, o IP real do cliente é o trustedHops-ésimo a partir da direita
+// - Ex: com 1 proxy (TRUST_PROXY_HOPS=1), o último valor; com CDN + load balancer (2), o penúltimo
+// - Menos valores que trustedHops: a requisição não passou por todos os proxies, vale o RemoteAddr
+func (rl *RateLimiter) clientIP(r *http.Request) string {
+	if rl.trustedHops > 0 {
+		// Vários headers X-Forwarded-For equivalem a um só com os valores unidos por vírgula
+		var hops []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(header, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		if len(hops) >= rl.trustedHops {
+			if ip := hops[len(hops)-rl.trustedHops]; ip != "" {
+				return ip
+			}
+		}
+	}
+
+	// RemoteAddr tem o formato "ip:porta"
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// sweep remove periodicamente os baldes de IPs que não aparecem há um tempo
+// Sem isso, o map cresceria para sempre com cada IP que já acessou a API
+func (rl *RateLimiter) sweep(ctx context.Context) {
+	ticker := time.NewTicker(rateLimitSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rl.mu.Lock()
+			for ip, c := range rl.clients {
+				if time.Since(c.lastSeen) > rateLimitIdleTimeout {
+					delete(rl.clients, ip)
+				}
+			}
+			rl.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// ============================================
+// MIDDLEWARE DE RECUPERAÇÃO DE PANIC
+// ============================================
+// Um panic (ex: acessar um ponteiro nil) derruba a goroutine da requisição
+// Sem tratamento, o servidor do Go fecha a conexão e o cliente não recebe nenhuma resposta
+//
+// COMO FUNCIONA:
+// - defer + recover() "captura" o panic antes que ele suba para o net/http
+// - Registramos a stack trace (onde o panic aconteceu) com o ID da requisição
+// e os headers, com os sensíveis escondidos pelo HeaderRedactor (um panic não pode vazar um token)
+// - O cliente recebe 500 no mesmo formato JSON dos outros erros da API
+//
+// POR QUE REGISTRAR PRIMEIRO?
+// - Middlewares executam na ordem de registro: o primeiro envolve todos os outros
+// - Assim um panic em QUALQUER middleware ou handler seguinte é capturado
+
+// NewRecoveryMiddleware cria o middleware que transforma panics em 500 JSON
+// O ID da requisição vem do middleware.RequestID do chi (registrado antes dele)
+func NewRecoveryMiddleware(logger *slog.Logger, redactor *HeaderRedactor) func(http.Handler) http.Handler {
+	logger = logger.With("component", "recovery")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				// http.ErrAbortHandler é o jeito "oficial" de abortar uma resposta:
+				// deixamos o net/http tratá-lo normalmente
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				logger.Error("panic recovered",
+					"request_id", middleware.GetReqID(r.Context()),
+					"method", r.Method,
+					"path", r.URL.Path,
+					"headers", redactor.LogValue(r.Header),
+					"panic", rec,
+					"stack", string(debug.Stack()),
+				)
+
+				// Se o handler já tinha começado a escrever a resposta,
+				// o status não pode mais ser trocado - o cliente recebe uma resposta truncada
+				writeError(w, r, http.StatusInternalServerError, "internal server error")
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	recovery := NewRecoveryMiddleware(logger, NewHeaderRedactor(nil))
+
+	panics := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		var user *struct{ Name string }
+		_ = user.Name // nil dereference
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	r.Header.Set("Authorization", "Bearer secret-token")
+	rec := httptest.NewRecorder()
+	// RequestID roda antes, como no main: o log do panic leva o request_id
+	middleware.RequestID(recovery(panics)).ServeHTTP(rec, r)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	body := decodeErrorBody(t, rec)
+	if body["code"] != CodeInternal || body["error"] != "internal server error" {
+		t.Errorf("body = %v, want the INTERNAL_ERROR shape", body)
+	}
+
+	for _, want := range []string{`"msg":"panic recovered"`, `"request_id":"`, `"stack":"`, "nil pointer dereference"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log does not contain %s:\n%s", want, logs.String())
+		}
+	}
+	if strings.Contains(logs.String(), "secret-token") {
+		t.Errorf("log leaks the Authorization header:\n%s", logs.String())
+	}
+}
+
+func TestRecoveryMiddlewarePassThrough(t *testing.T) {
+	recovery := NewRecoveryMiddleware(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)), NewHeaderRedactor(nil))
+
+	t.Run("without panic", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		recovery(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("status = %d, want 204", rec.Code)
+		}
+	})
+
+	t.Run("http.ErrAbortHandler is re-panicked", func(t *testing.T) {
+		defer func() {
+			if rec := recover(); rec != http.ErrAbortHandler {
+				t.Errorf("recovered %v, want http.ErrAbortHandler", rec)
+			}
+		}()
+		recovery(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			panic(http.ErrAbortHandler)
+		})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
@@ -0,0 +1,74 @@
+package http
+
+// ============================================
+// CORPOS DAS REQUISIÇÕES
+// ============================================
+// Tipos nomeados (em vez de structs anônimas) para que o swag gere um SCHEMA
+// completo no Swagger: nomes, tipos e limites de cada campo
+// Esse schema é o mesmo usado pelo validador OpenAPI (ver openapi_validator.go),
+// então documentação e validação não ficam fora de sincronia
+//
+// SOBRE AS TAGS:
+// - validate:"required": o campo precisa estar presente no JSON
+// - maxLength: limite documentado e conferido pelo validador
+// - As regras de negócio (formato do email, E.164, chaves de metadata) continuam no usecase
+
+// CreateUserRequest é o corpo de POST /api/v1/users
+type CreateUserRequest struct {
+	Name  string `json:"name" minLength:"2" maxLength:"200" example:"Maria Silva"`
+	Email string `json:"email" validate:"required" maxLength:"320" example:"maria@example.com"`
+	Phone string `json:"phone,omitempty" example:"+5511987654321"` // Opcional: formato E.164
+
+	// Opcional: atributos livres do consumidor
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// BulkUpdateRequest é o corpo de PATCH /api/v1/users/bulk
+type BulkUpdateRequest struct {
+	// Quais usuários alterar; os filtros se combinam e {} (vazio) alcança TODOS
+	Filter BulkUpdateFilterRequest `json:"filter"`
+
+	// O que alterar em cada um
+	Changes BulkUpdateChangesRequest `json:"changes" validate:"required"`
+
+	// Obrigatório com filtro vazio ou quando mais de 1000 usuários casam com o filtro
+	Confirm bool `json:"confirm,omitempty"`
+}
+
+// BulkUpdateFilterRequest escolhe os usuários de PATCH /api/v1/users/bulk
+type BulkUpdateFilterRequest struct {
+	Name       string `json:"name,omitempty" maxLength:"100" example:"silva"`     // Parte do nome (case-insensitive)
+	NamePrefix string `json:"name_prefix,omitempty" maxLength:"100" example:"Ma"` // Início do nome (case-insensitive)
+	Verified   *bool  `json:"verified,omitempty"`
+}
+
+// BulkUpdateChangesRequest são as alterações de PATCH /api/v1/users/bulk
+type BulkUpdateChangesRequest struct {
+	// Chave com valor grava a chave; chave com null a remove. As demais chaves são mantidas
+	Metadata map[string]*string `json:"metadata,omitempty"`
+}
+
+// UpdateUserRequest é o corpo de PUT /api/v1/users/{id}
+// Todos os campos são opcionais: os ausentes mantêm o valor atual
+// Email, se enviado, precisa ser o principal atual (a troca é pelo change-email)
+type UpdateUserRequest struct {
+	Name  string `json:"name,omitempty" maxLength:"200" example:"Maria Silva"`
+	Email string `json:"email,omitempty" maxLength:"320" example:"maria@example.com"`
+	Phone string `json:"phone,omitempty" example:"+5511987654321"`
+
+	// Versão lida pelo cliente; se informada, a atualização só acontece
+	// se o usuário ainda estiver nessa versão (senão 409 Conflict)
+	Version int `json:"version,omitempty" minimum:"0" example:"1"`
+
+	// Ausente (nil) mantém os metadados; presente substitui todos ({} limpa)
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// MergeUsersRequest é o corpo de POST /api/v1/users/{id}/merge
+type MergeUsersRequest struct {
+	// Usuário duplicado: é fundido no {id} da URL e depois removido
+	SecondaryID string `json:"secondary_id" validate:"required" example:"507f1f77bcf86cd799439011"`
+
+	// Copia as chaves de metadata do duplicado que o {id} não tem
+	CopyMetadata bool `json:"copy_metadata,omitempty"`
+}
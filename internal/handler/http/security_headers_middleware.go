@@ -0,0 +1,59 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SecurityHeaders são os valores dos headers de segurança enviados em toda resposta
+// Valor vazio = header desabilitado
+type SecurityHeaders struct {
+	ContentTypeOptions    string // X-Content-Type-Options (ex: "nosniff")
+	FrameOptions          string // X-Frame-Options (ex: "DENY")
+	ReferrerPolicy        string // Referrer-Policy (ex: "no-referrer")
+	ContentSecurityPolicy string // Content-Security-Policy das rotas da API
+	SwaggerCSP            string // Content-Security-Policy do Swagger UI (/swagger/)
+}
+
+// ============================================
+// MIDDLEWARE DE HEADERS DE SEGURANÇA
+// ============================================
+// NewSecurityHeaders adiciona os headers de segurança a todas as respostas
+//
+// O QUE CADA UM FAZ:
+// - X-Content-Type-Options: nosniff → o navegador respeita o Content-Type (não "adivinha" que um JSON é HTML)
+// - X-Frame-Options: DENY → nenhuma página pode exibir a API dentro de um <iframe> (clickjacking)
+// - Referrer-Policy: no-referrer → links seguidos a partir das respostas não vazam a URL (com IDs e filtros)
+// - Content-Security-Policy → o que uma resposta aberta no navegador pode carregar e executar
+//
+// POR QUE UMA CSP DIFERENTE PARA O SWAGGER?
+// - A API só devolve dados: "default-src 'none'" proíbe tudo, e nada quebra
+// - O Swagger UI é uma página de verdade: carrega seus scripts e estilos, tem um <script> inline
+// e busca o doc.json; com a CSP da API ele ficaria em branco
+//
+// Os headers são definidos ANTES do handler: valem também para erros (404, 405, 414, 500...)
+func NewSecurityHeaders(headers SecurityHeaders) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			setHeader(h, "X-Content-Type-Options", headers.ContentTypeOptions)
+			setHeader(h, "X-Frame-Options", headers.FrameOptions)
+			setHeader(h, "Referrer-Policy", headers.ReferrerPolicy)
+
+			csp := headers.ContentSecurityPolicy
+			if strings.HasPrefix(r.URL.Path, swaggerPath) {
+				csp = headers.SwaggerCSP
+			}
+			setHeader(h, "Content-Security-Policy", csp)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setHeader define o header só quando há valor (vazio = desabilitado na configuração)
+func setHeader(h http.Header, key, value string) {
+	if value != "" {
+		h.Set(key, value)
+	}
+}
@@ -0,0 +1,34 @@
+package http
+
+import (
+	"net/http"
+
+	"user-api/internal/domain"
+)
+
+// staleWarning é o header Warning das respostas servidas do cache desatualizado
+// 110 = "Response is Stale" (RFC 7234); "-" no lugar do agente, que é o próprio servidor
+const staleWarning = `110 - "Response is Stale"`
+
+// ============================================
+// RESPOSTAS DESATUALIZADAS (MONGODB FORA)
+// ============================================
+// TrackStaleReads prepara o context para o repositório marcar a resposta como desatualizada
+//
+// COMO FUNCIONA:
+// - Com USER_CACHE_STALE_TTL, o cache responde leituras com dados expirados se o MongoDB está inalcançável
+// - Ele liga a marca do context (domain.MarkStale); writeResponse vê a marca e envia o header Warning
+// - O cliente sabe assim que os dados podem estar desatualizados, em vez de receber um 500
+func TrackStaleReads(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(domain.ContextWithStaleTracking(r.Context())))
+	})
+}
+
+// warnIfStale acrescenta o header Warning se algum dado da resposta veio do cache desatualizado
+// Precisa rodar antes do WriteHeader (writeResponse e o 304 do GET /{id} o chamam)
+func warnIfStale(w http.ResponseWriter, r *http.Request) {
+	if domain.IsStale(r.Context()) {
+		w.Header().Set("Warning", staleWarning)
+	}
+}
@@ -4,13 +4,19 @@ import (
 	"github.com/go-chi/chi/v5"
 	httpSwagger "github.com/swaggo/http-swagger"
 
-	_ "user-api/docs" // Importa o pacote docs gerado pelo swag init
+	// Importa o pacote docs gerado pelo swag init: o init dele registra o spec no swag
+	// Registrar o spec não depende da rota existir (sem ENABLE_SWAGGER o import continua inofensivo)
+	_ "user-api/docs"
 )
 
+// swaggerPath é o prefixo das rotas do Swagger UI
+const swaggerPath = "/swagger/"
+
 // RegisterSwagger registra as rotas do Swagger UI
 // A documentação interativa estará disponível em /swagger/index.html
+// Só é chamado com ENABLE_SWAGGER (desligado por padrão em produção)
 func RegisterSwagger(r chi.Router) {
 	// WrapHandler serve automaticamente os arquivos do pacote docs
 	// quando ele está importado (linha acima)
-	r.Get("/swagger/*", httpSwagger.WrapHandler)
+	r.Get(swaggerPath+"*", httpSwagger.WrapHandler)
 }
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"user-api/internal/domain"
+)
+
+// TenantHeader é o header que identifica o tenant da requisição (MULTI_TENANT=true)
+const TenantHeader = "X-Tenant-ID"
+
+// Regras do ID do tenant: ele vira parte do nome da collection ("users_acme")
+const (
+	maxTenantIDLength = 64
+	tenantScopePrefix = "/api/" // Só as rotas da API são por tenant (health, métricas e swagger não)
+)
+
+// ============================================
+// MIDDLEWARE DE TENANT (MULTI-TENANCY)
+// ============================================
+// RequireTenant lê o tenant do header X-Tenant-ID e o guarda no context (domain.ContextWithTenant)
+// O repositório usa o tenant para escolher a collection: cada tenant só enxerga os próprios usuários
+//
+// REGRAS:
+// - Vale só para as rotas /api/...; /healthz, /readyz, /metrics, /version e o swagger continuam sem tenant
+// - Header ausente: 400 TENANT_REQUIRED
+// - ID fora do formato: 400 INVALID_TENANT (1 a 64 caracteres: letras minúsculas, dígitos, "-" e "_")
+//
+// POR QUE SÓ MINÚSCULAS?
+// - Nomes de collection diferenciam maiúsculas: "Acme" e "acme" virariam dois tenants diferentes
+// - Recusar é mais claro que normalizar em silêncio
+//
+// O header NÃO autentica: quem garante que o cliente pode agir em nome do tenant
+// é a autenticação (ex: um gateway na frente da API que fixa o header)
+//
+// Deve rodar antes do roteamento (r.Use no router principal)
+func RequireTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, tenantScopePrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tenant := r.Header.Get(TenantHeader)
+		if tenant == "" {
+			writeErrorCode(w, r, http.StatusBadRequest, CodeTenantRequired, "X-Tenant-ID header is required")
+			return
+		}
+		if !isValidTenantID(tenant) {
+			writeErrorCode(w, r, http.StatusBadRequest, CodeInvalidTenant, "X-Tenant-ID must be 1 to 64 lowercase letters, digits, '-' or '_'")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(domain.ContextWithTenant(r.Context(), tenant)))
+	})
+}
+
+// isValidTenantID confere o formato do ID do tenant (ver RequireTenant)
+func isValidTenantID(tenant string) bool {
+	if len(tenant) == 0 || len(tenant) > maxTenantIDLength {
+		return false
+	}
+	for _, c := range tenant {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// ============================================
+// MIDDLEWARE DE TIMEOUT POR REQUISIÇÃO
+// ============================================
+// NewTimeoutMiddleware define um prazo máximo (deadline) para cada requisição
+//
+// COMO FUNCIONA:
+// - Cria um context com timeout a partir do context da requisição
+// - Esse context desce até o repository (handler → usecase → repository)
+// - Quando o prazo acaba, o driver do MongoDB cancela a consulta em andamento
+// - O handler percebe o prazo estourado e responde 503 em JSON (writeServerError)
+//
+// POR QUE NÃO http.TimeoutHandler?
+// - TimeoutHandler bufferiza a resposta e não suporta http.Flusher (streaming)
+// - Sua resposta de timeout é texto puro, não o JSON de erro da API
+//
+// Respostas longas não têm prazo (ver isLongLived): duram enquanto o cliente estiver conectado
+func NewTimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isLongLived(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Caminhos das respostas longas: a exportação (CSV ou JSON) e a listagem, que com ?stream=ndjson vira streaming
+const (
+	UserExportPath = "/api/v1/users/export"
+	UserListPath   = "/api/v1/users"
+)
+
+// isLongLived diz se a requisição é uma resposta longa, sem REQUEST_TIMEOUT:
+// - O stream de alterações (SSE): a conexão dura enquanto o cliente quiser
+// - A exportação e o ?stream=ndjson da listagem: uma collection grande leva mais que o REQUEST_TIMEOUT para ser lida inteira
+//
+// POR QUE NÃO UM PRAZO MAIOR?
+// - O 200 sai com a primeira linha: um prazo estourado no meio só corta a resposta
+// - O cliente recebe um arquivo truncado com status de sucesso, sem como perceber
+// - Sem prazo, a leitura termina quando acaba ou quando o cliente desconecta (o context é cancelado)
+func isLongLived(r *http.Request) bool {
+	switch r.URL.Path {
+	case UserStreamPath, UserExportPath:
+		return true
+	case UserListPath:
+		return r.URL.Query().Has("stream")
+	}
+	return false
+}
+
+// clearWriteDeadline remove o WRITE_TIMEOUT do servidor só para esta conexão (ver isLongLived)
+// ResponseController alcança o ResponseWriter original através dos middlewares (Unwrap)
+// Um ResponseWriter sem suporte (ex: nos testes) mantém o prazo: só registramos o aviso
+func clearWriteDeadline(w http.ResponseWriter, logger *slog.Logger) {
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		logger.Warn("response keeps the server write timeout", "error", err)
+	}
+}
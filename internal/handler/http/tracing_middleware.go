@@ -0,0 +1,55 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ============================================
+// MIDDLEWARE DE TRACING
+// ============================================
+// TracingMiddleware abre um span para cada requisição HTTP
+//
+// - O header traceparent (se vier) liga o span ao trace de quem chamou
+// - O span vai no context da requisição: os spans do MongoDB viram "filhos" dele
+// - Como no MetricsMiddleware, o nome usa o PADRÃO da rota (/api/v1/users/{id})
+// - O padrão só é conhecido depois do roteamento, por isso o nome é trocado no final
+//
+// Com o tracing desabilitado, o tracer global é no-op e o custo é desprezível
+func TracingMiddleware(next http.Handler) http.Handler {
+	tracer := otel.Tracer("user-api/http")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, "HTTP "+r.Method, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		route := chi.RouteContext(r.Context()).RoutePattern()
+		if route != "" {
+			span.SetName("HTTP " + r.Method + " " + route)
+		}
+		span.SetAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", status),
+		)
+		// Só erros do servidor (5xx) marcam o span como erro; 4xx é erro do cliente
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
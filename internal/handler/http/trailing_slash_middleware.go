@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ============================================
+// MIDDLEWARE DE BARRA FINAL
+// ============================================
+// RedirectTrailingSlash redireciona caminhos terminados em "/" para a forma canônica, SEM a barra
+// Exemplo: GET /api/v1/users/ → 308 Location: /api/v1/users
+//
+// POR QUE?
+// - Sem ele, "/api/v1/users" e "/api/v1/users/" caem na mesma rota em alguns casos e em 404 em outros
+// - Com o redirecionamento, cada recurso tem UMA URL e as duas formas se comportam igual
+//
+// POR QUE 308 E NÃO 301?
+// - Com 301/302, muitos clientes refazem um POST como GET (e perdem o corpo)
+// - 308 Permanent Redirect obriga o cliente a repetir o MESMO método com o MESMO corpo
+//
+// A query string é preservada. Barras repetidas no início ("//evil.com/") são reduzidas a uma:
+// sem isso o Location "//evil.com" seria uma URL para OUTRO host (open redirect)
+//
+// Deve rodar antes do roteamento (r.Use no router principal)
+func RedirectTrailingSlash(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if len(path) > 1 && strings.HasSuffix(path, "/") {
+			target := "/" + strings.Trim(path, "/")
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusPermanentRedirect)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,45 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectTrailingSlash(t *testing.T) {
+	userPath := "/api/v1/users/" + testUserID
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		wantStatus   int
+		wantLocation string
+	}{
+		{name: "list", method: http.MethodGet, target: "/api/v1/users/", wantStatus: http.StatusPermanentRedirect, wantLocation: "/api/v1/users"},
+		{name: "create", method: http.MethodPost, target: "/api/v1/users/", wantStatus: http.StatusPermanentRedirect, wantLocation: "/api/v1/users"},
+		{name: "get", method: http.MethodGet, target: userPath + "/", wantStatus: http.StatusPermanentRedirect, wantLocation: userPath},
+		{name: "update", method: http.MethodPut, target: userPath + "/", wantStatus: http.StatusPermanentRedirect, wantLocation: userPath},
+		{name: "delete", method: http.MethodDelete, target: userPath + "/", wantStatus: http.StatusPermanentRedirect, wantLocation: userPath},
+		{name: "query string is kept", method: http.MethodGet, target: "/api/v1/users/?limit=5&name=ana", wantStatus: http.StatusPermanentRedirect, wantLocation: "/api/v1/users?limit=5&name=ana"},
+		{name: "repeated leading slashes stay on this host", method: http.MethodGet, target: "//evil.com/", wantStatus: http.StatusPermanentRedirect, wantLocation: "/evil.com"},
+		{name: "canonical list passes through", method: http.MethodGet, target: "/api/v1/users", wantStatus: http.StatusOK},
+		{name: "canonical user passes through", method: http.MethodDelete, target: userPath, wantStatus: http.StatusOK},
+		{name: "root passes through", method: http.MethodGet, target: "/", wantStatus: http.StatusOK},
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			RedirectTrailingSlash(next).ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
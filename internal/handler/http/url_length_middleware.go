@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ============================================
+// MIDDLEWARE DE TAMANHO DA URL
+// ============================================
+// NewURLLengthLimit recusa com 414 URI Too Long as requisições cujo caminho
+// passa de maxPath bytes ou cuja query string passa de maxQuery bytes
+// Zero desabilita o limite correspondente
+//
+// POR QUE, SE O net/http JÁ LIMITA OS HEADERS?
+// - O limite do servidor (MaxHeaderBytes, 1MB por padrão) vale para a linha da requisição E todos os headers juntos
+// - Uma URL de centenas de KB passaria: viraria chave de log, atributo de span, label de métricas...
+// - Nenhuma rota desta API precisa de URLs longas; recusar cedo é mais barato que carregar a URL por toda a cadeia
+//
+// Os tamanhos são medidos como o cliente enviou (codificados): "%20" conta 3 bytes
+// Deve rodar antes do roteamento (r.Use no router principal)
+func NewURLLengthLimit(maxPath, maxQuery int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if maxPath > 0 && len(r.URL.EscapedPath()) > maxPath {
+				writeError(w, r, http.StatusRequestURITooLong, "URL path too long")
+				return
+			}
+			if maxQuery > 0 && len(r.URL.RawQuery) > maxQuery {
+				writeError(w, r, http.StatusRequestURITooLong, "Query string too long")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ============================================
+// VALIDAÇÃO DO {id}
+// ============================================
+// objectIDLength é o tamanho de um ObjectID em hexadecimal (12 bytes = 24 caracteres)
+const objectIDLength = 24
+
+// RequireObjectID recusa com 400 as rotas cujo parâmetro {param} não é um ObjectID (24 caracteres hexadecimais)
+//
+// POR QUE NO HANDLER, ANTES DO USECASE?
+// - Um ID como "abc" ou "../../etc" nunca vai existir: consultar o MongoDB seria uma ida ao banco à toa
+// - O cliente recebe um 400 INVALID_ID, que diz o que está errado, em vez de um 404 ambíguo
+//
+// Usado com r.With nas rotas com {id}: o parâmetro só existe DEPOIS que a rota casou
+func RequireObjectID(param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isObjectIDHex(chi.URLParam(r, param)) {
+				writeErrorCode(w, r, http.StatusBadRequest, CodeInvalidID, "id must be a 24-character hexadecimal ObjectID")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isObjectIDHex confere tamanho e caracteres sem alocar (maiúsculas também são hexadecimais)
+func isObjectIDHex(s string) bool {
+	if len(s) != objectIDLength {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
@@ -1,8 +1,16 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
@@ -28,23 +36,107 @@ import (
 // - Não acessa banco de dados diretamente (isso é do repository)
 // - Não valida regras de negócio (ex: email válido - isso é do usecase)
 type UserHandler struct {
-	uc domain.UserUseCase // Dependência: o usecase que contém a lógica de negócio
+	uc           domain.UserUseCase      // Dependência: o usecase que contém a lógica de negócio
+	maxBodyBytes int64                   // Tamanho máximo aceito para o corpo JSON
+	pageDefault  int                     // Tamanho da página sem ?limit=
+	pageMax      int                     // Maior tamanho de página (?limit= acima disso é reduzido)
+	logger       *slog.Logger            // Logger estruturado (já com component=handler)
+	idempotency  domain.IdempotencyStore // Store de Idempotency-Key (nil = desabilitado)
+	bulkUpdate   bool                    // Registra PATCH /bulk (só com ENABLE_ADMIN)
+	basePath     string                  // Prefixo dos links _links (BASE_PATH; "" = raiz do host)
+	streams      chan struct{}           // Vagas de GET /stream: um semáforo (ver streamUsers)
 }
 
 // NewUserHandler cria um novo handler recebendo o usecase como dependência
+// maxBodyBytes limita o tamanho do corpo JSON em create/update
+// pageDefault e pageMax controlam o ?limit= da paginação (PAGE_DEFAULT e PAGE_MAX)
+// idempotency guarda as chaves do header Idempotency-Key (nil desabilita o recurso)
+// bulkUpdate registra a atualização em massa (PATCH /bulk), uma rota de administração
+// basePath é o prefixo sob o qual a API é publicada, usado nos links de ?hateoas=true (BASE_PATH)
+// maxStreams limita as conexões simultâneas do stream de alterações (USER_STREAM_MAX_CLIENTS)
 // Retorna *UserHandler (ponteiro) - padrão em Go para structs
-func NewUserHandler(uc domain.UserUseCase) *UserHandler {
-	return &UserHandler{uc: uc}
+func NewUserHandler(uc domain.UserUseCase, maxBodyBytes int64, pageDefault, pageMax int, idempotency domain.IdempotencyStore, bulkUpdate bool, basePath string, maxStreams int, logger *slog.Logger) *UserHandler {
+	return &UserHandler{
+		uc:           uc,
+		maxBodyBytes: maxBodyBytes,
+		pageDefault:  pageDefault,
+		pageMax:      pageMax,
+		logger:       logger.With("component", "handler"),
+		idempotency:  idempotency,
+		bulkUpdate:   bulkUpdate,
+		basePath:     basePath,
+		streams:      make(chan struct{}, maxStreams),
+	}
 }
 
 // RegisterRoutes registra todas as rotas de usuários no router
-func (h *UserHandler) RegisterRoutes(r chi.Router) {
+// As rotas de escrita (POST, PUT, DELETE) e a exportação passam pelo middleware de autenticação
+// As demais rotas de leitura (GET) continuam públicas
+// validate confere o corpo de criação e atualização contra o schema OpenAPI (ver openapi_validator.go)
+// limitEmailCheck é o rate limit próprio da consulta de email disponível (ver checkEmailAvailable)
+func (h *UserHandler) RegisterRoutes(r chi.Router, auth, validate, limitEmailCheck func(http.Handler) http.Handler) {
+	// IDs fora do formato ObjectID recebem 400 sem consultar o banco
+	validID := RequireObjectID("id")
+
 	r.Route("/api/v1/users", func(r chi.Router) {
-		r.Post("/", h.createUser)
-		r.Get("/", h.listUsers)
-		r.Get("/{id}", h.getUser)
-		r.Put("/{id}", h.updateUser)
-		r.Delete("/{id}", h.deleteUser)
+		// 405 com o header Allow calculado a partir das rotas deste sub-router
+		r.MethodNotAllowed(NewMethodNotAllowedHandler(r))
+		// Permite ao cache marcar respostas servidas com dados desatualizados (ver stale.go)
+		r.Use(TrackStaleReads)
+
+		// OPTIONS /api/v1/users lista as operações deste sub-router (sempre JSON, sem autenticação)
+		r.Options("/", NewOptionsHandler(r))
+
+		// A exportação escolhe o formato por ?format= (csv ou json), não pelo Accept:
+		// fica fora da negociação de conteúdo (um "Accept: text/csv" não pode virar 406)
+		r.With(auth).Get("/export", h.exportUsers)
+		// A listagem de IDs também é sempre JSON (com streaming quando não paginada)
+		r.Get("/ids", h.listUserIDs)
+		// O stream de alterações é sempre text/event-stream (SSE); como a exportação, expõe todos os usuários
+		r.With(auth).Get("/stream", h.streamUsers)
+		// Pelo mesmo motivo, ?stream=ndjson desvia da negociação (ver listOrStream)
+		r.Get("/", h.listOrStream(NegotiateContentType(http.HandlerFunc(h.listUsers))))
+
+		// As demais rotas respondem JSON ou XML conforme o Accept (ver content_negotiation.go)
+		r.Group(func(r chi.Router) {
+			r.Use(NegotiateContentType)
+
+			r.Get("/count", h.countUsers)
+			r.Get("/search", h.searchUsers)
+			r.With(limitEmailCheck).Get("/email-available", h.checkEmailAvailable)
+			r.With(validID).Get("/{id}", h.getUser)
+			r.With(validID).Head("/{id}", h.headUser)
+			// batch-get é uma LEITURA (pública como os GETs); usa POST só para levar a lista no corpo
+			r.With(RequireJSON).Post("/batch-get", h.batchGetUsers)
+			// verify é público: quem prova a identidade é o próprio token (recebido por email)
+			r.With(RequireJSON).Post("/verify", h.verifyEmail)
+
+			// r.Group cria um subgrupo que compartilha os mesmos middlewares
+			r.Group(func(r chi.Router) {
+				r.Use(auth)
+				// Corpos de POST/PUT/PATCH precisam ser JSON (senão 415)
+				// Dentro do Group o middleware só roda DEPOIS que a rota casou:
+				// um método inexistente continua recebendo 405, não 415
+				r.Use(RequireJSON)
+				// /me é uma rota estática: o chi a prefere a /{id}, não importa a ordem
+				r.Get("/me", h.getCurrentUser)
+				r.With(validate).Post("/", h.createUser)
+				r.Post("/bulk-delete", h.bulkDeleteUsers)
+				// A atualização em massa só existe com ENABLE_ADMIN; sem ela, PATCH /bulk
+				// cai em /{id} (que não aceita PATCH) e recebe 405
+				if h.bulkUpdate {
+					r.Patch("/bulk", h.bulkUpdateUsers)
+				}
+				r.With(validID, validate).Put("/{id}", h.updateUser)
+				r.With(validID).Delete("/{id}", h.deleteUser)
+				r.With(validID).Post("/{id}/emails", h.addEmail)
+				r.With(validID).Post("/{id}/change-email", h.changeEmail)
+				// Fusão de contas duplicadas: remove o secundário (ver usecase.MergeUsers)
+				r.With(validID).Post("/{id}/merge", h.mergeUsers)
+				// O histórico mostra quem alterou o quê: só para clientes autenticados
+				r.With(validID).Get("/{id}/audit", h.getUserAudit)
+			})
+		})
 	})
 }
 
@@ -53,162 +145,1004 @@ func (h *UserHandler) RegisterRoutes(r chi.Router) {
 // ============================================
 // createUser trata requisições POST /api/v1/users
 // @Summary Create user
+// @Description With upsert=true, a user whose primary email matches is updated instead (200) and a new one is created otherwise (201). The response then includes "created" and "changed"
 // @Tags users
 // @Accept json
-// @Produce json
-// @Param user body object true "User payload" example({"name":"string","email":"string"})
+// @Produce json,application/xml
+// @Param user body CreateUserRequest true "User payload"
+// @Param upsert query bool false "Update the user with this primary email instead of failing with 422 EMAIL_TAKEN"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeated key returns the original user (ignored with upsert=true)"
+// @Param envelope query bool false "Wrap the response as {\"data\": ..., \"meta\": ...}"
+// @Param X-Envelope header bool false "Same as envelope=true"
+// @Param hateoas query bool false "Add _links (self, update, delete) with absolute URLs to each user"
 // @Success 201 {object} domain.User
-// @Failure 400 {object} map[string]string
+// @Success 200 {object} domain.User "Existing user updated (upsert=true)"
+// @Header 201 {string} Location "URL of the created user"
+// @Failure 400 {object} map[string]string "Malformed JSON"
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string "Idempotency-Key still in progress"
+// @Failure 422 {object} map[string]string "Invalid data, email already in use, or Idempotency-Key reused with another body"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Failure 415 {object} map[string]string
 // @Router /api/v1/users [post]
 func (h *UserHandler) createUser(w http.ResponseWriter, r *http.Request) {
 	// SOBRE OS PARÂMETROS:
 	//   - w http.ResponseWriter: usado para escrever a resposta HTTP
 	//   - r *http.Request: contém informações da requisição (body, headers, etc.)
 	//     O * significa que é um ponteiro - Go passa por referência para evitar cópia
-	// Define uma struct anônima para receber os dados do JSON
+	// CreateUserRequest (requests.go) recebe os dados do JSON
 	// As tags json:"name" mapeiam os campos do JSON para os campos da struct
 	// Se o JSON tiver "name", vai para req.Name
-	var req struct {
-		Name  string `json:"name"`  // Campo Name mapeia para "name" no JSON
-		Email string `json:"email"` // Campo Email mapeia para "email" no JSON
-	}
+	var req CreateUserRequest
 
 	// Lê e decodifica o JSON do corpo da requisição
-	//
-	// SOBRE json.NewDecoder(r.Body).Decode(&req):
-	// - r.Body é um io.Reader com os bytes do JSON enviado
-	// - json.NewDecoder cria um decodificador que lê desse Reader
-	// - .Decode(&req) converte o JSON para a struct req
-	// - O & passa um ponteiro para req, permitindo que Decode preencha os campos
-	//
-	// Se o JSON for inválido (ex: sintaxe errada, tipo errado), retorna erro
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "Invalid request body")
+	// Se o JSON for inválido ou grande demais, decodeJSON já escreveu o erro 400
+	if !h.decodeJSON(w, r, &req) {
 		return // Para a execução aqui - não continua
 	}
 
+	// ?upsert=true: "cria se não existir, senão atualiza", pelo email principal
+	// O Idempotency-Key não se aplica: repetir um upsert já chega ao mesmo resultado
+	// (o validador OpenAPI já recusou valores que não são booleanos)
+	if upsert, _ := strconv.ParseBool(r.URL.Query().Get("upsert")); upsert {
+		h.upsertUser(w, r, req)
+		return
+	}
+
+	// Idempotency-Key: uma repetição devolve o usuário original em vez de criar outro
+	// (detalhes em idempotency.go)
+	key := r.Header.Get(idempotencyKeyHeader)
+	reserved := false
+	if key != "" && h.idempotency != nil {
+		var done bool
+		reserved, done = h.reserveIdempotencyKey(w, r, key, requestHash(req.Name, req.Email, req.Phone, req.Metadata))
+		if done {
+			return
+		}
+	}
+
 	// Chama o usecase para criar o usuário
 	// A validação do email (deve conter '@') acontece dentro do usecase
 	//
 	// CreateUser retorna (*domain.User, error)
 	// - Se sucesso: user contém o usuário criado (com ID populado)
 	// - Se erro: user é nil e err contém o erro
-	user, err := h.uc.CreateUser(req.Name, req.Email)
+	user, err := h.uc.CreateUser(r.Context(), req.Name, req.Email, req.Phone, req.Metadata)
+	if reserved {
+		h.finishIdempotencyKey(r, key, user)
+	}
 	if err != nil {
 		// Tratamento de erros: traduz erros do usecase para status HTTP
-		// ErrInvalidEmail → 400 Bad Request (erro do cliente)
-		if err == usecase.ErrInvalidEmail {
-			writeError(w, http.StatusBadRequest, err.Error())
+		// JSON válido, mas dados recusados pelas regras (email inválido, nome vazio,
+		// email já usado por outro usuário) → 422 Unprocessable Entity (ver writeValidationError)
+		if isValidationError(err) || err == usecase.ErrEmailTaken {
+			writeValidationError(w, r, err)
 			return
 		}
 		// Outros erros (ex: banco indisponível) → 500 Internal Server Error
-		writeError(w, http.StatusInternalServerError, "Failed to create user")
+		// (ou 503 se o prazo da requisição estourou)
+		h.writeServerError(w, r, err, "Failed to create user")
 		return
 	}
 
+	// O header Location aponta para o recurso recém-criado
+	// Clientes REST usam esse header para buscar o usuário sem montar a URL
+	w.Header().Set("Location", "/api/v1/users/"+user.ID)
+
 	// Retorna 201 Created com o usuário criado em JSON
 	// 201 Created é o status HTTP padrão para criação bem-sucedida
-	writeJSON(w, http.StatusCreated, user)
+	writeResource(w, r, http.StatusCreated, h.userResource(r, user, nil))
+}
+
+// upsertUser trata POST /api/v1/users?upsert=true
+//
+// CRIADO OU ATUALIZADO? Está no status E no corpo:
+// - 201 Created + Location: não havia usuário com este email principal
+// - 200 OK: o usuário existente foi atualizado
+// - O corpo é o usuário com "created" (true/false) e "changed" (campos alterados; {} na criação)
+func (h *UserHandler) upsertUser(w http.ResponseWriter, r *http.Request, req CreateUserRequest) {
+	user, created, changes, err := h.uc.UpsertUser(r.Context(), req.Name, req.Email, req.Phone, req.Metadata)
+	if err != nil {
+		// ErrEmailTaken: o email é secundário de outro usuário (o upsert só casa com o principal)
+		if isValidationError(err) || err == usecase.ErrEmailTaken {
+			writeValidationError(w, r, err)
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to upsert user")
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		w.Header().Set("Location", "/api/v1/users/"+user.ID)
+		status = http.StatusCreated
+	}
+	writeResource(w, r, status, userUpsertResult{
+		userWithChanges: userWithChanges{User: user, Changed: changes, Links: h.userLinks(r, user.ID)},
+		Created:         created,
+	})
+}
+
+// userUpsertResult é a resposta do upsert: o usuário com "changed" e "created"
+// {"id": "...", "name": "Maria", ..., "changed": {...}, "created": false}
+type userUpsertResult struct {
+	userWithChanges
+	Created bool `json:"created"`
 }
 
 // listUsers trata requisições GET /api/v1/users
 // @Summary List users
 // @Tags users
-// @Produce json
+// @Produce json,application/xml,application/x-ndjson
+// @Param name query string false "Filter by name (partial, case-insensitive)"
+// @Param after query string false "Cursor: return users after this ID (enables cursor pagination)"
+// @Param limit query int false "Page size (enables cursor pagination; default PAGE_DEFAULT, values above PAGE_MAX are clamped)"
+// @Param offset query int false "Skip this many users (enables offset pagination)"
+// @Param updatedSince query string false "Only users changed at or after this RFC 3339 date; results are ordered by updated_at (removed users are never listed)"
+// @Param fields query string false "Comma-separated fields to return (id,name,email,emails,phone,verified,metadata,version,login_count,created_at,updated_at)"
+// @Param envelope query bool false "Wrap the response as {\"data\": ..., \"meta\": ...}"
+// @Param X-Envelope header bool false "Same as envelope=true"
+// @Param hateoas query bool false "Add _links (self, update, delete) with absolute URLs to each user"
+// @Param stream query string false "ndjson streams every matching user as one JSON object per line (application/x-ndjson); cannot be combined with after, limit or offset" Enums(ndjson)
 // @Success 200 {array} domain.User
+// @Header 200 {string} Link "Pagination links (rel=first, prev, next) when paginating"
+// @Failure 400 {object} map[string]string
 // @Router /api/v1/users [get]
 func (h *UserHandler) listUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.uc.ListUsers()
+	// Com ?after= ou ?limit= a resposta é paginada por cursor
+	// Sem eles, mantemos o comportamento antigo (array com todos os usuários)
+	// ?fields= escolhe os campos da resposta (ver fields.go)
+	fields, err := parseFields(r)
+	if err != nil {
+		writeErrorCode(w, r, http.StatusBadRequest, CodeInvalidFields, err.Error())
+		return
+	}
+	filter, err := parseListFilter(r)
+	if err != nil {
+		writeUsecaseError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	filter.Fields = fields
+
+	query := r.URL.Query()
+	if query.Has("offset") {
+		h.listUsersOffset(w, r, filter)
+		return
+	}
+	if query.Has("after") || query.Has("limit") {
+		h.listUsersPage(w, r, filter)
+		return
+	}
+
+	users, err := h.uc.ListUsers(r.Context(), filter)
+	if err != nil {
+		h.writeServerError(w, r, err, "Failed to list users")
+		return
+	}
+
+	// Com envelope: {"data": [...], "meta": {"total": N}} (sem paginação, o total é o tamanho da lista)
+	if wantsEnvelope(w, r) {
+		if users == nil {
+			users = []*domain.User{}
+		}
+		total := int64(len(users))
+		writeList(w, r, http.StatusOK, h.userResources(r, users, fields), listMeta{Total: &total})
+		return
+	}
+	writeResponse(w, r, http.StatusOK, h.userResources(r, users, fields))
+}
+
+// listUsersPage responde uma página da paginação por cursor:
+//
+//	{"data": [...], "next": "507f1f77bcf86cd799439011", "limit": 20}
+//
+// Para buscar a próxima página, o cliente envia ?after=<next>
+// next vazio ("") indica que não há mais páginas
+func (h *UserHandler) listUsersPage(w http.ResponseWriter, r *http.Request, filter domain.UserFilter) {
+	query := r.URL.Query()
+
+	limit, err := h.parseLimit(query.Get("limit"))
+	if err != nil {
+		writeUsecaseError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	users, next, err := h.uc.ListUsersPage(r.Context(), filter, query.Get("after"), limit)
+	if err != nil {
+		if err == usecase.ErrInvalidCursor || err == usecase.ErrInvalidLimit {
+			writeUsecaseError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to list users")
+		return
+	}
+
+	// Página vazia vira [] no JSON (um slice nil viraria null)
+	if users == nil {
+		users = []*domain.User{}
+	}
+
+	// Link: rel="first" e rel="next" (o cursor só avança, então não há rel="prev")
+	links := []string{link(r, "first", map[string]string{"after": "", "limit": strconv.Itoa(limit)})}
+	if next != "" {
+		links = append(links, link(r, "next", map[string]string{"after": next, "limit": strconv.Itoa(limit)}))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+
+	if wantsEnvelope(w, r) {
+		total, ok := h.countForEnvelope(w, r, filter)
+		if !ok {
+			return
+		}
+		writeList(w, r, http.StatusOK, h.userResources(r, users, filter.Fields), listMeta{Total: &total, Limit: limit, Next: next})
+		return
+	}
+
+	// limit é o valor EFETIVO (já com o padrão e o teto aplicados)
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"data":  h.userResources(r, users, filter.Fields),
+		"next":  next,
+		"limit": limit,
+	})
+}
+
+// listUsersOffset responde uma página da paginação por offset:
+//
+//	{"data": [...], "offset": 40, "limit": 20}
+//
+// Além do corpo, o header Link aponta para as páginas first, prev e next
+func (h *UserHandler) listUsersOffset(w http.ResponseWriter, r *http.Request, filter domain.UserFilter) {
+	query := r.URL.Query()
+
+	limit, err := h.parseLimit(query.Get("limit"))
+	if err != nil {
+		writeUsecaseError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	offset, err := strconv.Atoi(query.Get("offset"))
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Failed to list users")
+		writeUsecaseError(w, r, http.StatusBadRequest, usecase.ErrInvalidOffset)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, users)
+	users, hasNext, err := h.uc.ListUsersOffset(r.Context(), filter, offset, limit)
+	if err != nil {
+		if err == usecase.ErrInvalidOffset || err == usecase.ErrInvalidLimit {
+			writeUsecaseError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to list users")
+		return
+	}
+
+	if users == nil {
+		users = []*domain.User{}
+	}
+
+	w.Header().Set("Link", strings.Join(offsetLinks(r, offset, limit, hasNext), ", "))
+
+	if wantsEnvelope(w, r) {
+		total, ok := h.countForEnvelope(w, r, filter)
+		if !ok {
+			return
+		}
+		writeList(w, r, http.StatusOK, h.userResources(r, users, filter.Fields), listMeta{Total: &total, Limit: limit, Offset: &offset})
+		return
+	}
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"data":   h.userResources(r, users, filter.Fields),
+		"offset": offset,
+		"limit":  limit,
+	})
+}
+
+// countForEnvelope conta os usuários do filtro para o meta.total das páginas com envelope
+// É uma consulta a mais (CountDocuments), feita só quando o cliente pede o envelope
+// Em caso de erro, a resposta já foi escrita e ok é false
+func (h *UserHandler) countForEnvelope(w http.ResponseWriter, r *http.Request, filter domain.UserFilter) (int64, bool) {
+	total, err := h.uc.CountUsers(r.Context(), filter)
+	if err != nil {
+		h.writeServerError(w, r, err, "Failed to count users")
+		return 0, false
+	}
+	return total, true
+}
+
+// parseLimit lê ?limit= e devolve o tamanho de página EFETIVO
+// - Ausente: h.pageDefault (PAGE_DEFAULT)
+// - Acima de h.pageMax (PAGE_MAX): reduzido ao máximo em vez de recusado
+// - Não numérico: ErrInvalidLimit (zero e negativos são recusados pelo usecase)
+//
+// POR QUE REDUZIR EM VEZ DE RECUSAR?
+// - O cliente recebe uma página válida e vê o limite aplicado no campo "limit" da resposta
+// - O banco continua protegido: nenhuma consulta traz mais que PAGE_MAX documentos
+func (h *UserHandler) parseLimit(v string) (int, error) {
+	if v == "" {
+		return h.pageDefault, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, usecase.ErrInvalidLimit
+	}
+	if n > h.pageMax {
+		n = h.pageMax
+	}
+	return n, nil
+}
+
+// countUsers trata requisições GET /api/v1/users/count
+// Aceita os mesmos filtros da listagem
+//
+// Com COUNT_CACHE_INTERVAL, o total sem filtro vem do cache (ver repository.CountCacheRepository)
+// e a resposta avisa: {"count": 1234, "cached": true, "as_of": "2024-01-31T12:00:00Z"}
+// Sem cache (ou com filtro), a resposta continua só {"count": 1234}
+//
+// @Summary Count users
+// @Description Without filters and with COUNT_CACHE_INTERVAL set, the total comes from a periodically refreshed cache: the response then has "cached": true and "as_of" (time of the last database count)
+// @Tags users
+// @Produce json,application/xml
+// @Param name query string false "Filter by name (partial, case-insensitive)"
+// @Param updatedSince query string false "Only users changed at or after this RFC 3339 date"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/users/count [get]
+func (h *UserHandler) countUsers(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseListFilter(r)
+	if err != nil {
+		writeUsecaseError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	ctx := domain.ContextWithCountTracking(r.Context())
+	count, err := h.uc.CountUsers(ctx, filter)
+	if err != nil {
+		h.writeServerError(w, r, err, "Failed to count users")
+		return
+	}
+
+	if asOf, ok := domain.CountCachedAt(ctx); ok {
+		writeResponse(w, r, http.StatusOK, map[string]interface{}{
+			"count":  count,
+			"cached": true,
+			"as_of":  asOf.Format(time.RFC3339),
+		})
+		return
+	}
+	writeResponse(w, r, http.StatusOK, map[string]int64{"count": count})
+}
+
+// ============================================
+// EMAIL AVAILABLE
+// ============================================
+// checkEmailAvailable trata requisições GET /api/v1/users/email-available?email=...
+// Responde {"available": true} ou {"available": false}; emails malformados recebem 400
+//
+// POR QUE UM RATE LIMIT PRÓPRIO?
+// - A rota é pública: sem limite, um script testaria listas de emails para descobrir quem é cliente
+// - O limite geral (RATE_LIMIT_RPS) é pensado para a navegação normal, alto demais para isso
+// - Um formulário de cadastro consulta poucas vezes por minuto: EMAIL_CHECK_RATE_LIMIT_RPS pode ser bem menor
+//
+// @Summary Check email availability
+// @Description Tells signup forms whether an email can still be registered. Emails of removed users remain unavailable. Rate limited per IP (EMAIL_CHECK_RATE_LIMIT_RPS)
+// @Tags users
+// @Produce json,application/xml
+// @Param email query string true "Email to check, exactly as it would be sent on create"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /api/v1/users/email-available [get]
+func (h *UserHandler) checkEmailAvailable(w http.ResponseWriter, r *http.Request) {
+	available, err := h.uc.IsEmailAvailable(r.Context(), r.URL.Query().Get("email"))
+	if err != nil {
+		if isValidationError(err) {
+			writeUsecaseError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to check email availability")
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, map[string]bool{"available": available})
+}
+
+// ============================================
+// SEARCH USERS
+// ============================================
+// searchUsers trata requisições GET /api/v1/users/search
+// Combina busca parcial por nome e email, intervalo de criação, ordenação e paginação:
+//
+//	{"data": [...], "total": 57, "offset": 20, "limit": 20}
+//
+// total é o número de usuários que casam com os critérios (todas as páginas)
+//
+// POR QUE UM ENDPOINT SEPARADO DA LISTAGEM?
+// - A listagem (GET /api/v1/users) tem dois modos de paginação, header Link e projeção
+// - Somar mais filtros e ordenações ali multiplicaria as combinações a manter
+// - Aqui há UM modo (offset) e a resposta já traz o total, sem uma chamada extra a /count
+//
+// @Summary Search users
+// @Description All criteria are optional and combined with AND. name and email match substrings (case-insensitive, at most 100 characters each). createdAfter is inclusive and createdBefore exclusive (RFC 3339). Results are ordered by sort/order with the ID as tiebreaker; offset goes up to 10000
+// @Tags users
+// @Produce json,application/xml
+// @Param name query string false "Name contains (case-insensitive)" maxlength(100)
+// @Param email query string false "Primary email contains (case-insensitive)" maxlength(100)
+// @Param createdAfter query string false "Created at or after (RFC 3339)" format(date-time)
+// @Param createdBefore query string false "Created before (RFC 3339)" format(date-time)
+// @Param sort query string false "Sort field" Enums(name, email, created_at) default(created_at)
+// @Param order query string false "Sort direction" Enums(asc, desc) default(asc)
+// @Param offset query int false "Results to skip" minimum(0) maximum(10000) default(0)
+// @Param limit query int false "Page size (capped at PAGE_MAX)" minimum(1)
+// @Param envelope query bool false "Wrap the response as {\"data\": ..., \"meta\": ...}"
+// @Param X-Envelope header bool false "Same as envelope=true"
+// @Param hateoas query bool false "Add _links (self, update, delete) with absolute URLs to each user"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/users/search [get]
+func (h *UserHandler) searchUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, err := h.parseLimit(query.Get("limit"))
+	if err != nil {
+		writeUsecaseError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	offset := 0
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil {
+			writeUsecaseError(w, r, http.StatusBadRequest, usecase.ErrInvalidOffset)
+			return
+		}
+	}
+	createdAfter, err := parseSearchDate(query.Get("createdAfter"))
+	if err != nil {
+		writeUsecaseError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	createdBefore, err := parseSearchDate(query.Get("createdBefore"))
+	if err != nil {
+		writeUsecaseError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	criteria := domain.SearchCriteria{
+		Name:          query.Get("name"),
+		Email:         query.Get("email"),
+		CreatedAfter:  createdAfter,
+		CreatedBefore: createdBefore,
+		Sort:          query.Get("sort"),
+		Order:         query.Get("order"),
+		Offset:        offset,
+		Limit:         limit,
+	}
+	users, total, err := h.uc.SearchUsers(r.Context(), criteria)
+	if err != nil {
+		switch err {
+		case usecase.ErrInvalidLimit, usecase.ErrInvalidOffset, usecase.ErrOffsetTooLarge,
+			usecase.ErrSearchTermTooLong, usecase.ErrInvalidSort, usecase.ErrInvalidOrder, usecase.ErrInvalidDateRange:
+			writeUsecaseError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to search users")
+		return
+	}
+
+	if users == nil {
+		users = []*domain.User{}
+	}
+	if wantsEnvelope(w, r) {
+		writeList(w, r, http.StatusOK, h.userResources(r, users, nil), listMeta{Total: &total, Limit: limit, Offset: &offset})
+		return
+	}
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"data":   h.userResources(r, users, nil),
+		"total":  total,
+		"offset": offset,
+		"limit":  limit,
+	})
+}
+
+// parseSearchDate lê uma data RFC 3339 (ex: 2024-01-31T00:00:00Z) da busca
+// Vazio = sem limite (time.Time zero)
+func parseSearchDate(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, usecase.ErrInvalidDate
+	}
+	return t.UTC(), nil
+}
+
+// parseFilter lê os filtros da query string (ex: ?name=jo)
+// r.URL.Query().Get retorna "" quando o parâmetro não existe
+func parseFilter(r *http.Request) domain.UserFilter {
+	return domain.UserFilter{
+		Name: r.URL.Query().Get("name"),
+	}
+}
+
+// parseListFilter lê os filtros da listagem e da contagem: os de parseFilter mais ?updatedSince=
+// updatedSince é uma data RFC 3339 (ex: 2024-01-01T00:00:00Z); fora do formato → ErrInvalidUpdatedSince
+func parseListFilter(r *http.Request) (domain.UserFilter, error) {
+	filter := parseFilter(r)
+	if v := r.URL.Query().Get("updatedSince"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return domain.UserFilter{}, usecase.ErrInvalidUpdatedSince
+		}
+		filter.UpdatedSince = t.UTC()
+	}
+	return filter, nil
 }
 
 // getUser trata requisições GET /api/v1/users/{id}
 // @Summary Get user by ID
 // @Tags users
-// @Produce json
+// @Produce json,application/xml
 // @Param id path string true "User ID"
+// @Param If-None-Match header string false "ETag from a previous response"
+// @Param fields query string false "Comma-separated fields to return (id,name,email,emails,phone,verified,metadata,version,login_count,created_at,updated_at)"
+// @Param envelope query bool false "Wrap the response as {\"data\": ..., \"meta\": ...}"
+// @Param X-Envelope header bool false "Same as envelope=true"
+// @Param hateoas query bool false "Add _links (self, update, delete) with absolute URLs to each user"
 // @Success 200 {object} domain.User
+// @Success 304 "Not Modified"
+// @Header 200 {string} ETag "Entity tag of the user"
+// @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 410 {object} map[string]string "User was deleted"
 // @Router /api/v1/users/{id} [get]
 func (h *UserHandler) getUser(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
+	h.serveUser(w, r, chi.URLParam(r, "id"), false)
+}
+
+// getCurrentUser trata requisições GET /api/v1/users/me
+// Devolve o usuário do token (claim "sub"), sem que o cliente precise saber o próprio ID
+//
+// POR QUE 404 (E NÃO 410) PARA UM USUÁRIO REMOVIDO?
+// - O 410 do GET /{id} diz que AQUELA URL existiu; /me não identifica um recurso fixo
+// - Para quem chama, o token é válido mas aponta para um usuário que não existe mais
+//
+// Com API key, o autenticado é um serviço ("apikey:<label>"), não um usuário: a resposta é 404
+//
+// @Summary Get the authenticated user
+// @Description Returns the user identified by the token's "sub" claim. 404 when that user does not exist (or was deleted)
+// @Tags users
+// @Produce json,application/xml
+// @Param If-None-Match header string false "ETag from a previous response"
+// @Param fields query string false "Comma-separated fields to return (id,name,email,emails,phone,verified,metadata,version,login_count,created_at,updated_at)"
+// @Param envelope query bool false "Wrap the response as {\"data\": ..., \"meta\": ...}"
+// @Param X-Envelope header bool false "Same as envelope=true"
+// @Param hateoas query bool false "Add _links (self, update, delete) with absolute URLs to each user"
+// @Success 200 {object} domain.User
+// @Success 304 "Not Modified"
+// @Header 200 {string} ETag "Entity tag of the user"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/v1/users/me [get]
+func (h *UserHandler) getCurrentUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	h.serveUser(w, r, id, true)
+}
 
-	user, err := h.uc.GetUser(id)
+// serveUser escreve o usuário id (com ETag, If-None-Match e ?fields=): o GET /{id} e o /me
+// current indica o /me, em que um usuário removido também responde 404 (ver getCurrentUser)
+func (h *UserHandler) serveUser(w http.ResponseWriter, r *http.Request, id string, current bool) {
+	fields, err := parseFields(r)
 	if err != nil {
-		if err == usecase.ErrNotFound {
-			writeError(w, http.StatusNotFound, "User not found")
+		writeErrorCode(w, r, http.StatusBadRequest, CodeInvalidFields, err.Error())
+		return
+	}
+
+	// Aqui não há projeção no banco: o documento é pequeno e o ETag
+	// precisa ser calculado sobre o usuário COMPLETO (o mesmo ETag do PUT/DELETE)
+	user, err := h.uc.GetUser(r.Context(), id)
+	if err != nil {
+		if current && (err == usecase.ErrNotFound || err == usecase.ErrGone) {
+			writeErrorCode(w, r, http.StatusNotFound, CodeUserNotFound, "User not found")
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "Failed to get user")
+		if writeMissingUser(w, r, err) {
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to get user")
+		return
+	}
+
+	// Se o cliente já tem esta versão em cache, não reenviamos o corpo
+	etag := computeETag(user)
+	w.Header().Set("ETag", etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+		warnIfStale(w, r)
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, user)
+	writeResource(w, r, http.StatusOK, h.userResource(r, user, fields))
+}
+
+// headUser trata requisições HEAD /api/v1/users/{id}
+// @Summary Check if user exists
+// @Description Same status and headers (ETag, Content-Length) as GET, without the body
+// @Tags users
+// @Param id path string true "User ID"
+// @Param If-None-Match header string false "ETag from a previous response"
+// @Success 200 "User exists"
+// @Success 304 "Not Modified"
+// @Header 200 {string} ETag "Entity tag of the user"
+// @Failure 400 "Malformed ID"
+// @Failure 404 "User not found"
+// @Failure 410 "User was deleted"
+// @Router /api/v1/users/{id} [head]
+//
+// POR QUE REAPROVEITAR O getUser?
+// - Status, ETag e 304 ficam idênticos aos do GET sem duplicar código
+// - headResponseWriter descarta o corpo, mas conta os bytes para o Content-Length
+func (h *UserHandler) headUser(w http.ResponseWriter, r *http.Request) {
+	hw := &headResponseWriter{ResponseWriter: w, status: http.StatusOK}
+	h.getUser(hw, r)
+
+	// 304 não tem corpo no GET, então também não informa Content-Length
+	if hw.status != http.StatusNotModified {
+		w.Header().Set("Content-Length", strconv.Itoa(hw.length))
+	}
+	w.WriteHeader(hw.status)
+}
+
+// headResponseWriter segura o status e descarta o corpo, contando seu tamanho
+// O status só é enviado no final, para dar tempo de definir o Content-Length
+type headResponseWriter struct {
+	http.ResponseWriter
+	status int
+	length int
+}
+
+func (w *headResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	w.length += len(b)
+	return len(b), nil
 }
 
 // @Summary Update user
+// @Description email, if sent, must be the current primary email: changing it requires POST /api/v1/users/{id}/change-email (422 EMAIL_CHANGE_REQUIRES_VERIFICATION otherwise)
 // @Tags users
 // @Accept json
-// @Produce json
+// @Produce json,application/xml
 // @Param id path string true "User ID"
-// @Param user body object true "User payload" example({"name":"string","email":"string"})
+// @Param If-Match header string false "ETag the client last read"
+// @Param user body UpdateUserRequest true "User payload"
+// @Param diff query bool false "Include a \"changed\" map with the old and new value of each changed field"
+// @Param envelope query bool false "Wrap the response as {\"data\": ..., \"meta\": ...}"
+// @Param X-Envelope header bool false "Same as envelope=true"
+// @Param hateoas query bool false "Add _links (self, update, delete) with absolute URLs to each user"
 // @Success 200 {object} domain.User
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 410 {object} map[string]string "User was deleted"
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Failure 412 {object} map[string]string
+// @Failure 409 {object} map[string]string "Concurrent update"
+// @Failure 415 {object} map[string]string
+// @Failure 422 {object} map[string]string "Invalid data, or an email other than the current primary (use change-email)"
 // @Router /api/v1/users/{id} [put]
 // updateUser trata requisições PUT /api/v1/users/{id}
 func (h *UserHandler) updateUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
+	// Version é opcional: quando informado, a atualização só acontece
+	// se o usuário ainda estiver nessa versão (senão 409 Conflict)
+	var req UpdateUserRequest
+
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	// If-Match: só atualiza se o usuário não mudou desde a última leitura do cliente
+	if !h.checkIfMatch(w, r, id) {
+		return
+	}
+
+	user, changes, err := h.uc.UpdateUser(r.Context(), id, req.Name, req.Email, req.Phone, req.Metadata, req.Version)
+	if err != nil {
+		if writeMissingUser(w, r, err) {
+			return
+		}
+		if err == usecase.ErrVersionConflict {
+			writeUsecaseError(w, r, http.StatusConflict, err)
+			return
+		}
+		// Outro email principal: a troca é pelo change-email, com verificação (422 EMAIL_CHANGE_REQUIRES_VERIFICATION)
+		if isValidationError(err) || err == usecase.ErrEmailTaken || err == usecase.ErrEmailChangeRequiresVerification {
+			writeValidationError(w, r, err)
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to update user")
+		return
+	}
+
+	w.Header().Set("ETag", computeETag(user))
+
+	// ?diff=true acrescenta "changed" ao usuário; sem ele, a resposta é a de sempre
+	// (o validador OpenAPI já recusou valores que não são booleanos)
+	if diff, _ := strconv.ParseBool(r.URL.Query().Get("diff")); diff {
+		writeResource(w, r, http.StatusOK, userWithChanges{User: user, Changed: changes, Links: h.userLinks(r, user.ID)})
+		return
+	}
+	writeResource(w, r, http.StatusOK, h.userResource(r, user, nil))
+}
+
+// userWithChanges é a resposta do PUT com ?diff=true
+//
+// SOBRE O EMBEDDING:
+// - *domain.User embutido (sem nome de campo) tem os campos "promovidos" no JSON
+// - O resultado é o mesmo objeto do usuário, com a chave "changed" a mais:
+// {"id": "...", "name": "Maria", ..., "changed": {"name": {"old": "João", "new": "Maria"}}}
+// - Sem alterações (ex: mesmos valores), changed vem como {}
+// - Com ?hateoas=true, "_links" também entra (ver hateoas.go)
+type userWithChanges struct {
+	*domain.User
+	Changed domain.UserChanges `json:"changed"`
+	Links   *userLinks         `json:"_links,omitempty"`
+}
+
+// @Summary Add email
+// @Description Adds a secondary (non-primary) email address to the user
+// @Tags users
+// @Accept json
+// @Produce json,application/xml
+// @Param id path string true "User ID"
+// @Param body body object true "Email to add" example({"email":"string"})
+// @Param envelope query bool false "Wrap the response as {\"data\": ..., \"meta\": ...}"
+// @Param X-Envelope header bool false "Same as envelope=true"
+// @Param hateoas query bool false "Add _links (self, update, delete) with absolute URLs to each user"
+// @Success 200 {object} domain.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 410 {object} map[string]string "User was deleted"
+// @Failure 409 {object} map[string]string "Concurrent update"
+// @Failure 415 {object} map[string]string
+// @Failure 422 {object} map[string]string "Invalid email, email already in use, or too many emails"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/v1/users/{id}/emails [post]
+// addEmail trata requisições POST /api/v1/users/{id}/emails
+// O email principal só muda pelo POST /api/v1/users/{id}/change-email (com verificação do endereço novo)
+func (h *UserHandler) addEmail(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
 	var req struct {
-		Name  string `json:"name"`
 		Email string `json:"email"`
 	}
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.uc.AddEmail(r.Context(), id, req.Email)
+	if err != nil {
+		if writeMissingUser(w, r, err) {
+			return
+		}
+		// Escrita concorrente → 409
+		if err == usecase.ErrVersionConflict {
+			writeUsecaseError(w, r, http.StatusConflict, err)
+			return
+		}
+		// Endereço já em uso (por este ou outro usuário) ou limite de emails → 422
+		if isValidationError(err) || err == usecase.ErrEmailTaken || err == usecase.ErrTooManyEmails {
+			writeValidationError(w, r, err)
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to add email")
+		return
+	}
+
+	w.Header().Set("ETag", computeETag(user))
+	writeResource(w, r, http.StatusOK, h.userResource(r, user, nil))
+}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "Invalid request body")
+// @Summary Change primary email
+// @Description Stores the new address as pending_email and sends it a verification token; the current email stays primary until POST /api/v1/users/verify confirms the new one
+// @Tags users
+// @Accept json
+// @Produce json,application/xml
+// @Param id path string true "User ID"
+// @Param body body object true "New primary email" example({"email":"string"})
+// @Param envelope query bool false "Wrap the response as {\"data\": ..., \"meta\": ...}"
+// @Param X-Envelope header bool false "Same as envelope=true"
+// @Param hateoas query bool false "Add _links (self, update, delete) with absolute URLs to each user"
+// @Success 202 {object} domain.User "Change requested: the user with pending_email"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 410 {object} map[string]string "User was deleted"
+// @Failure 409 {object} map[string]string "Already the primary email, or concurrent update"
+// @Failure 415 {object} map[string]string
+// @Failure 422 {object} map[string]string "Invalid email or email already in use"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/v1/users/{id}/change-email [post]
+// changeEmail trata requisições POST /api/v1/users/{id}/change-email
+// Responde 202 Accepted: a troca só acontece quando o novo endereço for verificado
+func (h *UserHandler) changeEmail(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
-	user, err := h.uc.UpdateUser(id, req.Name, req.Email)
+	user, err := h.uc.ChangeEmail(r.Context(), id, req.Email)
 	if err != nil {
-		if err == usecase.ErrNotFound {
-			writeError(w, http.StatusNotFound, "User not found")
+		if writeMissingUser(w, r, err) {
+			return
+		}
+		// Já é o principal, ou escrita concorrente → 409
+		if err == usecase.ErrEmailUnchanged || err == usecase.ErrVersionConflict {
+			writeUsecaseError(w, r, http.StatusConflict, err)
 			return
 		}
-		if err == usecase.ErrInvalidEmail {
-			writeError(w, http.StatusBadRequest, err.Error())
+		// Endereço de outro usuário → 422, como as demais regras sobre o email enviado
+		if isValidationError(err) || err == usecase.ErrEmailTaken {
+			writeValidationError(w, r, err)
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "Failed to update user")
+		h.writeServerError(w, r, err, "Failed to change email")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, user)
+	w.Header().Set("ETag", computeETag(user))
+	writeResource(w, r, http.StatusAccepted, h.userResource(r, user, nil))
+}
+
+// @Summary Merge duplicate users
+// @Description Merges secondary_id into the user in the path inside a transaction: the path user keeps its identity (name, emails, verification), takes the secondary's phone if it has none, sums login_count and, with copy_metadata, gains the secondary's metadata keys it lacks. The secondary is then soft-deleted. Requires a replica set (501 otherwise)
+// @Tags users
+// @Accept json
+// @Produce json,application/xml
+// @Param id path string true "User ID (kept)"
+// @Param body body MergeUsersRequest true "Duplicate user to merge and remove"
+// @Param envelope query bool false "Wrap the response as {\"data\": ..., \"meta\": ...}"
+// @Param X-Envelope header bool false "Same as envelope=true"
+// @Param hateoas query bool false "Add _links (self, update, delete) with absolute URLs to each user"
+// @Success 200 {object} domain.User "The merged user"
+// @Failure 400 {object} map[string]string "Malformed JSON or invalid secondary_id"
+// @Failure 422 {object} map[string]string "Same user on both sides, or too many metadata keys"
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 410 {object} map[string]string "One of the users was deleted"
+// @Failure 409 {object} map[string]string "Concurrent update"
+// @Failure 415 {object} map[string]string
+// @Failure 501 {object} map[string]string "Transactions unsupported"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/v1/users/{id}/merge [post]
+// mergeUsers trata requisições POST /api/v1/users/{id}/merge
+// 404/410 valem para qualquer um dos dois usuários
+func (h *UserHandler) mergeUsers(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req MergeUsersRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	if !isObjectIDHex(req.SecondaryID) {
+		writeErrorCode(w, r, http.StatusBadRequest, CodeInvalidID, "secondary_id must be a 24-character hexadecimal ObjectID")
+		return
+	}
+
+	user, err := h.uc.MergeUsers(r.Context(), id, req.SecondaryID, req.CopyMetadata)
+	if err != nil {
+		if writeMissingUser(w, r, err) {
+			return
+		}
+		switch {
+		case err == usecase.ErrMergeSameUser || isValidationError(err):
+			writeValidationError(w, r, err)
+		case err == usecase.ErrVersionConflict:
+			writeUsecaseError(w, r, http.StatusConflict, err)
+		case err == usecase.ErrTransactionsUnsupported:
+			writeUsecaseError(w, r, http.StatusNotImplemented, err)
+		default:
+			h.writeServerError(w, r, err, "Failed to merge users")
+		}
+		return
+	}
+
+	w.Header().Set("ETag", computeETag(user))
+	writeResource(w, r, http.StatusOK, h.userResource(r, user, nil))
+}
+
+// @Summary Verify email
+// @Description Consumes a single-use verification token and marks the user as verified; a token sent by change-email makes the pending email the primary one
+// @Tags users
+// @Accept json
+// @Produce json,application/xml
+// @Param body body object true "Token received by email" example({"token":"string"})
+// @Param envelope query bool false "Wrap the response as {\"data\": ..., \"meta\": ...}"
+// @Param X-Envelope header bool false "Same as envelope=true"
+// @Param hateoas query bool false "Add _links (self, update, delete) with absolute URLs to each user"
+// @Success 200 {object} domain.User
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string "Pending email was taken by another user, or concurrent update"
+// @Failure 415 {object} map[string]string
+// @Router /api/v1/users/verify [post]
+// verifyEmail trata requisições POST /api/v1/users/verify
+func (h *UserHandler) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.uc.VerifyEmail(r.Context(), req.Token)
+	if err != nil {
+		// Token desconhecido ou já usado → 400
+		if err == usecase.ErrInvalidToken {
+			writeUsecaseError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		// Token expirado → 410 Gone: ele existiu, mas não vale mais
+		if err == usecase.ErrTokenExpired {
+			writeUsecaseError(w, r, http.StatusGone, err)
+			return
+		}
+		// Troca de email: o endereço pendente foi pego por outro usuário, ou escrita concorrente → 409
+		if err == usecase.ErrEmailTaken || err == usecase.ErrVersionConflict {
+			writeUsecaseError(w, r, http.StatusConflict, err)
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to verify email")
+		return
+	}
+
+	w.Header().Set("ETag", computeETag(user))
+	writeResource(w, r, http.StatusOK, h.userResource(r, user, nil))
 }
 
 // @Summary Delete user
 // @Tags users
 // @Param id path string true "User ID"
+// @Param If-Match header string false "ETag the client last read"
 // @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 410 {object} map[string]string "User was deleted"
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Failure 412 {object} map[string]string
 // @Router /api/v1/users/{id} [delete]
 // deleteUser trata requisições DELETE /api/v1/users/{id}
 func (h *UserHandler) deleteUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	err := h.uc.DeleteUser(id)
+	if !h.checkIfMatch(w, r, id) {
+		return
+	}
+
+	err := h.uc.DeleteUser(r.Context(), id)
 	if err != nil {
-		if err == usecase.ErrNotFound {
-			writeError(w, http.StatusNotFound, "User not found")
+		if writeMissingUser(w, r, err) {
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "Failed to delete user")
+		h.writeServerError(w, r, err, "Failed to delete user")
 		return
 	}
 
@@ -216,16 +1150,428 @@ func (h *UserHandler) deleteUser(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ============================================
+// AUDIT LOG
+// ============================================
+// getUserAudit trata requisições GET /api/v1/users/{id}/audit
+// Resposta: {"data": [...]} com as alterações mais recentes primeiro
+//
+// @Summary User audit log
+// @Description Returns the user's change history (create/update/delete), newest first. Empty when AUDIT_ENABLED is off
+// @Tags users
+// @Produce json,application/xml
+// @Param id path string true "User ID"
+// @Param limit query int false "Max entries to return (default PAGE_DEFAULT, capped at PAGE_MAX)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/v1/users/{id}/audit [get]
+func (h *UserHandler) getUserAudit(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	limit, err := h.parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		writeUsecaseError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	entries, err := h.uc.GetUserAudit(r.Context(), id, limit)
+	if err != nil {
+		if err == usecase.ErrInvalidLimit {
+			writeUsecaseError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to get audit log")
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{"data": entries})
+}
+
 // writeJSON escreve uma resposta JSON com o status HTTP informado
-func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+// Compacta por padrão; indentada quando o cliente pede (ver wantsPrettyJSON)
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
+	enc := json.NewEncoder(w)
+	if wantsPrettyJSON(r) {
+		enc.SetIndent("", "  ")
+	}
+	enc.Encode(data)
 }
 
-// writeError escreve uma resposta de erro em JSON
-func writeError(w http.ResponseWriter, status int, msg string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+// batchGetUsers trata requisições POST /api/v1/users/batch-get
+// Corpo: {"ids": ["...", "..."]}
+// Resposta: {"data": [...], "invalid_ids": [...]}
+// IDs inexistentes ficam fora de data; IDs com formato inválido vão para invalid_ids
+//
+// @Summary Batch get users
+// @Tags users
+// @Accept json
+// @Produce json,application/xml
+// @Param body body object true "IDs to fetch" example({"ids":["507f1f77bcf86cd799439011"]})
+// @Param hateoas query bool false "Add _links (self, update, delete) with absolute URLs to each user"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Router /api/v1/users/batch-get [post]
+func (h *UserHandler) batchGetUsers(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	users, invalid, err := h.uc.GetUsers(r.Context(), req.IDs)
+	if err != nil {
+		if err == usecase.ErrNoIDs || err == usecase.ErrTooManyIDs {
+			writeUsecaseError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to get users")
+		return
+	}
+
+	// Slices nil viram null no JSON: devolvemos [] para o cliente não precisar tratar null
+	if users == nil {
+		users = []*domain.User{}
+	}
+	if invalid == nil {
+		invalid = []string{}
+	}
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"data":        h.userResources(r, users, nil),
+		"invalid_ids": invalid,
+	})
+}
+
+// bulkDeleteUsers trata requisições POST /api/v1/users/bulk-delete
+// Corpo: {"ids": ["...", "..."]}
+// Resposta: {"deleted": N, "invalid_ids": [...], "results": [{"index": 0, "id": "...", "status": 200}, ...]}
+// IDs inválidos ou inexistentes não derrubam a requisição: cada um tem o seu resultado em results
+// O status geral é 200 (todos removidos), 207 (misturado) ou 400 (nenhum removido) - ver multi_status.go
+//
+// @Summary Bulk delete users
+// @Description Each ID gets its own entry in results (200 deleted, 404 USER_NOT_FOUND, 400 INVALID_ID). The overall status is 200 when every ID was deleted, 207 when results are mixed and 400 when none was
+// @Tags users
+// @Accept json
+// @Produce json,application/xml
+// @Param body body object true "IDs to delete" example({"ids":["507f1f77bcf86cd799439011"]})
+// @Success 200 {object} map[string]interface{}
+// @Success 207 {object} map[string]interface{} "Some IDs were not deleted: see results"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Failure 415 {object} map[string]string
+// @Router /api/v1/users/bulk-delete [post]
+func (h *UserHandler) bulkDeleteUsers(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	deleted, notFound, invalid, err := h.uc.DeleteUsers(r.Context(), req.IDs)
+	if err != nil {
+		if err == usecase.ErrNoIDs || err == usecase.ErrTooManyIDs {
+			writeUsecaseError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to delete users")
+		return
+	}
+
+	isInvalid := make(map[string]bool, len(invalid))
+	for _, id := range invalid {
+		isInvalid[id] = true
+	}
+	isNotFound := make(map[string]bool, len(notFound))
+	for _, id := range notFound {
+		isNotFound[id] = true
+	}
+	results := make([]itemResult, len(req.IDs))
+	for i, id := range req.IDs {
+		res := itemResult{Index: i, ID: id, Status: http.StatusOK}
+		switch {
+		case isInvalid[id]:
+			res.Status, res.Code, res.Error = http.StatusBadRequest, CodeInvalidID, "id must be a 24-character hexadecimal ObjectID"
+		case isNotFound[id]:
+			res.Status, res.Code, res.Error = http.StatusNotFound, CodeUserNotFound, "User not found"
+		}
+		results[i] = res
+	}
+
+	if invalid == nil {
+		invalid = []string{}
+	}
+	writeMultiStatus(w, r, http.StatusOK, results, map[string]interface{}{
+		"deleted":     deleted,
+		"invalid_ids": invalid,
+	})
+}
+
+// bulkUpdateUsers trata requisições PATCH /api/v1/users/bulk
+// Corpo: {"filter": {"name_prefix": "Ma"}, "changes": {"metadata": {"beta": "true"}}, "confirm": false}
+// Resposta: {"matched": N, "modified": M}
+//
+// É uma rota de administração (ENABLE_ADMIN): altera muitos usuários de uma vez
+// Sem "confirm": true, filtro vazio ou mais de 1000 usuários no filtro → 422 CONFIRMATION_REQUIRED e nada muda
+// Cada atualização vai para o log em WARN (o audit log por usuário não a registra)
+//
+// @Summary Bulk update users (admin)
+// @Description Sets (string) or removes (null) metadata keys on every user matching the filter. Requires confirm=true when the filter is empty or matches more than 1000 users. Only available with ENABLE_ADMIN=true
+// @Tags admin
+// @Accept json
+// @Produce json,application/xml
+// @Param body body BulkUpdateRequest true "Filter and changes"
+// @Success 200 {object} map[string]int64
+// @Failure 400 {object} map[string]string "Malformed JSON"
+// @Failure 401 {object} map[string]string
+// @Failure 405 {object} map[string]string "ENABLE_ADMIN is off"
+// @Failure 415 {object} map[string]string
+// @Failure 422 {object} map[string]string "No changes, invalid metadata, or confirm=true required"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/v1/users/bulk [patch]
+func (h *UserHandler) bulkUpdateUsers(w http.ResponseWriter, r *http.Request) {
+	var req BulkUpdateRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	filter := domain.BulkUpdateFilter{
+		Name:       req.Filter.Name,
+		NamePrefix: req.Filter.NamePrefix,
+		Verified:   req.Filter.Verified,
+	}
+	matched, modified, err := h.uc.BulkUpdateUsers(r.Context(), filter, domain.BulkUpdateChanges{Metadata: req.Changes.Metadata}, req.Confirm)
+	if err != nil {
+		if isValidationError(err) || err == usecase.ErrNoChanges || err == usecase.ErrSearchTermTooLong || err == usecase.ErrBulkConfirmRequired {
+			writeValidationError(w, r, err)
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to update users")
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	verified := "any"
+	if filter.Verified != nil {
+		verified = strconv.FormatBool(*filter.Verified)
+	}
+	h.logger.Warn("bulk update applied",
+		"user_id", userID,
+		"name", filter.Name,
+		"name_prefix", filter.NamePrefix,
+		"verified", verified,
+		"metadata_keys", len(req.Changes.Metadata),
+		"matched", matched,
+		"modified", modified,
+	)
+	writeResponse(w, r, http.StatusOK, map[string]int64{"matched": matched, "modified": modified})
+}
+
+// writeMissingUser responde quando o usuário não está disponível e diz se respondeu:
+// - ErrNotFound → 404 USER_NOT_FOUND (o ID nunca existiu, ou já passou pelo purge)
+// - ErrGone → 410 USER_GONE (o usuário existiu e foi removido)
+//
+// POR QUE 410?
+// - Com soft delete, a API sabe a diferença; um 404 para os dois casos esconderia isso
+// - Um cliente que sincroniza dados pode apagar a sua cópia local com segurança ao ver 410
+func writeMissingUser(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch err {
+	case usecase.ErrNotFound:
+		writeErrorCode(w, r, http.StatusNotFound, CodeUserNotFound, "User not found")
+	case usecase.ErrGone:
+		writeErrorCode(w, r, http.StatusGone, CodeUserGone, "User was deleted")
+	default:
+		return false
+	}
+	return true
+}
+
+// checkIfMatch implementa a pré-condição If-Match em PUT e DELETE
+// Sem o header, a operação segue normalmente
+// Com o header, buscamos o estado atual e comparamos os ETags:
+// se o usuário mudou desde a leitura do cliente, responde 412 e retorna false
+func (h *UserHandler) checkIfMatch(w http.ResponseWriter, r *http.Request, id string) bool {
+	match := r.Header.Get("If-Match")
+	if match == "" {
+		return true
+	}
+
+	current, err := h.uc.GetUser(r.Context(), id)
+	if err != nil {
+		if writeMissingUser(w, r, err) {
+			return false
+		}
+		h.writeServerError(w, r, err, "Failed to get user")
+		return false
+	}
+
+	if !etagMatches(match, computeETag(current)) {
+		writeError(w, r, http.StatusPreconditionFailed, "User was modified since it was last read")
+		return false
+	}
+	return true
+}
+
+// decodeJSON lê o corpo da requisição para dst, limitando seu tamanho
+// Retorna false (e já escreve a resposta 400, ou 413 para corpo grande demais) quando o corpo é inválido
+//
+// SOBRE json.NewDecoder(r.Body).Decode(dst):
+// - r.Body é um io.Reader com os bytes do JSON enviado
+// - json.NewDecoder cria um decodificador que lê desse Reader
+// - .Decode(dst) converte o JSON para a struct apontada por dst
+//
+// SOBRE http.MaxBytesReader:
+// - "Envolve" o r.Body e para de ler após maxBodyBytes
+// - Sem isso, um cliente malicioso poderia enviar um corpo enorme e esgotar a memória
+// - Quando o limite é ultrapassado, a leitura retorna *http.MaxBytesError
+func (h *UserHandler) decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBodyBytes)
+
+	// DisallowUnknownFields faz o Decode falhar quando o JSON tem campos que
+	// não existem na struct (ex: {"naem": "x"} por erro de digitação)
+	// Sem isso, o campo seria ignorado em silêncio e o nome ficaria vazio
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		// Corpo acima do limite (deste MaxBytesReader ou do global, ver body_limit_middleware.go) → 413
+		if writeBodyTooLarge(w, r, err) {
+			return false
+		}
+
+		// O pacote encoding/json não exporta um tipo para campo desconhecido,
+		// então identificamos pela mensagem: json: unknown field "naem"
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			writeErrorCode(w, r, http.StatusBadRequest, CodeUnknownField, fmt.Sprintf("Unknown field %s in request body", field))
+			return false
+		}
+
+		// JSON válido, mas com o tipo errado em algum campo: {"name": 123}
+		// errors.As verifica se o erro (ou algum erro embrulhado) é do tipo informado
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			writeErrorCode(w, r, http.StatusBadRequest, CodeInvalidFieldType, typeErrorMessage(typeErr))
+			return false
+		}
+
+		// Qualquer outro erro (sintaxe, corpo vazio, JSON cortado) cai na mensagem genérica
+		writeErrorCode(w, r, http.StatusBadRequest, CodeInvalidJSON, "Invalid JSON in request body")
+		return false
+	}
+	return true
+}
+
+// typeErrorMessage descreve um erro de tipo do JSON com o campo e os tipos esperado e recebido
+//
+//	{"name": 123}             → name must be a string, got number
+//	{"metadata": {"plan": 1}} → metadata.plan must be a string, got number
+//	{"version": 1.5}          → version must be an integer, got number
+//	[...] no lugar do objeto  → request body must be an object, got array
+//
+// Field já vem com o caminho pelos nomes do JSON (não os da struct Go)
+func typeErrorMessage(err *json.UnmarshalTypeError) string {
+	field := err.Field
+	if field == "" {
+		field = "request body"
+	}
+
+	// Value diz o que chegou: "string", "number", "bool", "array", "object"
+	// (números que não cabem no tipo vêm como "number 1.5")
+	got, _, _ := strings.Cut(err.Value, " ")
+	if got == "bool" {
+		got = "boolean"
+	}
+
+	want := jsonTypeName(err.Type)
+	article := "a"
+	if strings.ContainsAny(want[:1], "aeiou") {
+		article = "an"
+	}
+	return fmt.Sprintf("%s must be %s %s, got %s", field, article, want, got)
+}
+
+// jsonTypeName é o nome, em termos de JSON, do tipo Go que o decoder esperava
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// isValidationError indica se o erro do usecase é uma falha de validação
+// Esses erros viram 400 Bad Request com a mensagem do próprio erro
+func isValidationError(err error) bool {
+	return err == usecase.ErrInvalidEmail ||
+		err == usecase.ErrNameTooLong ||
+		err == usecase.ErrNameRequired ||
+		err == usecase.ErrNameTooShort ||
+		err == usecase.ErrInvalidName ||
+		err == usecase.ErrEmailTooLong ||
+		err == usecase.ErrInvalidPhone ||
+		err == usecase.ErrTooManyMetadataKeys ||
+		err == usecase.ErrInvalidMetadataKey ||
+		err == usecase.ErrMetadataValueTooLong
+}
+
+// writeValidationError escreve uma regra do usecase violada por um corpo bem formado: 422 Unprocessable Entity
+//
+// 400 OU 422?
+// - 400 Bad Request: a requisição nem pôde ser lida (JSON malformado, campo desconhecido, tipo errado, ID malformado)
+// - 422 Unprocessable Entity: o JSON foi lido, mas os dados foram recusados (email inválido, nome vazio, email já usado)
+// - O cliente que recebe 400 tem um bug no código que monta a requisição; com 422, mostra o erro ao usuário
+//
+// O código (INVALID_EMAIL, VALIDATION_FAILED, EMAIL_TAKEN...) é o mesmo de antes: só o status mudou
+// Parâmetros de query inválidos (?limit=abc, ?email= em email-available) continuam 400
+func writeValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	writeUsecaseError(w, r, http.StatusUnprocessableEntity, err)
+}
+
+// writeServerError escreve um erro inesperado (falha no banco, por exemplo)
+// Se o prazo da requisição estourou (middleware de timeout), responde 503
+// Se o pool de conexões do MongoDB está esgotado (ErrDatabaseBusy), responde 503 com Retry-After
+// Se o documento passaria do limite de 16MB do MongoDB (ErrDocumentTooLarge), responde 413
+// Caso contrário responde 500 com a mensagem informada
+// O erro original é registrado em log - o cliente recebe apenas a mensagem genérica
+func (h *UserHandler) writeServerError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		h.logger.Warn("request timed out", "method", r.Method, "path", r.URL.Path, "error", err)
+		writeErrorCode(w, r, http.StatusServiceUnavailable, CodeTimeout, "Request timed out")
+		return
+	}
+	if errors.Is(err, usecase.ErrDatabaseBusy) {
+		h.logger.Warn("database busy", "method", r.Method, "path", r.URL.Path, "error", err)
+		w.Header().Set("Retry-After", "1")
+		writeErrorCode(w, r, http.StatusServiceUnavailable, CodeDatabaseBusy, "Database is busy, try again later")
+		return
+	}
+	if errors.Is(err, usecase.ErrDocumentTooLarge) {
+		h.logger.Warn("document too large", "method", r.Method, "path", r.URL.Path, "user_id", chi.URLParam(r, "id"), "error", err)
+		writeErrorCode(w, r, http.StatusRequestEntityTooLarge, CodeDocumentTooLarge, usecase.ErrDocumentTooLarge.Error())
+		return
+	}
+	h.logger.Error(msg, "method", r.Method, "path", r.URL.Path, "user_id", chi.URLParam(r, "id"), "error", err)
+	writeError(w, r, http.StatusInternalServerError, msg)
 }
@@ -0,0 +1,296 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"user-api/internal/domain"
+	"user-api/internal/usecase"
+)
+
+const testUserID = "65a1b2c3d4e5f6a7b8c9d0e1"
+
+// TestMissingUser confere o 404 (nunca existiu) e o 410 (removido) em leitura e escrita
+func TestMissingUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "get unknown", method: http.MethodGet, err: usecase.ErrNotFound, wantStatus: http.StatusNotFound, wantCode: CodeUserNotFound},
+		{name: "get deleted", method: http.MethodGet, err: usecase.ErrGone, wantStatus: http.StatusGone, wantCode: CodeUserGone},
+		{name: "put unknown", method: http.MethodPut, body: `{"name":"Ana"}`, err: usecase.ErrNotFound, wantStatus: http.StatusNotFound, wantCode: CodeUserNotFound},
+		{name: "put deleted", method: http.MethodPut, body: `{"name":"Ana"}`, err: usecase.ErrGone, wantStatus: http.StatusGone, wantCode: CodeUserGone},
+		{name: "delete unknown", method: http.MethodDelete, err: usecase.ErrNotFound, wantStatus: http.StatusNotFound, wantCode: CodeUserNotFound},
+		{name: "delete deleted", method: http.MethodDelete, err: usecase.ErrGone, wantStatus: http.StatusGone, wantCode: CodeUserGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &fakeUseCase{
+				getUser: func(context.Context, string) (*domain.User, error) { return nil, tt.err },
+				updateUser: func(context.Context, string, string, string, string, map[string]string, int) (*domain.User, domain.UserChanges, error) {
+					return nil, nil, tt.err
+				},
+				deleteUser: func(context.Context, string) error { return tt.err },
+			}
+			rec := httptest.NewRecorder()
+			newTestRouter(newTestHandler(uc, nil)).ServeHTTP(rec, newJSONRequest(tt.method, "/api/v1/users/"+testUserID, tt.body))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if code := decodeErrorCode(t, rec); code != tt.wantCode {
+				t.Errorf("code = %q, want %q", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCreateUserLocation(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		createErr    error
+		wantStatus   int
+		wantLocation string
+	}{
+		{name: "created", body: `{"name":"Ana","email":"ana@example.com"}`, wantStatus: http.StatusCreated, wantLocation: "/api/v1/users/" + testUserID},
+		{name: "email taken", body: `{"name":"Ana","email":"ana@example.com"}`, createErr: usecase.ErrEmailTaken, wantStatus: http.StatusUnprocessableEntity},
+		{name: "malformed body", body: `{"name":`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &fakeUseCase{
+				createUser: func(_ context.Context, name, email, _ string, _ map[string]string) (*domain.User, error) {
+					if tt.createErr != nil {
+						return nil, tt.createErr
+					}
+					return &domain.User{ID: testUserID, Name: name, Email: email, Version: 1}, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			newTestRouter(newTestHandler(uc, nil)).ServeHTTP(rec, newJSONRequest(http.MethodPost, "/api/v1/users", tt.body))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
+
+// TestUnknownFields confere que um campo desconhecido (erro de digitação) vira 400 com o nome do campo
+func TestUnknownFields(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantField  string
+	}{
+		{name: "create with typo", method: http.MethodPost, target: "/api/v1/users", body: `{"naem":"Ana","email":"ana@example.com"}`, wantStatus: http.StatusBadRequest, wantField: `"naem"`},
+		{name: "create valid", method: http.MethodPost, target: "/api/v1/users", body: `{"name":"Ana","email":"ana@example.com"}`, wantStatus: http.StatusCreated},
+		{name: "update with typo", method: http.MethodPut, target: "/api/v1/users/" + testUserID, body: `{"name":"Ana","phon":"+5511987654321"}`, wantStatus: http.StatusBadRequest, wantField: `"phon"`},
+		{name: "update valid", method: http.MethodPut, target: "/api/v1/users/" + testUserID, body: `{"name":"Ana"}`, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &domain.User{ID: testUserID, Name: "Ana", Email: "ana@example.com", Version: 2}
+			uc := &fakeUseCase{
+				createUser: func(context.Context, string, string, string, map[string]string) (*domain.User, error) {
+					return user, nil
+				},
+				updateUser: func(context.Context, string, string, string, string, map[string]string, int) (*domain.User, domain.UserChanges, error) {
+					return user, nil, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			newTestRouter(newTestHandler(uc, nil)).ServeHTTP(rec, newJSONRequest(tt.method, tt.target, tt.body))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantField == "" {
+				return
+			}
+			body := decodeErrorBody(t, rec)
+			if body["code"] != CodeUnknownField {
+				t.Errorf("code = %q, want %q", body["code"], CodeUnknownField)
+			}
+			if !strings.Contains(body["error"], tt.wantField) {
+				t.Errorf("error %q does not name the field %s", body["error"], tt.wantField)
+			}
+		})
+	}
+}
+
+func TestUpdateUserEmailChangeIsRejected(t *testing.T) {
+	uc := &fakeUseCase{
+		updateUser: func(context.Context, string, string, string, string, map[string]string, int) (*domain.User, domain.UserChanges, error) {
+			return nil, nil, usecase.ErrEmailChangeRequiresVerification
+		},
+	}
+	rec := httptest.NewRecorder()
+	newTestRouter(newTestHandler(uc, nil)).ServeHTTP(rec, newJSONRequest(http.MethodPut, "/api/v1/users/"+testUserID, `{"email":"other@example.com"}`))
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422 (%s)", rec.Code, rec.Body.String())
+	}
+	if code := decodeErrorCode(t, rec); code != CodeEmailChangeRequiresVerification {
+		t.Errorf("code = %q, want %q", code, CodeEmailChangeRequiresVerification)
+	}
+}
+
+// TestDocumentTooLarge confere que a recusa por tamanho do MongoDB vira 413, não 500
+func TestDocumentTooLarge(t *testing.T) {
+	tooLarge := fmt.Errorf("%w: object to insert too large", usecase.ErrDocumentTooLarge)
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "create too large", method: http.MethodPost, target: "/api/v1/users", err: tooLarge, wantStatus: http.StatusRequestEntityTooLarge, wantCode: CodeDocumentTooLarge},
+		{name: "update too large", method: http.MethodPut, target: "/api/v1/users/" + testUserID, err: tooLarge, wantStatus: http.StatusRequestEntityTooLarge, wantCode: CodeDocumentTooLarge},
+		{name: "other database error", method: http.MethodPut, target: "/api/v1/users/" + testUserID, err: errors.New("connection reset"), wantStatus: http.StatusInternalServerError, wantCode: CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &fakeUseCase{
+				createUser: func(context.Context, string, string, string, map[string]string) (*domain.User, error) {
+					return nil, tt.err
+				},
+				updateUser: func(context.Context, string, string, string, string, map[string]string, int) (*domain.User, domain.UserChanges, error) {
+					return nil, nil, tt.err
+				},
+			}
+			rec := httptest.NewRecorder()
+			newTestRouter(newTestHandler(uc, nil)).ServeHTTP(rec, newJSONRequest(tt.method, tt.target, `{"name":"Ana","email":"ana@example.com"}`))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if code := decodeErrorCode(t, rec); code != tt.wantCode {
+				t.Errorf("code = %q, want %q", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+// TestUpdateUserWithoutChanges confere que um PUT sem mudanças ainda responde 200 com o usuário atual
+func TestUpdateUserWithoutChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{name: "plain", target: "/api/v1/users/" + testUserID},
+		{name: "with diff", target: "/api/v1/users/" + testUserID + "?diff=true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := &domain.User{ID: testUserID, Name: "Ana", Email: "ana@example.com", Version: 1}
+			uc := &fakeUseCase{
+				updateUser: func(context.Context, string, string, string, string, map[string]string, int) (*domain.User, domain.UserChanges, error) {
+					return current, domain.UserChanges{}, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			newTestRouter(newTestHandler(uc, nil)).ServeHTTP(rec, newJSONRequest(http.MethodPut, tt.target, `{"name":"Ana","version":1}`))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (%s)", rec.Code, rec.Body.String())
+			}
+			if got, want := rec.Header().Get("ETag"), computeETag(current); got != want {
+				t.Errorf("ETag = %q, want %q (the current record)", got, want)
+			}
+			var body struct {
+				ID      string         `json:"id"`
+				Version int            `json:"version"`
+				Changed map[string]any `json:"changed"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body.ID != testUserID || body.Version != 1 {
+				t.Errorf("body id = %q, version = %d; want the current user at version 1", body.ID, body.Version)
+			}
+			if len(body.Changed) != 0 {
+				t.Errorf("changed = %v, want empty", body.Changed)
+			}
+		})
+	}
+}
+
+func TestMergeUsers(t *testing.T) {
+	const secondaryID = "65a1b2c3d4e5f6a7b8c9d0e2"
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "merged", body: `{"secondary_id":"` + secondaryID + `","copy_metadata":true}`, wantStatus: http.StatusOK},
+		{name: "unknown user", body: `{"secondary_id":"` + secondaryID + `"}`, err: usecase.ErrNotFound, wantStatus: http.StatusNotFound, wantCode: CodeUserNotFound},
+		{name: "deleted user", body: `{"secondary_id":"` + secondaryID + `"}`, err: usecase.ErrGone, wantStatus: http.StatusGone, wantCode: CodeUserGone},
+		{name: "merge into itself", body: `{"secondary_id":"` + testUserID + `"}`, err: usecase.ErrMergeSameUser, wantStatus: http.StatusUnprocessableEntity, wantCode: CodeMergeSameUser},
+		{name: "invalid secondary_id", body: `{"secondary_id":"not-an-id"}`, wantStatus: http.StatusBadRequest, wantCode: CodeInvalidID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPrimary, gotSecondary string
+			var gotCopy bool
+			uc := &fakeUseCase{
+				mergeUsers: func(_ context.Context, primaryID, secondaryID string, copyMetadata bool) (*domain.User, error) {
+					gotPrimary, gotSecondary, gotCopy = primaryID, secondaryID, copyMetadata
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &domain.User{ID: primaryID, Name: "Ana", Email: "ana@example.com", Version: 2}, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			newTestRouter(newTestHandler(uc, nil)).ServeHTTP(rec, newJSONRequest(http.MethodPost, "/api/v1/users/"+testUserID+"/merge", tt.body))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode != "" {
+				if code := decodeErrorCode(t, rec); code != tt.wantCode {
+					t.Errorf("code = %q, want %q", code, tt.wantCode)
+				}
+				return
+			}
+			if gotPrimary != testUserID || gotSecondary != secondaryID || !gotCopy {
+				t.Errorf("MergeUsers(%q, %q, %v), want (%q, %q, true)", gotPrimary, gotSecondary, gotCopy, testUserID, secondaryID)
+			}
+			var body struct {
+				ID      string `json:"id"`
+				Version int    `json:"version"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body.ID != testUserID || body.Version != 2 {
+				t.Errorf("body id = %q, version = %d; want the merged primary", body.ID, body.Version)
+			}
+			if rec.Header().Get("ETag") == "" {
+				t.Error("missing ETag")
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"user-api/internal/domain"
+	"user-api/internal/usecase"
+)
+
+// UserStreamPath é o caminho do stream de alterações
+// Fica fora do REQUEST_TIMEOUT e do MAX_CONCURRENT_REQUESTS: a conexão dura enquanto o cliente quiser
+const UserStreamPath = "/api/v1/users/stream"
+
+// streamHeartbeatInterval é o intervalo dos comentários ": ping" enviados sem alterações
+// Proxies e load balancers costumam fechar conexões paradas há 30-60s
+const streamHeartbeatInterval = 15 * time.Second
+
+// userChangeEvent é o "data" de cada evento SSE
+type userChangeEvent struct {
+	Type   domain.EventType `json:"type"`
+	UserID string           `json:"user_id"`
+	User   *domain.User     `json:"user"`
+}
+
+// ============================================
+// STREAM DE ALTERAÇÕES (SERVER-SENT EVENTS)
+// ============================================
+// streamUsers trata requisições GET /api/v1/users/stream
+// Cada criação, atualização e remoção de usuário vira um evento SSE, assim que acontece:
+//
+//	event: user.updated
+//	data: {"type":"user.updated","user_id":"507f...","user":{"id":"507f...","name":"Maria",...}}
+//
+// De onde vêm as alterações: um change stream do MongoDB (ver repository.Watch)
+// - Vê as escritas de TODAS as instâncias da API (e de scripts), não só as desta
+// - Exige replica set ou cluster shardeado: em um standalone, 501 CHANGE_STREAMS_UNSUPPORTED
+//
+// SOBRE A CONEXÃO:
+// - Sem alterações, um comentário ": ping" a cada 15s mantém a conexão viva em proxies
+// - Quando o cliente desconecta, r.Context() é cancelado: a goroutine de leitura termina e só então o change stream é fechado
+// - O prazo de escrita do servidor (WRITE_TIMEOUT) é removido só para esta conexão
+// - Um cliente que reconecta (EventSource faz isso sozinho) recebe as alterações a partir da reconexão
+//
+// POR QUE UM LIMITE DE CONEXÕES?
+// - Cada cliente conectado segura um change stream (um cursor no MongoDB) e uma goroutine
+// - Acima de USER_STREAM_MAX_CLIENTS, a resposta é 503 TOO_MANY_STREAMS com Retry-After
+//
+// @Summary Stream user changes (Server-Sent Events)
+// @Description Pushes user.created, user.updated and user.deleted events as they happen, backed by a MongoDB change stream. Requires a replica set (501 otherwise). Concurrent connections are limited by USER_STREAM_MAX_CLIENTS
+// @Tags users
+// @Produce text/event-stream
+// @Success 200 {string} string "Event stream"
+// @Failure 401 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/v1/users/stream [get]
+func (h *UserHandler) streamUsers(w http.ResponseWriter, r *http.Request) {
+	// Semáforo sem espera: com todas as vagas ocupadas, recusa na hora
+	select {
+	case h.streams <- struct{}{}:
+		defer func() { <-h.streams }()
+	default:
+		w.Header().Set("Retry-After", "5")
+		writeErrorCode(w, r, http.StatusServiceUnavailable, CodeTooManyStreams, "Too many open change streams, try again later")
+		return
+	}
+
+	// cancel também encerra a goroutine de leitura quando o handler retorna por outro motivo (ex: Flush falhou)
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	stream, err := h.uc.WatchUsers(ctx)
+	if err != nil {
+		if errors.Is(err, usecase.ErrChangeStreamsUnsupported) {
+			writeUsecaseError(w, r, http.StatusNotImplemented, err)
+			return
+		}
+		h.writeServerError(w, r, err, "Failed to open change stream")
+		return
+	}
+	defer func() {
+		// ctx já pode estar cancelado: o Close usa um prazo próprio para avisar o servidor
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		stream.Close(closeCtx)
+	}()
+
+	clearWriteDeadline(w, h.logger)
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// nginx guarda a resposta em buffer por padrão: os eventos só chegariam no fim
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	if err := rc.Flush(); err != nil {
+		h.logger.Error("streaming not supported", "error", err)
+		return
+	}
+
+	// Next bloqueia até a próxima alteração: roda em outra goroutine para o loop poder mandar os pings
+	// O channel é fechado quando o stream acaba; só então stream.Err() é lido
+	changes := make(chan domain.UserChange)
+	go func() {
+		defer close(changes)
+		for stream.Next(ctx) {
+			select {
+			case changes <- stream.Change():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// ANTES DO Close (os defers rodam na ordem inversa): cancela e espera a goroutine sair do Next
+	// O change stream não é seguro para uso concorrente: um Close durante o Next seria uma data race
+	// O channel só é fechado quando a goroutine termina; ler até o fim é esperar por ela
+	defer func() {
+		cancel()
+		for range changes {
+		}
+	}()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case change, ok := <-changes:
+			if !ok {
+				if err := stream.Err(); err != nil && ctx.Err() == nil {
+					h.logger.Error("change stream failed", "error", err)
+				}
+				return
+			}
+			data, err := json.Marshal(userChangeEvent{Type: change.Type, UserID: change.User.ID, User: change.User})
+			if err != nil {
+				h.logger.Error("failed to encode change event", "error", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", change.Type, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
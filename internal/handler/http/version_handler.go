@@ -0,0 +1,30 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"user-api/internal/build"
+)
+
+// RegisterVersion registra a rota que informa qual build está rodando
+// Útil para confirmar um deploy sem precisar entrar no container
+func RegisterVersion(r chi.Router) {
+	r.Get("/version", version)
+}
+
+// version retorna os metadados injetados na compilação (pacote build)
+//
+// @Summary Build version
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /version [get]
+func version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, map[string]string{
+		"version": build.Version,
+		"commit":  build.Commit,
+		"built":   build.Time,
+	})
+}
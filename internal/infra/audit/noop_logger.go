@@ -0,0 +1,26 @@
+package audit
+
+import (
+	"context"
+
+	"user-api/internal/domain"
+)
+
+// NoopLogger implementa domain.AuditLogger sem gravar nada
+// É o padrão quando o audit log está desabilitado (AUDIT_ENABLED=false)
+type NoopLogger struct{}
+
+// NewNoopLogger cria um audit logger que descarta todas as entradas
+func NewNoopLogger() domain.AuditLogger {
+	return NoopLogger{}
+}
+
+// Record descarta a entrada e nunca falha
+func (NoopLogger) Record(ctx context.Context, entry *domain.AuditEntry) error {
+	return nil
+}
+
+// ListByUser sempre retorna um histórico vazio
+func (NoopLogger) ListByUser(ctx context.Context, userID string, limit int) ([]*domain.AuditEntry, error) {
+	return []*domain.AuditEntry{}, nil
+}
@@ -0,0 +1,21 @@
+package event
+
+import (
+	"context"
+
+	"user-api/internal/domain"
+)
+
+// NoopPublisher implementa domain.EventPublisher sem fazer nada
+// É o padrão quando nenhum barramento de eventos está configurado
+type NoopPublisher struct{}
+
+// NewNoopPublisher cria um publisher que descarta todos os eventos
+func NewNoopPublisher() domain.EventPublisher {
+	return NoopPublisher{}
+}
+
+// Publish descarta o evento e nunca falha
+func (NoopPublisher) Publish(ctx context.Context, event domain.Event) error {
+	return nil
+}
@@ -0,0 +1,125 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"user-api/internal/domain"
+)
+
+// SignatureHeader é o header com a assinatura HMAC-SHA256 do corpo
+// Formato: "sha256=<hex>" - o receptor recalcula com o mesmo secret e compara
+const SignatureHeader = "X-Webhook-Signature"
+
+// ============================================
+// PUBLISHER DE WEBHOOK
+// ============================================
+// WebhookPublisher envia cada evento como um POST JSON para uma URL configurada
+//
+// GARANTIAS:
+// - Assinatura HMAC: o receptor verifica que o evento veio desta API
+// - Timeout por tentativa: um receptor lento não segura a goroutine para sempre
+// - Retry com backoff em erros de rede e respostas 5xx
+// - Assíncrono: Publish retorna na hora; a requisição HTTP nunca espera pelo webhook
+type WebhookPublisher struct {
+	url        string
+	secret     []byte
+	client     *http.Client
+	maxRetries int
+	baseDelay  time.Duration
+	logger     *slog.Logger
+}
+
+// NewWebhookPublisher cria um publisher que faz POST em url
+// timeout vale para CADA tentativa; maxRetries é o número de novas tentativas
+// depois da primeira falha
+func NewWebhookPublisher(url, secret string, timeout time.Duration, maxRetries int, logger *slog.Logger) *WebhookPublisher {
+	return &WebhookPublisher{
+		url:        url,
+		secret:     []byte(secret),
+		client:     &http.Client{Timeout: timeout},
+		maxRetries: maxRetries,
+		baseDelay:  500 * time.Millisecond,
+		logger:     logger.With("component", "webhook"),
+	}
+}
+
+// Publish serializa o evento e dispara o envio em uma goroutine
+// Só retorna erro se o evento não puder ser serializado
+func (p *WebhookPublisher) Publish(ctx context.Context, event domain.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal webhook payload: %w", err)
+	}
+
+	// A goroutine NÃO usa o ctx da requisição: ele é cancelado assim que
+	// a resposta HTTP é enviada, o que abortaria a entrega do webhook
+	go p.deliver(event, body)
+	return nil
+}
+
+// deliver tenta entregar o corpo, repetindo em falhas transitórias
+func (p *WebhookPublisher) deliver(event domain.Event, body []byte) {
+	delay := p.baseDelay
+	logger := p.logger.With("event", event.Type, "user_id", event.UserID)
+
+	for attempt := 0; attempt <= p.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		retryable, err := p.send(body)
+		if err == nil {
+			return
+		}
+		if !retryable {
+			logger.Error("webhook delivery rejected", "error", err)
+			return
+		}
+		logger.Warn("webhook delivery failed", "attempt", attempt+1, "max_attempts", p.maxRetries+1, "error", err)
+	}
+
+	logger.Error("giving up webhook delivery")
+}
+
+// send faz um único POST e indica se vale a pena tentar de novo
+// - erro de rede/timeout ou status 5xx → retryable
+// - status 4xx → o receptor rejeitou o evento; repetir não adianta
+func (p *WebhookPublisher) send(body []byte) (retryable bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, "sha256="+p.sign(body))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return true, fmt.Errorf("receiver responded %d", resp.StatusCode)
+	case resp.StatusCode >= 400:
+		return false, fmt.Errorf("receiver responded %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// sign calcula o HMAC-SHA256 do corpo usando o secret compartilhado
+func (p *WebhookPublisher) sign(body []byte) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
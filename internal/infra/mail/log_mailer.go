@@ -0,0 +1,28 @@
+package mail
+
+import (
+	"context"
+	"log/slog"
+
+	"user-api/internal/domain"
+)
+
+// LogMailer implementa domain.Mailer escrevendo o email no log em vez de enviá-lo
+// É o padrão enquanto não há um provedor de email configurado
+//
+// ATENÇÃO: o token aparece no log - use apenas em desenvolvimento
+// Em produção, troque por uma implementação que envie o email de verdade
+type LogMailer struct {
+	logger *slog.Logger
+}
+
+// NewLogMailer cria um mailer que apenas registra os emails no log
+func NewLogMailer(logger *slog.Logger) domain.Mailer {
+	return &LogMailer{logger: logger.With("component", "mailer")}
+}
+
+// SendVerification registra o email de verificação no log e nunca falha
+func (m *LogMailer) SendVerification(ctx context.Context, to, token string) error {
+	m.logger.InfoContext(ctx, "verification email", "to", to, "token", token)
+	return nil
+}
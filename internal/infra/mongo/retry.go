@@ -0,0 +1,80 @@
+package mongo
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxRetryDelay limita o tempo de espera entre tentativas
+// Sem limite, o backoff exponencial cresceria rápido demais (1s, 2s, 4s, ..., 512s)
+const maxRetryDelay = 30 * time.Second
+
+// ============================================
+// CONEXÃO COM RETRY
+// ============================================
+// ConnectWithRetry tenta conectar ao MongoDB várias vezes antes de desistir
+//
+// POR QUE ISSO É NECESSÁRIO?
+// - Em containers (docker compose, Kubernetes) a API pode subir ANTES do MongoDB
+// - depends_on garante ordem, mas não que o banco já aceita conexões
+// - Em vez de derrubar a aplicação na primeira falha, esperamos e tentamos de novo
+//
+// BACKOFF EXPONENCIAL COM JITTER:
+// - A espera dobra a cada tentativa: baseDelay, 2*baseDelay, 4*baseDelay...
+// - Jitter adiciona um valor aleatório para evitar que réplicas reconectem juntas
+//
+// URI MAL FORMADA NÃO É RETENTADA:
+// - ValidateURI roda antes da primeira tentativa; o erro volta na hora
+// - No log, a URI aparece sempre redigida (RedactURI): a senha nunca é registrada
+func ConnectWithRetry(uri string, opts ClientOptions, maxAttempts int, baseDelay time.Duration, logger *slog.Logger) (*mongo.Client, error) {
+	if err := ValidateURI(uri); err != nil {
+		return nil, err
+	}
+	logger.Info("connecting to MongoDB", "uri", RedactURI(uri))
+
+	var lastErr error
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		client, err := NewClient(uri, opts)
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("connected to MongoDB", "attempt", attempt, "max_attempts", maxAttempts)
+			}
+			return client, nil
+		}
+		lastErr = err
+
+		// Na última tentativa não faz sentido esperar
+		if attempt == maxAttempts {
+			break
+		}
+
+		delay := backoffDelay(attempt, baseDelay)
+		logger.Warn("MongoDB connection attempt failed",
+			"attempt", attempt,
+			"max_attempts", maxAttempts,
+			"retry_in", delay.String(),
+			"error", err,
+		)
+		time.Sleep(delay)
+	}
+
+	return nil, fmt.Errorf("giving up after %d attempts: %w", maxAttempts, lastErr)
+}
+
+// backoffDelay calcula a espera antes da próxima tentativa
+// attempt=1 → ~baseDelay, attempt=2 → ~2*baseDelay, attempt=3 → ~4*baseDelay...
+// O resultado fica entre 50% e 100% do valor exponencial (jitter)
+func backoffDelay(attempt int, baseDelay time.Duration) time.Duration {
+	delay := baseDelay << (attempt - 1)
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
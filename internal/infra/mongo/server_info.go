@@ -0,0 +1,102 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Topologias possíveis em ServerInfo.Topology
+const (
+	TopologyStandalone = "standalone"
+	TopologyReplicaSet = "replica_set"
+	TopologySharded    = "sharded"
+)
+
+// unauthorizedCode é o código do MongoDB para "usuário sem permissão para o comando"
+const unauthorizedCode = 13
+
+// ServerInfo descreve o servidor MongoDB conectado, para diagnóstico (GET /api/v1/admin/mongo)
+type ServerInfo struct {
+	Version    string   `json:"version,omitempty"`     // Versão do servidor (buildInfo); vazia se o comando foi negado
+	Topology   string   `json:"topology"`              // standalone, replica_set ou sharded
+	ReplicaSet string   `json:"replica_set,omitempty"` // Nome do replica set (setName)
+	Primary    string   `json:"primary,omitempty"`     // host:porta do primário atual
+	Me         string   `json:"me,omitempty"`          // host:porta do membro que respondeu
+	Writable   bool     `json:"is_writable_primary"`   // O membro que respondeu é o primário
+	Hosts      []string `json:"hosts,omitempty"`       // Membros do replica set (exceto árbitros e ocultos)
+
+	// Transações e change streams exigem replica set ou cluster shardeado
+	TransactionsSupported  bool `json:"transactions_supported"`
+	ChangeStreamsSupported bool `json:"change_streams_supported"`
+
+	// Warnings lista o que não pôde ser consultado (ex: buildInfo sem permissão)
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ============================================
+// INFORMAÇÕES DO SERVIDOR
+// ============================================
+// GetServerInfo consulta a versão e a topologia do servidor com dois comandos leves
+//
+// POR QUE hello E buildInfo (E NÃO serverStatus)?
+// - hello é o comando que o próprio driver usa para descobrir a topologia: sempre permitido, até sem autenticação
+// - buildInfo traz a versão e também costuma ser permitido a qualquer usuário autenticado
+// - serverStatus exige o papel clusterMonitor: em um Atlas ou banco com usuário restrito, seria negado
+//
+// DEGRADAÇÃO:
+// - Falha no hello → erro (sem ele não há o que mostrar)
+// - Falha no buildInfo (ex: Unauthorized) → Version vazia e um aviso em Warnings; o resto continua valendo
+//
+// O prazo vem de ctx: quem chama deve usar um timeout curto
+func GetServerInfo(ctx context.Context, client *mongo.Client) (*ServerInfo, error) {
+	admin := client.Database("admin")
+
+	var hello struct {
+		SetName  string   `bson:"setName"`
+		Msg      string   `bson:"msg"`
+		Primary  string   `bson:"primary"`
+		Me       string   `bson:"me"`
+		Writable bool     `bson:"isWritablePrimary"`
+		Hosts    []string `bson:"hosts"`
+	}
+	if err := admin.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return nil, fmt.Errorf("hello: %w", err)
+	}
+
+	info := &ServerInfo{
+		Topology:   TopologyStandalone,
+		ReplicaSet: hello.SetName,
+		Primary:    hello.Primary,
+		Me:         hello.Me,
+		Writable:   hello.Writable,
+		Hosts:      hello.Hosts,
+	}
+	// Mesmas regras do repositório (isReplicated): setName → replica set, "isdbgrid" → mongos
+	switch {
+	case hello.SetName != "":
+		info.Topology = TopologyReplicaSet
+	case hello.Msg == "isdbgrid":
+		info.Topology = TopologySharded
+	}
+	info.TransactionsSupported = info.Topology != TopologyStandalone
+	info.ChangeStreamsSupported = info.TransactionsSupported
+
+	var build struct {
+		Version string `bson:"version"`
+	}
+	if err := admin.RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&build); err != nil {
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Code == unauthorizedCode {
+			info.Warnings = append(info.Warnings, "buildInfo not authorized: server version unavailable")
+		} else {
+			info.Warnings = append(info.Warnings, "buildInfo failed: "+err.Error())
+		}
+	}
+	info.Version = build.Version
+
+	return info, nil
+}
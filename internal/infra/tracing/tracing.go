@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"user-api/internal/build"
+)
+
+// ============================================
+// TRACING DISTRIBUÍDO (OPENTELEMETRY)
+// ============================================
+// Um trace mostra o caminho completo de uma requisição, dividido em spans:
+//
+//	HTTP GET /api/v1/users/{id}   ████████████████  12ms
+//	  mongo users.find              ██████████      8ms
+//
+// Cada span tem início, duração e atributos (rota, status, collection...)
+// Os spans são enviados via OTLP para um coletor (Jaeger, Tempo, Honeycomb...)
+//
+// COMO O TRACE ATRAVESSA AS CAMADAS?
+// - O span atual viaja dentro do context.Context
+// - Como handler → usecase → repository já repassam o ctx, nada muda nas assinaturas
+//
+// CUSTO ZERO QUANDO DESABILITADO:
+// - Sem endpoint configurado, o TracerProvider global do OpenTelemetry é no-op
+// - Spans no-op não alocam nem registram nada
+
+// ShutdownFunc envia os spans pendentes e libera o exporter
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup configura o tracing global
+// endpoint vazio = tracing desabilitado (retorna um ShutdownFunc que não faz nada)
+//
+// O exporter lê as variáveis padrão do OpenTelemetry (OTEL_EXPORTER_OTLP_ENDPOINT,
+// OTEL_EXPORTER_OTLP_HEADERS...), então não repassamos o endpoint manualmente
+func Setup(ctx context.Context, endpoint, serviceName string) (ShutdownFunc, error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+
+	// resource descreve QUEM gerou os spans (nome e versão do serviço)
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(build.Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("create tracing resource: %w", err)
+	}
+
+	// WithBatcher agrupa os spans e envia em lotes, fora do caminho da requisição
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(provider)
+	// TraceContext lê/escreve o header traceparent (W3C): um serviço que chama
+	// esta API com traceparent vê os nossos spans dentro do trace dele
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,75 @@
+package job
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// JOB DE PURGE
+// ============================================
+// PurgeJob apaga periodicamente os usuários removidos (soft delete)
+// há mais tempo que o período de retenção
+//
+// POR QUE UM PERÍODO DE RETENÇÃO?
+// - Um DELETE por engano ainda pode ser revertido durante a retenção
+// - Depois dela o dado some de verdade (exigência de LGPD/GDPR)
+//
+// COMO ELE PARA?
+// - Run fica em loop até o context ser cancelado
+// - No encerramento da aplicação (SIGTERM), main cancela o context e o loop termina
+type PurgeJob struct {
+	uc        domain.UserUseCase
+	interval  time.Duration // Intervalo entre execuções
+	retention time.Duration // Idade mínima da remoção para o purge
+	logger    *slog.Logger
+}
+
+// NewPurgeJob cria o job; ele só começa a rodar quando Run é chamado
+func NewPurgeJob(uc domain.UserUseCase, interval, retention time.Duration, logger *slog.Logger) *PurgeJob {
+	return &PurgeJob{
+		uc:        uc,
+		interval:  interval,
+		retention: retention,
+		logger:    logger.With("component", "purge_job"),
+	}
+}
+
+// Run executa o purge a cada interval até ctx ser cancelado
+// Deve ser chamado em uma goroutine: go job.Run(ctx)
+func (j *PurgeJob) Run(ctx context.Context) {
+	j.logger.Info("purge job started", "interval", j.interval.String(), "retention", j.retention.String())
+
+	// time.Ticker envia um valor no canal C a cada interval
+	// Stop libera o ticker quando o loop termina
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		// select espera o que acontecer PRIMEIRO:
+		// o cancelamento do context ou o próximo "tique" do ticker
+		select {
+		case <-ctx.Done():
+			j.logger.Info("purge job stopped")
+			return
+		case <-ticker.C:
+			j.runOnce(ctx)
+		}
+	}
+}
+
+// runOnce executa um único purge e registra quantos usuários foram apagados
+// Falhas só são registradas: o job tenta de novo na próxima execução
+func (j *PurgeJob) runOnce(ctx context.Context) {
+	purged, err := j.uc.PurgeDeletedUsers(ctx, j.retention)
+	if err != nil {
+		if ctx.Err() == nil {
+			j.logger.Error("purge failed", "error", err)
+		}
+		return
+	}
+	j.logger.Info("purge finished", "purged", purged)
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// ============================================
+// LOGGER ESTRUTURADO (slog)
+// ============================================
+// Logs estruturados têm campos nomeados em vez de texto livre:
+//
+//	{"time":"...","level":"ERROR","msg":"failed to update user","component":"usecase","user_id":"507f...","error":"..."}
+//
+// Ferramentas de agregação (Loki, Elasticsearch, CloudWatch) conseguem
+// filtrar por campo (ex: todos os erros do user_id X) sem usar regex
+//
+// FORMATOS:
+// - "json": uma linha JSON por log (produção, agregadores)
+// - "text": chave=valor legível (desenvolvimento local)
+
+// New cria um logger com o nível e o formato informados
+// level: "debug", "info", "warn" ou "error"
+// format: "json" ou "text"
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch strings.ToLower(format) {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q (use json or text)", format)
+	}
+}
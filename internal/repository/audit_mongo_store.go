@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// AUDIT LOG (MONGODB)
+// ============================================
+// Guarda cada alteração de usuário em uma collection própria (padrão "audit")
+//
+// SEM TTL:
+// - Diferente das chaves de idempotência e dos tokens, o histórico não expira sozinho
+// - Por quanto tempo guardar é decisão de compliance; limpe com um job próprio se precisar
+// - O purge de usuários removidos também não apaga o histórico deles
+type auditDoc struct {
+	ID         primitive.ObjectID        `bson:"_id,omitempty"`
+	UserID     string                    `bson:"userId"`
+	Tenant     string                    `bson:"tenant,omitempty"` // Com multi-tenancy, o tenant da alteração
+	Operation  string                    `bson:"operation"`
+	Actor      string                    `bson:"actor,omitempty"`
+	Changes    map[string]auditChangeDoc `bson:"changes,omitempty"`
+	OccurredAt time.Time                 `bson:"occurredAt"`
+}
+
+// auditChangeDoc é o valor antigo e o novo de um campo
+// Os valores são gravados como vieram (string, bool, lista de emails, metadata)
+type auditChangeDoc struct {
+	Old any `bson:"old"`
+	New any `bson:"new"`
+}
+
+// AuditMongoStore implementa domain.AuditLogger usando MongoDB
+type AuditMongoStore struct {
+	collection *mongo.Collection
+	opTimeout  time.Duration // Prazo de cada operação (mesmo MONGO_OP_TIMEOUT do repositório)
+}
+
+// NewAuditMongoStore cria o store e garante o índice usado na consulta por usuário
+// opTimeout é o prazo de cada operação (zero usa DefaultOpTimeout)
+//
+// SOBRE DefaultDocumentM:
+// - Old e New são "any": ao ler, o driver precisa escolher um tipo Go para subdocumentos
+// - O padrão é primitive.D (lista de pares chave/valor), que vira um JSON estranho
+// - Com DefaultDocumentM, subdocumentos viram bson.M (map) e o JSON fica igual ao gravado
+func NewAuditMongoStore(ctx context.Context, db *mongo.Database, collectionName string, opTimeout time.Duration) (*AuditMongoStore, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	collection := db.Collection(collectionName,
+		options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true}))
+
+	// {userId: 1, _id: -1}: filtra pelo usuário e já entrega da entrada mais nova para a mais antiga
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "_id", Value: -1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opTimeout <= 0 {
+		opTimeout = DefaultOpTimeout
+	}
+	return &AuditMongoStore{collection: collection, opTimeout: opTimeout}, nil
+}
+
+// Record grava a entrada e preenche entry.ID
+func (s *AuditMongoStore) Record(ctx context.Context, entry *domain.AuditEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, s.opTimeout)
+	defer cancel()
+
+	tenant, _ := domain.TenantFromContext(ctx)
+	doc := auditDoc{
+		ID:         primitive.NewObjectID(),
+		UserID:     entry.UserID,
+		Tenant:     tenant,
+		Operation:  string(entry.Operation),
+		Actor:      entry.Actor,
+		OccurredAt: entry.OccurredAt,
+	}
+	if len(entry.Changes) > 0 {
+		doc.Changes = make(map[string]auditChangeDoc, len(entry.Changes))
+		for field, change := range entry.Changes {
+			doc.Changes[field] = auditChangeDoc{Old: change.Old, New: change.New}
+		}
+	}
+
+	if _, err := s.collection.InsertOne(ctx, doc); err != nil {
+		return err
+	}
+	entry.ID = doc.ID.Hex()
+	return nil
+}
+
+// ListByUser retorna as limit entradas mais recentes do usuário
+// ObjectIDs crescem com o tempo: ordenar por _id decrescente é ordenar da mais nova para a mais antiga
+// Só entram as entradas do tenant da requisição ({"tenant": nil} casa com as gravadas sem tenant)
+func (s *AuditMongoStore) ListByUser(ctx context.Context, userID string, limit int) ([]*domain.AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opTimeout)
+	defer cancel()
+
+	query := bson.M{"userId": userID, "tenant": nil}
+	if tenant, ok := domain.TenantFromContext(ctx); ok {
+		query["tenant"] = tenant
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := s.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []*domain.AuditEntry{}
+	for cursor.Next(ctx) {
+		var doc auditDoc
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		entry := &domain.AuditEntry{
+			ID:         doc.ID.Hex(),
+			UserID:     doc.UserID,
+			Operation:  domain.AuditOperation(doc.Operation),
+			Actor:      doc.Actor,
+			OccurredAt: doc.OccurredAt,
+		}
+		if len(doc.Changes) > 0 {
+			entry.Changes = make(domain.UserChanges, len(doc.Changes))
+			for field, change := range doc.Changes {
+				entry.Changes[field] = domain.FieldChange{Old: change.Old, New: change.New}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, cursor.Err()
+}
@@ -0,0 +1,390 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+
+	"user-api/internal/domain"
+)
+
+// maxStaleListSize limita o tamanho das listagens guardadas para servir desatualizadas
+// Listas maiores (ex: GET /api/v1/users sem paginação) ocupariam memória demais: não são guardadas
+const maxStaleListSize = 1000
+
+// Métricas do cache: a taxa de acerto é hits / (hits + misses)
+var (
+	userCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "user_cache_hits_total",
+		Help: "GetByID calls answered from the in-memory cache.",
+	})
+	userCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "user_cache_misses_total",
+		Help: "GetByID calls that went to the database (absent or expired in the cache).",
+	})
+	userCacheStaleServed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "user_cache_stale_served_total",
+		Help: "Reads answered from expired cache entries because MongoDB was unreachable.",
+	})
+)
+
+// ============================================
+// DECORATOR: CACHE DO GetByID
+// ============================================
+// CachedUserRepository envolve outro domain.UserRepository e guarda em memória
+// os resultados do GetByID (LRU com tamanho máximo e TTL)
+//
+// COMO FUNCIONA:
+// - GetByID: se o ID está no cache e não expirou, responde sem ir ao banco (hit)
+// - Senão busca no repositório de dentro e guarda o resultado (miss)
+// - Update, Upsert, Delete, DeleteMany, MarkVerified e IncrementField removem do cache os usuários que alteram
+// - UpdateMany (atualização em massa) e DropAll esvaziam o cache
+// - Só resultados encontrados entram no cache: um 404 sempre consulta o banco
+//
+// LEITURAS DESATUALIZADAS (STALE) SÃO POSSÍVEIS DENTRO DO TTL:
+// - O cache é DESTE processo: com várias instâncias, uma alteração feita em outra não invalida este
+// - Uma leitura que começou antes de uma alteração pode guardar o valor antigo logo depois da invalidação
+// - Alterações feitas direto no banco (scripts, outro serviço) também não são vistas
+// - Em todos os casos, o valor antigo dura no máximo o TTL. Use um TTL curto se isso importar
+//
+// LRU (Least Recently Used): cheio, o cache descarta o usuário usado há mais tempo
+//
+// TRANSAÇÕES (ver WithTransaction):
+// - Dentro da transação, GetByID vai sempre ao banco: a leitura precisa ser da transação, não do cache
+// - As invalidações são repetidas depois do commit: antes dele, uma leitura concorrente ainda vê (e guardaria) o valor antigo
+//
+// Com multi-tenancy, a chave inclui o tenant (ver tenantScoped): um tenant nunca lê do cache o usuário de outro
+//
+// DEGRADAÇÃO COM O MONGODB FORA (staleTTL > 0, USER_CACHE_STALE_TTL):
+// - Se o banco falha com erro de conectividade (ver isUnavailable), GetByID, List, ListAfter e ListOffset
+// respondem com o que o cache tiver, mesmo expirado há até staleTTL, em vez de devolver o erro
+// - Para isso, as listagens bem-sucedidas também são guardadas (até maxStaleListSize usuários cada),
+// mas só são usadas nessa situação: com o banco no ar, a listagem sempre vai ao banco
+// - A resposta é marcada com domain.MarkStale (o handler envia o header Warning)
+// - Escritas não mudam: com o banco fora, falham como antes
+type CachedUserRepository struct {
+	domain.UserRepository // Métodos não sobrescritos vão direto para o repositório de dentro
+
+	ttl      time.Duration
+	staleTTL time.Duration // Quanto tempo depois de expirar um item ainda serve com o banco fora (0 = nunca)
+
+	// mu protege entries e order: os handlers chamam o repositório de várias goroutines
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List // Frente = usado mais recentemente
+}
+
+// cacheEntry é um item do cache: um usuário (GetByID) ou uma listagem (só para servir desatualizada)
+type cacheEntry struct {
+	key       string
+	user      *domain.User
+	users     []*domain.User
+	expiresAt time.Time
+}
+
+// NewCachedUserRepository envolve next com um cache de até size itens, cada um válido por ttl
+// staleTTL > 0 liga a degradação com o MongoDB fora (ver CachedUserRepository)
+func NewCachedUserRepository(next domain.UserRepository, size int, ttl, staleTTL time.Duration) domain.UserRepository {
+	return &CachedUserRepository{
+		UserRepository: next,
+		ttl:            ttl,
+		staleTTL:       staleTTL,
+		size:           size,
+		entries:        make(map[string]*list.Element, size),
+		order:          list.New(),
+	}
+}
+
+// GetByID responde do cache quando possível
+//
+// POR QUE Clone?
+// - Quem recebe o usuário pode alterá-lo (o UpdateUser altera os campos antes de salvar)
+// - Sem a cópia, essa alteração mudaria o usuário guardado no cache, antes de ir ao banco
+func (r *CachedUserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	// Na transação, o cache fica de fora: nem responde (o valor pode ser anterior a ela)
+	// nem guarda (o valor lido pode ser desfeito no abort)
+	if _, ok := ctx.Value(txKey{}).(*txInvalidations); ok {
+		return r.UserRepository.GetByID(ctx, id)
+	}
+
+	key := userKey(ctx, id)
+	if entry, ok := r.get(key, false); ok {
+		userCacheHits.Inc()
+		return entry.user.Clone(), nil
+	}
+	userCacheMisses.Inc()
+
+	user, err := r.UserRepository.GetByID(ctx, id)
+	if err != nil {
+		if entry, ok := r.stale(ctx, key, err); ok {
+			return entry.user.Clone(), nil
+		}
+		return nil, err
+	}
+	r.put(&cacheEntry{key: key, user: user.Clone()})
+	return user, nil
+}
+
+func (r *CachedUserRepository) List(ctx context.Context, filter domain.UserFilter) ([]*domain.User, error) {
+	return r.staleList(ctx, listKey(ctx, "list", filter, "", 0, 0), func() ([]*domain.User, error) {
+		return r.UserRepository.List(ctx, filter)
+	})
+}
+
+func (r *CachedUserRepository) ListAfter(ctx context.Context, filter domain.UserFilter, after string, limit int) ([]*domain.User, error) {
+	return r.staleList(ctx, listKey(ctx, "after", filter, after, 0, limit), func() ([]*domain.User, error) {
+		return r.UserRepository.ListAfter(ctx, filter, after, limit)
+	})
+}
+
+func (r *CachedUserRepository) ListOffset(ctx context.Context, filter domain.UserFilter, offset, limit int) ([]*domain.User, error) {
+	return r.staleList(ctx, listKey(ctx, "offset", filter, "", offset, limit), func() ([]*domain.User, error) {
+		return r.UserRepository.ListOffset(ctx, filter, offset, limit)
+	})
+}
+
+// staleList executa a listagem e, com staleTTL, guarda o resultado para servi-lo se o banco cair
+// Sem staleTTL é só um repasse: listagens não são cacheadas no funcionamento normal
+func (r *CachedUserRepository) staleList(ctx context.Context, key string, list func() ([]*domain.User, error)) ([]*domain.User, error) {
+	if r.staleTTL <= 0 {
+		return list()
+	}
+
+	users, err := list()
+	if err != nil {
+		if entry, ok := r.stale(ctx, key, err); ok {
+			return slices.Clone(entry.users), nil
+		}
+		return nil, err
+	}
+	if len(users) <= maxStaleListSize {
+		r.put(&cacheEntry{key: key, users: slices.Clone(users)})
+	}
+	return users, nil
+}
+
+// userKey identifica um usuário no cache: o ID em minúsculas (com o tenant)
+// IDs são ObjectIDs em hexadecimal: "507F..." e "507f..." são o mesmo usuário e precisam da mesma chave,
+// senão o invalidate (que recebe o ID canônico, em minúsculas) deixaria a variante maiúscula desatualizada
+func userKey(ctx context.Context, id string) string {
+	return tenantScoped(ctx, strings.ToLower(id))
+}
+
+// listKey identifica uma listagem no cache: operação, filtro e parâmetros da página (com o tenant)
+// %q nos textos livres evita que um nome com "|" produza a chave de outra listagem
+func listKey(ctx context.Context, op string, filter domain.UserFilter, after string, offset, limit int) string {
+	return tenantScoped(ctx, fmt.Sprintf("list:%s|%q|%d|%s|%q|%d|%d",
+		op, filter.Name, filter.UpdatedSince.UnixMilli(), strings.Join(filter.Fields, ","), after, offset, limit))
+}
+
+// stale procura o item para responder no lugar de err, se err for de conectividade
+// Marca o context (domain.MarkStale) quando encontra
+func (r *CachedUserRepository) stale(ctx context.Context, key string, err error) (*cacheEntry, bool) {
+	if r.staleTTL <= 0 || !isUnavailable(err) {
+		return nil, false
+	}
+	entry, ok := r.get(key, true)
+	if !ok {
+		return nil, false
+	}
+	userCacheStaleServed.Inc()
+	domain.MarkStale(ctx)
+	return entry, true
+}
+
+// isUnavailable diz se err indica que o banco está inalcançável (e não um erro da operação)
+// - Nenhum servidor selecionável (MongoDB fora, rede partida)
+// - Erros passageiros de rede ou de failover (os mesmos do retry, ver isRetryable)
+// - Conexão recusada ou caída no PostgreSQL (ver isPostgresUnavailable)
+// - Prazo estourado esperando o banco
+// ErrDatabaseBusy (pool cheio) não entra: o banco está no ar, só sobrecarregado
+func isUnavailable(err error) bool {
+	var selectionErr topology.ServerSelectionError
+	return errors.As(err, &selectionErr) || errors.Is(err, context.DeadlineExceeded) || isRetryable(err) || isPostgresUnavailable(err)
+}
+
+func (r *CachedUserRepository) Update(ctx context.Context, user *domain.User) error {
+	defer r.invalidate(ctx, user.ID)
+	return r.UserRepository.Update(ctx, user)
+}
+
+func (r *CachedUserRepository) Upsert(ctx context.Context, user *domain.User) (*domain.User, error) {
+	before, err := r.UserRepository.Upsert(ctx, user)
+	if before != nil {
+		r.invalidate(ctx, before.ID)
+	}
+	return before, err
+}
+
+func (r *CachedUserRepository) Delete(ctx context.Context, id string) error {
+	defer r.invalidate(ctx, id)
+	return r.UserRepository.Delete(ctx, id)
+}
+
+func (r *CachedUserRepository) DeleteMany(ctx context.Context, ids []string) (int64, []string, error) {
+	defer r.invalidate(ctx, ids...)
+	return r.UserRepository.DeleteMany(ctx, ids)
+}
+
+func (r *CachedUserRepository) MarkVerified(ctx context.Context, id, email string) error {
+	defer r.invalidate(ctx, id)
+	return r.UserRepository.MarkVerified(ctx, id, email)
+}
+
+func (r *CachedUserRepository) IncrementField(ctx context.Context, id, field string, delta int) error {
+	defer r.invalidate(ctx, id)
+	return r.UserRepository.IncrementField(ctx, id, field, delta)
+}
+
+// txKey guarda no context as invalidações da transação aberta por WithTransaction
+type txKey struct{}
+
+// txInvalidations são as chaves alteradas dentro da transação, invalidadas de novo depois do commit
+// all: UpdateMany ou DropAll na transação, o cache inteiro é esvaziado
+// mu: por segurança, caso fn faça escritas em paralelo
+type txInvalidations struct {
+	mu   sync.Mutex
+	keys []string
+	all  bool
+}
+
+// WithTransaction marca o context da transação para o cache (GetByID direto no banco)
+// e, depois do commit, invalida de novo tudo o que fn alterou
+//
+// POR QUE INVALIDAR DE NOVO?
+// - As escritas de fn já invalidam na hora, mas os dados só ficam visíveis no COMMIT
+// - Entre uma coisa e outra, um GetByID de fora da transação lê o valor antigo e o guarda de novo
+// - Sem a segunda invalidação, esse valor antigo seria servido até o TTL
+//
+// Se a transação falhar, nada mudou no banco: não há o que invalidar
+// Vale para qualquer banco: o marcador é do cache, não da sessão do MongoDB ou da transação do PostgreSQL
+func (r *CachedUserRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx := &txInvalidations{}
+	err := r.UserRepository.WithTransaction(context.WithValue(ctx, txKey{}, tx), fn)
+	if err != nil {
+		return err
+	}
+
+	if tx.all {
+		r.clear()
+		return nil
+	}
+	r.remove(tx.keys...)
+	return nil
+}
+
+// UpdateMany não diz QUAIS usuários alterou: o cache inteiro fica inválido
+func (r *CachedUserRepository) UpdateMany(ctx context.Context, filter domain.BulkUpdateFilter, changes domain.BulkUpdateChanges, maxMatched int64) (int64, int64, error) {
+	defer r.clearAll(ctx)
+	return r.UserRepository.UpdateMany(ctx, filter, changes, maxMatched)
+}
+
+// DropAll apaga todos os usuários: o cache inteiro fica inválido
+func (r *CachedUserRepository) DropAll(ctx context.Context) error {
+	defer r.clearAll(ctx)
+	return r.UserRepository.DropAll(ctx)
+}
+
+// get devolve o item guardado se ele ainda não expirou (e o marca como usado agora)
+// Com stale, também vale um item expirado há até staleTTL
+// Itens que passaram do prazo em que ainda serviriam desatualizados são descartados
+func (r *CachedUserRepository) get(key string, stale bool) (*cacheEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	el, ok := r.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cacheEntry)
+	now := time.Now()
+	if now.After(entry.expiresAt.Add(r.staleTTL)) {
+		r.order.Remove(el)
+		delete(r.entries, key)
+		return nil, false
+	}
+	if !stale && now.After(entry.expiresAt) {
+		return nil, false
+	}
+	r.order.MoveToFront(el)
+	return entry, true
+}
+
+// put guarda o item, descartando o menos usado recentemente se o cache estiver cheio
+func (r *CachedUserRepository) put(entry *cacheEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.expiresAt = time.Now().Add(r.ttl)
+	if el, ok := r.entries[entry.key]; ok {
+		el.Value = entry
+		r.order.MoveToFront(el)
+		return
+	}
+
+	r.entries[entry.key] = r.order.PushFront(entry)
+	if r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// invalidate remove os usuários do cache
+// É chamado DEPOIS da escrita (defer): invalidar antes deixaria uma leitura
+// concorrente guardar de novo o valor antigo enquanto a escrita acontece
+// Dentro de WithTransaction, as chaves também ficam anotadas para depois do commit
+func (r *CachedUserRepository) invalidate(ctx context.Context, ids ...string) {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, userKey(ctx, id))
+	}
+	if tx, ok := ctx.Value(txKey{}).(*txInvalidations); ok {
+		tx.mu.Lock()
+		tx.keys = append(tx.keys, keys...)
+		tx.mu.Unlock()
+	}
+	r.remove(keys...)
+}
+
+// remove tira as chaves do cache
+func (r *CachedUserRepository) remove(keys ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := r.entries[key]; ok {
+			r.order.Remove(el)
+			delete(r.entries, key)
+		}
+	}
+}
+
+// clearAll esvazia o cache e, dentro de WithTransaction, de novo depois do commit
+func (r *CachedUserRepository) clearAll(ctx context.Context) {
+	if tx, ok := ctx.Value(txKey{}).(*txInvalidations); ok {
+		tx.mu.Lock()
+		tx.all = true
+		tx.mu.Unlock()
+	}
+	r.clear()
+}
+
+// clear esvazia o cache
+func (r *CachedUserRepository) clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = make(map[string]*list.Element, r.size)
+	r.order.Init()
+}
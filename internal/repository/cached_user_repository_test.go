@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"user-api/internal/usecase"
+)
+
+func TestIsUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "deadline exceeded", err: context.DeadlineExceeded, want: true},
+		{name: "postgres connect error", err: &pgconn.ConnectError{}, want: true},
+		{name: "postgres bad connection", err: fmt.Errorf("query: %w", driver.ErrBadConn), want: true},
+		{name: "network error", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, want: true},
+		{name: "postgres connection exception", err: &pgconn.PgError{Code: "08006"}, want: true},
+		{name: "postgres shutting down", err: &pgconn.PgError{Code: "57P01"}, want: true},
+		{name: "postgres starting up", err: &pgconn.PgError{Code: "57P03"}, want: true},
+		{name: "postgres unique violation", err: &pgconn.PgError{Code: uniqueViolation}, want: false},
+		{name: "not found", err: usecase.ErrNotFound, want: false},
+		{name: "database busy", err: usecase.ErrDatabaseBusy, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUnavailable(tt.err); got != tt.want {
+				t.Errorf("isUnavailable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
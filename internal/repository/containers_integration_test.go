@@ -0,0 +1,22 @@
+//go:build integration
+
+package repository
+
+import (
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// skipWithoutDocker pula o teste quando não há Docker (ou Podman) para subir o container
+// SkipIfProviderIsNotHealthy já pula com um Docker parado, mas entra em panic
+// quando não encontra nenhum socket: o recover transforma esse caso em skip também
+func skipWithoutDocker(t *testing.T) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Skipf("Docker is not available: %v", r)
+		}
+	}()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// DECORATOR: TOTAL DE USUÁRIOS EM CACHE
+// ============================================
+// CountCacheRepository envolve outro domain.UserRepository e guarda em memória o total de usuários
+// (Count sem filtro), em vez de um CountDocuments a cada GET /api/v1/users/count
+//
+// O PROBLEMA:
+// - CountDocuments percorre o índice (ou a collection) inteiro: em milhões de usuários, cada chamada pesa
+// - Painéis que consultam o total a cada poucos segundos multiplicam esse custo
+//
+// COMO FUNCIONA:
+// - Run (em uma goroutine) reconta no banco a cada interval e guarda o valor com a hora da contagem
+// - Entre uma contagem e outra, Create, Upsert (quando cria), Delete, DeleteMany e DropAll ajustam o valor
+// - Count sem filtro responde do cache e marca o context (domain.MarkCountCached): o handler informa
+// "cached": true e "as_of" (a hora da última contagem no banco)
+// - Count com filtro (?name=, ?updatedSince=) sempre vai ao banco
+// - Antes da primeira contagem (ou se ela falhou), Count também vai ao banco
+//
+// É UMA APROXIMAÇÃO (POR ISSO É OPCIONAL):
+// - Os ajustes são DESTE processo: com várias instâncias, as escritas feitas em outra só aparecem na próxima contagem
+// - Alterações feitas direto no banco (scripts, purge de outra instância) também só aparecem na próxima contagem
+// - Uma escrita que acontece durante a contagem pode ser contada duas vezes ou nenhuma
+// - Um Create dentro de uma transação desfeita (abort) já ajustou o valor
+// - Em todos os casos, a próxima contagem corrige o total
+type CountCacheRepository struct {
+	domain.UserRepository // Métodos não sobrescritos vão direto para o repositório de dentro
+
+	interval time.Duration
+	logger   *slog.Logger
+
+	// mu protege os campos abaixo: Run e os handlers os acessam de goroutines diferentes
+	mu     sync.Mutex
+	loaded bool      // false até a primeira contagem bem-sucedida
+	count  int64     // Total da última contagem, mais os ajustes desde então
+	asOf   time.Time // Hora da última contagem no banco
+}
+
+// NewCountCacheRepository envolve next com o total em cache, recontado a cada interval
+// Devolve o tipo concreto: main precisa chamar Run (go repo.Run(ctx))
+func NewCountCacheRepository(next domain.UserRepository, interval time.Duration, logger *slog.Logger) *CountCacheRepository {
+	return &CountCacheRepository{
+		UserRepository: next,
+		interval:       interval,
+		logger:         logger.With("component", "count_cache"),
+	}
+}
+
+// Run reconta o total agora e depois a cada interval, até ctx ser cancelado
+// Deve ser chamado em uma goroutine: go repo.Run(ctx)
+//
+// COMO ELE PARA?
+// - No encerramento da aplicação (SIGTERM), main cancela ctx e o loop termina
+// - Uma contagem em andamento também é cancelada por ctx: nada fica rodando depois de Run voltar
+func (r *CountCacheRepository) Run(ctx context.Context) {
+	r.logger.Info("count cache started", "interval", r.interval.String())
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.refresh(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("count cache stopped")
+			return
+		case <-ticker.C:
+			r.refresh(ctx)
+		}
+	}
+}
+
+// refresh reconta o total no banco e substitui o valor guardado
+// Falhas só são registradas: o valor anterior continua valendo até a próxima contagem
+func (r *CountCacheRepository) refresh(ctx context.Context) {
+	count, err := r.UserRepository.Count(ctx, domain.UserFilter{})
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.Error("count refresh failed", "error", err)
+		}
+		return
+	}
+
+	r.mu.Lock()
+	r.loaded, r.count, r.asOf = true, count, time.Now().UTC()
+	r.mu.Unlock()
+}
+
+// add ajusta o total guardado entre duas contagens (delta negativo para remoções)
+// Antes da primeira contagem não há o que ajustar: ela já verá a escrita
+func (r *CountCacheRepository) add(delta int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loaded {
+		r.count = max(r.count+delta, 0)
+	}
+}
+
+// Count responde do cache quando o filtro é vazio (o total de usuários)
+// Fields não conta como filtro: só escolhe campos, não quais usuários
+func (r *CountCacheRepository) Count(ctx context.Context, filter domain.UserFilter) (int64, error) {
+	if filter.Name != "" || !filter.UpdatedSince.IsZero() {
+		return r.UserRepository.Count(ctx, filter)
+	}
+
+	r.mu.Lock()
+	loaded, count, asOf := r.loaded, r.count, r.asOf
+	r.mu.Unlock()
+	if !loaded {
+		return r.UserRepository.Count(ctx, filter)
+	}
+
+	domain.MarkCountCached(ctx, asOf)
+	return count, nil
+}
+
+func (r *CountCacheRepository) Create(ctx context.Context, user *domain.User) error {
+	err := r.UserRepository.Create(ctx, user)
+	if err == nil {
+		r.add(1)
+	}
+	return err
+}
+
+// Upsert só ajusta o total quando criou o usuário (before nil)
+func (r *CountCacheRepository) Upsert(ctx context.Context, user *domain.User) (*domain.User, error) {
+	before, err := r.UserRepository.Upsert(ctx, user)
+	if err == nil && before == nil {
+		r.add(1)
+	}
+	return before, err
+}
+
+func (r *CountCacheRepository) Delete(ctx context.Context, id string) error {
+	err := r.UserRepository.Delete(ctx, id)
+	if err == nil {
+		r.add(-1)
+	}
+	return err
+}
+
+func (r *CountCacheRepository) DeleteMany(ctx context.Context, ids []string) (int64, []string, error) {
+	deleted, invalid, err := r.UserRepository.DeleteMany(ctx, ids)
+	if deleted > 0 {
+		r.add(-deleted)
+	}
+	return deleted, invalid, err
+}
+
+// DropAll zera o total: a collection foi recriada vazia
+func (r *CountCacheRepository) DropAll(ctx context.Context) error {
+	err := r.UserRepository.DropAll(ctx)
+	if err == nil {
+		r.mu.Lock()
+		r.loaded, r.count, r.asOf = true, 0, time.Now().UTC()
+		r.mu.Unlock()
+	}
+	return err
+}
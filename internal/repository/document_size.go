@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver"
+
+	"user-api/internal/usecase"
+)
+
+// ============================================
+// DOCUMENTO GRANDE DEMAIS (LIMITE DE 16MB)
+// ============================================
+// O MongoDB recusa documentos BSON com mais de 16MB
+// Com metadata livre (e vários emails), uma escrita pode passar disso
+//
+// SEM ESTA TRADUÇÃO:
+// - O erro do driver chegava ao handler como um erro qualquer: 500 INTERNAL_ERROR
+// - O cliente não tinha como saber que o problema é o tamanho do que ele enviou
+//
+// COM ELA:
+// - do (user_mongo_repository.go) devolve usecase.ErrDocumentTooLarge
+// - O handler responde 413 DOCUMENT_TOO_LARGE (ver writeServerError)
+
+// documentTooLargeCodes são os códigos do servidor para um documento acima do limite
+var documentTooLargeCodes = []int{
+	10334, // BSONObjectTooLarge: o documento enviado já passa do limite
+	17419, // O documento resultante de um update passaria do limite
+	17420, // O documento inserido por um upsert passaria do limite
+}
+
+// isDocumentTooLarge diz se err é a recusa de um documento grande demais
+// O driver também recusa sozinho (driver.ErrDocumentTooLarge), sem ir ao servidor;
+// o insert do servidor responde BadValue (código 2) com "object to insert too large"
+func isDocumentTooLarge(err error) bool {
+	if errors.Is(err, driver.ErrDocumentTooLarge) {
+		return true
+	}
+
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+	for _, code := range documentTooLargeCodes {
+		if serverErr.HasErrorCode(code) {
+			return true
+		}
+	}
+	return serverErr.HasErrorCodeWithMessage(2, "too large")
+}
+
+// asDocumentTooLarge traduz a recusa por tamanho para usecase.ErrDocumentTooLarge
+// O erro original continua na cadeia (%w só no ErrDocumentTooLarge, o texto do driver vai para o log)
+func asDocumentTooLarge(err error) error {
+	if err == nil || !isDocumentTooLarge(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", usecase.ErrDocumentTooLarge, err)
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.mongodb.org/mongo-driver/x/mongo/driver"
+
+	"user-api/internal/usecase"
+)
+
+func TestIsDocumentTooLarge(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "rejected by the driver", err: driver.ErrDocumentTooLarge, want: true},
+		{name: "rejected by the driver, wrapped", err: fmt.Errorf("insert: %w", driver.ErrDocumentTooLarge), want: true},
+		{name: "BSONObjectTooLarge", err: mongo.CommandError{Code: 10334, Message: "BSONObj size is invalid"}, want: true},
+		{name: "update result too large", err: mongo.CommandError{Code: 17419, Message: "Resulting document after update is larger than 16777216"}, want: true},
+		{name: "upsert result too large", err: mongo.CommandError{Code: 17420, Message: "Document to upsert is larger than 16777216"}, want: true},
+		{name: "insert BadValue too large", err: mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 2, Message: "object to insert too large"}}}, want: true},
+		{name: "other BadValue", err: mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 2, Message: "unknown operator"}}}, want: false},
+		{name: "duplicate key", err: mongo.CommandError{Code: 11000, Message: duplicateKeyError.Message}, want: false},
+		{name: "not a server error", err: errors.New("connection reset"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDocumentTooLarge(tt.err); got != tt.want {
+				t.Errorf("isDocumentTooLarge() = %v, want %v", got, tt.want)
+			}
+			// asDocumentTooLarge só troca o erro quando ele é a recusa por tamanho
+			translated := asDocumentTooLarge(tt.err)
+			if errors.Is(translated, usecase.ErrDocumentTooLarge) != tt.want {
+				t.Errorf("asDocumentTooLarge() = %v, want ErrDocumentTooLarge: %v", translated, tt.want)
+			}
+			if !tt.want && translated.Error() != tt.err.Error() {
+				t.Errorf("asDocumentTooLarge() = %v, want the original error", translated)
+			}
+		})
+	}
+	if asDocumentTooLarge(nil) != nil {
+		t.Error("asDocumentTooLarge(nil) != nil")
+	}
+}
+
+func TestCreateDocumentTooLarge(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("insert rejected by the server", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 2, Message: "object to insert too large"}))
+
+		err := newMockRepository(mt).Create(context.Background(), newTestUser("Ana", "ana@example.com"))
+		if !errors.Is(err, usecase.ErrDocumentTooLarge) {
+			t.Errorf("Create error = %v, want ErrDocumentTooLarge", err)
+		}
+	})
+}
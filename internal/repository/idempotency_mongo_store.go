@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// STORE DE IDEMPOTÊNCIA (MONGODB)
+// ============================================
+// Guarda as chaves de idempotência em uma collection pequena
+//
+// SOBRE O ÍNDICE TTL:
+// - Um índice TTL faz o próprio MongoDB apagar documentos antigos
+// - expireAfterSeconds conta a partir do campo createdAt
+// - Assim a collection não cresce para sempre e não precisamos de um job de limpeza
+// - A remoção roda a cada ~60s: um documento pode sobreviver um pouco além do TTL
+//
+// POR QUE A CHAVE É O _id?
+// - _id é único: se duas requisições com a mesma chave chegam juntas, só um InsertOne vence
+// - O outro recebe erro de chave duplicada e passa a ler o registro existente
+type idempotencyDoc struct {
+	Key         string    `bson:"_id"`
+	RequestHash string    `bson:"requestHash"`
+	UserID      string    `bson:"userId"`
+	CreatedAt   time.Time `bson:"createdAt"`
+}
+
+// IdempotencyMongoStore implementa domain.IdempotencyStore usando MongoDB
+type IdempotencyMongoStore struct {
+	collection *mongo.Collection
+	opTimeout  time.Duration // Prazo de cada operação (mesmo MONGO_OP_TIMEOUT do repositório)
+}
+
+// NewIdempotencyMongoStore cria o store e garante o índice TTL
+// ttl define por quanto tempo uma chave continua válida
+// opTimeout é o prazo de cada operação (zero usa DefaultOpTimeout)
+func NewIdempotencyMongoStore(ctx context.Context, db *mongo.Database, collectionName string, ttl, opTimeout time.Duration) (*IdempotencyMongoStore, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	collection := db.Collection(collectionName)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "createdAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(ttl.Seconds())),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opTimeout <= 0 {
+		opTimeout = DefaultOpTimeout
+	}
+	return &IdempotencyMongoStore{collection: collection, opTimeout: opTimeout}, nil
+}
+
+// Reserve insere a chave; se ela já existe, devolve o registro guardado
+func (s *IdempotencyMongoStore) Reserve(ctx context.Context, key, requestHash string) (*domain.IdempotencyRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opTimeout)
+	defer cancel()
+
+	// Com multi-tenancy, a mesma chave em dois tenants são reservas diferentes
+	doc := idempotencyDoc{
+		Key:         tenantScoped(ctx, key),
+		RequestHash: requestHash,
+		CreatedAt:   time.Now().UTC(),
+	}
+	_, err := s.collection.InsertOne(ctx, doc)
+	if err == nil {
+		return nil, nil // Chave nova: reserva feita
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+
+	// Chave já usada: busca o registro original
+	var existing idempotencyDoc
+	if err := s.collection.FindOne(ctx, bson.M{"_id": doc.Key}).Decode(&existing); err != nil {
+		return nil, err
+	}
+	return &domain.IdempotencyRecord{
+		Key:         key,
+		RequestHash: existing.RequestHash,
+		UserID:      existing.UserID,
+		CreatedAt:   existing.CreatedAt,
+	}, nil
+}
+
+// Complete grava o ID do usuário criado na chave reservada
+func (s *IdempotencyMongoStore) Complete(ctx context.Context, key, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.opTimeout)
+	defer cancel()
+
+	_, err := s.collection.UpdateOne(ctx, bson.M{"_id": tenantScoped(ctx, key)}, bson.M{"$set": bson.M{"userId": userID}})
+	return err
+}
+
+// Release remove a reserva para que a chave possa ser usada de novo
+func (s *IdempotencyMongoStore) Release(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.opTimeout)
+	defer cancel()
+
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": tenantScoped(ctx, key)})
+	return err
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+
+	"user-api/internal/usecase"
+)
+
+// mongoPoolWaitTimeouts conta as operações recusadas por falta de conexão livre
+var mongoPoolWaitTimeouts = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "mongo_pool_wait_timeouts_total",
+	Help: "MongoDB operations rejected because no pool connection became free in time.",
+})
+
+// ============================================
+// ESPERA POR CONEXÃO DO POOL (BACKPRESSURE)
+// ============================================
+// PoolGate limita quanto uma operação espera por uma conexão livre do pool do MongoDB
+//
+// O PROBLEMA:
+// - Com o pool cheio (MONGO_MAX_POOL_SIZE conexões em uso), o driver ENFILEIRA a operação
+// - Ela espera até o prazo do context (MONGO_OP_TIMEOUT), segurando a goroutine da requisição
+// - Sob saturação, as requisições se acumulam esperando, em vez de o cliente saber logo que deve tentar depois
+//
+// POR QUE UM SEMÁFORO AQUI, E NÃO UMA OPÇÃO DO DRIVER?
+// - O driver (1.x) não tem mais um prazo próprio para a fila do pool (o antigo waitQueueTimeoutMS)
+// - Ele só desiste quando o context inteiro da operação vence
+// - O semáforo tem o mesmo tamanho do pool: com todas as vagas ocupadas, a operação
+// espera no máximo "wait" e falha com usecase.ErrDatabaseBusy (503 DATABASE_BUSY)
+//
+// É UMA APROXIMAÇÃO:
+// - O pool é por servidor; com leituras em secundários (MONGO_READ_PREFERENCE) há mais conexões que vagas
+// - Streams (exportação, NDJSON, IDs) e transações não passam pelo semáforo
+//
+// Um *PoolGate nil não limita nada (MONGO_POOL_WAIT_TIMEOUT=0 ou pool sem limite)
+type PoolGate struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// NewPoolGate cria o semáforo com size vagas (o MONGO_MAX_POOL_SIZE)
+// Devolve nil (sem limite) quando size ou wait são zero
+func NewPoolGate(size uint64, wait time.Duration) *PoolGate {
+	if size == 0 || wait <= 0 {
+		return nil
+	}
+	return &PoolGate{slots: make(chan struct{}, size), wait: wait}
+}
+
+// acquire ocupa uma vaga, esperando no máximo g.wait
+// release devolve a vaga; chame-a (com defer) só se err for nil
+func (g *PoolGate) acquire(ctx context.Context) (release func(), err error) {
+	if g == nil {
+		return func() {}, nil
+	}
+	release = func() { <-g.slots }
+
+	// Caminho rápido: há vaga livre, sem criar timer
+	select {
+	case g.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	timer := time.NewTimer(g.wait)
+	defer timer.Stop()
+
+	select {
+	case g.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		mongoPoolWaitTimeouts.Inc()
+		return nil, usecase.ErrDatabaseBusy
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// asPoolTimeout traduz o "timed out while checking out a connection" do driver para ErrDatabaseBusy
+// Acontece sem o PoolGate (ou com o pool dividido com streams): o prazo da operação
+// venceu ainda na fila do pool, sem que ela chegasse ao servidor
+// O erro original continua na cadeia (%w só no ErrDatabaseBusy, o texto do driver vai para o log)
+func asPoolTimeout(err error) error {
+	var waitErr topology.WaitQueueTimeoutError
+	if err == nil || !errors.As(err, &waitErr) || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	mongoPoolWaitTimeouts.Inc()
+	return fmt.Errorf("%w: %v", usecase.ErrDatabaseBusy, err)
+}
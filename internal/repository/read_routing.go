@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// readTarget diz de onde uma leitura pode vir (ver readColl)
+type readTarget int
+
+const (
+	// readDefault: leituras pontuais (GetByID, GetByIDs, EmailExists), sempre com a preferência do cliente
+	readDefault readTarget = iota
+	// readList: listagens, buscas, contagens e varreduras, que podem ir para secundários
+	readList
+)
+
+// listReadPref é a preferência das leituras readList com READ_FROM_SECONDARY
+// secondaryPreferred, e não secondary: sem secundário disponível (standalone, failover), lê do primário
+var listReadPref = readpref.SecondaryPreferred()
+
+// ============================================
+// LEITURAS NOS SECUNDÁRIOS
+// ============================================
+// readColl devolve a collection da requisição (ver coll) para uma leitura do tipo target
+// Com READ_FROM_SECONDARY, as leituras readList usam uma cópia da collection com listReadPref
+//
+// POR QUE SÓ AS LISTAGENS?
+// - São as leituras caras e frequentes (painéis, exportações, sincronizações): tirá-las do primário alivia as escritas
+// - Quem lista aceita um resultado alguns milissegundos atrasado: a réplica logo alcança o primário
+// - GetByID não: o cliente que acabou de criar um usuário e faz GET em seguida precisa encontrá-lo
+// (a réplica pode ainda não ter recebido o insert); o mesmo vale para GetByIDs e EmailExists
+// - Escritas sempre vão para o primário, independentemente da preferência
+//
+// E DENTRO DE UMA TRANSAÇÃO?
+// - Transações só leem do primário (ver WithTransaction): com uma sessão no context, usa a collection de sempre
+//
+// A cópia (Clone) só troca as opções: não abre conexões nem consulta o servidor
+func (r *UserMongoRepository) readColl(ctx context.Context, target readTarget) (*mongo.Collection, error) {
+	coll, err := r.coll(ctx)
+	if err != nil || target != readList || !r.readFromSecondary || mongo.SessionFromContext(ctx) != nil {
+		return coll, err
+	}
+	return coll.Clone(options.Collection().SetReadPreference(listReadPref))
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxOpRetryDelay limita a espera entre tentativas de uma mesma operação
+// A operação inteira ainda precisa caber no opTimeout (MONGO_OP_TIMEOUT)
+const maxOpRetryDelay = time.Second
+
+// retryableCodes são códigos de erro do servidor que indicam falha passageira
+// A maioria aparece durante a eleição de um novo primário (failover) ou no desligamento de um nó
+var retryableCodes = []int{
+	6,     // HostUnreachable
+	7,     // HostNotFound
+	89,    // NetworkTimeout
+	91,    // ShutdownInProgress
+	189,   // PrimarySteppedDown
+	9001,  // SocketException
+	10107, // NotWritablePrimary
+	11600, // InterruptedAtShutdown
+	11602, // InterruptedDueToReplStateChange
+	13435, // NotPrimaryNoSecondaryOk
+	13436, // NotPrimaryOrSecondary
+}
+
+// ============================================
+// RETRY DE OPERAÇÕES
+// ============================================
+// RetryPolicy define quantas vezes uma operação é repetida em erros passageiros do MongoDB
+//
+// POR QUE REPETIR?
+// - Uma troca de primário (failover ou manutenção do cluster) leva alguns segundos
+// - Nesse intervalo, operações falham com erros de rede ou "not primary"
+// - A mesma operação, repetida logo depois, costuma dar certo
+//
+// E O RETRY DO PRÓPRIO DRIVER?
+// - O driver já repete UMA vez leituras e escritas (retryReads/retryWrites)
+// - Uma eleição pode demorar mais que isso; esta política acrescenta tentativas com backoff
+//
+// O QUE NÃO É REPETIDO:
+// - Erros definitivos (chave duplicada, documento inválido...): falham na primeira vez
+// - context cancelado ou prazo estourado: não adianta tentar de novo
+// - Operações dentro de uma transação: quem repete é session.WithTransaction (a transação inteira)
+//
+// O valor zero (MaxAttempts 0) faz uma única tentativa, sem retry
+type RetryPolicy struct {
+	MaxAttempts int           // Total de tentativas, contando a primeira (MONGO_RETRY_MAX_ATTEMPTS)
+	BaseDelay   time.Duration // Espera antes da segunda tentativa; dobra a cada nova falha (MONGO_RETRY_BASE_DELAY)
+}
+
+// do executa fn, repetindo enquanto o erro for passageiro e houver tentativas e prazo
+//
+// SOBRE O PRAZO:
+// - ctx já carrega o opTimeout: todas as tentativas dividem o mesmo prazo
+// - Se a próxima espera passaria do prazo, desistimos e devolvemos o último erro
+func (p RetryPolicy) do(ctx context.Context, fn func() error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 || mongo.SessionFromContext(ctx) != nil {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= attempts || ctx.Err() != nil || !isRetryable(err) {
+			return err
+		}
+
+		delay := opRetryDelay(attempt, p.BaseDelay)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// isRetryable diz se err é uma falha passageira do MongoDB
+//
+// SOBRE OS LABELS:
+// - O servidor marca erros seguros de repetir com labels (ex: "RetryableWriteError")
+// - mongo.ServerError cobre CommandError, WriteException e BulkWriteException
+//
+// mongo.IsTimeout também é true para context.DeadlineExceeded, mas esse caso
+// já foi descartado em do (ctx.Err() != nil)
+func isRetryable(err error) bool {
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return true
+	}
+
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+	if serverErr.HasErrorLabel("RetryableWriteError") || serverErr.HasErrorLabel("TransientTransactionError") {
+		return true
+	}
+	for _, code := range retryableCodes {
+		if serverErr.HasErrorCode(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// opRetryDelay calcula a espera antes da próxima tentativa (backoff exponencial com jitter)
+// attempt=1 → ~baseDelay, attempt=2 → ~2*baseDelay... limitado a maxOpRetryDelay
+func opRetryDelay(attempt int, baseDelay time.Duration) time.Duration {
+	if baseDelay <= 0 {
+		return 0
+	}
+	delay := baseDelay << (attempt - 1)
+	if delay <= 0 || delay > maxOpRetryDelay {
+		delay = maxOpRetryDelay
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// failingOp faz o papel de uma collection com falhas: a chamada i devolve errs[i]
+// e, acabada a lista, dá certo
+type failingOp struct {
+	errs  []error
+	calls int
+}
+
+func (op *failingOp) run() error {
+	op.calls++
+	if op.calls <= len(op.errs) {
+		return op.errs[op.calls-1]
+	}
+	return nil
+}
+
+// Embrulhados com %w: errors.Is compara o ponteiro (mongo.CommandError tem slices e não é comparável)
+var (
+	errStepDown  = fmt.Errorf("insert: %w", mongo.CommandError{Code: 189, Name: "PrimarySteppedDown", Message: "primary stepped down"})
+	errDuplicate = fmt.Errorf("insert: %w", mongo.CommandError{Code: 11000, Message: duplicateKeyError.Message})
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "not writable primary", err: mongo.CommandError{Code: 10107}, want: true},
+		{name: "primary stepped down", err: errStepDown, want: true},
+		{name: "retryable write label", err: mongo.CommandError{Code: 1, Labels: []string{"RetryableWriteError"}}, want: true},
+		{name: "transient transaction label", err: mongo.CommandError{Code: 1, Labels: []string{"TransientTransactionError"}}, want: true},
+		{name: "network error label", err: mongo.CommandError{Labels: []string{"NetworkError"}}, want: true},
+		{name: "duplicate key", err: errDuplicate, want: false},
+		{name: "duplicate key in a write", err: mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, want: false},
+		{name: "plain error", err: errors.New("invalid document"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    RetryPolicy
+		errs      []error
+		wantErr   error
+		wantCalls int
+	}{
+		{name: "succeeds on a later attempt", policy: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}, errs: []error{errStepDown, errStepDown}, wantErr: nil, wantCalls: 3},
+		{name: "duplicate key fails at once", policy: RetryPolicy{MaxAttempts: 3}, errs: []error{errDuplicate}, wantErr: errDuplicate, wantCalls: 1},
+		{name: "MaxAttempts is the budget", policy: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}, errs: []error{errStepDown, errStepDown, errStepDown, errStepDown}, wantErr: errStepDown, wantCalls: 3},
+		{name: "zero value makes a single attempt", policy: RetryPolicy{}, errs: []error{errStepDown}, wantErr: errStepDown, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := &failingOp{errs: tt.errs}
+			err := tt.policy.do(context.Background(), op.run)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("do() error = %v, want %v", err, tt.wantErr)
+			}
+			if op.calls != tt.wantCalls {
+				t.Errorf("fn called %d times, want %d", op.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// TestRetryPolicyDeadline confere que o prazo do ctx encurta o backoff em vez de esperar por ele
+func TestRetryPolicyDeadline(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second}
+
+	t.Run("the next delay would pass the deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		op := &failingOp{errs: []error{errStepDown, errStepDown}}
+		start := time.Now()
+		err := policy.do(ctx, op.run)
+		if !errors.Is(err, errStepDown) || op.calls != 1 {
+			t.Errorf("do() = %v after %d calls, want the first error after 1 call", err, op.calls)
+		}
+		if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+			t.Errorf("do() took %v, want it to give up without waiting", elapsed)
+		}
+	})
+
+	t.Run("ctx canceled during the backoff", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		op := &failingOp{errs: []error{errStepDown, errStepDown}}
+		start := time.Now()
+		err := policy.do(ctx, op.run)
+		if !errors.Is(err, errStepDown) || op.calls != 1 {
+			t.Errorf("do() = %v after %d calls, want the first error after 1 call", err, op.calls)
+		}
+		// O backoff seria de pelo menos 500ms (metade do BaseDelay, pelo jitter)
+		if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+			t.Errorf("do() took %v, want it to stop when ctx was canceled", elapsed)
+		}
+	})
+}
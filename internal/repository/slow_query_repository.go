@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// DECORATOR: LOG DE CONSULTAS LENTAS
+// ============================================
+// SlowQueryRepository envolve outro domain.UserRepository e registra em WARN
+// toda operação que demora mais que threshold
+//
+// POR QUE UM DECORATOR E NÃO CÓDIGO EM CADA MÉTODO DO MONGO?
+// - Ele implementa a MESMA interface: o usecase recebe o repositório e não percebe a diferença
+// - A medição fica em um lugar só, sem espalhar time.Now() pelos métodos do MongoDB
+// - Vale para qualquer implementação (MongoDB hoje, outra amanhã)
+// - Desligar é não envolver: main.go decide (SLOW_QUERY_THRESHOLD=0)
+//
+// CUSTO:
+// - Abaixo do limite, só um time.Now() no início e um time.Since() no fim
+//
+// O tempo medido é o que o usecase esperou: inclui o retry e a espera por uma conexão do pool
+type SlowQueryRepository struct {
+	next      domain.UserRepository
+	threshold time.Duration
+	logger    *slog.Logger
+}
+
+// NewSlowQueryRepository envolve next; operações acima de threshold são registradas em logger
+func NewSlowQueryRepository(next domain.UserRepository, threshold time.Duration, logger *slog.Logger) domain.UserRepository {
+	return &SlowQueryRepository{
+		next:      next,
+		threshold: threshold,
+		logger:    logger.With("component", "repository"),
+	}
+}
+
+// observe registra a operação se ela passou do limite
+// Uso: defer r.observe("create", time.Now()) - o time.Now() é avaliado na linha do defer
+func (r *SlowQueryRepository) observe(operation string, start time.Time) {
+	if elapsed := time.Since(start); elapsed > r.threshold {
+		r.logger.Warn("slow query",
+			"operation", operation,
+			"duration_ms", elapsed.Milliseconds(),
+			"threshold_ms", r.threshold.Milliseconds(),
+		)
+	}
+}
+
+func (r *SlowQueryRepository) Create(ctx context.Context, user *domain.User) error {
+	defer r.observe("create", time.Now())
+	return r.next.Create(ctx, user)
+}
+
+func (r *SlowQueryRepository) Upsert(ctx context.Context, user *domain.User) (*domain.User, error) {
+	defer r.observe("upsert", time.Now())
+	return r.next.Upsert(ctx, user)
+}
+
+func (r *SlowQueryRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	defer r.observe("get_by_id", time.Now())
+	return r.next.GetByID(ctx, id)
+}
+
+func (r *SlowQueryRepository) List(ctx context.Context, filter domain.UserFilter) ([]*domain.User, error) {
+	defer r.observe("list", time.Now())
+	return r.next.List(ctx, filter)
+}
+
+func (r *SlowQueryRepository) ListAfter(ctx context.Context, filter domain.UserFilter, after string, limit int) ([]*domain.User, error) {
+	defer r.observe("list_after", time.Now())
+	return r.next.ListAfter(ctx, filter, after, limit)
+}
+
+func (r *SlowQueryRepository) ListOffset(ctx context.Context, filter domain.UserFilter, offset, limit int) ([]*domain.User, error) {
+	defer r.observe("list_offset", time.Now())
+	return r.next.ListOffset(ctx, filter, offset, limit)
+}
+
+func (r *SlowQueryRepository) Search(ctx context.Context, criteria domain.SearchCriteria) ([]*domain.User, int64, error) {
+	defer r.observe("search", time.Now())
+	return r.next.Search(ctx, criteria)
+}
+
+// Stream e ListIDs chamam fn para cada documento: o tempo total inclui o trabalho de quem consome
+// (ex: escrever a resposta para um cliente lento) e não diz nada sobre o banco. Não são medidos
+func (r *SlowQueryRepository) Stream(ctx context.Context, filter domain.UserFilter, fn func(*domain.User) error) error {
+	return r.next.Stream(ctx, filter, fn)
+}
+
+func (r *SlowQueryRepository) ListIDs(ctx context.Context, after string, limit int, fn func(id string) error) error {
+	return r.next.ListIDs(ctx, after, limit, fn)
+}
+
+func (r *SlowQueryRepository) EmailExists(ctx context.Context, email string) (bool, error) {
+	defer r.observe("email_exists", time.Now())
+	return r.next.EmailExists(ctx, email)
+}
+
+func (r *SlowQueryRepository) Count(ctx context.Context, filter domain.UserFilter) (int64, error) {
+	defer r.observe("count", time.Now())
+	return r.next.Count(ctx, filter)
+}
+
+func (r *SlowQueryRepository) Update(ctx context.Context, user *domain.User) error {
+	defer r.observe("update", time.Now())
+	return r.next.Update(ctx, user)
+}
+
+func (r *SlowQueryRepository) Delete(ctx context.Context, id string) error {
+	defer r.observe("delete", time.Now())
+	return r.next.Delete(ctx, id)
+}
+
+func (r *SlowQueryRepository) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, []string, error) {
+	defer r.observe("get_by_ids", time.Now())
+	return r.next.GetByIDs(ctx, ids)
+}
+
+func (r *SlowQueryRepository) EnsureIndexes(ctx context.Context) error {
+	defer r.observe("ensure_indexes", time.Now())
+	return r.next.EnsureIndexes(ctx)
+}
+
+func (r *SlowQueryRepository) DropAll(ctx context.Context) error {
+	defer r.observe("drop_all", time.Now())
+	return r.next.DropAll(ctx)
+}
+
+func (r *SlowQueryRepository) PurgeDeletedBefore(ctx context.Context, t time.Time) (int64, error) {
+	defer r.observe("purge_deleted", time.Now())
+	return r.next.PurgeDeletedBefore(ctx, t)
+}
+
+func (r *SlowQueryRepository) MarkVerified(ctx context.Context, id, email string) error {
+	defer r.observe("mark_verified", time.Now())
+	return r.next.MarkVerified(ctx, id, email)
+}
+
+func (r *SlowQueryRepository) IncrementField(ctx context.Context, id, field string, delta int) error {
+	defer r.observe("increment_field", time.Now())
+	return r.next.IncrementField(ctx, id, field, delta)
+}
+
+func (r *SlowQueryRepository) DeleteMany(ctx context.Context, ids []string) (int64, []string, error) {
+	defer r.observe("delete_many", time.Now())
+	return r.next.DeleteMany(ctx, ids)
+}
+
+func (r *SlowQueryRepository) UpdateMany(ctx context.Context, filter domain.BulkUpdateFilter, changes domain.BulkUpdateChanges, maxMatched int64) (int64, int64, error) {
+	defer r.observe("update_many", time.Now())
+	return r.next.UpdateMany(ctx, filter, changes, maxMatched)
+}
+
+// Watch não é medido: o stream dura enquanto o cliente estiver conectado
+func (r *SlowQueryRepository) Watch(ctx context.Context) (domain.UserChangeStream, error) {
+	return r.next.Watch(ctx)
+}
+
+// WithTransaction não é medido: a duração inclui fn, e as operações feitas dentro dela
+// passam por este mesmo decorator (o usecase chama r, não o repositório de dentro)
+func (r *SlowQueryRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.next.WithTransaction(ctx, fn)
+}
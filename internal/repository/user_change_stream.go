@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"user-api/internal/domain"
+	"user-api/internal/usecase"
+)
+
+// ============================================
+// CHANGE STREAM (ALTERAÇÕES EM TEMPO REAL)
+// ============================================
+// Watch abre um change stream na collection de usuários: o MongoDB avisa cada escrita assim que ela acontece
+//
+// COMO AS OPERAÇÕES VIRAM EVENTOS:
+// - insert → UserCreated
+// - update/replace → UserUpdated; se o update gravou deletedAt (soft delete) → UserDeleted
+// - delete (o purge apagando de vez um usuário já removido) não vira evento: o cliente já recebeu o UserDeleted
+// - Alterações em usuários já removidos também são ignoradas
+//
+// POR QUE fullDocument: updateLookup?
+// - Sem ele, um update traz só os campos alterados; o cliente quer o usuário inteiro
+// - O servidor busca o documento atual: se ele mudou de novo nesse meio tempo, vem a versão mais nova
+// - Se ele já foi apagado pelo purge, o evento é descartado
+//
+// POR QUE CHECAR O REPLICA SET ANTES?
+// - Change streams dependem do oplog, que só existe em replica set ou cluster shardeado
+// - Em um standalone o erro do driver é pouco claro: isReplicated (o mesmo das transações) decide antes
+//
+// Como o Stream, não aplica o timeout por operação nem passa pelo PoolGate: o stream dura até ctx ser cancelado
+// Com tenant no context, observa só a collection do tenant
+func (r *UserMongoRepository) Watch(ctx context.Context) (domain.UserChangeStream, error) {
+	replicated, err := r.isReplicated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !replicated {
+		return nil, usecase.ErrChangeStreamsUnsupported
+	}
+
+	coll, err := r.coll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace"}}}}},
+	}
+	cs, err := coll.Watch(ctx, pipeline, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	if err != nil {
+		return nil, err
+	}
+	return &userChangeStream{cs: cs}, nil
+}
+
+// changeEvent é a parte do evento do change stream que interessa
+type changeEvent struct {
+	OperationType     string   `bson:"operationType"`
+	FullDocument      *userDoc `bson:"fullDocument"`
+	UpdateDescription struct {
+		UpdatedFields bson.M `bson:"updatedFields"`
+	} `bson:"updateDescription"`
+}
+
+// userChangeStream adapta o *mongo.ChangeStream para domain.UserChangeStream
+type userChangeStream struct {
+	cs     *mongo.ChangeStream
+	change domain.UserChange
+	err    error // Erro de decodificação (os erros do banco ficam em cs.Err)
+}
+
+// Next avança até o próximo evento que vira uma alteração (ver Watch), pulando os descartados
+func (s *userChangeStream) Next(ctx context.Context) bool {
+	for s.cs.Next(ctx) {
+		var ev changeEvent
+		if err := s.cs.Decode(&ev); err != nil {
+			s.err = err
+			return false
+		}
+		if change, ok := toUserChange(ev); ok {
+			s.change = change
+			return true
+		}
+	}
+	return false
+}
+
+func (s *userChangeStream) Change() domain.UserChange {
+	return s.change
+}
+
+func (s *userChangeStream) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.cs.Err()
+}
+
+func (s *userChangeStream) Close(ctx context.Context) error {
+	return s.cs.Close(ctx)
+}
+
+// toUserChange traduz um evento do change stream; ok é false para os eventos descartados
+func toUserChange(ev changeEvent) (domain.UserChange, bool) {
+	doc := ev.FullDocument
+	if doc == nil {
+		return domain.UserChange{}, false
+	}
+
+	switch {
+	case ev.OperationType == "insert":
+		return domain.UserChange{Type: domain.UserCreated, User: doc.toDomain()}, true
+	case ev.UpdateDescription.UpdatedFields["deletedAt"] != nil:
+		return domain.UserChange{Type: domain.UserDeleted, User: doc.toDomain()}, true
+	case doc.DeletedAt != nil:
+		return domain.UserChange{}, false
+	default:
+		return domain.UserChange{Type: domain.UserUpdated, User: doc.toDomain()}, true
+	}
+}
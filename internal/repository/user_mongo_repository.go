@@ -2,11 +2,16 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"regexp"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 
 	"user-api/internal/domain"
 	"user-api/internal/usecase"
@@ -20,8 +25,9 @@ import (
 //
 // SOBRE AS TAGS BSON:
 // - `bson:"_id,omitempty"` significa:
-//   * O campo ID no Go vira "_id" no MongoDB
-//   * omitempty: se o campo estiver vazio, não inclui no documento
+//   - O campo ID no Go vira "_id" no MongoDB
+//   - omitempty: se o campo estiver vazio, não inclui no documento
+//
 // - `bson:"name"` mapeia o campo Name para "name" no MongoDB
 //
 // POR QUE TER DUAS ESTRUTURAS (userDoc e domain.User)?
@@ -30,9 +36,66 @@ import (
 // - Fazemos conversão entre elas (isso é responsabilidade do repository)
 // - Isso mantém o domínio independente do banco de dados
 type userDoc struct {
-	ID    primitive.ObjectID `bson:"_id,omitempty"`  // ObjectID é o tipo nativo do MongoDB
-	Name  string             `bson:"name"`
-	Email string             `bson:"email"`
+	ID        primitive.ObjectID `bson:"_id,omitempty"` // ObjectID é o tipo nativo do MongoDB
+	Name      string             `bson:"name"`
+	Email     string             `bson:"email"`
+	Emails    []emailDoc         `bson:"emails,omitempty"`       // Todos os endereços (o principal também fica em email)
+	Pending   string             `bson:"pendingEmail,omitempty"` // Troca de email aguardando verificação (fora do índice único)
+	Phone     string             `bson:"phone,omitempty"`        // Opcional: ausente quando vazio
+	Verified  bool               `bson:"verified"`               // Email principal confirmado (documentos antigos: false)
+	Metadata  map[string]string  `bson:"metadata,omitempty"`     // Atributos livres (chaves sem "." e "$")
+	Version   int                `bson:"version"`                // Documentos antigos não têm o campo (lido como 0)
+	Logins    int64              `bson:"loginCount,omitempty"`   // Contador de logins (só muda por $inc; ausente = 0)
+	CreatedAt time.Time          `bson:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt,omitempty"` // Última escrita (documentos antigos: ausente até a próxima)
+	DeletedAt *time.Time         `bson:"deletedAt,omitempty"` // Soft delete: preenchido quando o usuário é removido
+}
+
+// emailDoc é um item da lista de emails no MongoDB
+type emailDoc struct {
+	Address string `bson:"address"`
+	Primary bool   `bson:"primary"`
+}
+
+// toDomain converte o documento do MongoDB para a entidade do domínio
+// Centralizar a conversão evita esquecer campos em algum dos métodos
+func (d *userDoc) toDomain() *domain.User {
+	// Documentos antigos não têm createdAt: usamos o horário embutido no ObjectID
+	// (os 4 primeiros bytes de um ObjectID são o timestamp de criação)
+	createdAt := d.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = d.ID.Timestamp()
+	}
+	// Sem updatedAt, o documento não foi alterado desde antes do campo existir: vale a criação
+	updatedAt := d.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = createdAt
+	}
+
+	// Documentos anteriores à lista de emails só têm "email":
+	// a lista é montada com ele como principal
+	emails := make([]domain.EmailAddress, 0, len(d.Emails))
+	for _, e := range d.Emails {
+		emails = append(emails, domain.EmailAddress{Address: e.Address, Primary: e.Primary})
+	}
+	if len(emails) == 0 && d.Email != "" {
+		emails = append(emails, domain.EmailAddress{Address: d.Email, Primary: true})
+	}
+
+	return &domain.User{
+		ID:           d.ID.Hex(), // Converte ObjectID para string hex
+		Name:         d.Name,
+		Email:        d.Email,
+		Emails:       emails,
+		PendingEmail: d.Pending,
+		Phone:        d.Phone,
+		Verified:     d.Verified,
+		Metadata:     d.Metadata,
+		Version:      d.Version,
+		LoginCount:   d.Logins,
+		CreatedAt:    createdAt.UTC(),
+		UpdatedAt:    updatedAt.UTC(),
+	}
 }
 
 // ============================================
@@ -44,10 +107,20 @@ type userDoc struct {
 // - collection é um ponteiro para a collection do MongoDB
 // - Collection é como uma "tabela" no MongoDB
 // - Todas as operações (insert, find, update, delete) usam esta collection
+// - Com multi-tenancy, cada tenant tem a sua (ver coll)
 type UserMongoRepository struct {
-	collection *mongo.Collection  // Ponteiro para a collection "users" do MongoDB
+	collection *mongo.Collection // Ponteiro para a collection de usuários do MongoDB (sem tenant)
+	opTimeout  time.Duration     // Prazo de cada operação simples (MONGO_OP_TIMEOUT)
+	retry      RetryPolicy       // Novas tentativas em erros passageiros (failover, rede)
+	gate       *PoolGate         // Espera máxima por uma conexão do pool (nil = sem limite)
+	indexed    sync.Map          // Tenants cujas collections já têm os índices (tenant → struct{})
+
+	readFromSecondary bool // Listagens, buscas e contagens leem de secundários (ver readColl)
 }
 
+// DefaultOpTimeout é o prazo usado quando nenhum timeout de operação é informado
+const DefaultOpTimeout = 5 * time.Second
+
 // NewUserMongoRepository cria um repositório MongoDB
 //
 // PARÂMETRO db *mongo.Database:
@@ -58,20 +131,105 @@ type UserMongoRepository struct {
 // RETORNO &UserMongoRepository{...}:
 // - O & cria um ponteiro para a struct UserMongoRepository
 // - Retornamos ponteiro porque:
-//   1. Evita cópia da struct (mais eficiente)
-//   2. Permite que métodos modifiquem o estado interno (se necessário)
-//   3. É padrão em Go retornar ponteiros de structs
+//  1. Evita cópia da struct (mais eficiente)
+//  2. Permite que métodos modifiquem o estado interno (se necessário)
+//  3. É padrão em Go retornar ponteiros de structs
+//
+// PARÂMETRO collectionName:
+// - Nome da collection onde os usuários são salvos (padrão "users" via config)
+// - Permite rodar vários ambientes no mesmo cluster com collections diferentes
+//
+// PARÂMETRO opTimeout:
+// - Prazo máximo de cada operação simples (buscar, inserir, atualizar...)
+// - Zero ou negativo usa DefaultOpTimeout (5s)
+// - Índices (10s) e operações em massa como o purge (30s) mantêm prazos próprios, maiores
+//
+// COMO SE COMBINA COM O PRAZO DA REQUISIÇÃO?
+// - context.WithTimeout nunca ESTENDE o prazo do context pai: vale o que vencer primeiro
+// - Com REQUEST_TIMEOUT=2s e opTimeout=5s, a consulta é cancelada em 2s (e a resposta é 503)
+// - Com REQUEST_TIMEOUT=30s e opTimeout=5s, uma consulta travada é cancelada em 5s
+//
+// PARÂMETRO retry:
+// - Quantas vezes repetir uma operação que falhou por erro passageiro (veja RetryPolicy)
+// - Todas as tentativas cabem no mesmo opTimeout
+//
+// PARÂMETRO gate:
+// - Quanto uma operação espera por uma conexão livre do pool (veja PoolGate); nil = sem limite
+//
+// PARÂMETRO readFromSecondary:
+// - Manda listagens, buscas e contagens para secundários (READ_FROM_SECONDARY, veja readColl)
+// - GetByID e as escritas continuam no primário
 //
 // POR QUE RETORNAR domain.UserRepository (interface)?
 // - Retornamos a interface, não o tipo concreto
 // - Isso permite que o código que usa não dependa de MongoDB
 // - Se mudarmos para PostgreSQL, só mudamos esta implementação
-func NewUserMongoRepository(db *mongo.Database) domain.UserRepository {
+func NewUserMongoRepository(db *mongo.Database, collectionName string, opTimeout time.Duration, retry RetryPolicy, gate *PoolGate, readFromSecondary bool) domain.UserRepository {
+	if opTimeout <= 0 {
+		opTimeout = DefaultOpTimeout
+	}
 	return &UserMongoRepository{
-		collection: db.Collection("users"),  // Obtém a collection "users"
+		collection: db.Collection(collectionName),
+		opTimeout:  opTimeout,
+		retry:      retry,
+		gate:       gate,
+
+		readFromSecondary: readFromSecondary,
 	}
 }
 
+// do executa uma operação simples: ocupa uma vaga do pool (gate) e aplica o retry
+// Pool esgotado (sem vaga a tempo ou fila do driver estourada) vira usecase.ErrDatabaseBusy
+// Documento acima de 16MB vira usecase.ErrDocumentTooLarge (ver document_size.go)
+func (r *UserMongoRepository) do(ctx context.Context, fn func() error) error {
+	release, err := r.gate.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return asDocumentTooLarge(asPoolTimeout(r.retry.do(ctx, fn)))
+}
+
+// ============================================
+// COLLECTION POR TENANT
+// ============================================
+// coll devolve a collection da requisição:
+// - Sem tenant no context: a collection padrão (ex: "users")
+// - Com tenant: uma collection só dele, "<collection>_<tenant>" (ex: "users_acme")
+//
+// POR QUE UMA COLLECTION POR TENANT (E NÃO UM CAMPO tenantId)?
+// - O isolamento não depende de lembrar o filtro: nenhuma consulta "esquece" o tenant e vaza dados
+// - Índices, contagens e o DropAll de um tenant não enxergam os outros
+// - O índice único de emails vale por tenant: o mesmo email pode existir em dois tenants
+//
+// Na primeira requisição de cada tenant, os índices da collection nova são criados
+// (CreateMany é idempotente; depois disso o mapa indexed evita repetir a ida ao banco)
+func (r *UserMongoRepository) coll(ctx context.Context) (*mongo.Collection, error) {
+	tenant, ok := domain.TenantFromContext(ctx)
+	if !ok {
+		return r.collection, nil
+	}
+
+	c := r.collection.Database().Collection(r.collection.Name() + "_" + tenant)
+	if _, done := r.indexed.Load(tenant); !done {
+		if err := createIndexes(ctx, c); err != nil {
+			return nil, err
+		}
+		r.indexed.Store(tenant, struct{}{})
+	}
+	return c, nil
+}
+
+// tenantScoped prefixa key com o tenant da requisição (se houver): "acme/507f..."
+// Usado onde os tenants dividem a mesma estrutura (cache, chaves de idempotência)
+func tenantScoped(ctx context.Context, key string) string {
+	if tenant, ok := domain.TenantFromContext(ctx); ok {
+		return tenant + "/" + key
+	}
+	return key
+}
+
 // ============================================
 // CREATE
 // ============================================
@@ -82,36 +240,72 @@ func NewUserMongoRepository(db *mongo.Database) domain.UserRepository {
 // - Recebe um ponteiro para poder MODIFICAR o campo ID
 // - Quando o MongoDB gera o ID, precisamos colocá-lo de volta no user
 // - Se recebêssemos domain.User (valor), modificaríamos apenas uma cópia
-func (r *UserMongoRepository) Create(user *domain.User) error {
+func (r *UserMongoRepository) Create(ctx context.Context, user *domain.User) error {
 	// Context com timeout evita que a operação trave indefinidamente
 	// Se o MongoDB estiver lento ou travado, após 5 segundos a operação cancela
 	//
 	// SOBRE CONTEXT:
-	// - context.Background() cria um contexto vazio (raiz)
-	// - WithTimeout adiciona um timeout de 5 segundos
+	// - ctx vem da requisição HTTP (carrega cancelamento e deadline do cliente)
+	// - WithTimeout cria um contexto "filho" com timeout de 5 segundos
+	// - Vale o prazo que acabar PRIMEIRO: o da requisição ou os 5 segundos
 	// - cancel() é uma função para cancelar manualmente (se necessário)
 	// - defer cancel() garante que o contexto seja cancelado ao final
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
 	defer cancel()
 
 	// Converte a entidade do domínio (domain.User) para o formato do MongoDB (userDoc)
-	// Note: não incluímos o ID porque o MongoDB vai gerar automaticamente
-	// O campo ID em userDoc tem tag `omitempty`, então será ignorado se vazio
+	// O ID é preenchido logo abaixo, antes do InsertOne (veja o comentário sobre retry)
+	// Todo usuário começa na versão 1; cada Update incrementa
+	user.Version = 1
+	// O MongoDB guarda datas com precisão de milissegundos
+	user.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	user.UpdatedAt = user.CreatedAt
 	doc := userDoc{
-		Name:  user.Name,
-		Email: user.Email,
-		// ID não é definido - MongoDB vai gerar automaticamente
+		Name:      user.Name,
+		Email:     user.Email,
+		Emails:    toEmailDocs(user.Emails),
+		Phone:     user.Phone,
+		Verified:  user.Verified,
+		Metadata:  user.Metadata,
+		Version:   user.Version,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
 	}
 
 	// Insere o documento no MongoDB
 	// InsertOne retorna um resultado com o ID gerado
-	result, err := r.collection.InsertOne(ctx, doc)
+	//
+	// POR QUE GERAR O _id AQUI?
+	// - Com retry, todas as tentativas enviam o MESMO documento
+	// - Se o driver gerasse o _id, cada tentativa teria um _id novo
+	// - Uma tentativa pode ter gravado o documento e só a resposta se perdeu (ex: rede caiu)
+	// - Com o mesmo _id, a tentativa seguinte esbarra na chave duplicada, em vez de criar uma cópia
+	doc.ID = primitive.NewObjectID()
+	coll, err := r.coll(ctx)
+	if err != nil {
+		return err
+	}
+	var result *mongo.InsertOneResult
+	err = r.do(ctx, func() error {
+		var err error
+		result, err = coll.InsertOne(ctx, doc)
+		return err
+	})
 	if err != nil {
-		return err  // Propaga o erro (ex: banco indisponível, conexão perdida)
+		// O índice único em emails.address rejeita endereços já usados por outro usuário
+		if mongo.IsDuplicateKeyError(err) {
+			// A chave duplicada pode ser o nosso próprio _id, gravado por uma tentativa anterior
+			if insertedByPreviousAttempt(ctx, coll, doc.ID) {
+				user.ID = doc.ID.Hex()
+				return nil
+			}
+			return usecase.ErrEmailTaken
+		}
+		return err // Propaga o erro (ex: banco indisponível, conexão perdida)
 	}
 
 	// Pega o ID gerado pelo MongoDB e converte para string hexadecimal
-	// 
+	//
 	// SOBRE A CONVERSÃO:
 	// - result.InsertedID é do tipo interface{} (tipo genérico)
 	// - Fazemos type assertion: .(primitive.ObjectID) para converter
@@ -131,13 +325,131 @@ func (r *UserMongoRepository) Create(user *domain.User) error {
 	return nil
 }
 
+// insertedByPreviousAttempt verifica se o documento com este _id já existe
+// Só é chamado após uma chave duplicada: o _id acabou de ser gerado, então
+// se ele existe, foi gravado por uma tentativa anterior deste mesmo Create
+func insertedByPreviousAttempt(ctx context.Context, coll *mongo.Collection, id primitive.ObjectID) bool {
+	n, err := coll.CountDocuments(ctx, bson.M{"_id": id})
+	return err == nil && n > 0
+}
+
+// ============================================
+// UPSERT
+// ============================================
+// Upsert atualiza o usuário com este email principal ou cria um novo, em UMA operação atômica
+//
+// SOBRE UPSERT:
+// - SetUpsert(true): se nenhum documento casa com o filtro, o MongoDB INSERE um novo
+// - $set vale nos dois casos (atualização e inserção)
+// - $setOnInsert só vale na inserção: _id, emails, verified e createdAt de um usuário novo
+// - $inc em um campo ausente parte de zero: version vira 1 na inserção e avança na atualização
+//
+// POR QUE FindOneAndUpdate E NÃO UpdateOne?
+// - Os dois aceitam upsert, mas UpdateOne só diz SE atualizou (MatchedCount/UpsertedID)
+// - Com ReturnDocument(Before), FindOneAndUpdate devolve o documento ANTERIOR na mesma operação:
+// ErrNoDocuments = foi inserido; um documento = foi atualizado (e é a base do diff e do audit log)
+// - Ler antes e atualizar depois seriam duas operações, com uma janela para outra escrita no meio
+//
+// O filtro é o email PRINCIPAL: um endereço secundário de outro usuário esbarra no índice único
+// de emails.address na inserção e vira ErrEmailTaken, como no Create
+func (r *UserMongoRepository) Upsert(ctx context.Context, user *domain.User) (*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	// Mesmo motivo do Create: todas as tentativas do retry inserem o MESMO _id
+	id := primitive.NewObjectID()
+	createdAt := time.Now().UTC().Truncate(time.Millisecond)
+
+	set := bson.M{"name": user.Name, "updatedAt": createdAt}
+	if user.Phone != "" {
+		set["phone"] = user.Phone
+	}
+	if user.Metadata != nil {
+		set["metadata"] = user.Metadata
+	}
+	update := bson.M{
+		"$set": set,
+		"$setOnInsert": bson.M{
+			"_id":       id,
+			"emails":    toEmailDocs(user.Emails),
+			"verified":  false,
+			"createdAt": createdAt,
+		},
+		"$inc": bson.M{"version": 1},
+	}
+	// emailCollation: "Foo@x.com" casa com o "foo@x.com" já gravado, como no EmailExists e no índice único
+	// (sem ela, o filtro não casaria, a inserção esbarraria no índice e o upsert viraria ErrEmailTaken)
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.Before).
+		SetCollation(emailCollation)
+
+	coll, err := r.coll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var before userDoc
+	upsert := func() error {
+		return r.do(ctx, func() error {
+			return coll.FindOneAndUpdate(ctx, notDeleted(bson.M{"email": user.Email}), update, opts).Decode(&before)
+		})
+	}
+	err = upsert()
+
+	// CORRIDA ENTRE DOIS UPSERTS DO MESMO EMAIL NOVO:
+	// - Os dois não encontram ninguém e tentam inserir; o índice único deixa só um passar
+	// - O outro recebe chave duplicada (código 11000), mas o esperado de um upsert é ATUALIZAR
+	// - Uma nova tentativa encontra o usuário recém-criado pelo filtro e o atualiza
+	// - Se a chave duplicada se repetir, o endereço é de fato de outro usuário (secundário ou removido)
+	//
+	// O MongoDB só refaz isso sozinho quando o filtro é uma igualdade no próprio campo do índice único;
+	// aqui o filtro é email + deletedAt e o índice é emails.address
+	if mongo.IsDuplicateKeyError(err) {
+		err = upsert()
+	}
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, usecase.ErrEmailTaken
+		}
+		return nil, err
+	}
+
+	// Inserido: nada casou (ErrNoDocuments) ou o documento "anterior" é o que uma tentativa
+	// anterior deste mesmo Upsert inseriu (a resposta se perdeu e o retry só reaplicou $set e $inc)
+	if err == mongo.ErrNoDocuments || before.ID == id {
+		user.ID = id.Hex()
+		user.Verified = false
+		user.Version = before.Version + 1
+		user.CreatedAt = createdAt
+		user.UpdatedAt = createdAt
+		return nil, nil
+	}
+
+	// Atualizado: o que não está em $set continua como estava
+	previous := before.toDomain()
+	user.ID = previous.ID
+	user.Emails = previous.Emails
+	user.Verified = previous.Verified
+	user.Version = previous.Version + 1
+	user.CreatedAt = previous.CreatedAt
+	user.UpdatedAt = createdAt
+	if user.Phone == "" {
+		user.Phone = previous.Phone
+	}
+	if user.Metadata == nil {
+		user.Metadata = previous.Metadata
+	}
+	return previous, nil
+}
+
 // ============================================
 // GET BY ID
 // ============================================
 // GetByID busca um usuário pelo ID
 // Retorna um ponteiro (*domain.User) para evitar copiar a struct
-func (r *UserMongoRepository) GetByID(id string) (*domain.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *UserMongoRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
 	defer cancel()
 
 	// Converte a string hexadecimal para ObjectID do MongoDB
@@ -153,8 +465,13 @@ func (r *UserMongoRepository) GetByID(id string) (*domain.User, error) {
 
 	// Declara uma variável do tipo userDoc (vazia)
 	// O Decode vai preencher esta struct com os dados do MongoDB
+	coll, err := r.coll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var doc userDoc
-	
+
 	// Busca o documento no MongoDB e decodifica no struct doc
 	//
 	// SOBRE bson.M{"_id": oid}:
@@ -166,7 +483,16 @@ func (r *UserMongoRepository) GetByID(id string) (*domain.User, error) {
 	// - Decode converte o documento BSON do MongoDB para a struct Go
 	// - O & passa um ponteiro para doc, permitindo que Decode preencha os campos
 	// - Se não passar ponteiro, Decode não conseguiria modificar doc
-	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&doc)
+	//
+	// mongo.ErrNoDocuments não é passageiro: retry.do devolve na primeira tentativa
+	//
+	// SEM notDeleted, AO CONTRÁRIO DAS OUTRAS CONSULTAS:
+	// - Buscamos também os removidos para distinguir "nunca existiu" (ErrNotFound, 404)
+	// de "existiu e foi removido" (ErrGone, 410)
+	// - Depois do purge o documento some de vez e a resposta volta a ser ErrNotFound
+	err = r.do(ctx, func() error {
+		return coll.FindOne(ctx, bson.M{"_id": oid}).Decode(&doc)
+	})
 	if err != nil {
 		// Se não encontrar documento, retorna erro específico
 		if err == mongo.ErrNoDocuments {
@@ -175,6 +501,9 @@ func (r *UserMongoRepository) GetByID(id string) (*domain.User, error) {
 		// Outros erros (ex: conexão perdida) são propagados
 		return nil, err
 	}
+	if doc.DeletedAt != nil {
+		return nil, usecase.ErrGone
+	}
 
 	// Converte de volta para a entidade do domínio
 	// Retornamos um ponteiro usando & para evitar cópia
@@ -183,32 +512,274 @@ func (r *UserMongoRepository) GetByID(id string) (*domain.User, error) {
 	// - domain.User pode crescer (adicionar mais campos)
 	// - Retornar ponteiro é mais eficiente (não copia a struct)
 	// - Permite que o chamador modifique se necessário (embora não façamos isso)
-	return &domain.User{
-		ID:    doc.ID.Hex(),      // Converte ObjectID para string hex
-		Name:  doc.Name,
-		Email: doc.Email,
-	}, nil
+	return doc.toDomain(), nil
 }
 
 // ============================================
 // LIST
 // ============================================
-// List retorna todos os usuários
+// List retorna os usuários que atendem ao filtro
 // Retorna []*domain.User (slice de ponteiros) - mais eficiente que []domain.User
-func (r *UserMongoRepository) List() ([]*domain.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *UserMongoRepository) List(ctx context.Context, filter domain.UserFilter) ([]*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
 	defer cancel()
 
-	// Busca todos os documentos
-	// bson.M{} significa "sem filtro" (equivalente a SELECT * FROM users)
+	// Busca os documentos que atendem ao filtro
+	// Filtro vazio vira bson.M{} - "sem filtro" (equivalente a SELECT * FROM users)
 	// Find retorna um Cursor, que é um iterador sobre os resultados
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	// SetProjection limita os campos que o MongoDB envia (nil = todos)
+	opts := options.Find().SetProjection(buildProjection(filter.Fields))
+	if !filter.UpdatedSince.IsZero() {
+		opts.SetSort(listSort(filter))
+	}
+	return r.findUsers(ctx, readList, buildFilter(filter), opts)
+}
+
+// findUsers executa o Find e decodifica todos os documentos, com retry
+//
+// A tentativa inclui a leitura do cursor: se a conexão cair no meio,
+// a consulta inteira recomeça (nada foi devolvido ao chamador ainda)
+// target escolhe de onde a leitura vem (ver readColl)
+func (r *UserMongoRepository) findUsers(ctx context.Context, target readTarget, query bson.M, opts *options.FindOptions) ([]*domain.User, error) {
+	coll, err := r.readColl(ctx, target)
 	if err != nil {
 		return nil, err
 	}
-	// Garante que o cursor seja fechado ao final (libera recursos)
-	defer cursor.Close(ctx)
 
+	var users []*domain.User
+	err = r.do(ctx, func() error {
+		// Find retorna um Cursor, que é um iterador sobre os resultados
+		cursor, err := coll.Find(ctx, query, opts)
+		if err != nil {
+			return err
+		}
+		// Garante que o cursor seja fechado ao final (libera recursos)
+		defer cursor.Close(ctx)
+
+		users, err = decodeUsers(ctx, cursor)
+		return err
+	})
+	return users, err
+}
+
+// ============================================
+// LIST AFTER (PAGINAÇÃO POR CURSOR)
+// ============================================
+// ListAfter retorna até limit usuários com _id MAIOR que o cursor "after"
+//
+// POR QUE CURSOR EM VEZ DE OFFSET?
+//   - Com offset (pular N), inserções/remoções no meio da iteração fazem
+//     itens serem pulados ou repetidos entre as páginas
+//   - Com cursor, cada página começa exatamente após o último _id visto
+//
+// IMPORTANTE: o cursor só funciona com ordenação por _id
+// A ordem é a de criação (ObjectIDs são crescentes no tempo), não alfabética
+func (r *UserMongoRepository) ListAfter(ctx context.Context, filter domain.UserFilter, after string, limit int) ([]*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	query := buildFilter(filter)
+	if after != "" && !filter.UpdatedSince.IsZero() {
+		if err := afterUpdated(query, after); err != nil {
+			return nil, err
+		}
+	} else if after != "" {
+		oid, err := primitive.ObjectIDFromHex(after)
+		if err != nil {
+			return nil, usecase.ErrInvalidCursor
+		}
+		// $gt: "maior que" - começa logo depois do último _id da página anterior
+		query["_id"] = bson.M{"$gt": oid}
+	}
+
+	opts := options.Find().
+		SetSort(listSort(filter)).
+		SetLimit(int64(limit)).
+		SetProjection(buildProjection(filter.Fields))
+
+	return r.findUsers(ctx, readList, query, opts)
+}
+
+// afterUpdated acrescenta à query o cursor da ordem (updatedAt, _id) - ver domain.UpdatedCursor
+// "Depois de (t, id)" = alterado depois de t, ou alterado em t com _id maior
+func afterUpdated(query bson.M, after string) error {
+	updatedAt, id, ok := domain.ParseUpdatedCursor(after)
+	if !ok {
+		return usecase.ErrInvalidCursor
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return usecase.ErrInvalidCursor
+	}
+	query["$or"] = bson.A{
+		bson.M{"updatedAt": bson.M{"$gt": updatedAt}},
+		bson.M{"updatedAt": updatedAt, "_id": bson.M{"$gt": oid}},
+	}
+	return nil
+}
+
+// ============================================
+// LIST OFFSET (PAGINAÇÃO POR OFFSET)
+// ============================================
+// ListOffset pula os offset primeiros usuários e retorna até limit
+//
+// SOBRE SKIP:
+// - Skip(N) faz o MongoDB percorrer e DESCARTAR N documentos
+// - Páginas distantes ficam mais lentas (offset 10000 lê 10000 documentos à toa)
+// - Em troca, dá para ir direto a qualquer página e voltar (a paginação por cursor só avança)
+//
+// A ordenação por _id (ou updatedAt + _id) garante que as páginas sejam estáveis entre requisições
+func (r *UserMongoRepository) ListOffset(ctx context.Context, filter domain.UserFilter, offset, limit int) ([]*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(listSort(filter)).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)).
+		SetProjection(buildProjection(filter.Fields))
+
+	return r.findUsers(ctx, readList, buildFilter(filter), opts)
+}
+
+// ============================================
+// SEARCH
+// ============================================
+// Search busca uma página de usuários pelos critérios e conta o total de resultados
+//
+// A QUERY COMPOSTA (bson.M com uma chave por critério = E lógico):
+//
+//	{
+//	  "deletedAt": null,
+//	  "name":  {"$regex": "jo", "$options": "i"},
+//	  "email": {"$regex": "example\\.com", "$options": "i"},
+//	  "$or": [
+//	    {"createdAt": {"$gte": after, "$lt": before}},
+//	    {"createdAt": {"$exists": false}, "_id": {"$gte": ObjectID(after), "$lt": ObjectID(before)}}
+//	  ]
+//	}
+//
+// POR QUE O $or NAS DATAS?
+// - Documentos antigos não têm createdAt: a data de criação vem do ObjectID (ver toDomain)
+// - O segundo ramo compara o _id com ObjectIDs gerados a partir das datas (precisão de segundos)
+//
+// ORDENAÇÃO: o _id entra sempre como desempate, para que as páginas sejam estáveis
+// (dois usuários com o mesmo nome nunca trocam de página entre requisições)
+// created_at ordena pelo próprio _id: é a ordem de criação e vale também para documentos antigos
+func (r *UserMongoRepository) Search(ctx context.Context, criteria domain.SearchCriteria) ([]*domain.User, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	query := buildSearchQuery(criteria)
+
+	direction := 1
+	if criteria.Order == "desc" {
+		direction = -1
+	}
+	sort := bson.D{{Key: "_id", Value: direction}}
+	if criteria.Sort == "name" || criteria.Sort == "email" {
+		sort = bson.D{{Key: criteria.Sort, Value: direction}, {Key: "_id", Value: direction}}
+	}
+
+	opts := options.Find().
+		SetSort(sort).
+		SetSkip(int64(criteria.Offset)).
+		SetLimit(int64(criteria.Limit))
+
+	users, err := r.findUsers(ctx, readList, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	coll, err := r.readColl(ctx, readList)
+	if err != nil {
+		return nil, 0, err
+	}
+	var total int64
+	err = r.do(ctx, func() error {
+		var err error
+		total, err = coll.CountDocuments(ctx, query)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// buildSearchQuery monta a query composta de Search (ver o exemplo acima)
+// Critérios vazios simplesmente não entram na query
+func buildSearchQuery(criteria domain.SearchCriteria) bson.M {
+	query := notDeleted(bson.M{})
+	if criteria.Name != "" {
+		query["name"] = primitive.Regex{Pattern: regexp.QuoteMeta(criteria.Name), Options: "i"}
+	}
+	if criteria.Email != "" {
+		query["email"] = primitive.Regex{Pattern: regexp.QuoteMeta(criteria.Email), Options: "i"}
+	}
+
+	if criteria.CreatedAfter.IsZero() && criteria.CreatedBefore.IsZero() {
+		return query
+	}
+	createdAt, byID := bson.M{}, bson.M{}
+	if !criteria.CreatedAfter.IsZero() {
+		createdAt["$gte"] = criteria.CreatedAfter
+		byID["$gte"] = primitive.NewObjectIDFromTimestamp(criteria.CreatedAfter)
+	}
+	if !criteria.CreatedBefore.IsZero() {
+		createdAt["$lt"] = criteria.CreatedBefore
+		byID["$lt"] = primitive.NewObjectIDFromTimestamp(criteria.CreatedBefore)
+	}
+	query["$or"] = bson.A{
+		bson.M{"createdAt": createdAt},
+		bson.M{"createdAt": bson.M{"$exists": false}, "_id": byID},
+	}
+	return query
+}
+
+// bsonFieldNames traduz os nomes do JSON (domain.UserFieldNames) para os campos do documento
+var bsonFieldNames = map[string]string{
+	"id":          "_id",
+	"name":        "name",
+	"email":       "email",
+	"emails":      "emails",
+	"phone":       "phone",
+	"verified":    "verified",
+	"metadata":    "metadata",
+	"version":     "version",
+	"login_count": "loginCount",
+	"created_at":  "createdAt",
+	"updated_at":  "updatedAt",
+}
+
+// buildProjection monta a projeção do MongoDB a partir dos campos pedidos
+//
+// SOBRE PROJEÇÃO:
+// - {"name": 1, "email": 1} faz o MongoDB devolver só esses campos
+// - O _id vem SEMPRE, a menos que seja excluído explicitamente ({"_id": 0})
+// - Mantemos o _id: a paginação por cursor depende dele
+// - createdAt também vem sempre que pedido: documentos antigos usam o _id como fallback
+// - updated_at traz junto o createdAt, que é o fallback de documentos sem updatedAt
+//
+// Retorna nil (todos os campos) quando nenhum campo foi pedido
+func buildProjection(fields []string) bson.M {
+	if len(fields) == 0 {
+		return nil
+	}
+	projection := bson.M{}
+	for _, f := range fields {
+		if name, ok := bsonFieldNames[f]; ok {
+			projection[name] = 1
+		}
+		if f == "updated_at" {
+			projection["createdAt"] = 1
+		}
+	}
+	return projection
+}
+
+// decodeUsers percorre o cursor convertendo cada documento para domain.User
+func decodeUsers(ctx context.Context, cursor *mongo.Cursor) ([]*domain.User, error) {
 	// Cria um slice vazio de ponteiros para domain.User
 	// []*domain.User significa "slice de ponteiros para domain.User"
 	//
@@ -217,26 +788,22 @@ func (r *UserMongoRepository) List() ([]*domain.User, error) {
 	// - Com []*domain.User, apenas copiamos o ponteiro (8 bytes) em vez da struct
 	// - Mais eficiente, especialmente com muitos usuários
 	var users []*domain.User
-	
+
 	// Itera sobre o cursor convertendo cada documento
 	// cursor.Next() retorna true enquanto houver mais documentos
 	for cursor.Next(ctx) {
 		var doc userDoc
-		
+
 		// Decode converte o documento atual do cursor para a struct doc
 		// O & passa ponteiro para doc, permitindo que Decode preencha os campos
 		if err := cursor.Decode(&doc); err != nil {
 			return nil, err
 		}
-		
+
 		// Cria um novo domain.User e adiciona ao slice
 		// O & cria um ponteiro para a struct criada
 		// append adiciona o ponteiro ao slice (não copia a struct)
-		users = append(users, &domain.User{
-			ID:    doc.ID.Hex(),
-			Name:  doc.Name,
-			Email: doc.Email,
-		})
+		users = append(users, doc.toDomain())
 	}
 
 	// Verifica se houve erro durante a iteração do cursor
@@ -248,13 +815,200 @@ func (r *UserMongoRepository) List() ([]*domain.User, error) {
 	return users, nil
 }
 
+// ============================================
+// STREAM
+// ============================================
+// Stream percorre os usuários chamando fn para cada documento lido do cursor
+//
+// DIFERENÇA PARA List:
+// - List acumula todos os usuários em um slice (memória cresce com o total)
+// - Stream entrega um usuário por vez (memória constante)
+//
+// SOBRE O TIMEOUT:
+// - Não aplicamos o timeout fixo de 5 segundos: uma exportação grande demora mais
+// - O limite vem do ctx da requisição (cliente desconectou ou REQUEST_TIMEOUT)
+func (r *UserMongoRepository) Stream(ctx context.Context, filter domain.UserFilter, fn func(*domain.User) error) error {
+	opts := options.Find().
+		SetSort(listSort(filter)).
+		SetProjection(buildProjection(filter.Fields))
+
+	coll, err := r.readColl(ctx, readList)
+	if err != nil {
+		return err
+	}
+	cursor, err := coll.Find(ctx, buildFilter(filter), opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc userDoc
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		if err := fn(doc.toDomain()); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// ============================================
+// LIST IDS
+// ============================================
+// ListIDs percorre os IDs dos usuários em ordem de _id, a partir do cursor after
+//
+// POR QUE PROJEÇÃO {_id: 1}?
+// - Jobs de sincronização só comparam CONJUNTOS de IDs: nome, emails e metadata seriam tráfego à toa
+// - A ordenação por _id e a projeção são cobertas pelo índice de _id
+//
+// Como o Stream, lê do cursor um documento por vez e não aplica o timeout por operação:
+// sem limit, a varredura inteira pode demorar; o prazo vem do ctx da requisição
+func (r *UserMongoRepository) ListIDs(ctx context.Context, after string, limit int, fn func(id string) error) error {
+	query := notDeleted(bson.M{})
+	if after != "" {
+		oid, err := primitive.ObjectIDFromHex(after)
+		if err != nil {
+			return usecase.ErrInvalidCursor
+		}
+		query["_id"] = bson.M{"$gt": oid}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"_id": 1}).
+		SetLimit(int64(limit)) // 0 = sem limite
+
+	coll, err := r.readColl(ctx, readList)
+	if err != nil {
+		return err
+	}
+	cursor, err := coll.Find(ctx, query, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		if err := fn(doc.ID.Hex()); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// ============================================
+// COUNT
+// ============================================
+// Count retorna quantos documentos atendem ao filtro
+// CountDocuments conta no servidor - nenhum documento é transferido
+func (r *UserMongoRepository) Count(ctx context.Context, filter domain.UserFilter) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	coll, err := r.readColl(ctx, readList)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = r.do(ctx, func() error {
+		var err error
+		count, err = coll.CountDocuments(ctx, buildFilter(filter))
+		return err
+	})
+	return count, err
+}
+
+// ============================================
+// EMAIL EXISTS
+// ============================================
+// EmailExists verifica se o endereço já está na lista de emails de algum usuário
+//
+// POR QUE FindOne COM PROJEÇÃO EM VEZ DE GetByEmail?
+// - Só interessa SE existe: a projeção {_id: 1} faz o servidor devolver apenas o _id
+// - A consulta usa o índice único de emails.address; com a projeção, nada do resto do documento trafega
+// - CountDocuments contaria todos os que casam; FindOne para no primeiro
+//
+// A collation é a do índice (emailCollation): sem ela o índice não seria usado,
+// e "A@x.com" não encontraria "a@x.com", que o índice considera o mesmo endereço
+//
+// Sem notDeleted: o índice único também vale para usuários removidos,
+// então o email deles continua indisponível (o Create falharia com ErrEmailTaken)
+func (r *UserMongoRepository) EmailExists(ctx context.Context, email string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	coll, err := r.coll(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	opts := options.FindOne().SetProjection(bson.M{"_id": 1}).SetCollation(emailCollation)
+	err = r.do(ctx, func() error {
+		return coll.FindOne(ctx, bson.M{"emails.address": email}, opts).Err()
+	})
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// buildFilter converte domain.UserFilter para uma query do MongoDB
+// List e Count usam esta mesma função para que os resultados sejam consistentes
+//
+// SOBRE $regex:
+// - Faz busca parcial: "jo" encontra "João", "Jorge", "Marjorie"
+// - A opção "i" torna a busca case-insensitive
+// - regexp.QuoteMeta escapa caracteres especiais (ex: ".", "*") digitados pelo cliente
+// Usuários removidos (soft delete) nunca entram na listagem nem na contagem
+//
+// UpdatedSince vira {"updatedAt": {"$gte": t}}: documentos antigos, sem o campo,
+// só passam a casar depois da primeira escrita
+func buildFilter(filter domain.UserFilter) bson.M {
+	query := notDeleted(bson.M{})
+	if filter.Name != "" {
+		query["name"] = primitive.Regex{Pattern: regexp.QuoteMeta(filter.Name), Options: "i"}
+	}
+	if !filter.UpdatedSince.IsZero() {
+		query["updatedAt"] = bson.M{"$gte": filter.UpdatedSince}
+	}
+	return query
+}
+
+// listSort é a ordem da listagem: por _id (criação) ou, com UpdatedSince, por updatedAt
+// O _id desempata usuários alterados no mesmo milissegundo (ex: atualização em massa)
+func listSort(filter domain.UserFilter) bson.D {
+	if !filter.UpdatedSince.IsZero() {
+		return bson.D{{Key: "updatedAt", Value: 1}, {Key: "_id", Value: 1}}
+	}
+	return bson.D{{Key: "_id", Value: 1}}
+}
+
+// notDeleted acrescenta à query a condição "não foi removido"
+// {"deletedAt": nil} casa com documentos SEM o campo (ou com valor null)
+func notDeleted(query bson.M) bson.M {
+	query["deletedAt"] = nil
+	return query
+}
+
 // ============================================
 // UPDATE
 // ============================================
 // Update atualiza um usuário existente
 // Recebe *domain.User (ponteiro) com os campos já modificados pelo usecase
-func (r *UserMongoRepository) Update(user *domain.User) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *UserMongoRepository) Update(ctx context.Context, user *domain.User) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
 	defer cancel()
 
 	// Converte o ID (string hex) para ObjectID do MongoDB
@@ -275,35 +1029,97 @@ func (r *UserMongoRepository) Update(user *domain.User) error {
 	// e fizermos $set: {name: "Maria"}, o resultado será:
 	// {_id: ..., name: "Maria", email: "joao@email.com", age: 30}
 	// (email e age permanecem inalterados)
+	//
+	// SOBRE $inc:
+	// - Incrementa o campo numérico de forma atômica no servidor
+	// - Aqui usamos para avançar a versão a cada atualização
+	updatedAt := time.Now().UTC().Truncate(time.Millisecond)
+	set := bson.M{
+		"name":   user.Name,
+		"email":  user.Email,
+		"emails": toEmailDocs(user.Emails),
+		"phone":  user.Phone,
+		// Trocar o email principal volta verified para false (decisão do usecase)
+		"verified":  user.Verified,
+		"metadata":  user.Metadata,
+		"updatedAt": updatedAt,
+	}
 	update := bson.M{
-		"$set": bson.M{
-			"name":  user.Name,
-			"email": user.Email,
-		},
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	}
+	// Sem troca de email pendente, o campo sai do documento ($unset) em vez de ficar ""
+	if user.PendingEmail != "" {
+		set["pendingEmail"] = user.PendingEmail
+	} else {
+		update["$unset"] = bson.M{"pendingEmail": ""}
+	}
+
+	// OPTIMISTIC LOCKING:
+	// O filtro exige o _id E a versão que o usecase leu
+	// Se outra requisição atualizou o documento antes, a versão mudou e
+	// nenhum documento casa com o filtro - a atualização não acontece
+	//
+	// Documentos criados antes do controle de versão não têm o campo "version"
+	// ({"version": null} casa com campo ausente)
+	filter := notDeleted(bson.M{"_id": oid, "version": user.Version})
+	if user.Version == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
 	}
 
 	// Executa a atualização no MongoDB
-	// UpdateByID atualiza o documento com o _id especificado
-	result, err := r.collection.UpdateByID(ctx, oid, update)
+	//
+	// Se uma tentativa gravar e só a resposta se perder, a seguinte não casa mais com a
+	// versão antiga e o resultado é ErrVersionConflict: o cliente relê e decide (nada é gravado duas vezes)
+	coll, err := r.coll(ctx)
 	if err != nil {
 		return err
 	}
+	var result *mongo.UpdateResult
+	err = r.do(ctx, func() error {
+		result, err = coll.UpdateOne(ctx, filter, update)
+		return err
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return usecase.ErrEmailTaken
+		}
+		return err
+	}
 
-	// Verifica se algum documento foi encontrado e atualizado
-	// MatchedCount = 0 significa que o ID não existe no banco
+	// MatchedCount = 0 tem três causas possíveis:
+	// - o ID existe, mas a versão mudou → ErrVersionConflict
+	// - o usuário foi removido → ErrGone (o mesmo 410 do GetByID)
+	// - o ID não existe no banco → ErrNotFound
 	if result.MatchedCount == 0 {
-		return usecase.ErrNotFound
+		exists, err := coll.CountDocuments(ctx, notDeleted(bson.M{"_id": oid}))
+		if err != nil {
+			return err
+		}
+		if exists == 0 {
+			return missingError(ctx, coll, oid)
+		}
+		return usecase.ErrVersionConflict
 	}
 
+	// Reflete no user a versão (e a data) que acabou de ser gravada
+	user.Version++
+	user.UpdatedAt = updatedAt
 	return nil
 }
 
 // ============================================
-// DELETE
+// DELETE (SOFT DELETE)
 // ============================================
-// Delete remove um usuário
-func (r *UserMongoRepository) Delete(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// Delete marca o usuário como removido em vez de apagar o documento
+//
+// SOFT DELETE:
+// - Gravamos deletedAt com a data da remoção; o documento continua no banco
+// - Todas as consultas ignoram documentos com deletedAt (notDeleted)
+// - Para a API, o usuário deixa de existir (GET responde 410 Gone até o purge, e 404 depois)
+// - A remoção definitiva acontece depois do período de retenção (PurgeDeletedBefore)
+func (r *UserMongoRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
 	defer cancel()
 
 	// Converte o ID para ObjectID
@@ -312,18 +1128,542 @@ func (r *UserMongoRepository) Delete(id string) error {
 		return usecase.ErrNotFound
 	}
 
-	// Remove o documento do MongoDB
-	// DeleteOne remove apenas um documento (o primeiro que encontrar)
-	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	// O filtro notDeleted impede "remover de novo" um usuário já removido
+	// (a data original de remoção é preservada)
+	coll, err := r.coll(ctx)
+	if err != nil {
+		return err
+	}
+	update := bson.M{"$set": bson.M{"deletedAt": time.Now().UTC()}}
+	var result *mongo.UpdateResult
+	err = r.do(ctx, func() error {
+		result, err = coll.UpdateOne(ctx, notDeleted(bson.M{"_id": oid}), update)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// MatchedCount = 0 significa que o ID não existe (ErrNotFound) ou já foi removido (ErrGone)
+	if result.MatchedCount == 0 {
+		return missingError(ctx, coll, oid)
+	}
+
+	return nil
+}
+
+// missingError explica por que uma escrita com notDeleted não casou com o _id:
+// o usuário foi removido (ErrGone) ou não existe (ErrNotFound)
+// A consulta extra só acontece nesse caso, e pelo _id (o índice padrão)
+func missingError(ctx context.Context, coll *mongo.Collection, oid primitive.ObjectID) error {
+	removed, err := coll.CountDocuments(ctx, bson.M{"_id": oid, "deletedAt": bson.M{"$ne": nil}})
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		return usecase.ErrGone
+	}
+	return usecase.ErrNotFound
+}
+
+// ============================================
+// MARK VERIFIED
+// ============================================
+// MarkVerified marca o usuário como verificado, desde que email ainda seja o principal
+//
+// POR QUE FILTRAR PELO EMAIL?
+// - O token foi enviado para um endereço específico
+// - Se o usuário trocou o email principal depois disso, o token antigo não prova o endereço novo
+//
+// Incrementa a versão como qualquer outra alteração (ETag e optimistic locking continuam valendo)
+func (r *UserMongoRepository) MarkVerified(ctx context.Context, id, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return usecase.ErrNotFound
+	}
+
+	update := bson.M{
+		"$set": bson.M{"verified": true, "updatedAt": time.Now().UTC().Truncate(time.Millisecond)},
+		"$inc": bson.M{"version": 1},
+	}
+	coll, err := r.coll(ctx)
 	if err != nil {
 		return err
 	}
+	var result *mongo.UpdateResult
+	err = r.do(ctx, func() error {
+		// Mesma collation do índice de emails: o token vale para o endereço sem diferenciar maiúsculas
+		result, err = coll.UpdateOne(ctx, notDeleted(bson.M{"_id": oid, "email": email}), update, options.Update().SetCollation(emailCollation))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return usecase.ErrNotFound
+	}
+	return nil
+}
+
+// ============================================
+// INCREMENT FIELD
+// ============================================
+// counterFields lista os contadores aceitos por IncrementField: nome do JSON → campo do documento
+// Um campo fora daqui é recusado, então o $inc nunca atinge version, dados do usuário ou campos inventados
+var counterFields = map[string]string{
+	domain.CounterLoginCount: "loginCount",
+}
 
-	// Verifica se algum documento foi deletado
-	// DeletedCount = 0 significa que o ID não existe no banco
-	if result.DeletedCount == 0 {
+// IncrementField soma delta ao contador com $inc, em uma única operação no servidor
+//
+// POR QUE $inc E NÃO GetByID + Update?
+// - Ler, somar e gravar abre uma janela: dois logins simultâneos leem 5 e os dois gravam 6
+// - O $inc é atômico no documento: os dois incrementos valem (7), sem conflito de versão
+//
+// SOBRE version E updatedAt:
+// - Não mudam: um contador não é uma alteração dos dados do usuário
+// - Assim um login não faz o PUT de outro cliente falhar por versão desatualizada,
+// nem devolve o usuário na sincronização incremental (updatedSince)
+//
+// SOBRE O RETRY:
+// - O retry do próprio driver (retryWrites) aplica a escrita uma única vez
+// - O nosso (RetryPolicy) repete a operação: se só a resposta se perdeu num failover, o contador
+// pode somar duas vezes - aceitável para uma estatística como login_count
+func (r *UserMongoRepository) IncrementField(ctx context.Context, id, field string, delta int) error {
+	name, ok := counterFields[field]
+	if !ok {
+		return usecase.ErrUnknownCounter
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
 		return usecase.ErrNotFound
 	}
 
+	coll, err := r.coll(ctx)
+	if err != nil {
+		return err
+	}
+	var result *mongo.UpdateResult
+	err = r.do(ctx, func() error {
+		result, err = coll.UpdateOne(ctx, notDeleted(bson.M{"_id": oid}), bson.M{"$inc": bson.M{name: delta}})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return missingError(ctx, coll, oid)
+	}
+	return nil
+}
+
+// ============================================
+// GET BY IDS
+// ============================================
+// GetByIDs busca todos os usuários cujos IDs estão na lista em UMA consulta
+// Com $in, resolver 50 referências custa uma ida ao banco em vez de 50
+//
+// IDs que não existem simplesmente não aparecem no resultado (não é erro)
+// A ordem do resultado é a do _id, não a da lista recebida
+func (r *UserMongoRepository) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, []string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	oids, invalid := parseObjectIDs(ids)
+	if len(oids) == 0 {
+		return nil, invalid, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	users, err := r.findUsers(ctx, readDefault, notDeleted(bson.M{"_id": bson.M{"$in": oids}}), opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return users, invalid, nil
+}
+
+// ============================================
+// DELETE MANY
+// ============================================
+// DeleteMany remove (soft delete) todos os usuários cujos IDs estão na lista
+//
+// SOBRE $in:
+// - {"_id": {"$in": [a, b, c]}} casa com qualquer documento cujo _id esteja na lista
+// - Uma única ida ao banco, em vez de um Delete por ID
+//
+// IDs que não são ObjectIDs válidos não derrubam a operação inteira:
+// são pulados e devolvidos para o chamador informar ao cliente
+func (r *UserMongoRepository) DeleteMany(ctx context.Context, ids []string) (int64, []string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	oids, invalid := parseObjectIDs(ids)
+	if len(oids) == 0 {
+		return 0, invalid, nil
+	}
+
+	coll, err := r.coll(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	update := bson.M{"$set": bson.M{"deletedAt": time.Now().UTC()}}
+	var result *mongo.UpdateResult
+	err = r.do(ctx, func() error {
+		var err error
+		result, err = coll.UpdateMany(ctx, notDeleted(bson.M{"_id": bson.M{"$in": oids}}), update)
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return result.ModifiedCount, invalid, nil
+}
+
+// ============================================
+// UPDATE MANY (ATUALIZAÇÃO EM MASSA)
+// ============================================
+// UpdateMany aplica as alterações de metadata a todos os usuários do filtro, em um UpdateMany
+//
+// POR QUE UM PIPELINE DE AGREGAÇÃO NO UPDATE?
+// - Com {"$set": {"metadata.beta": "true"}}, o MongoDB falha em documentos com "metadata": null
+// (o PUT grava null quando o cliente limpa os metadados)
+// - No pipeline, $mergeObjects junta as chaves novas ao metadata atual ({} quando ausente ou null)
+// - $literal impede que um valor como "$name" seja lido como referência a um campo
+//
+// A TRAVA maxMatched É UMA CONTAGEM ANTES DO UPDATE:
+// - CountDocuments com limit maxMatched+1 para de contar assim que passa da trava
+// - Usuários criados entre a contagem e o update também são alterados (a trava é contra acidentes, não exata)
+func (r *UserMongoRepository) UpdateMany(ctx context.Context, filter domain.BulkUpdateFilter, changes domain.BulkUpdateChanges, maxMatched int64) (int64, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	coll, err := r.coll(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	query := buildBulkFilter(filter)
+
+	if maxMatched > 0 {
+		var count int64
+		err = r.do(ctx, func() error {
+			var err error
+			count, err = coll.CountDocuments(ctx, query, options.Count().SetLimit(maxMatched+1))
+			return err
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+		if count > maxMatched {
+			return 0, 0, usecase.ErrBulkConfirmRequired
+		}
+	}
+
+	set := bson.M{}
+	var unset bson.A
+	for key, value := range changes.Metadata {
+		if value == nil {
+			unset = append(unset, "metadata."+key)
+			continue
+		}
+		set[key] = bson.M{"$literal": *value}
+	}
+	pipeline := bson.A{
+		bson.M{"$set": bson.M{
+			"metadata":  bson.M{"$mergeObjects": bson.A{bson.M{"$ifNull": bson.A{"$metadata", bson.M{}}}, set}},
+			"version":   bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+			"updatedAt": time.Now().UTC().Truncate(time.Millisecond),
+		}},
+	}
+	if len(unset) > 0 {
+		pipeline = append(pipeline, bson.M{"$unset": unset})
+	}
+
+	var result *mongo.UpdateResult
+	err = r.do(ctx, func() error {
+		var err error
+		result, err = coll.UpdateMany(ctx, query, pipeline)
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return result.MatchedCount, result.ModifiedCount, nil
+}
+
+// buildBulkFilter converte domain.BulkUpdateFilter para uma query do MongoDB
+// Usuários removidos (soft delete) nunca são alterados
+// verified=false também casa com documentos antigos, sem o campo
+func buildBulkFilter(filter domain.BulkUpdateFilter) bson.M {
+	query := buildFilter(domain.UserFilter{Name: filter.Name})
+	if filter.NamePrefix != "" {
+		prefix := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(filter.NamePrefix), Options: "i"}
+		if _, ok := query["name"]; ok {
+			query["$and"] = bson.A{bson.M{"name": query["name"]}, bson.M{"name": prefix}}
+			delete(query, "name")
+		} else {
+			query["name"] = prefix
+		}
+	}
+	if filter.Verified != nil {
+		if *filter.Verified {
+			query["verified"] = true
+		} else {
+			query["verified"] = bson.M{"$ne": true}
+		}
+	}
+	return query
+}
+
+// ============================================
+// ÍNDICES
+// ============================================
+// EnsureIndexes cria os índices da collection de usuários
+//
+// SOBRE ÍNDICES:
+// - Sem índice, o MongoDB lê TODOS os documentos para achar os que casam (collection scan)
+// - deletedAt: usado pelo purge ({"deletedAt": {"$lt": t}})
+// - updatedAt + _id: filtro e ordem da sincronização incremental (?updatedSince=)
+// - emails.address (único): um endereço pertence a no máximo UM usuário
+//
+// SOBRE O ÍNDICE ÚNICO EM emails.address:
+// - Em arrays, o MongoDB indexa CADA elemento (índice "multikey")
+// - Ou seja, a unicidade vale para todos os endereços, não só o principal
+// - partialFilterExpression: documentos antigos, sem a lista, ficam fora do índice
+// - Eles passam a ser cobertos na próxima atualização, que grava a lista
+// - Usuários removidos (soft delete) continuam no índice até o purge
+// - Ou seja, o endereço só fica livre depois do período de retenção
+//
+// POR QUE COLLATION NO ÍNDICE DE EMAILS?
+// - Sem ela, a comparação é byte a byte: "A@x.com" e "a@x.com" seriam endereços diferentes
+// - Com emailCollation (strength 2), maiúsculas e minúsculas são iguais para o índice
+// - A unicidade vale mesmo que alguma parte da aplicação esqueça de normalizar o email (defesa em profundidade)
+//
+// CUIDADO - CONSULTAS PRECISAM DA MESMA COLLATION:
+// - O MongoDB só usa um índice com collation em consultas com a MESMA collation
+// - Uma consulta em emails.address sem ela compara byte a byte e não usa o índice (collection scan)
+// - Por isso EmailExists passa emailCollation: a busca usa o índice e ignora maiúsculas
+//
+// CreateMany é idempotente: criar um índice que já existe (com as mesmas opções) não faz nada
+// Com tenant no context, vale para a collection do tenant
+func (r *UserMongoRepository) EnsureIndexes(ctx context.Context) error {
+	coll, err := r.coll(ctx)
+	if err != nil {
+		return err
+	}
+	return createIndexes(ctx, coll)
+}
+
+// emailCollation compara emails sem diferenciar maiúsculas e minúsculas
+// Strength 2: letras e acentos contam, caixa não ("A@x.com" == "a@x.com")
+var emailCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// Nomes do índice único de emails: o atual (com collation) e o antigo (sem collation)
+const (
+	emailIndexName       = "emails_address_ci"
+	legacyEmailIndexName = "emails.address_1"
+)
+
+// indexNotFoundCode é o código do servidor para "índice não existe" (IndexNotFound)
+const indexNotFoundCode = 27
+
+// createIndexes cria os índices de EnsureIndexes em coll
+//
+// MIGRAÇÃO DO ÍNDICE ANTIGO:
+// - Collections antigas têm o índice único de emails sem collation (nome padrão "emails.address_1")
+// - O novo tem outro nome: os dois podem coexistir (mesmas chaves, collations diferentes)
+// - O antigo só é apagado DEPOIS que o novo existe: a unicidade nunca fica sem índice
+// - Se a collection já tiver o mesmo email com caixas diferentes, o novo índice falha e a aplicação
+// não inicia (preflight "indexes"): resolva os duplicados antes
+func createIndexes(ctx context.Context, coll *mongo.Collection) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "deletedAt", Value: 1}}},
+		{Keys: bson.D{{Key: "updatedAt", Value: 1}, {Key: "_id", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "emails.address", Value: 1}},
+			Options: options.Index().
+				SetName(emailIndexName).
+				SetUnique(true).
+				SetCollation(emailCollation).
+				SetPartialFilterExpression(bson.M{"emails.address": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = coll.Indexes().DropOne(ctx, legacyEmailIndexName)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == indexNotFoundCode {
+		return nil
+	}
+	return err
+}
+
+// toEmailDocs converte a lista de emails do domínio para o formato do MongoDB
+func toEmailDocs(emails []domain.EmailAddress) []emailDoc {
+	docs := make([]emailDoc, 0, len(emails))
+	for _, e := range emails {
+		docs = append(docs, emailDoc{Address: e.Address, Primary: e.Primary})
+	}
+	return docs
+}
+
+// ============================================
+// DROP ALL (SOMENTE TESTES)
+// ============================================
+// DropAll apaga a collection inteira e a recria com os índices
+// Drop remove documentos E índices de uma vez - bem mais rápido que DeleteMany({})
+// Com tenant no context, apaga só a collection do tenant
+func (r *UserMongoRepository) DropAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	coll, err := r.coll(ctx)
+	if err != nil {
+		return err
+	}
+	if err := coll.Drop(ctx); err != nil {
+		return err
+	}
+	return createIndexes(ctx, coll)
+}
+
+// ============================================
+// PURGE (REMOÇÃO DEFINITIVA)
+// ============================================
+// PurgeDeletedBefore apaga DE VERDADE os usuários removidos antes de t
+// É o fim do período de retenção do soft delete (ex: 30 dias, estilo LGPD/GDPR)
+//
+// {"deletedAt": {"$lt": t}} só casa com documentos que TÊM deletedAt:
+// usuários ativos nunca são apagados aqui
+//
+// O timeout é maior que o das outras operações: um purge pode apagar muitos documentos
+//
+// O job de purge roda sem tenant no context: ele percorre a collection padrão
+// E as collections dos tenants ("<collection>_*"), para que a retenção valha para todos
+func (r *UserMongoRepository) PurgeDeletedBefore(ctx context.Context, t time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	colls := []*mongo.Collection{r.collection}
+	if _, ok := domain.TenantFromContext(ctx); ok {
+		coll, err := r.coll(ctx)
+		if err != nil {
+			return 0, err
+		}
+		colls = []*mongo.Collection{coll}
+	} else {
+		db := r.collection.Database()
+		prefix := "^" + regexp.QuoteMeta(r.collection.Name()+"_")
+		names, err := db.ListCollectionNames(ctx, bson.M{"name": bson.M{"$regex": prefix}})
+		if err != nil {
+			return 0, err
+		}
+		for _, name := range names {
+			colls = append(colls, db.Collection(name))
+		}
+	}
+
+	var purged int64
+	for _, coll := range colls {
+		result, err := coll.DeleteMany(ctx, bson.M{"deletedAt": bson.M{"$lt": t}})
+		if err != nil {
+			return purged, err
+		}
+		purged += result.DeletedCount
+	}
+	return purged, nil
+}
+
+// parseObjectIDs converte IDs hex para ObjectID, separando os inválidos
+func parseObjectIDs(ids []string) ([]primitive.ObjectID, []string) {
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	var invalid []string
+	for _, id := range ids {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			invalid = append(invalid, id)
+			continue
+		}
+		oids = append(oids, oid)
+	}
+	return oids, invalid
+}
+
+// ============================================
+// TRANSAÇÕES
+// ============================================
+// WithTransaction executa fn dentro de uma transação do MongoDB
+//
+// COMO FUNCIONA:
+// 1. Inicia uma sessão (StartSession) no cliente
+// 2. session.WithTransaction inicia a transação e chama fn
+// 3. Se fn retornar nil → commit; se retornar erro → abort (tudo é desfeito)
+// 4. Erros transitórios (ex: troca de primário) fazem o driver repetir fn
+//
+// SOBRE O ctx PASSADO PARA fn:
+// - É um mongo.SessionContext: carrega a sessão dentro do context
+// - Os métodos do repositório chamados com esse ctx participam da transação
+// - Por isso fn pode ser executada mais de uma vez: ela deve ser idempotente
+func (r *UserMongoRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// Transações exigem replica set; em um servidor standalone o erro do
+	// driver é pouco claro, então verificamos antes e retornamos um erro explícito
+	if err := r.ensureTransactionsSupported(ctx); err != nil {
+		return err
+	}
+
+	session, err := r.collection.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	// Leituras dentro de uma transação SÓ podem ir para o primário
+	// Fixamos primary aqui para não herdar MONGO_READ_PREFERENCE do cliente
+	txnOpts := options.Transaction().SetReadPreference(readpref.Primary())
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	}, txnOpts)
+	return err
+}
+
+// ensureTransactionsSupported devolve ErrTransactionsUnsupported em um servidor standalone
+func (r *UserMongoRepository) ensureTransactionsSupported(ctx context.Context) error {
+	replicated, err := r.isReplicated(ctx)
+	if err != nil {
+		return err
+	}
+	if !replicated {
+		return usecase.ErrTransactionsUnsupported
+	}
 	return nil
 }
+
+// isReplicated consulta o comando "hello" do servidor
+// - setName preenchido → replica set (suporta transações e change streams)
+// - msg "isdbgrid" → mongos de um cluster shardeado (suporta transações e change streams)
+// - caso contrário → servidor standalone (NÃO suporta)
+func (r *UserMongoRepository) isReplicated(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := r.collection.Database().RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return false, err
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid", nil
+}
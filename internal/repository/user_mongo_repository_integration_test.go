@@ -0,0 +1,274 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	tcmongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"user-api/internal/domain"
+	"user-api/internal/usecase"
+)
+
+// ============================================
+// TESTES DE INTEGRAÇÃO DO REPOSITÓRIO MONGODB
+// ============================================
+// Sobem um MongoDB de verdade em um container (testcontainers) e exercitam o repositório
+// O que o fake em memória não mostra: índice único, collation dos emails, transações
+//
+// POR QUE REPLICA SET?
+// - Transações só existem em replica set (em standalone, WithTransaction devolve ErrTransactionsUnsupported)
+// - WithReplicaSet inicia um replica set de um nó só: o suficiente para o teste
+// - directConnection=true: o membro se anuncia com o IP interno do container, inalcançável daqui
+//
+// Só rodam com a build tag integration; sem Docker (ou Podman) disponível, são pulados:
+//
+//	make test-integration
+
+// newMongoTestRepository sobe um replica set de um nó e devolve o repositório com os índices criados
+func newMongoTestRepository(t *testing.T) domain.UserRepository {
+	t.Helper()
+	return startMongoRepository(t, tcmongodb.WithReplicaSet("rs0"))
+}
+
+// startMongoRepository sobe o container com as opções informadas (sem nenhuma: um standalone)
+func startMongoRepository(t *testing.T, opts ...testcontainers.ContainerCustomizer) domain.UserRepository {
+	t.Helper()
+	skipWithoutDocker(t)
+	ctx := context.Background()
+
+	container, err := tcmongodb.Run(ctx, "mongo:7", opts...)
+	if err != nil {
+		t.Fatalf("start MongoDB container: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := testcontainers.TerminateContainer(container); err != nil {
+			t.Logf("terminate MongoDB container: %v", err)
+		}
+	})
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("connection string: %v", err)
+	}
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri+"/?directConnection=true"))
+	if err != nil {
+		t.Fatalf("connect to MongoDB: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+
+	repo := NewUserMongoRepository(client.Database("userdb_test"), "users", 5*time.Second, RetryPolicy{}, nil, false)
+	if err := repo.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("create indexes: %v", err)
+	}
+	return repo
+}
+
+func TestUserMongoRepository(t *testing.T) {
+	repo := newMongoTestRepository(t)
+	ctx := context.Background()
+
+	ana := newTestUser("Ana", "ana@example.com")
+	if err := repo.Create(ctx, ana); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if ana.ID == "" || ana.Version != 1 {
+		t.Fatalf("Create: ID = %q, Version = %d; want an ID and version 1", ana.ID, ana.Version)
+	}
+
+	t.Run("GetByID", func(t *testing.T) {
+		got, err := repo.GetByID(ctx, ana.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Name != "Ana" || got.Email != "ana@example.com" {
+			t.Errorf("GetByID = %+v", got)
+		}
+		if _, err := repo.GetByID(ctx, "65a1b2c3d4e5f6a7b8c9d0e1"); !errors.Is(err, usecase.ErrNotFound) {
+			t.Errorf("GetByID(unknown) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("unique email index ignores case", func(t *testing.T) {
+		tests := []struct {
+			email string
+			want  bool
+		}{
+			{email: "ana@example.com", want: true},
+			{email: "ANA@Example.com", want: true},
+			{email: "nobody@example.com", want: false},
+		}
+		for _, tt := range tests {
+			exists, err := repo.EmailExists(ctx, tt.email)
+			if err != nil || exists != tt.want {
+				t.Errorf("EmailExists(%q) = %v, %v; want %v", tt.email, exists, err, tt.want)
+			}
+		}
+		if err := repo.Create(ctx, newTestUser("Other", "Ana@Example.com")); !errors.Is(err, usecase.ErrEmailTaken) {
+			t.Errorf("Create with a taken email in other case: error = %v, want ErrEmailTaken", err)
+		}
+	})
+
+	t.Run("Update checks the version", func(t *testing.T) {
+		user, err := repo.GetByID(ctx, ana.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		user.Name = "Ana Maria"
+		if err := repo.Update(ctx, user); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if user.Version != 2 {
+			t.Errorf("Version after Update = %d, want 2", user.Version)
+		}
+
+		stale := user.Clone()
+		stale.Version = 1
+		if err := repo.Update(ctx, stale); !errors.Is(err, usecase.ErrVersionConflict) {
+			t.Errorf("Update with stale version: error = %v, want ErrVersionConflict", err)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		bruno := newTestUser("Bruno", "bruno@example.com")
+		if err := repo.Create(ctx, bruno); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		users, err := repo.List(ctx, domain.UserFilter{})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(users) != 2 {
+			t.Fatalf("List returned %d users, want 2", len(users))
+		}
+		filtered, err := repo.List(ctx, domain.UserFilter{Name: "brU"})
+		if err != nil {
+			t.Fatalf("List(name): %v", err)
+		}
+		if len(filtered) != 1 || filtered[0].ID != bruno.ID {
+			t.Errorf("List(name=brU) = %v, want only Bruno", filtered)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := repo.Delete(ctx, ana.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.GetByID(ctx, ana.ID); !errors.Is(err, usecase.ErrGone) {
+			t.Errorf("GetByID after Delete: error = %v, want ErrGone", err)
+		}
+		if err := repo.Delete(ctx, "65a1b2c3d4e5f6a7b8c9d0e1"); !errors.Is(err, usecase.ErrNotFound) {
+			t.Errorf("Delete(unknown) error = %v, want ErrNotFound", err)
+		}
+
+		// Escritas em um removido também respondem ErrGone (410), não ErrNotFound
+		deleted := ana.Clone()
+		deleted.Name = "Ana Removida"
+		if err := repo.Update(ctx, deleted); !errors.Is(err, usecase.ErrGone) {
+			t.Errorf("Update after Delete: error = %v, want ErrGone", err)
+		}
+		if err := repo.IncrementField(ctx, ana.ID, domain.CounterLoginCount, 1); !errors.Is(err, usecase.ErrGone) {
+			t.Errorf("IncrementField after Delete: error = %v, want ErrGone", err)
+		}
+
+		users, err := repo.List(ctx, domain.UserFilter{})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		for _, u := range users {
+			if u.ID == ana.ID {
+				t.Error("List still returns the deleted user")
+			}
+		}
+	})
+}
+
+// TestUserMongoRepositoryTransactions confere o commit, o rollback e a recusa em um standalone
+func TestUserMongoRepositoryTransactions(t *testing.T) {
+	repo := newMongoTestRepository(t)
+	ctx := context.Background()
+
+	ana := newTestUser("Ana", "ana@example.com")
+	if err := repo.Create(ctx, ana); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// writeBoth cria um usuário e renomeia a Ana: as duas escritas da mesma transação
+	writeBoth := func(ctx context.Context, email, name string) error {
+		if err := repo.Create(ctx, newTestUser("Bruno", email)); err != nil {
+			return err
+		}
+		user, err := repo.GetByID(ctx, ana.ID)
+		if err != nil {
+			return err
+		}
+		user.Name = name
+		return repo.Update(ctx, user)
+	}
+
+	t.Run("a committed transaction is visible afterwards", func(t *testing.T) {
+		err := repo.WithTransaction(ctx, func(ctx context.Context) error {
+			return writeBoth(ctx, "commit@example.com", "Ana Maria")
+		})
+		if err != nil {
+			t.Fatalf("WithTransaction: %v", err)
+		}
+
+		if exists, err := repo.EmailExists(ctx, "commit@example.com"); err != nil || !exists {
+			t.Errorf("EmailExists(commit@example.com) = %v, %v; want the committed user", exists, err)
+		}
+		got, err := repo.GetByID(ctx, ana.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Name != "Ana Maria" || got.Version != 2 {
+			t.Errorf("after commit: Name = %q, Version = %d; want Ana Maria, 2", got.Name, got.Version)
+		}
+	})
+
+	t.Run("an error from fn rolls every write back", func(t *testing.T) {
+		errAbort := errors.New("abort")
+		err := repo.WithTransaction(ctx, func(ctx context.Context) error {
+			if err := writeBoth(ctx, "rollback@example.com", "Ana Rollback"); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("WithTransaction error = %v, want the error returned by fn", err)
+		}
+
+		if exists, _ := repo.EmailExists(ctx, "rollback@example.com"); exists {
+			t.Error("the user created inside the aborted transaction was persisted")
+		}
+		got, err := repo.GetByID(ctx, ana.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Name != "Ana Maria" || got.Version != 2 {
+			t.Errorf("after rollback: Name = %q, Version = %d; want the committed Ana Maria, 2", got.Name, got.Version)
+		}
+	})
+
+	t.Run("a standalone server has no transactions", func(t *testing.T) {
+		standalone := startMongoRepository(t)
+		called := false
+		err := standalone.WithTransaction(ctx, func(context.Context) error {
+			called = true
+			return nil
+		})
+		if !errors.Is(err, usecase.ErrTransactionsUnsupported) {
+			t.Errorf("WithTransaction error = %v, want ErrTransactionsUnsupported", err)
+		}
+		if called {
+			t.Error("fn ran without a transaction")
+		}
+	})
+}
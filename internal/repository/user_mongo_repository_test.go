@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"user-api/internal/domain"
+	"user-api/internal/usecase"
+)
+
+// ============================================
+// TESTES COM O SERVIDOR SIMULADO DO DRIVER (mtest)
+// ============================================
+// mtest.Mock troca o servidor por respostas enfileiradas pelo teste (AddMockResponses)
+// Cada comando enviado pelo repositório consome a próxima resposta da fila
+// Assim simulamos erros do servidor (chave duplicada, documento grande demais) sem um MongoDB
+
+// duplicateKeyError é a resposta do servidor quando o índice único recusa a escrita
+var duplicateKeyError = mtest.CommandError{Code: 11000, Message: "E11000 duplicate key error collection: userdb.users index: emails.address_1"}
+
+// newMockRepository cria o repositório sobre a collection do servidor simulado
+func newMockRepository(mt *mtest.T) domain.UserRepository {
+	return NewUserMongoRepository(mt.DB, mt.Coll.Name(), time.Second, RetryPolicy{}, nil, false)
+}
+
+func TestUpsertDuplicateKeyRace(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	existing := bson.D{
+		{Key: "_id", Value: primitive.NewObjectID()},
+		{Key: "name", Value: "Ana"},
+		{Key: "email", Value: "ana@example.com"},
+		{Key: "version", Value: 3},
+	}
+
+	mt.Run("the retry finds the user created by the other upsert", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCommandErrorResponse(duplicateKeyError),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: existing}),
+		)
+
+		before, err := newMockRepository(mt).Upsert(context.Background(), newTestUser("Ana Maria", "ana@example.com"))
+		if err != nil {
+			t.Fatalf("Upsert error = %v, want the retry to succeed", err)
+		}
+		if before == nil || before.Name != "Ana" || before.Version != 3 {
+			t.Errorf("Upsert before = %+v, want the existing user", before)
+		}
+	})
+
+	mt.Run("a second duplicate key means the email belongs to another user", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCommandErrorResponse(duplicateKeyError),
+			mtest.CreateCommandErrorResponse(duplicateKeyError),
+		)
+
+		_, err := newMockRepository(mt).Upsert(context.Background(), newTestUser("Ana", "ana@example.com"))
+		if !errors.Is(err, usecase.ErrEmailTaken) {
+			t.Errorf("Upsert error = %v, want ErrEmailTaken", err)
+		}
+	})
+}
+
+func TestCreateDuplicateKey(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("email taken", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: duplicateKeyError.Message}),
+			// insertedByPreviousAttempt: nenhum documento com o _id desta tentativa
+			mtest.CreateCursorResponse(0, mt.DB.Name()+"."+mt.Coll.Name(), mtest.FirstBatch),
+		)
+
+		err := newMockRepository(mt).Create(context.Background(), newTestUser("Ana", "ana@example.com"))
+		if !errors.Is(err, usecase.ErrEmailTaken) {
+			t.Errorf("Create error = %v, want ErrEmailTaken", err)
+		}
+	})
+}
+
+func TestWithTransactionReplicaSetCheck(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	// A resposta do "hello" sem setName (nem msg "isdbgrid") é a de um servidor standalone
+	mt.Run("standalone", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "isWritablePrimary", Value: true}))
+
+		called := false
+		err := newMockRepository(mt).WithTransaction(context.Background(), func(context.Context) error {
+			called = true
+			return nil
+		})
+		if !errors.Is(err, usecase.ErrTransactionsUnsupported) {
+			t.Errorf("WithTransaction error = %v, want ErrTransactionsUnsupported", err)
+		}
+		if called {
+			t.Error("fn ran without a transaction")
+		}
+	})
+}
+
+// newTestUser monta um usuário como o CreateUser monta (o email na lista, como principal)
+// Também usado pelos testes de integração
+func newTestUser(name, email string) *domain.User {
+	u := &domain.User{Name: name}
+	u.SetPrimaryEmail(email)
+	return u
+}
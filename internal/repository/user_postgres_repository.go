@@ -0,0 +1,1147 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"user-api/internal/domain"
+	"user-api/internal/usecase"
+)
+
+// ============================================
+// ESQUEMA NO POSTGRESQL
+// ============================================
+// Duas tabelas por tenant (sem tenant: "users" e "users$emails"):
+//
+//	users:        uma linha por usuário (metadata em JSONB, deleted_at para o soft delete)
+//	users$emails: uma linha por endereço, com índice único em lower(address)
+//
+// POR QUE UMA TABELA SÓ PARA OS EMAILS?
+// - A unicidade vale para TODOS os endereços do usuário, não só o principal (como o índice multikey do MongoDB)
+// - Um índice único não alcança os elementos de um array ou JSONB: cada endereço precisa ser uma linha
+// - lower(address) faz "A@x.com" e "a@x.com" colidirem (o mesmo papel da emailCollation)
+// - Usuários removidos (soft delete) mantêm os endereços até o purge: o email continua ocupado
+//
+// POR QUE "$" NO NOME?
+// - X-Tenant-ID só aceita letras minúsculas, dígitos, '-' e '_': o "$" nunca aparece em um tenant
+// - Com "_emails", a tabela de emails do tenant "acme" seria a de usuários do tenant "acme_emails"
+//
+// Os IDs continuam sendo ObjectIDs (24 caracteres hex), gerados aqui:
+// - A API valida o formato (400 INVALID_ID) igual para os dois bancos
+// - ObjectIDs crescem com o tempo: ordenar por id é ordenar por criação, como no MongoDB
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS %[1]s (
+	id            CHAR(24)    PRIMARY KEY,
+	name          TEXT        NOT NULL,
+	email         TEXT        NOT NULL,
+	pending_email TEXT        NOT NULL DEFAULT '',
+	phone         TEXT        NOT NULL DEFAULT '',
+	verified      BOOLEAN     NOT NULL DEFAULT FALSE,
+	metadata      JSONB,
+	version       INTEGER     NOT NULL DEFAULT 1,
+	login_count   BIGINT      NOT NULL DEFAULT 0,
+	created_at    TIMESTAMPTZ NOT NULL,
+	updated_at    TIMESTAMPTZ NOT NULL,
+	deleted_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS %[3]s ON %[1]s (deleted_at);
+CREATE INDEX IF NOT EXISTS %[4]s ON %[1]s (updated_at, id);
+CREATE TABLE IF NOT EXISTS %[2]s (
+	user_id    CHAR(24) NOT NULL REFERENCES %[1]s (id) ON DELETE CASCADE,
+	position   INTEGER  NOT NULL,
+	address    TEXT     NOT NULL,
+	is_primary BOOLEAN  NOT NULL,
+	PRIMARY KEY (user_id, position)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS %[5]s ON %[2]s (lower(address));
+`
+
+// uniqueViolation é o SQLSTATE do PostgreSQL para chave única duplicada
+const uniqueViolation = "23505"
+
+// pgTables são os nomes (já entre aspas) das tabelas de uma requisição (ver tables)
+type pgTables struct {
+	name   string // Nome sem aspas da tabela de usuários (base dos nomes dos índices)
+	users  string
+	emails string
+}
+
+// pgQuerier é o que *sql.DB e *sql.Tx têm em comum: os métodos funcionam dentro ou fora de uma transação
+type pgQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// pgTxKey guarda no context a transação aberta por WithTransaction
+type pgTxKey struct{}
+
+// ============================================
+// REPOSITÓRIO POSTGRESQL
+// ============================================
+// UserPostgresRepository implementa domain.UserRepository usando PostgreSQL (database/sql + driver pgx)
+// Selecionado com STORAGE=postgres; o usecase e os handlers não mudam
+//
+// AS MESMAS REGRAS DO MONGODB:
+// - ID em formato inválido → ErrNotFound (o handler já recusa com 400 antes de chegar aqui)
+// - Usuário removido → ErrGone no GetByID e nas escritas pelo ID (Update, Delete, IncrementField), até o purge
+// - Email já usado (por qualquer usuário, inclusive removido) → ErrEmailTaken
+// - Update com versão desatualizada → ErrVersionConflict
+//
+// O QUE NÃO EXISTE AQUI:
+// - Watch: não há change streams → usecase.ErrChangeStreamsUnsupported (GET /users/stream responde 501)
+// - Projeção (UserFilter.Fields): as linhas vêm inteiras; o handler monta o JSON só com os campos pedidos
+// - Retry e PoolGate: o pool do database/sql já espera por uma conexão livre até o prazo do context
+type UserPostgresRepository struct {
+	db        *sql.DB
+	table     string        // Tabela de usuários sem tenant (POSTGRES_TABLE)
+	opTimeout time.Duration // Prazo de cada operação simples
+	bootstrap sync.Map      // Tenants cujas tabelas já foram criadas (tenant → struct{})
+}
+
+// NewUserPostgresRepository cria um repositório PostgreSQL sobre o pool db
+// As tabelas são criadas por EnsureIndexes (o preflight chama na inicialização)
+// table é o nome da tabela de usuários; opTimeout <= 0 usa DefaultOpTimeout
+func NewUserPostgresRepository(db *sql.DB, table string, opTimeout time.Duration) domain.UserRepository {
+	if opTimeout <= 0 {
+		opTimeout = DefaultOpTimeout
+	}
+	return &UserPostgresRepository{db: db, table: table, opTimeout: opTimeout}
+}
+
+// quoteIdent coloca um identificador entre aspas (tenants podem ter '-', que não vale sem aspas)
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// tablesFor monta os nomes das tabelas a partir do nome da tabela de usuários
+func tablesFor(name string) pgTables {
+	return pgTables{name: name, users: quoteIdent(name), emails: quoteIdent(name + "$emails")}
+}
+
+// tables devolve as tabelas da requisição: "<tabela>_<tenant>" com tenant, a padrão sem
+// Mesmo esquema do coll do MongoDB: na primeira requisição de cada tenant, as tabelas são criadas
+func (r *UserPostgresRepository) tables(ctx context.Context) (pgTables, error) {
+	tenant, ok := domain.TenantFromContext(ctx)
+	if !ok {
+		return tablesFor(r.table), nil
+	}
+
+	t := tablesFor(r.table + "_" + tenant)
+	if _, done := r.bootstrap.Load(tenant); !done {
+		if err := r.createTables(ctx, t); err != nil {
+			return pgTables{}, err
+		}
+		r.bootstrap.Store(tenant, struct{}{})
+	}
+	return t, nil
+}
+
+// querier devolve a transação de WithTransaction, se houver, ou o pool
+func (r *UserPostgresRepository) querier(ctx context.Context) pgQuerier {
+	if tx, ok := ctx.Value(pgTxKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return r.db
+}
+
+// inTx executa fn em uma transação: a de WithTransaction, se houver, ou uma aberta só para fn
+// Usado pelas escritas que mexem nas duas tabelas (usuário e emails): ou grava tudo, ou nada
+func (r *UserPostgresRepository) inTx(ctx context.Context, fn func(q pgQuerier) error) error {
+	if tx, ok := ctx.Value(pgTxKey{}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// isUniqueViolation informa se err é uma chave única duplicada (o índice de emails)
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// isPostgresUnavailable diz se err indica que o PostgreSQL está inalcançável (o par do isUnavailable do MongoDB)
+// - Falha ao abrir a conexão (servidor fora, DNS, recusa): *pgconn.ConnectError
+// - Conexão caída no meio do uso: driver.ErrBadConn ou erro de rede (net.Error)
+// - O servidor recusando conexões: classe 08, desligamento (57P01) e "ainda subindo" (57P03)
+// Erros da operação (email duplicado, SQL inválido) não entram
+func isPostgresUnavailable(err error) bool {
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || pgErr.Code == "57P01" || pgErr.Code == "57P03"
+	}
+	return false
+}
+
+// parseUserID valida o ID (hex de um ObjectID) e o devolve normalizado (minúsculas)
+func parseUserID(id string) (string, bool) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return "", false
+	}
+	return oid.Hex(), true
+}
+
+// pgNow é o horário das escritas: UTC e truncado em milissegundos
+// O PostgreSQL guarda microssegundos, mas o cursor da sincronização (domain.UpdatedCursor) tem milissegundos:
+// gravar mais precisão faria o "depois de" do cursor devolver de novo o último usuário da página
+func pgNow() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// ============================================
+// LEITURA DAS LINHAS
+// ============================================
+// selectUsers é o SELECT de usuários com a lista de emails agregada em JSON (uma consulta só)
+func selectUsers(t pgTables) string {
+	return `SELECT u.id, u.name, u.email, u.pending_email, u.phone, u.verified, u.metadata,
+		(SELECT COALESCE(json_agg(json_build_object('address', e.address, 'primary', e.is_primary) ORDER BY e.position), '[]')
+			FROM ` + t.emails + ` e WHERE e.user_id = u.id),
+		u.version, u.login_count, u.created_at, u.updated_at, u.deleted_at
+	FROM ` + t.users + ` u`
+}
+
+// userRow é uma linha de selectUsers (o equivalente do userDoc)
+type userRow struct {
+	id, name, email, pending, phone string
+	verified                        bool
+	metadata, emails                []byte // JSON; metadata nil = NULL
+	version                         int
+	logins                          int64
+	createdAt, updatedAt            time.Time
+	deletedAt                       sql.NullTime
+}
+
+// scan lê a linha atual de rows (ou o resultado de QueryRow) na ordem de selectUsers
+func (u *userRow) scan(row interface{ Scan(dest ...any) error }) error {
+	return row.Scan(&u.id, &u.name, &u.email, &u.pending, &u.phone, &u.verified, &u.metadata, &u.emails,
+		&u.version, &u.logins, &u.createdAt, &u.updatedAt, &u.deletedAt)
+}
+
+// toDomain converte a linha para a entidade do domínio
+func (u *userRow) toDomain() (*domain.User, error) {
+	user := &domain.User{
+		ID:           u.id,
+		Name:         u.name,
+		Email:        u.email,
+		PendingEmail: u.pending,
+		Phone:        u.phone,
+		Verified:     u.verified,
+		Version:      u.version,
+		LoginCount:   u.logins,
+		CreatedAt:    u.createdAt.UTC(),
+		UpdatedAt:    u.updatedAt.UTC(),
+	}
+	if err := json.Unmarshal(u.emails, &user.Emails); err != nil {
+		return nil, err
+	}
+	if u.metadata != nil {
+		if err := json.Unmarshal(u.metadata, &user.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// queryUsers executa a consulta e converte todas as linhas
+func queryUsers(ctx context.Context, q pgQuerier, query string, args ...any) ([]*domain.User, error) {
+	var users []*domain.User
+	err := eachUser(ctx, q, query, args, func(user *domain.User) error {
+		users = append(users, user)
+		return nil
+	})
+	return users, err
+}
+
+// eachUser executa a consulta e chama fn para cada linha, sem acumular em memória
+func eachUser(ctx context.Context, q pgQuerier, query string, args []any, fn func(*domain.User) error) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row userRow
+		if err := row.scan(rows); err != nil {
+			return err
+		}
+		user, err := row.toDomain()
+		if err != nil {
+			return err
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// marshalMetadata converte o metadata para JSONB (nil → NULL)
+func marshalMetadata(metadata map[string]string) (any, error) {
+	if metadata == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// replaceEmails regrava a lista de emails do usuário (apaga e insere de novo, na ordem)
+// Dentro da mesma transação da escrita do usuário: um endereço duplicado desfaz tudo
+func replaceEmails(ctx context.Context, q pgQuerier, t pgTables, id string, emails []domain.EmailAddress) error {
+	if _, err := q.ExecContext(ctx, `DELETE FROM `+t.emails+` WHERE user_id = $1`, id); err != nil {
+		return err
+	}
+	for i, e := range emails {
+		_, err := q.ExecContext(ctx, `INSERT INTO `+t.emails+` (user_id, position, address, is_primary) VALUES ($1, $2, $3, $4)`,
+			id, i, e.Address, e.Primary)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ============================================
+// FILTROS (WHERE)
+// ============================================
+// sqlWhere monta um WHERE com parâmetros numerados ($1, $2...)
+// Os valores do cliente nunca entram no texto da consulta: sem SQL injection
+type sqlWhere struct {
+	conds []string
+	args  []any
+}
+
+// arg guarda o valor e devolve o seu parâmetro ("$n")
+func (w *sqlWhere) arg(value any) string {
+	w.args = append(w.args, value)
+	return "$" + strconv.Itoa(len(w.args))
+}
+
+// and acrescenta uma condição (as condições se combinam com AND)
+func (w *sqlWhere) and(cond string) {
+	w.conds = append(w.conds, cond)
+}
+
+// String devolve o WHERE completo ("" sem condições)
+func (w *sqlWhere) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// likeEscaper escapa os curingas do LIKE: o texto do cliente é buscado literalmente (como o regexp.QuoteMeta)
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// pgFilter é o buildFilter do PostgreSQL: não removidos, nome parcial (ILIKE) e UpdatedSince
+func pgFilter(filter domain.UserFilter) *sqlWhere {
+	w := &sqlWhere{}
+	w.and("u.deleted_at IS NULL")
+	if filter.Name != "" {
+		w.and("u.name ILIKE " + w.arg("%"+likeEscaper.Replace(filter.Name)+"%"))
+	}
+	if !filter.UpdatedSince.IsZero() {
+		w.and("u.updated_at >= " + w.arg(filter.UpdatedSince))
+	}
+	return w
+}
+
+// pgListOrder é o listSort do PostgreSQL: por id ou, com UpdatedSince, por (updated_at, id)
+func pgListOrder(filter domain.UserFilter) string {
+	if !filter.UpdatedSince.IsZero() {
+		return " ORDER BY u.updated_at, u.id"
+	}
+	return " ORDER BY u.id"
+}
+
+// limitOffset acrescenta LIMIT e OFFSET (zero = sem limite / sem pular)
+func limitOffset(w *sqlWhere, limit, offset int) string {
+	var clause string
+	if limit > 0 {
+		clause += " LIMIT " + w.arg(limit)
+	}
+	if offset > 0 {
+		clause += " OFFSET " + w.arg(offset)
+	}
+	return clause
+}
+
+// ============================================
+// CREATE E UPSERT
+// ============================================
+// Create insere o usuário e os seus emails em uma transação
+// Um endereço já usado esbarra no índice único de emails → ErrEmailTaken
+func (r *UserPostgresRepository) Create(ctx context.Context, user *domain.User) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	t, err := r.tables(ctx)
+	if err != nil {
+		return err
+	}
+	metadata, err := marshalMetadata(user.Metadata)
+	if err != nil {
+		return err
+	}
+
+	id := primitive.NewObjectID().Hex()
+	now := pgNow()
+	err = r.inTx(ctx, func(q pgQuerier) error {
+		return insertUser(ctx, q, t, id, user, metadata, now)
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return usecase.ErrEmailTaken
+		}
+		return err
+	}
+
+	user.ID = id
+	user.Version = 1
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	return nil
+}
+
+// insertUser grava a linha do usuário (versão 1) e a lista de emails
+func insertUser(ctx context.Context, q pgQuerier, t pgTables, id string, user *domain.User, metadata any, now time.Time) error {
+	_, err := q.ExecContext(ctx, `INSERT INTO `+t.users+`
+		(id, name, email, phone, verified, metadata, version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, 1, $7, $7)`,
+		id, user.Name, user.Email, user.Phone, user.Verified, metadata, now)
+	if err != nil {
+		return err
+	}
+	return replaceEmails(ctx, q, t, id, user.Emails)
+}
+
+// Upsert atualiza o usuário com este email principal ou cria um novo, em uma transação
+//
+// SELECT ... FOR UPDATE trava a linha encontrada: outra escrita no mesmo usuário espera o commit
+// (o papel da atomicidade do FindOneAndUpdate no MongoDB)
+// O email principal é comparado com lower(), sem diferenciar maiúsculas (o papel da emailCollation)
+//
+// CORRIDA ENTRE DOIS UPSERTS DO MESMO EMAIL NOVO (mesma regra do MongoDB):
+// - Nenhum encontra o usuário e os dois inserem; o índice único deixa só um passar
+// - O outro tenta de novo (transação nova) e dessa vez encontra e atualiza
+// - Dentro de WithTransaction não há segunda tentativa: o erro já abortou a transação inteira
+func (r *UserPostgresRepository) Upsert(ctx context.Context, user *domain.User) (*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	t, err := r.tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := r.upsertOnce(ctx, t, user)
+	if _, inTx := ctx.Value(pgTxKey{}).(*sql.Tx); isUniqueViolation(err) && !inTx {
+		previous, err = r.upsertOnce(ctx, t, user)
+	}
+	if isUniqueViolation(err) {
+		return nil, usecase.ErrEmailTaken
+	}
+	return previous, err
+}
+
+// upsertOnce é uma tentativa do Upsert
+func (r *UserPostgresRepository) upsertOnce(ctx context.Context, t pgTables, user *domain.User) (*domain.User, error) {
+	metadata, err := marshalMetadata(user.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous *domain.User
+	id := primitive.NewObjectID().Hex()
+	now := pgNow()
+	err = r.inTx(ctx, func(q pgQuerier) error {
+		var row userRow
+		err := row.scan(q.QueryRowContext(ctx, selectUsers(t)+` WHERE lower(u.email) = lower($1) AND u.deleted_at IS NULL FOR UPDATE OF u`, user.Email))
+		if errors.Is(err, sql.ErrNoRows) {
+			// Usuário novo: entra como não verificado, como no $setOnInsert do MongoDB
+			user.Verified = false
+			return insertUser(ctx, q, t, id, user, metadata, now)
+		}
+		if err != nil {
+			return err
+		}
+		if previous, err = row.toDomain(); err != nil {
+			return err
+		}
+
+		// Phone vazio e Metadata nil mantêm os valores atuais (COALESCE/NULLIF)
+		_, err = q.ExecContext(ctx, `UPDATE `+t.users+`
+			SET name = $2, phone = COALESCE(NULLIF($3, ''), phone), metadata = COALESCE($4::jsonb, metadata),
+				version = version + 1, updated_at = $5
+			WHERE id = $1`,
+			previous.ID, user.Name, user.Phone, metadata, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if previous == nil {
+		user.ID = id
+		user.Version = 1
+		user.CreatedAt = now
+		user.UpdatedAt = now
+		return nil, nil
+	}
+
+	user.ID = previous.ID
+	user.Emails = previous.Emails
+	user.Verified = previous.Verified
+	user.Version = previous.Version + 1
+	user.CreatedAt = previous.CreatedAt
+	user.UpdatedAt = now
+	if user.Phone == "" {
+		user.Phone = previous.Phone
+	}
+	if user.Metadata == nil {
+		user.Metadata = previous.Metadata
+	}
+	return previous, nil
+}
+
+// ============================================
+// LEITURAS
+// ============================================
+// GetByID busca um usuário pelo ID, inclusive os removidos (para distinguir ErrNotFound de ErrGone)
+func (r *UserPostgresRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	key, ok := parseUserID(id)
+	if !ok {
+		return nil, usecase.ErrNotFound
+	}
+	t, err := r.tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var row userRow
+	err = row.scan(r.querier(ctx).QueryRowContext(ctx, selectUsers(t)+` WHERE u.id = $1`, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecase.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if row.deletedAt.Valid {
+		return nil, usecase.ErrGone
+	}
+	return row.toDomain()
+}
+
+// List retorna os usuários que atendem ao filtro
+func (r *UserPostgresRepository) List(ctx context.Context, filter domain.UserFilter) ([]*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	t, err := r.tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w := pgFilter(filter)
+	return queryUsers(ctx, r.querier(ctx), selectUsers(t)+w.String()+pgListOrder(filter), w.args...)
+}
+
+// ListAfter retorna até limit usuários depois do cursor after (id, ou UpdatedCursor com UpdatedSince)
+// A comparação de tuplas (updated_at, id) > (t, id) é o "$or" do MongoDB em uma condição só
+func (r *UserPostgresRepository) ListAfter(ctx context.Context, filter domain.UserFilter, after string, limit int) ([]*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	w := pgFilter(filter)
+	if after != "" && !filter.UpdatedSince.IsZero() {
+		updatedAt, id, ok := domain.ParseUpdatedCursor(after)
+		key, valid := parseUserID(id)
+		if !ok || !valid {
+			return nil, usecase.ErrInvalidCursor
+		}
+		w.and("(u.updated_at, u.id) > (" + w.arg(updatedAt) + ", " + w.arg(key) + ")")
+	} else if after != "" {
+		key, ok := parseUserID(after)
+		if !ok {
+			return nil, usecase.ErrInvalidCursor
+		}
+		w.and("u.id > " + w.arg(key))
+	}
+
+	t, err := r.tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := selectUsers(t) + w.String() + pgListOrder(filter) + limitOffset(w, limit, 0)
+	return queryUsers(ctx, r.querier(ctx), query, w.args...)
+}
+
+// ListOffset pula os offset primeiros usuários e retorna até limit
+func (r *UserPostgresRepository) ListOffset(ctx context.Context, filter domain.UserFilter, offset, limit int) ([]*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	t, err := r.tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w := pgFilter(filter)
+	query := selectUsers(t) + w.String() + pgListOrder(filter) + limitOffset(w, limit, offset)
+	return queryUsers(ctx, r.querier(ctx), query, w.args...)
+}
+
+// Search busca uma página de usuários pelos critérios e conta o total de resultados
+// Mesma ordenação do MongoDB: o id entra sempre como desempate
+func (r *UserPostgresRepository) Search(ctx context.Context, criteria domain.SearchCriteria) ([]*domain.User, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	t, err := r.tables(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	w := pgFilter(domain.UserFilter{Name: criteria.Name})
+	if criteria.Email != "" {
+		w.and("u.email ILIKE " + w.arg("%"+likeEscaper.Replace(criteria.Email)+"%"))
+	}
+	if !criteria.CreatedAfter.IsZero() {
+		w.and("u.created_at >= " + w.arg(criteria.CreatedAfter))
+	}
+	if !criteria.CreatedBefore.IsZero() {
+		w.and("u.created_at < " + w.arg(criteria.CreatedBefore))
+	}
+
+	var total int64
+	q := r.querier(ctx)
+	if err := q.QueryRowContext(ctx, `SELECT count(*) FROM `+t.users+` u`+w.String(), w.args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	direction := " ASC"
+	if criteria.Order == "desc" {
+		direction = " DESC"
+	}
+	order := " ORDER BY u.id" + direction
+	if criteria.Sort == "name" || criteria.Sort == "email" {
+		order = " ORDER BY u." + criteria.Sort + direction + ", u.id" + direction
+	}
+
+	where := w.String()
+	users, err := queryUsers(ctx, q, selectUsers(t)+where+order+limitOffset(w, criteria.Limit, criteria.Offset), w.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// Stream percorre os usuários do filtro chamando fn para cada linha lida
+// Como no MongoDB, sem o prazo por operação: o limite vem do ctx da requisição
+func (r *UserPostgresRepository) Stream(ctx context.Context, filter domain.UserFilter, fn func(*domain.User) error) error {
+	t, err := r.tables(ctx)
+	if err != nil {
+		return err
+	}
+	w := pgFilter(filter)
+	return eachUser(ctx, r.querier(ctx), selectUsers(t)+w.String()+pgListOrder(filter), w.args, fn)
+}
+
+// ListIDs chama fn com o ID de cada usuário, em ordem, a partir do cursor after
+// Só a coluna id é lida (coberta pela chave primária)
+func (r *UserPostgresRepository) ListIDs(ctx context.Context, after string, limit int, fn func(id string) error) error {
+	w := &sqlWhere{}
+	w.and("deleted_at IS NULL")
+	if after != "" {
+		key, ok := parseUserID(after)
+		if !ok {
+			return usecase.ErrInvalidCursor
+		}
+		w.and("id > " + w.arg(key))
+	}
+
+	t, err := r.tables(ctx)
+	if err != nil {
+		return err
+	}
+	rows, err := r.querier(ctx).QueryContext(ctx, `SELECT id FROM `+t.users+w.String()+` ORDER BY id`+limitOffset(w, limit, 0), w.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		if err := fn(id); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Count retorna quantos usuários atendem ao filtro
+func (r *UserPostgresRepository) Count(ctx context.Context, filter domain.UserFilter) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	t, err := r.tables(ctx)
+	if err != nil {
+		return 0, err
+	}
+	w := pgFilter(filter)
+	var count int64
+	err = r.querier(ctx).QueryRowContext(ctx, `SELECT count(*) FROM `+t.users+` u`+w.String(), w.args...).Scan(&count)
+	return count, err
+}
+
+// EmailExists informa se o endereço está na lista de algum usuário (removidos inclusive, até o purge)
+// lower(address) = lower($1) usa o índice único, que ignora maiúsculas
+func (r *UserPostgresRepository) EmailExists(ctx context.Context, email string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	t, err := r.tables(ctx)
+	if err != nil {
+		return false, err
+	}
+	var exists bool
+	err = r.querier(ctx).QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+t.emails+` WHERE lower(address) = lower($1))`, email).Scan(&exists)
+	return exists, err
+}
+
+// GetByIDs busca todos os usuários da lista em uma consulta (id = ANY($1)), ordenados por id
+func (r *UserPostgresRepository) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, []string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	keys, invalid := parseUserIDs(ids)
+	if len(keys) == 0 {
+		return nil, invalid, nil
+	}
+	t, err := r.tables(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	users, err := queryUsers(ctx, r.querier(ctx), selectUsers(t)+` WHERE u.id = ANY($1) AND u.deleted_at IS NULL ORDER BY u.id`, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	return users, invalid, nil
+}
+
+// parseUserIDs normaliza os IDs válidos e separa os inválidos (o parseObjectIDs do PostgreSQL)
+func parseUserIDs(ids []string) ([]string, []string) {
+	keys := make([]string, 0, len(ids))
+	var invalid []string
+	for _, id := range ids {
+		key, ok := parseUserID(id)
+		if !ok {
+			invalid = append(invalid, id)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, invalid
+}
+
+// ============================================
+// ESCRITAS
+// ============================================
+// Update grava o usuário se a versão ainda for user.Version (optimistic locking)
+// Nenhuma linha alterada: a versão mudou (ErrVersionConflict), o usuário foi removido (ErrGone) ou não existe (ErrNotFound)
+func (r *UserPostgresRepository) Update(ctx context.Context, user *domain.User) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	key, ok := parseUserID(user.ID)
+	if !ok {
+		return usecase.ErrNotFound
+	}
+	t, err := r.tables(ctx)
+	if err != nil {
+		return err
+	}
+	metadata, err := marshalMetadata(user.Metadata)
+	if err != nil {
+		return err
+	}
+
+	updatedAt := pgNow()
+	err = r.inTx(ctx, func(q pgQuerier) error {
+		n, err := rowsAffected(q.ExecContext(ctx, `UPDATE `+t.users+`
+			SET name = $3, email = $4, phone = $5, verified = $6, metadata = $7, pending_email = $8,
+				version = version + 1, updated_at = $9
+			WHERE id = $1 AND version = $2 AND deleted_at IS NULL`,
+			key, user.Version, user.Name, user.Email, user.Phone, user.Verified, metadata, user.PendingEmail, updatedAt))
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			var exists bool
+			err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+t.users+` WHERE id = $1 AND deleted_at IS NULL)`, key).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return missingRow(ctx, q, t, key)
+			}
+			return usecase.ErrVersionConflict
+		}
+		return replaceEmails(ctx, q, t, key, user.Emails)
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return usecase.ErrEmailTaken
+		}
+		return err
+	}
+
+	user.Version++
+	user.UpdatedAt = updatedAt
+	return nil
+}
+
+// Delete marca o usuário como removido (soft delete); um usuário já removido resulta em ErrGone
+func (r *UserPostgresRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	key, ok := parseUserID(id)
+	if !ok {
+		return usecase.ErrNotFound
+	}
+	t, err := r.tables(ctx)
+	if err != nil {
+		return err
+	}
+
+	q := r.querier(ctx)
+	n, err := rowsAffected(q.ExecContext(ctx, `UPDATE `+t.users+` SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`, key, time.Now().UTC()))
+	if err != nil || n > 0 {
+		return err
+	}
+	return missingRow(ctx, q, t, key)
+}
+
+// missingRow explica por que uma escrita com "deleted_at IS NULL" não alterou a linha:
+// o usuário foi removido (ErrGone) ou não existe (ErrNotFound)
+func missingRow(ctx context.Context, q pgQuerier, t pgTables, key string) error {
+	var removed bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+t.users+` WHERE id = $1 AND deleted_at IS NOT NULL)`, key).Scan(&removed)
+	if err != nil {
+		return err
+	}
+	if removed {
+		return usecase.ErrGone
+	}
+	return usecase.ErrNotFound
+}
+
+// rowsAffected devolve quantas linhas um Exec alterou
+func rowsAffected(result sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// MarkVerified marca o usuário como verificado se email ainda for o principal (e avança a versão)
+// lower() dos dois lados: o email do token casa com o principal sem diferenciar maiúsculas (como a collation no MongoDB)
+func (r *UserPostgresRepository) MarkVerified(ctx context.Context, id, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	key, ok := parseUserID(id)
+	if !ok {
+		return usecase.ErrNotFound
+	}
+	t, err := r.tables(ctx)
+	if err != nil {
+		return err
+	}
+
+	n, err := rowsAffected(r.querier(ctx).ExecContext(ctx, `UPDATE `+t.users+`
+		SET verified = TRUE, version = version + 1, updated_at = $3
+		WHERE id = $1 AND lower(email) = lower($2) AND deleted_at IS NULL`, key, email, pgNow()))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return usecase.ErrNotFound
+	}
+	return nil
+}
+
+// pgCounterColumns lista os contadores aceitos por IncrementField: nome do JSON → coluna
+var pgCounterColumns = map[string]string{
+	domain.CounterLoginCount: "login_count",
+}
+
+// IncrementField soma delta ao contador em um único UPDATE (col = col + delta é atômico na linha)
+// Como no MongoDB, version e updated_at não mudam
+func (r *UserPostgresRepository) IncrementField(ctx context.Context, id, field string, delta int) error {
+	column, ok := pgCounterColumns[field]
+	if !ok {
+		return usecase.ErrUnknownCounter
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	key, ok := parseUserID(id)
+	if !ok {
+		return usecase.ErrNotFound
+	}
+	t, err := r.tables(ctx)
+	if err != nil {
+		return err
+	}
+
+	q := r.querier(ctx)
+	n, err := rowsAffected(q.ExecContext(ctx, `UPDATE `+t.users+`
+		SET `+column+` = `+column+` + $2 WHERE id = $1 AND deleted_at IS NULL`, key, delta))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return missingRow(ctx, q, t, key)
+	}
+	return nil
+}
+
+// DeleteMany remove (soft delete) todos os usuários da lista em um UPDATE
+func (r *UserPostgresRepository) DeleteMany(ctx context.Context, ids []string) (int64, []string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	keys, invalid := parseUserIDs(ids)
+	if len(keys) == 0 {
+		return 0, invalid, nil
+	}
+	t, err := r.tables(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	n, err := rowsAffected(r.querier(ctx).ExecContext(ctx, `UPDATE `+t.users+`
+		SET deleted_at = $2 WHERE id = ANY($1) AND deleted_at IS NULL`, keys, time.Now().UTC()))
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, invalid, nil
+}
+
+// UpdateMany aplica as alterações de metadata a todos os usuários do filtro, em um UPDATE
+//
+// SOBRE OS OPERADORES DE JSONB:
+// - COALESCE(metadata, '{}') || $set junta as chaves novas ao metadata atual (NULL vira {})
+// - "- $unset::text[]" remove as chaves com valor nil
+//
+// A trava maxMatched é uma contagem antes do UPDATE (LIMIT maxMatched+1), como no MongoDB
+func (r *UserPostgresRepository) UpdateMany(ctx context.Context, filter domain.BulkUpdateFilter, changes domain.BulkUpdateChanges, maxMatched int64) (int64, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	t, err := r.tables(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	w := pgFilter(domain.UserFilter{Name: filter.Name})
+	if filter.NamePrefix != "" {
+		w.and("u.name ILIKE " + w.arg(likeEscaper.Replace(filter.NamePrefix)+"%"))
+	}
+	if filter.Verified != nil {
+		w.and("u.verified = " + w.arg(*filter.Verified))
+	}
+
+	q := r.querier(ctx)
+	if maxMatched > 0 {
+		var count int64
+		query := `SELECT count(*) FROM (SELECT 1 FROM ` + t.users + ` u` + w.String() + ` LIMIT ` + strconv.FormatInt(maxMatched+1, 10) + `) m`
+		if err := q.QueryRowContext(ctx, query, w.args...).Scan(&count); err != nil {
+			return 0, 0, err
+		}
+		if count > maxMatched {
+			return 0, 0, usecase.ErrBulkConfirmRequired
+		}
+	}
+
+	set := map[string]string{}
+	unset := []string{}
+	for key, value := range changes.Metadata {
+		if value == nil {
+			unset = append(unset, key)
+			continue
+		}
+		set[key] = *value
+	}
+	setJSON, err := json.Marshal(set)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	query := `UPDATE ` + t.users + ` u
+		SET metadata = (COALESCE(u.metadata, '{}'::jsonb) || ` + w.arg(string(setJSON)) + `::jsonb) - ` + w.arg(unset) + `::text[],
+			version = u.version + 1, updated_at = ` + w.arg(pgNow()) + w.String()
+	n, err := rowsAffected(q.ExecContext(ctx, query, w.args...))
+	if err != nil {
+		return 0, 0, err
+	}
+	// Toda linha do filtro recebe versão e data novas: casadas e alteradas são as mesmas
+	return n, n, nil
+}
+
+// ============================================
+// TABELAS (BOOTSTRAP), DROP E PURGE
+// ============================================
+// EnsureIndexes cria as tabelas e os índices (CREATE ... IF NOT EXISTS: idempotente)
+// É o "migration" deste repositório: roda no preflight, antes de o servidor aceitar requisições
+// Com tenant no context, vale para as tabelas do tenant
+func (r *UserPostgresRepository) EnsureIndexes(ctx context.Context) error {
+	t, err := r.tables(ctx)
+	if err != nil {
+		return err
+	}
+	return r.createTables(ctx, t)
+}
+
+// createTables executa postgresSchema para as tabelas t
+func (r *UserPostgresRepository) createTables(ctx context.Context, t pgTables) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	schema := fmt.Sprintf(postgresSchema, t.users, t.emails,
+		quoteIdent(t.name+"_deleted_at"), quoteIdent(t.name+"_updated_at_id"), quoteIdent(t.name+"_emails_address_ci"))
+	// Sem parâmetros, o pgx envia o texto pelo protocolo simples: vários comandos em um Exec
+	_, err := r.db.ExecContext(ctx, schema)
+	return err
+}
+
+// DropAll apaga as tabelas (emails antes, por causa da chave estrangeira) e as recria vazias
+// Uso exclusivo de ambientes de teste
+func (r *UserPostgresRepository) DropAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	t, err := r.tables(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := r.db.ExecContext(ctx, `DROP TABLE IF EXISTS `+t.emails+`, `+t.users); err != nil {
+		return err
+	}
+	return r.createTables(ctx, t)
+}
+
+// PurgeDeletedBefore apaga de vez os usuários removidos antes de t (os emails vão junto: ON DELETE CASCADE)
+// Sem tenant no context, percorre a tabela padrão e as dos tenants ("<tabela>_*"), como no MongoDB
+func (r *UserPostgresRepository) PurgeDeletedBefore(ctx context.Context, t time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var tables []pgTables
+	if _, ok := domain.TenantFromContext(ctx); ok {
+		current, err := r.tables(ctx)
+		if err != nil {
+			return 0, err
+		}
+		tables = []pgTables{current}
+	} else {
+		// Só as tabelas de usuários têm deleted_at: as de emails ficam de fora
+		rows, err := r.db.QueryContext(ctx, `SELECT table_name FROM information_schema.columns
+			WHERE table_schema = current_schema() AND column_name = 'deleted_at'
+				AND (table_name = $1 OR table_name LIKE $2)`,
+			r.table, likeEscaper.Replace(r.table+"_")+"%")
+		if err != nil {
+			return 0, err
+		}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			tables = append(tables, tablesFor(name))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	var purged int64
+	for _, table := range tables {
+		n, err := rowsAffected(r.db.ExecContext(ctx, `DELETE FROM `+table.users+` WHERE deleted_at < $1`, t))
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+	}
+	return purged, nil
+}
+
+// ============================================
+// CHANGE STREAMS E TRANSAÇÕES
+// ============================================
+// Watch não é suportado no PostgreSQL (não há change streams): GET /api/v1/users/stream responde 501
+func (r *UserPostgresRepository) Watch(ctx context.Context) (domain.UserChangeStream, error) {
+	return nil, usecase.ErrChangeStreamsUnsupported
+}
+
+// WithTransaction executa fn dentro de uma transação do PostgreSQL
+// A transação viaja no ctx recebido por fn: os métodos chamados com ele usam a mesma conexão (querier)
+// fn retornou erro → ROLLBACK; nil → COMMIT. Uma transação já aberta no ctx é reaproveitada
+//
+// Ao contrário do MongoDB, funciona em qualquer servidor: nunca devolve ErrTransactionsUnsupported
+func (r *UserPostgresRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(pgTxKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(context.WithValue(ctx, pgTxKey{}, tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,202 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+
+	"user-api/internal/domain"
+	"user-api/internal/infra/postgres"
+	"user-api/internal/usecase"
+)
+
+// ============================================
+// TESTES DE INTEGRAÇÃO DO REPOSITÓRIO POSTGRESQL
+// ============================================
+// Sobem um PostgreSQL de verdade em um container (testcontainers) e exercitam o repositório
+// Só rodam com a build tag integration; sem Docker (ou Podman) disponível, são pulados:
+//
+//	make test-integration
+
+// newPostgresTestRepository sobe o container e devolve o repositório com as tabelas criadas
+func newPostgresTestRepository(t *testing.T) domain.UserRepository {
+	t.Helper()
+	skipWithoutDocker(t)
+	ctx := context.Background()
+
+	container, err := tcpostgres.Run(ctx, "postgres:16-alpine",
+		tcpostgres.WithDatabase("userdb"),
+		tcpostgres.WithUsername("app"),
+		tcpostgres.WithPassword("secret"),
+		tcpostgres.BasicWaitStrategies(),
+	)
+	if err != nil {
+		t.Fatalf("start PostgreSQL container: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := testcontainers.TerminateContainer(container); err != nil {
+			t.Logf("terminate PostgreSQL container: %v", err)
+		}
+	})
+
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("connection string: %v", err)
+	}
+	db, err := postgres.Open(ctx, dsn, 5)
+	if err != nil {
+		t.Fatalf("open PostgreSQL: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repo := NewUserPostgresRepository(db, "users", 5*time.Second)
+	if err := repo.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("create tables: %v", err)
+	}
+	return repo
+}
+
+func TestUserPostgresRepository(t *testing.T) {
+	repo := newPostgresTestRepository(t)
+	ctx := context.Background()
+
+	ana := newTestUser("Ana", "ana@example.com")
+	if err := repo.Create(ctx, ana); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if ana.ID == "" || ana.Version != 1 {
+		t.Fatalf("Create: ID = %q, Version = %d; want an ID and version 1", ana.ID, ana.Version)
+	}
+
+	t.Run("GetByID", func(t *testing.T) {
+		got, err := repo.GetByID(ctx, ana.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Name != "Ana" || got.Email != "ana@example.com" {
+			t.Errorf("GetByID = %+v", got)
+		}
+		if _, err := repo.GetByID(ctx, "65a1b2c3d4e5f6a7b8c9d0e1"); !errors.Is(err, usecase.ErrNotFound) {
+			t.Errorf("GetByID(unknown) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("EmailExists ignores case", func(t *testing.T) {
+		for _, email := range []string{"ana@example.com", "ANA@Example.com"} {
+			exists, err := repo.EmailExists(ctx, email)
+			if err != nil || !exists {
+				t.Errorf("EmailExists(%q) = %v, %v; want true", email, exists, err)
+			}
+		}
+		if exists, _ := repo.EmailExists(ctx, "nobody@example.com"); exists {
+			t.Error("EmailExists(unused) = true")
+		}
+		if err := repo.Create(ctx, newTestUser("Other", "Ana@Example.com")); !errors.Is(err, usecase.ErrEmailTaken) {
+			t.Errorf("Create with a taken email in other case: error = %v, want ErrEmailTaken", err)
+		}
+	})
+
+	t.Run("Update checks the version", func(t *testing.T) {
+		user, err := repo.GetByID(ctx, ana.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		user.Name = "Ana Maria"
+		if err := repo.Update(ctx, user); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if user.Version != 2 {
+			t.Errorf("Version after Update = %d, want 2", user.Version)
+		}
+
+		stale := user.Clone()
+		stale.Version = 1
+		if err := repo.Update(ctx, stale); !errors.Is(err, usecase.ErrVersionConflict) {
+			t.Errorf("Update with stale version: error = %v, want ErrVersionConflict", err)
+		}
+	})
+
+	t.Run("Upsert and MarkVerified match the email in any case", func(t *testing.T) {
+		before, err := repo.Upsert(ctx, newTestUser("Ana Upsert", "ANA@EXAMPLE.COM"))
+		if err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+		if before == nil || before.ID != ana.ID {
+			t.Fatalf("Upsert created a new user instead of updating %s (before = %+v)", ana.ID, before)
+		}
+		if err := repo.MarkVerified(ctx, ana.ID, "Ana@Example.com"); err != nil {
+			t.Fatalf("MarkVerified: %v", err)
+		}
+		got, err := repo.GetByID(ctx, ana.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if !got.Verified {
+			t.Error("Verified = false after MarkVerified")
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		bruno := newTestUser("Bruno", "bruno@example.com")
+		if err := repo.Create(ctx, bruno); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		users, err := repo.List(ctx, domain.UserFilter{})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(users) != 2 {
+			t.Fatalf("List returned %d users, want 2", len(users))
+		}
+		filtered, err := repo.List(ctx, domain.UserFilter{Name: "brU"})
+		if err != nil {
+			t.Fatalf("List(name): %v", err)
+		}
+		if len(filtered) != 1 || filtered[0].ID != bruno.ID {
+			t.Errorf("List(name=brU) = %v, want only Bruno", filtered)
+		}
+	})
+
+	t.Run("Delete is a soft delete", func(t *testing.T) {
+		if err := repo.Delete(ctx, ana.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.GetByID(ctx, ana.ID); !errors.Is(err, usecase.ErrGone) {
+			t.Errorf("GetByID after Delete: error = %v, want ErrGone", err)
+		}
+
+		// Escritas em um removido também respondem ErrGone (410), não ErrNotFound
+		deleted := ana.Clone()
+		deleted.Name = "Ana Removida"
+		if err := repo.Update(ctx, deleted); !errors.Is(err, usecase.ErrGone) {
+			t.Errorf("Update after Delete: error = %v, want ErrGone", err)
+		}
+		if err := repo.IncrementField(ctx, ana.ID, domain.CounterLoginCount, 1); !errors.Is(err, usecase.ErrGone) {
+			t.Errorf("IncrementField after Delete: error = %v, want ErrGone", err)
+		}
+		if err := repo.Delete(ctx, ana.ID); !errors.Is(err, usecase.ErrGone) {
+			t.Errorf("Delete twice: error = %v, want ErrGone", err)
+		}
+
+		users, err := repo.List(ctx, domain.UserFilter{})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		for _, u := range users {
+			if u.ID == ana.ID {
+				t.Error("List still returns the deleted user")
+			}
+		}
+
+		// O email de um removido continua ocupado até o purge
+		if exists, _ := repo.EmailExists(ctx, "ana@example.com"); !exists {
+			t.Error("EmailExists(deleted user's email) = false, want true until the purge")
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// STORE DE TOKENS DE VERIFICAÇÃO (MONGODB)
+// ============================================
+// Guarda os tokens de verificação de email em uma collection própria
+//
+// POR QUE GUARDAR O HASH E NÃO O TOKEN?
+// - O _id é o SHA-256 do token: quem lê o banco (backup, acesso indevido) não consegue usá-lo
+// - Para conferir, calculamos o hash do token recebido e buscamos por ele
+// - Como o token já é aleatório e longo, um hash simples (sem salt) basta
+//
+// SOBRE O ÍNDICE TTL:
+// - O MongoDB apaga o documento verificationGracePeriod DEPOIS de expiresAt
+// - Nesse intervalo um token expirado ainda é encontrado e recebe o erro "expirado"
+// - Depois disso ele some e passa a ser tratado como inválido
+type verificationDoc struct {
+	TokenHash string    `bson:"_id"`
+	UserID    string    `bson:"userId"`
+	Email     string    `bson:"email"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// verificationGracePeriod é quanto tempo um token expirado fica guardado antes do TTL apagá-lo
+const verificationGracePeriod = 7 * 24 * time.Hour
+
+// VerificationMongoStore implementa domain.VerificationTokenStore usando MongoDB
+type VerificationMongoStore struct {
+	collection *mongo.Collection
+	opTimeout  time.Duration // Prazo de cada operação (mesmo MONGO_OP_TIMEOUT do repositório)
+}
+
+// NewVerificationMongoStore cria o store e garante o índice TTL
+// opTimeout é o prazo de cada operação (zero usa DefaultOpTimeout)
+func NewVerificationMongoStore(ctx context.Context, db *mongo.Database, collectionName string, opTimeout time.Duration) (*VerificationMongoStore, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	collection := db.Collection(collectionName)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(verificationGracePeriod.Seconds())),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opTimeout <= 0 {
+		opTimeout = DefaultOpTimeout
+	}
+	return &VerificationMongoStore{collection: collection, opTimeout: opTimeout}, nil
+}
+
+// Save guarda o hash do token
+func (s *VerificationMongoStore) Save(ctx context.Context, token domain.VerificationToken) error {
+	ctx, cancel := context.WithTimeout(ctx, s.opTimeout)
+	defer cancel()
+
+	_, err := s.collection.InsertOne(ctx, verificationDoc{
+		TokenHash: hashToken(token.Token),
+		UserID:    token.UserID,
+		Email:     token.Email,
+		ExpiresAt: token.ExpiresAt,
+	})
+	return err
+}
+
+// Consume busca e remove o token com FindOneAndDelete
+// A operação é atômica: se duas requisições usam o mesmo token ao mesmo tempo,
+// só uma recebe o documento - a outra recebe nil (token já usado)
+func (s *VerificationMongoStore) Consume(ctx context.Context, token string) (*domain.VerificationToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opTimeout)
+	defer cancel()
+
+	var doc verificationDoc
+	err := s.collection.FindOneAndDelete(ctx, bson.M{"_id": hashToken(token)}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.VerificationToken{
+		Token:     token,
+		UserID:    doc.UserID,
+		Email:     doc.Email,
+		ExpiresAt: doc.ExpiresAt,
+	}, nil
+}
+
+// hashToken calcula o SHA-256 (hex) do token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"strings"
+	"testing"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// FAKES DOS TESTES DO USECASE
+// ============================================
+// memoryRepo guarda os usuários em um map e implementa só o que os testes usam
+// Os demais métodos vêm da interface embutida (nil): chamá-los causa panic,
+// o que deixa claro que o usecase fez uma chamada que o teste não esperava
+type memoryRepo struct {
+	domain.UserRepository
+
+	users     map[string]*domain.User
+	deleted   map[string]bool // Removidos (soft delete): GetByID e Update respondem ErrGone
+	updates   int             // Quantas vezes Update foi chamado
+	deleteErr error           // Se preenchido, Delete falha com ele (para simular uma falha no meio da transação)
+}
+
+func newMemoryRepo(users ...*domain.User) *memoryRepo {
+	repo := &memoryRepo{users: map[string]*domain.User{}, deleted: map[string]bool{}}
+	for _, u := range users {
+		repo.users[u.ID] = u.Clone()
+	}
+	return repo
+}
+
+func (r *memoryRepo) Create(_ context.Context, user *domain.User) error {
+	for _, u := range r.users {
+		if strings.EqualFold(u.Email, user.Email) {
+			return ErrEmailTaken
+		}
+	}
+	user.ID = fmt.Sprintf("65a1b2c3d4e5f6a7b8c9d0%02d", len(r.users)+1)
+	user.Version = 1
+	r.users[user.ID] = user.Clone()
+	return nil
+}
+
+func (r *memoryRepo) GetByID(_ context.Context, id string) (*domain.User, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if r.deleted[id] {
+		return nil, ErrGone
+	}
+	return u.Clone(), nil
+}
+
+func (r *memoryRepo) Update(_ context.Context, user *domain.User) error {
+	r.updates++
+	current, ok := r.users[user.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if r.deleted[user.ID] {
+		return ErrGone
+	}
+	if current.Version != user.Version {
+		return ErrVersionConflict
+	}
+	user.Version++
+	r.users[user.ID] = user.Clone()
+	return nil
+}
+
+func (r *memoryRepo) Delete(_ context.Context, id string) error {
+	if r.deleteErr != nil {
+		return r.deleteErr
+	}
+	if _, ok := r.users[id]; !ok {
+		return ErrNotFound
+	}
+	if r.deleted[id] {
+		return ErrGone
+	}
+	r.deleted[id] = true
+	return nil
+}
+
+func (r *memoryRepo) IncrementField(_ context.Context, id, field string, delta int) error {
+	u, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if r.deleted[id] {
+		return ErrGone
+	}
+	if field != domain.CounterLoginCount {
+		return fmt.Errorf("memoryRepo: unexpected counter %q", field)
+	}
+	u.LoginCount += int64(delta)
+	return nil
+}
+
+// WithTransaction roda fn e, se fn falhar, devolve o repositório ao estado de antes (como o rollback do banco)
+func (r *memoryRepo) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	users := make(map[string]*domain.User, len(r.users))
+	for id, u := range r.users {
+		users[id] = u.Clone()
+	}
+	deleted := maps.Clone(r.deleted)
+
+	if err := fn(ctx); err != nil {
+		r.users, r.deleted = users, deleted
+		return err
+	}
+	return nil
+}
+
+// recordingPublisher guarda os eventos publicados
+// Com err preenchido, registra o evento e devolve o erro (um broker fora do ar)
+type recordingPublisher struct {
+	events []domain.Event
+	err    error
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event domain.Event) error {
+	p.events = append(p.events, event)
+	return p.err
+}
+
+// recordingAudit guarda as entradas do audit log
+type recordingAudit struct {
+	entries []*domain.AuditEntry
+}
+
+func (a *recordingAudit) Record(_ context.Context, entry *domain.AuditEntry) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+func (a *recordingAudit) ListByUser(context.Context, string, int) ([]*domain.AuditEntry, error) {
+	return a.entries, nil
+}
+
+// testUseCase junta o usecase e os fakes que os testes inspecionam
+type testUseCase struct {
+	domain.UserUseCase
+	repo      *memoryRepo
+	publisher *recordingPublisher
+	audit     *recordingAudit
+}
+
+// newTestUseCase cria o usecase sobre os fakes, sem verificação de email e com o log descartado
+func newTestUseCase(t *testing.T, users ...*domain.User) *testUseCase {
+	t.Helper()
+	tc := &testUseCase{repo: newMemoryRepo(users...), publisher: &recordingPublisher{}, audit: &recordingAudit{}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tc.UserUseCase = NewUserUseCase(tc.repo, tc.publisher, tc.audit, VerificationOptions{}, logger)
+	return tc
+}
+
+// testUserID é um ID válido (formato ObjectID) para os usuários dos testes
+const testUserID = "65a1b2c3d4e5f6a7b8c9d0e1"
+
+// newStoredUser monta um usuário já gravado (versão 1, email principal na lista)
+func newStoredUser(id, name, email string) *domain.User {
+	u := &domain.User{ID: id, Name: name, Version: 1}
+	u.SetPrimaryEmail(email)
+	return u
+}
@@ -1,8 +1,16 @@
 package usecase
 
 import (
+	"context"
 	"errors"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"user-api/internal/domain"
 )
@@ -18,8 +26,92 @@ import (
 // - Podemos comparar erros usando == (err == ErrInvalidEmail)
 // - Mais simples que criar structs complexas para erros
 var (
-	ErrInvalidEmail = errors.New("invalid email")  // Email sem '@'
-	ErrNotFound     = errors.New("user not found")  // Usuário não encontrado
+	ErrInvalidEmail = errors.New("invalid email")    // Email sem '@'
+	ErrNotFound     = errors.New("user not found")   // Usuário não encontrado
+	ErrGone         = errors.New("user was deleted") // Usuário existiu, mas foi removido (soft delete)
+	ErrNameTooLong  = errors.New("name must be at most 200 characters")
+	// Erros das regras do nome (ver validateName)
+	ErrNameRequired = errors.New("name is required")
+	ErrNameTooShort = errors.New("name must be at least 2 characters")
+	ErrInvalidName  = errors.New("name may only contain letters, spaces, hyphens, apostrophes and periods")
+	ErrEmailTooLong = errors.New("email must be at most 320 characters")
+	ErrInvalidPhone = errors.New("phone must be in E.164 format (e.g. +5511987654321)")
+	// Erros de metadata: limites de tamanho e chaves que o MongoDB não aceita bem
+	ErrTooManyMetadataKeys  = errors.New("metadata must have at most 20 keys")
+	ErrInvalidMetadataKey   = errors.New("metadata keys must have 1-64 characters and must not contain '.' or '$'")
+	ErrMetadataValueTooLong = errors.New("metadata values must be at most 512 characters")
+	// ErrVersionConflict indica que o usuário foi alterado por outra requisição
+	// depois que o cliente o leu (a versão enviada está desatualizada)
+	ErrVersionConflict = errors.New("user was modified by another request")
+	// ErrTransactionsUnsupported indica que o banco não suporta transações
+	// (MongoDB só suporta transações em replica set ou cluster shardeado)
+	ErrTransactionsUnsupported = errors.New("transactions require a MongoDB replica set or sharded cluster")
+	// ErrChangeStreamsUnsupported indica que o banco não tem change streams (mesma exigência das transações)
+	ErrChangeStreamsUnsupported = errors.New("change streams require a MongoDB replica set or sharded cluster")
+	// ErrDatabaseBusy indica que nenhuma conexão do pool do MongoDB ficou livre a tempo
+	// (sobrecarga passageira: o cliente deve tentar de novo em instantes)
+	ErrDatabaseBusy = errors.New("database is busy: no connection available in the pool")
+	// ErrDocumentTooLarge indica que o usuário gravado passaria do limite de 16MB do MongoDB
+	// (em geral por metadata grande demais: o cliente precisa enviar menos dados)
+	ErrDocumentTooLarge = errors.New("user document exceeds the 16MB storage limit")
+	// Erros de paginação: cursor que não é um ID válido ou limite fora da faixa
+	ErrInvalidCursor = errors.New("invalid pagination cursor")
+	ErrInvalidLimit  = errors.New("limit must be a positive integer")
+	ErrInvalidOffset = errors.New("offset must be a non-negative integer")
+	// ErrInvalidUpdatedSince indica um ?updatedSince= fora do formato RFC 3339
+	ErrInvalidUpdatedSince = errors.New("updatedSince must be an RFC 3339 date (e.g. 2024-01-01T00:00:00Z)")
+	// Erros das operações em lote
+	ErrNoIDs      = errors.New("ids must not be empty")
+	ErrTooManyIDs = errors.New("at most 1000 ids per request")
+	// ErrEmailTaken indica que o endereço já pertence a um usuário
+	ErrEmailTaken    = errors.New("email is already in use")
+	ErrTooManyEmails = errors.New("a user can have at most 10 emails")
+	// ErrEmailUnchanged indica um pedido de troca para o próprio email principal
+	ErrEmailUnchanged = errors.New("email is already the user's primary email")
+	// ErrEmailChangeRequiresVerification indica um UpdateUser com outro email principal (a troca é pelo ChangeEmail)
+	ErrEmailChangeRequiresVerification = errors.New("the primary email can only be changed through POST /api/v1/users/{id}/change-email")
+	// Erros da verificação de email: token desconhecido/já usado ou fora do prazo
+	ErrInvalidToken = errors.New("invalid or already used verification token")
+	ErrTokenExpired = errors.New("verification token has expired")
+	// Erros da busca: termos longos demais, ordenação desconhecida, datas inválidas e offset distante demais
+	ErrSearchTermTooLong = errors.New("name and email search terms must be at most 100 characters")
+	ErrInvalidSort       = errors.New("sort must be one of: name, email, created_at")
+	ErrInvalidOrder      = errors.New("order must be asc or desc")
+	ErrInvalidDate       = errors.New("createdAfter and createdBefore must be RFC 3339 dates (e.g. 2024-01-31T00:00:00Z)")
+	ErrInvalidDateRange  = errors.New("createdAfter must be before createdBefore")
+	ErrOffsetTooLarge    = errors.New("offset must be at most 10000")
+	// Erros da atualização em massa: nada a alterar, ou alcance grande demais sem confirmação
+	ErrNoChanges           = errors.New("changes must not be empty")
+	ErrBulkConfirmRequired = errors.New("bulk update matches every user or more than 1000 users: repeat with confirm=true")
+	// ErrUnknownCounter indica um campo que não está na lista de contadores (IncrementField)
+	ErrUnknownCounter = errors.New("field is not an incrementable counter")
+	// ErrMergeSameUser indica um pedido para fundir um usuário nele mesmo
+	ErrMergeSameUser = errors.New("a user cannot be merged into itself")
+)
+
+// Limites de tamanho dos campos
+// 320 é o tamanho máximo de um email segundo a RFC 5321 (64 local + @ + 255 domínio)
+const (
+	minNameLength  = 2
+	maxNameLength  = 200
+	maxEmailLength = 320
+	maxUserEmails  = 10 // Tamanho máximo da lista de emails de um usuário
+
+	// Limites de metadata: 20 × (64 + 512) deixa o documento bem abaixo dos 16MB do MongoDB
+	maxMetadataKeys        = 20
+	maxMetadataKeyLength   = 64
+	maxMetadataValueLength = 512
+)
+
+// maxBatchSize limita quantos IDs uma operação em lote pode receber
+const maxBatchSize = 1000
+
+// Limites da busca (GET /api/v1/users/search)
+// - maxSearchTermLength: cada termo vira uma $regex; termos enormes só encarecem a consulta
+// - maxSearchOffset: Skip percorre e descarta os documentos pulados; páginas muito distantes custam caro
+const (
+	maxSearchTermLength = 100
+	maxSearchOffset     = 10000
 )
 
 // ============================================
@@ -34,7 +126,11 @@ var (
 // - Isso permite que métodos modifiquem o estado interno (se houver)
 // - É uma prática comum em Go usar ponteiros como receptores
 type userUseCase struct {
-	repo domain.UserRepository  // Dependência: o repositório que vamos usar
+	repo         domain.UserRepository // Dependência: o repositório que vamos usar
+	publisher    domain.EventPublisher // Dependência: onde publicar os eventos de domínio
+	audit        domain.AuditLogger    // Dependência: onde registrar o histórico de alterações
+	verification VerificationOptions   // Tokens e envio do email de verificação
+	logger       *slog.Logger          // Logger estruturado (já com component=usecase)
 }
 
 // NewUserUseCase cria um novo usecase recebendo o repositório como dependência
@@ -44,8 +140,23 @@ type userUseCase struct {
 // - Retornamos um ponteiro para que todas as chamadas usem a mesma instância
 // - Se retornássemos userUseCase (valor), cada chamada criaria uma cópia
 // - O & cria um ponteiro para a struct criada
-func NewUserUseCase(repo domain.UserRepository) domain.UserUseCase {
-	return &userUseCase{repo: repo}
+//
+// O publisher recebe os eventos UserCreated/UserUpdated/UserDeleted
+// Use event.NewNoopPublisher() quando não houver barramento de eventos
+//
+// auditLogger registra cada criação/atualização/remoção com o autor da requisição
+// Use audit.NewNoopLogger() quando o audit log estiver desabilitado
+//
+// verification configura os tokens de verificação de email
+// VerificationOptions{} (sem Tokens) desliga o envio: usuários continuam nascendo não verificados
+func NewUserUseCase(repo domain.UserRepository, publisher domain.EventPublisher, auditLogger domain.AuditLogger, verification VerificationOptions, logger *slog.Logger) domain.UserUseCase {
+	return &userUseCase{
+		repo:         repo,
+		publisher:    publisher,
+		audit:        auditLogger,
+		verification: verification,
+		logger:       logger.With("component", "usecase"),
+	}
 }
 
 // ============================================
@@ -53,12 +164,13 @@ func NewUserUseCase(repo domain.UserRepository) domain.UserUseCase {
 // ============================================
 // CreateUser valida o email e cria um novo usuário
 // O repositório vai popular o campo ID quando persistir no banco
-func (uc *userUseCase) CreateUser(name, email string) (*domain.User, error) {
-	// Validação básica: email deve conter '@'
-	// Em produção, use uma biblioteca de validação mais robusta (ex: validator)
-	// Poderia validar: formato correto, domínio válido, não estar em blacklist, etc.
-	if !strings.Contains(email, "@") {
-		return nil, ErrInvalidEmail
+func (uc *userUseCase) CreateUser(ctx context.Context, name, email, phone string, metadata map[string]string) (*domain.User, error) {
+	// Espaços nas pontas não fazem parte do nome: " Maria " é salvo como "Maria"
+	name = strings.TrimSpace(name)
+
+	// Validação dos campos (regras do nome, formato e tamanho do email, telefone, metadata)
+	if err := uc.validateNewUser("create", name, email, phone, metadata); err != nil {
+		return nil, err
 	}
 
 	// Cria a entidade usando o operador & (address-of)
@@ -78,43 +190,302 @@ func (uc *userUseCase) CreateUser(name, email string) (*domain.User, error) {
 	//   // Como user é ponteiro, essa mudança é visível aqui também!
 	//   return user  // user.ID agora tem valor
 	user := &domain.User{
-		Name:  name,
-		Email: email,
+		Name:     name,
+		Phone:    phone,
+		Metadata: metadata,
 		// ID ainda está vazio - será populado pelo repositório
 	}
+	// Preenche Email e a lista de emails (com ele como principal)
+	user.SetPrimaryEmail(email)
 
 	// Persiste no banco através do repositório
 	// Se der erro (ex: banco indisponível), propaga para o handler
 	// O handler decide como tratar (retornar 500, 503, etc.)
-	if err := uc.repo.Create(user); err != nil {
+	if err := uc.repo.Create(ctx, user); err != nil {
+		// ErrEmailTaken é conflito do cliente (inclusive a corrida de dois creates), não falha do banco
+		if err != ErrEmailTaken {
+			uc.logger.Error("failed to create user", "error", err)
+		}
 		return nil, err
 	}
 
+	uc.publish(ctx, domain.UserCreated, user.ID)
+	uc.recordAudit(ctx, domain.AuditCreate, user.ID, nil)
+
+	// Todo usuário nasce não verificado: envia o token para o email principal
+	uc.sendVerification(ctx, user.ID, user.Email)
+
 	// Retorna o usuário criado (agora com ID populado)
 	// Como user é um ponteiro, retornamos o mesmo ponteiro
 	return user, nil
 }
 
+// validateNewUser valida os campos de um usuário novo (CreateUser e UpsertUser)
+// Falhas são registradas em nível Info: são erros do cliente, não do servidor
+func (uc *userUseCase) validateNewUser(operation, name, email, phone string, metadata map[string]string) error {
+	err := validateName(name)
+	if err == nil {
+		err = validateEmail(email)
+	}
+	// Telefone é opcional: só validamos quando informado
+	if err == nil && phone != "" {
+		err = validatePhone(phone)
+	}
+	if err == nil {
+		err = validateMetadata(metadata)
+	}
+	if err != nil {
+		uc.logger.Info("validation failed", "operation", operation, "error", err)
+	}
+	return err
+}
+
+// ============================================
+// UPSERT USER
+// ============================================
+// UpsertUser cria o usuário ou atualiza o que já tem este email como principal
+// Pensado para integrações de sincronização: o sistema de origem envia o registro
+// sem precisar saber se ele já existe aqui
+//
+// REGRAS:
+// - Mesmas validações do CreateUser (o email é obrigatório: é ele que identifica o usuário)
+// - Na atualização valem as regras do PUT: phone vazio e metadata nil mantêm os valores atuais
+// - O email nunca muda no upsert, então verified e a lista de emails ficam como estão
+// - Eventos, audit log e verificação de email seguem o que aconteceu: criação ou atualização
+func (uc *userUseCase) UpsertUser(ctx context.Context, name, email, phone string, metadata map[string]string) (*domain.User, bool, domain.UserChanges, error) {
+	name = strings.TrimSpace(name)
+	if err := uc.validateNewUser("upsert", name, email, phone, metadata); err != nil {
+		return nil, false, nil, err
+	}
+
+	user := &domain.User{
+		Name:     name,
+		Phone:    phone,
+		Metadata: metadata,
+	}
+	user.SetPrimaryEmail(email)
+
+	before, err := uc.repo.Upsert(ctx, user)
+	if err != nil {
+		if err != ErrEmailTaken {
+			uc.logger.Error("failed to upsert user", "error", err)
+		}
+		return nil, false, nil, err
+	}
+
+	if before == nil {
+		uc.publish(ctx, domain.UserCreated, user.ID)
+		uc.recordAudit(ctx, domain.AuditCreate, user.ID, nil)
+		uc.sendVerification(ctx, user.ID, user.Email)
+		return user, true, domain.UserChanges{}, nil
+	}
+
+	changes := before.Diff(user)
+	uc.publish(ctx, domain.UserUpdated, user.ID)
+	uc.recordAudit(ctx, domain.AuditUpdate, user.ID, changes)
+	return user, false, changes, nil
+}
+
+// ============================================
+// EMAIL AVAILABLE
+// ============================================
+// IsEmailAvailable diz se um cadastro com este email seria aceito (quanto ao email)
+//
+// O email passa pela MESMA validação do CreateUser e é consultado exatamente como chegou,
+// que é como o CreateUser o grava: a resposta daqui e o resultado do cadastro sempre concordam
+//
+// É uma fotografia do momento: entre a consulta e o POST outro cliente pode cadastrar o endereço,
+// e o POST responde 422 (EMAIL_TAKEN) nesse caso
+func (uc *userUseCase) IsEmailAvailable(ctx context.Context, email string) (bool, error) {
+	if err := validateEmail(email); err != nil {
+		return false, err
+	}
+
+	exists, err := uc.repo.EmailExists(ctx, email)
+	if err != nil {
+		uc.logger.Error("failed to check email availability", "error", err)
+		return false, err
+	}
+	return !exists, nil
+}
+
 // ============================================
 // GET USER
 // ============================================
 // GetUser busca um usuário por ID
 // Apenas repassa a chamada para o repositório
 // A lógica de negócio aqui é mínima - poderia adicionar cache, logging, etc.
-func (uc *userUseCase) GetUser(id string) (*domain.User, error) {
-	return uc.repo.GetByID(id)
+func (uc *userUseCase) GetUser(ctx context.Context, id string) (*domain.User, error) {
+	return uc.repo.GetByID(ctx, id)
 }
 
 // ============================================
 // LIST USERS
 // ============================================
-// ListUsers retorna todos os usuários
+// ListUsers retorna os usuários que atendem ao filtro
 // Em uma aplicação real, poderia adicionar:
 // - Paginação (limite, offset)
-// - Filtros (buscar por nome, email)
 // - Ordenação (por nome, data de criação)
-func (uc *userUseCase) ListUsers() ([]*domain.User, error) {
-	return uc.repo.List()
+func (uc *userUseCase) ListUsers(ctx context.Context, filter domain.UserFilter) ([]*domain.User, error) {
+	return uc.repo.List(ctx, filter)
+}
+
+// ============================================
+// LIST USERS PAGE (CURSOR)
+// ============================================
+// ListUsersPage retorna uma página de usuários e o cursor da próxima
+//
+// TRUQUE DO limit+1:
+// - Pedimos ao repositório UM usuário a mais do que o limite
+// - Se ele vier, sabemos que existe próxima página (sem precisar de Count)
+// - Devolvemos só "limit" usuários; o cursor é o ID do último devolvido
+// - Com filter.UpdatedSince, o cursor também leva a data de alteração (domain.UpdatedCursor)
+func (uc *userUseCase) ListUsersPage(ctx context.Context, filter domain.UserFilter, after string, limit int) ([]*domain.User, string, error) {
+	if limit < 1 {
+		return nil, "", ErrInvalidLimit
+	}
+
+	users, err := uc.repo.ListAfter(ctx, filter, after, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(users) > limit {
+		users = users[:limit]
+		last := users[len(users)-1]
+		next = last.ID
+		if !filter.UpdatedSince.IsZero() {
+			next = domain.UpdatedCursor(last)
+		}
+	}
+	return users, next, nil
+}
+
+// ListUsersOffset retorna uma página por offset e se existe uma página seguinte
+// Usa o mesmo truque do limit+1 de ListUsersPage para descobrir hasNext
+func (uc *userUseCase) ListUsersOffset(ctx context.Context, filter domain.UserFilter, offset, limit int) ([]*domain.User, bool, error) {
+	if limit < 1 {
+		return nil, false, ErrInvalidLimit
+	}
+	if offset < 0 {
+		return nil, false, ErrInvalidOffset
+	}
+
+	users, err := uc.repo.ListOffset(ctx, filter, offset, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasNext := len(users) > limit
+	if hasNext {
+		users = users[:limit]
+	}
+	return users, hasNext, nil
+}
+
+// ============================================
+// SEARCH USERS
+// ============================================
+// SearchUsers valida os critérios e busca a página de resultados com o total
+//
+// VALIDAÇÕES (todas antes de ir ao banco):
+// - limit >= 1 (o teto PAGE_MAX é aplicado pelo handler) e 0 <= offset <= maxSearchOffset
+// - name e email com no máximo maxSearchTermLength caracteres
+// - sort entre domain.SearchSortFields e order "asc" ou "desc" (vazios usam os padrões)
+// - createdAfter anterior a createdBefore quando os dois são informados
+func (uc *userUseCase) SearchUsers(ctx context.Context, criteria domain.SearchCriteria) ([]*domain.User, int64, error) {
+	if criteria.Limit < 1 {
+		return nil, 0, ErrInvalidLimit
+	}
+	if criteria.Offset < 0 {
+		return nil, 0, ErrInvalidOffset
+	}
+	if criteria.Offset > maxSearchOffset {
+		return nil, 0, ErrOffsetTooLarge
+	}
+	if utf8.RuneCountInString(criteria.Name) > maxSearchTermLength ||
+		utf8.RuneCountInString(criteria.Email) > maxSearchTermLength {
+		return nil, 0, ErrSearchTermTooLong
+	}
+
+	if criteria.Sort == "" {
+		criteria.Sort = "created_at"
+	}
+	if !slices.Contains(domain.SearchSortFields, criteria.Sort) {
+		return nil, 0, ErrInvalidSort
+	}
+	if criteria.Order == "" {
+		criteria.Order = "asc"
+	}
+	if criteria.Order != "asc" && criteria.Order != "desc" {
+		return nil, 0, ErrInvalidOrder
+	}
+
+	if !criteria.CreatedAfter.IsZero() && !criteria.CreatedBefore.IsZero() &&
+		!criteria.CreatedAfter.Before(criteria.CreatedBefore) {
+		return nil, 0, ErrInvalidDateRange
+	}
+
+	return uc.repo.Search(ctx, criteria)
+}
+
+// ============================================
+// STREAM USERS
+// ============================================
+// StreamUsers repassa cada usuário lido do repositório para fn
+// Usado em exportações, onde carregar tudo em memória não é viável
+func (uc *userUseCase) StreamUsers(ctx context.Context, filter domain.UserFilter, fn func(*domain.User) error) error {
+	return uc.repo.Stream(ctx, filter, fn)
+}
+
+// ============================================
+// LIST USER IDS
+// ============================================
+// ListUserIDsPage retorna uma página de IDs e o cursor da próxima (mesmo truque do limit+1)
+func (uc *userUseCase) ListUserIDsPage(ctx context.Context, after string, limit int) ([]string, string, error) {
+	if limit < 1 {
+		return nil, "", ErrInvalidLimit
+	}
+
+	ids := make([]string, 0, limit+1)
+	err := uc.repo.ListIDs(ctx, after, limit+1, func(id string) error {
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(ids) > limit {
+		ids = ids[:limit]
+		next = ids[len(ids)-1]
+	}
+	return ids, next, nil
+}
+
+// StreamUserIDs repassa cada ID lido do repositório para fn (todos os usuários, em ordem de _id)
+func (uc *userUseCase) StreamUserIDs(ctx context.Context, fn func(id string) error) error {
+	return uc.repo.ListIDs(ctx, "", 0, fn)
+}
+
+// ============================================
+// COUNT USERS
+// ============================================
+// CountUsers retorna quantos usuários atendem ao filtro
+// Usa o mesmo filtro do ListUsers para que os números batam com a listagem
+func (uc *userUseCase) CountUsers(ctx context.Context, filter domain.UserFilter) (int64, error) {
+	return uc.repo.Count(ctx, filter)
+}
+
+// ============================================
+// WATCH USERS
+// ============================================
+// WatchUsers abre o stream de alterações do banco (criações, atualizações e remoções)
+// O stream dura até ctx ser cancelado: quem chama deve fechá-lo com Close
+func (uc *userUseCase) WatchUsers(ctx context.Context) (domain.UserChangeStream, error) {
+	return uc.repo.Watch(ctx)
 }
 
 // ============================================
@@ -128,22 +499,41 @@ func (uc *userUseCase) ListUsers() ([]*domain.User, error) {
 // 2. Verifica se existe
 // 3. Atualiza apenas campos não vazios
 // 4. Valida email se foi informado
-// 5. Salva as alterações
-func (uc *userUseCase) UpdateUser(id, name, email string) (*domain.User, error) {
+// 5. Compara com o estado anterior: se nada mudou, devolve o usuário atual sem escrever
+// 6. Salva as alterações (falha com ErrVersionConflict se houve escrita concorrente)
+// 7. Devolve o usuário e os campos que mudaram
+//
+// POR QUE NÃO ESCREVER QUANDO NADA MUDOU?
+// - Um PUT repetido (retry do cliente, formulário reenviado) viraria uma escrita no MongoDB a cada vez
+// - updated_at e version avançariam sem alteração real: updated_at deixaria de dizer quando o usuário mudou
+// - Também não há evento nem entrada de auditoria: nada aconteceu
+// - A resposta continua 200 com o usuário atual (e "changed" vazio)
+func (uc *userUseCase) UpdateUser(ctx context.Context, id, name, email, phone string, metadata map[string]string, version int) (*domain.User, domain.UserChanges, error) {
 	// Primeiro busca o usuário atual
 	// GetByID retorna (*User, error)
 	// Se não encontrar, retorna (nil, ErrNotFound)
-	user, err := uc.repo.GetByID(id)
+	user, err := uc.repo.GetByID(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Verificação de segurança: se o repositório retornar nil sem erro
 	// (não deveria acontecer, mas é bom prevenir)
 	if user == nil {
-		return nil, ErrNotFound
+		return nil, nil, ErrNotFound
+	}
+
+	// Optimistic locking: se o cliente informou a versão que leu e ela já
+	// não é a atual, alguém atualizou o usuário nesse meio-tempo
+	// O repositório repete essa verificação de forma atômica no próprio update
+	if version != 0 && version != user.Version {
+		return nil, nil, ErrVersionConflict
 	}
 
+	// Guarda o estado atual para calcular o que mudou (UserChanges)
+	// Clone copia também a lista de emails, que SetPrimaryEmail altera no lugar
+	before := user.Clone()
+
 	// Atualiza apenas os campos informados (não vazios)
 	// Isso permite atualizar apenas name OU apenas email
 	//
@@ -153,35 +543,615 @@ func (uc *userUseCase) UpdateUser(id, name, email string) (*domain.User, error)
 	// - Essa modificação será persistida quando chamarmos repo.Update(user)
 	// - Não precisamos criar uma nova struct - modificamos a existente
 	if name != "" {
+		// Só espaços não é "campo ausente": vira ErrNameRequired
+		name = strings.TrimSpace(name)
+		if err := validateName(name); err != nil {
+			uc.logger.Info("validation failed", "operation", "update", "user_id", id, "error", err)
+			return nil, nil, err
+		}
 		user.Name = name
 	}
 
+	// O email principal NÃO muda aqui: a troca exige a prova do endereço novo (ChangeEmail)
+	// Trocar direto deixaria qualquer um com acesso à conta apontar o email de recuperação para outro endereço
+	// O mesmo email de agora é aceito: clientes que devolvem o usuário inteiro no PUT continuam funcionando
+	// "Mesmo" ignora maiúsculas, como o índice único e as buscas por email (emailCollation)
 	if email != "" {
-		// Valida o novo email se foi informado
 		// Mesma validação do CreateUser
-		if !strings.Contains(email, "@") {
-			return nil, ErrInvalidEmail
+		if err := validateEmail(email); err != nil {
+			uc.logger.Info("validation failed", "operation", "update", "user_id", id, "error", err)
+			return nil, nil, err
+		}
+		if !strings.EqualFold(email, user.Email) {
+			uc.logger.Info("validation failed", "operation", "update", "user_id", id, "error", ErrEmailChangeRequiresVerification)
+			return nil, nil, ErrEmailChangeRequiresVerification
+		}
+	}
+
+	if phone != "" {
+		if err := validatePhone(phone); err != nil {
+			uc.logger.Info("validation failed", "operation", "update", "user_id", id, "error", err)
+			return nil, nil, err
+		}
+		user.Phone = phone
+	}
+
+	// nil = campo ausente no JSON (mantém); {} = limpar; {...} = substituir tudo
+	if metadata != nil {
+		if err := validateMetadata(metadata); err != nil {
+			uc.logger.Info("validation failed", "operation", "update", "user_id", id, "error", err)
+			return nil, nil, err
 		}
-		user.Email = email
+		user.Metadata = metadata
+	}
+
+	// Valores iguais aos atuais: não há o que salvar
+	changes := before.Diff(user)
+	if len(changes) == 0 {
+		uc.logger.Debug("update skipped, nothing changed", "user_id", id)
+		return user, changes, nil
 	}
 
 	// Salva as alterações no banco
 	// O repositório recebe o ponteiro user com os campos já modificados
-	if err := uc.repo.Update(user); err != nil {
-		return nil, err
+	if err := uc.repo.Update(ctx, user); err != nil {
+		if err != ErrVersionConflict && err != ErrEmailTaken {
+			uc.logger.Error("failed to update user", "user_id", id, "error", err)
+		}
+		return nil, nil, err
 	}
 
-	// Retorna o usuário atualizado
+	uc.publish(ctx, domain.UserUpdated, user.ID)
+	uc.recordAudit(ctx, domain.AuditUpdate, user.ID, changes)
+
+	// Retorna o usuário atualizado e a diferença em relação ao estado anterior
 	// Como user é um ponteiro, retornamos o mesmo ponteiro (mesma instância)
+	return user, changes, nil
+}
+
+// ============================================
+// ADD EMAIL
+// ============================================
+// AddEmail adiciona um endereço secundário ao usuário
+//
+// REGRAS:
+// - Mesmo formato/tamanho de email do CreateUser
+// - O endereço não pode estar na lista do próprio usuário (ErrEmailTaken)
+// - Nem na de outro usuário: o índice único do repositório garante isso
+// - No máximo maxUserEmails endereços por usuário
+//
+// Salva com repo.Update, então uma escrita concorrente resulta em ErrVersionConflict
+func (uc *userUseCase) AddEmail(ctx context.Context, id, email string) (*domain.User, error) {
+	if err := validateEmail(email); err != nil {
+		uc.logger.Info("validation failed", "operation", "add_email", "user_id", id, "error", err)
+		return nil, err
+	}
+
+	user, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotFound
+	}
+
+	if user.HasEmail(email) {
+		return nil, ErrEmailTaken
+	}
+	if len(user.Emails) >= maxUserEmails {
+		return nil, ErrTooManyEmails
+	}
+	before := user.Clone()
+	user.Emails = append(user.Emails, domain.EmailAddress{Address: email})
+
+	if err := uc.repo.Update(ctx, user); err != nil {
+		if err != ErrVersionConflict && err != ErrEmailTaken {
+			uc.logger.Error("failed to add email", "user_id", id, "error", err)
+		}
+		return nil, err
+	}
+
+	uc.publish(ctx, domain.UserUpdated, user.ID)
+	uc.recordAudit(ctx, domain.AuditUpdate, user.ID, before.Diff(user))
 	return user, nil
 }
 
+// ============================================
+// CHANGE EMAIL
+// ============================================
+// ChangeEmail pede a troca do email principal, que só acontece depois da verificação
+//
+// POR QUE NÃO TROCAR NA HORA (e por que o PUT recusa outro email com ErrEmailChangeRequiresVerification)?
+// - Quem tem acesso à conta não necessariamente controla o endereço novo
+// - Trocar sem prova entregaria o email de recuperação da conta a um endereço qualquer
+//
+// FLUXO:
+// 1. O endereço novo fica em PendingEmail e recebe um token de verificação
+// 2. O email atual continua sendo o principal (e continua verificado) enquanto isso
+// 3. O token volta em POST /api/v1/users/verify: VerifyEmail promove o pendente a principal, já verificado
+//
+// REGRAS:
+// - Mesmo formato/tamanho de email do CreateUser
+// - O endereço não pode ser o principal atual (ErrEmailUnchanged)
+// - Nem pertencer a outro usuário (ErrEmailTaken); um secundário do próprio usuário pode virar o principal
+// - Um novo pedido substitui o pendente: tokens enviados ao endereço anterior deixam de valer
+func (uc *userUseCase) ChangeEmail(ctx context.Context, id, email string) (*domain.User, error) {
+	if err := validateEmail(email); err != nil {
+		uc.logger.Info("validation failed", "operation", "change_email", "user_id", id, "error", err)
+		return nil, err
+	}
+
+	user, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotFound
+	}
+
+	if email == user.Email {
+		return nil, ErrEmailUnchanged
+	}
+	// A checagem evita mandar token a um endereço que não poderá ser promovido
+	// O índice único ainda decide na confirmação, se outro usuário pegar o endereço nesse meio-tempo
+	if !user.HasEmail(email) {
+		taken, err := uc.repo.EmailExists(ctx, email)
+		if err != nil {
+			uc.logger.Error("failed to check email availability", "user_id", id, "error", err)
+			return nil, err
+		}
+		if taken {
+			return nil, ErrEmailTaken
+		}
+	}
+
+	before := user.Clone()
+	user.PendingEmail = email
+	if err := uc.repo.Update(ctx, user); err != nil {
+		if err != ErrVersionConflict {
+			uc.logger.Error("failed to request email change", "user_id", id, "error", err)
+		}
+		return nil, err
+	}
+
+	uc.publish(ctx, domain.UserUpdated, user.ID)
+	uc.recordAudit(ctx, domain.AuditUpdate, user.ID, before.Diff(user))
+	uc.sendVerification(ctx, user.ID, email)
+	return user, nil
+}
+
+// ============================================
+// RECORD LOGIN
+// ============================================
+// RecordLogin soma um ao contador de logins do usuário
+//
+// POR QUE NÃO O FLUXO DO UpdateUser?
+// - GetByID + Update perde incrementos: dois logins simultâneos leem o mesmo valor
+// - IncrementField faz a soma no banco ($inc), em uma operação só
+//
+// Sem evento, audit log ou nova versão: um login não altera os dados do usuário
+func (uc *userUseCase) RecordLogin(ctx context.Context, id string) error {
+	if err := uc.repo.IncrementField(ctx, id, domain.CounterLoginCount, 1); err != nil {
+		if err != ErrNotFound {
+			uc.logger.Error("failed to record login", "user_id", id, "error", err)
+		}
+		return err
+	}
+	return nil
+}
+
 // ============================================
 // DELETE USER
 // ============================================
 // DeleteUser remove um usuário
-// Apenas repassa para o repositório
+// Depois de remover, publica o evento UserDeleted
 // Poderia adicionar: soft delete, verificar dependências, etc.
-func (uc *userUseCase) DeleteUser(id string) error {
-	return uc.repo.Delete(id)
+func (uc *userUseCase) DeleteUser(ctx context.Context, id string) error {
+	if err := uc.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	uc.publish(ctx, domain.UserDeleted, id)
+	uc.recordAudit(ctx, domain.AuditDelete, id, nil)
+	return nil
+}
+
+// ============================================
+// RESET (SOMENTE TESTES)
+// ============================================
+// ResetUsers apaga todos os usuários, sem soft delete e sem eventos
+// Existe para suítes de teste começarem do zero; a rota só é registrada com ENABLE_ADMIN
+func (uc *userUseCase) ResetUsers(ctx context.Context) error {
+	if err := uc.repo.DropAll(ctx); err != nil {
+		uc.logger.Error("failed to reset users", "error", err)
+		return err
+	}
+	return nil
+}
+
+// ============================================
+// PURGE DE USUÁRIOS REMOVIDOS
+// ============================================
+// PurgeDeletedUsers apaga de vez os usuários removidos há mais de retention
+// DeleteUser só marca o usuário como removido (soft delete); este é o passo final
+func (uc *userUseCase) PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int64, error) {
+	before := time.Now().UTC().Add(-retention)
+	purged, err := uc.repo.PurgeDeletedBefore(ctx, before)
+	if err != nil {
+		uc.logger.Error("failed to purge deleted users", "before", before, "error", err)
+		return 0, err
+	}
+	return purged, nil
+}
+
+// ============================================
+// GET USERS (EM LOTE)
+// ============================================
+// GetUsers busca vários usuários em uma única consulta ao banco
+// Os mesmos limites do delete em lote valem aqui (lista não vazia, até 1000 IDs)
+func (uc *userUseCase) GetUsers(ctx context.Context, ids []string) ([]*domain.User, []string, error) {
+	if len(ids) == 0 {
+		return nil, nil, ErrNoIDs
+	}
+	if len(ids) > maxBatchSize {
+		return nil, nil, ErrTooManyIDs
+	}
+
+	users, invalid, err := uc.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		uc.logger.Error("failed to batch get users", "count", len(ids), "error", err)
+		return nil, nil, err
+	}
+	return users, invalid, nil
+}
+
+// ============================================
+// DELETE USERS (EM LOTE)
+// ============================================
+// DeleteUsers remove vários usuários em uma única operação no banco
+//
+// Não publicamos UserDeleted aqui: o DeleteMany do MongoDB informa QUANTOS
+// documentos foram removidos, mas não QUAIS - publicar para todos os IDs
+// geraria eventos para usuários que nem existiam
+//
+// O audit log, por outro lado, precisa registrar cada remoção (compliance)
+// Por isso buscamos antes quais IDs existem e registramos só esses
+// Um usuário removido por outra requisição entre a busca e o DeleteMany pode aparecer duas vezes no histórico
+//
+// A mesma busca diz quais IDs (válidos) não existiam: notFound, na ordem em que vieram
+func (uc *userUseCase) DeleteUsers(ctx context.Context, ids []string) (int64, []string, []string, error) {
+	if len(ids) == 0 {
+		return 0, nil, nil, ErrNoIDs
+	}
+	if len(ids) > maxBatchSize {
+		return 0, nil, nil, ErrTooManyIDs
+	}
+
+	existing, _, err := uc.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		uc.logger.Error("failed to bulk delete users", "count", len(ids), "error", err)
+		return 0, nil, nil, err
+	}
+
+	deleted, invalid, err := uc.repo.DeleteMany(ctx, ids)
+	if err != nil {
+		uc.logger.Error("failed to bulk delete users", "count", len(ids), "error", err)
+		return 0, nil, nil, err
+	}
+
+	found := make(map[string]bool, len(existing))
+	for _, user := range existing {
+		found[user.ID] = true
+		uc.recordAudit(ctx, domain.AuditDelete, user.ID, nil)
+	}
+	isInvalid := make(map[string]bool, len(invalid))
+	for _, id := range invalid {
+		isInvalid[id] = true
+	}
+	var notFound []string
+	for _, id := range ids {
+		// O repositório devolve os IDs em hexadecimal minúsculo
+		if !found[strings.ToLower(id)] && !isInvalid[id] {
+			notFound = append(notFound, id)
+		}
+	}
+	return deleted, notFound, invalid, nil
+}
+
+// ============================================
+// BULK UPDATE (ATUALIZAÇÃO EM MASSA)
+// ============================================
+// maxBulkUpdateUnconfirmed é quantos usuários uma atualização em massa alcança sem confirm=true
+const maxBulkUpdateUnconfirmed = 1000
+
+// BulkUpdateUsers aplica as mesmas alterações a todos os usuários do filtro (ex: uma flag em metadata)
+//
+// TRAVAS CONTRA ACIDENTES:
+// - Filtro vazio alcança TODOS os usuários: só com confirm
+// - Sem confirm, mais de maxBulkUpdateUnconfirmed usuários no filtro → ErrBulkConfirmRequired e nada muda
+//
+// As chaves de metadata seguem as regras do cadastro (ver validateMetadata)
+// O limite de 20 chaves vale para as alterações, não para o resultado em cada usuário:
+// quem já tem muitas chaves pode passar do limite
+//
+// Como no DeleteUsers, não há eventos por usuário; e o audit log também não registra
+// (o UpdateMany não diz QUAIS usuários mudou): a operação fica no log da aplicação (handler)
+func (uc *userUseCase) BulkUpdateUsers(ctx context.Context, filter domain.BulkUpdateFilter, changes domain.BulkUpdateChanges, confirm bool) (int64, int64, error) {
+	if len(changes.Metadata) == 0 {
+		return 0, 0, ErrNoChanges
+	}
+	metadata := make(map[string]string, len(changes.Metadata))
+	for key, value := range changes.Metadata {
+		metadata[key] = ""
+		if value != nil {
+			metadata[key] = *value
+		}
+	}
+	if err := validateMetadata(metadata); err != nil {
+		uc.logger.Info("bulk update validation failed", "error", err)
+		return 0, 0, err
+	}
+	if utf8.RuneCountInString(filter.Name) > maxSearchTermLength || utf8.RuneCountInString(filter.NamePrefix) > maxSearchTermLength {
+		return 0, 0, ErrSearchTermTooLong
+	}
+
+	var maxMatched int64
+	if !confirm {
+		if filter.IsEmpty() {
+			return 0, 0, ErrBulkConfirmRequired
+		}
+		maxMatched = maxBulkUpdateUnconfirmed
+	}
+
+	matched, modified, err := uc.repo.UpdateMany(ctx, filter, changes, maxMatched)
+	if err != nil {
+		if err != ErrBulkConfirmRequired {
+			uc.logger.Error("failed to bulk update users", "error", err)
+		}
+		return 0, 0, err
+	}
+	return matched, modified, nil
+}
+
+// ============================================
+// MERGE USERS
+// ============================================
+// MergeUsers funde a conta duplicada secondaryID em primaryID e remove a duplicada
+//
+// O QUE PASSA PARA O PRINCIPAL:
+// - Identidade (ID, nome, emails, verificação) é sempre a do principal
+// - phone: o do secundário, só se o principal não tiver
+// - metadata (com copyMetadata): as chaves do secundário que o principal não tem; nas repetidas, vale o principal
+// - login_count: soma dos dois (IncrementField)
+//
+// E OS EMAILS DO SECUNDÁRIO?
+// - Ficam com ele: o índice único continua valendo para usuários removidos (soft delete)
+// - Passá-los ao principal violaria o índice; depois do purge, podem ser adicionados (AddEmail)
+//
+// POR QUE UMA TRANSAÇÃO?
+// - Sem ela, uma falha entre as escritas deixaria o principal com os dados copiados e o secundário ainda ativo
+// - As leituras também são da transação: uma escrita concorrente em qualquer um dos dois faz tudo recomeçar
+// (o MongoDB repete fn em conflitos passageiros) ou falhar com ErrVersionConflict
+// - Com o cache do GetByID ligado (USER_CACHE_SIZE), o GetByID da transação não passa pelo cache, e os dois usuários
+// são invalidados de novo depois do commit (ver CachedUserRepository.WithTransaction)
+// - Exige replica set: em um standalone, ErrTransactionsUnsupported e nada muda
+//
+// Os eventos e o audit log saem só depois do commit: uma transação desfeita não avisa ninguém
+func (uc *userUseCase) MergeUsers(ctx context.Context, primaryID, secondaryID string, copyMetadata bool) (*domain.User, error) {
+	// ObjectIDs em hexadecimal: "507F..." e "507f..." são o mesmo usuário
+	if strings.EqualFold(primaryID, secondaryID) {
+		return nil, ErrMergeSameUser
+	}
+
+	var merged *domain.User
+	var changes domain.UserChanges
+	err := uc.repo.WithTransaction(ctx, func(ctx context.Context) error {
+		// fn pode rodar mais de uma vez: tudo é relido e recalculado a cada tentativa
+		primary, err := uc.repo.GetByID(ctx, primaryID)
+		if err != nil {
+			return err
+		}
+		secondary, err := uc.repo.GetByID(ctx, secondaryID)
+		if err != nil {
+			return err
+		}
+
+		before := primary.Clone()
+		if primary.Phone == "" {
+			primary.Phone = secondary.Phone
+		}
+		if copyMetadata && len(secondary.Metadata) > 0 {
+			metadata := maps.Clone(secondary.Metadata)
+			maps.Copy(metadata, primary.Metadata)
+			// Só o limite de chaves pode falhar: as chaves e valores já passaram pela validação
+			if err := validateMetadata(metadata); err != nil {
+				return err
+			}
+			primary.Metadata = metadata
+		}
+
+		// Como no UpdateUser, sem alteração não há escrita (version e updated_at não mudam)
+		changes = before.Diff(primary)
+		if len(changes) > 0 {
+			if err := uc.repo.Update(ctx, primary); err != nil {
+				return err
+			}
+		}
+		if secondary.LoginCount > 0 {
+			if err := uc.repo.IncrementField(ctx, primaryID, domain.CounterLoginCount, int(secondary.LoginCount)); err != nil {
+				return err
+			}
+			primary.LoginCount += secondary.LoginCount
+		}
+		if err := uc.repo.Delete(ctx, secondaryID); err != nil {
+			return err
+		}
+
+		merged = primary
+		return nil
+	})
+	if err != nil {
+		if err != ErrNotFound && err != ErrGone && err != ErrVersionConflict && err != ErrTransactionsUnsupported && err != ErrTooManyMetadataKeys {
+			uc.logger.Error("failed to merge users", "user_id", primaryID, "secondary_id", secondaryID, "error", err)
+		}
+		return nil, err
+	}
+
+	uc.logger.Info("users merged", "user_id", primaryID, "secondary_id", secondaryID)
+	if len(changes) > 0 {
+		uc.publish(ctx, domain.UserUpdated, primaryID)
+		uc.recordAudit(ctx, domain.AuditUpdate, primaryID, changes)
+	}
+	uc.publish(ctx, domain.UserDeleted, secondaryID)
+	uc.recordAudit(ctx, domain.AuditDelete, secondaryID, nil)
+	return merged, nil
+}
+
+// ============================================
+// EVENTOS
+// ============================================
+// publish envia um evento de domínio em modo "best-effort"
+// A operação já foi persistida: se o barramento de eventos estiver fora,
+// apenas registramos o erro em log - a requisição HTTP não falha por isso
+func (uc *userUseCase) publish(ctx context.Context, eventType domain.EventType, userID string) {
+	event := domain.Event{
+		Type:       eventType,
+		UserID:     userID,
+		OccurredAt: time.Now().UTC(),
+	}
+	if err := uc.publisher.Publish(ctx, event); err != nil {
+		uc.logger.Error("failed to publish event", "event", eventType, "user_id", userID, "error", err)
+	}
+}
+
+// ============================================
+// AUDIT LOG
+// ============================================
+// recordAudit registra a alteração no audit log em modo "best-effort"
+//
+// POR QUE NÃO FALHAR A OPERAÇÃO?
+// - A alteração JÁ foi salva: responder erro faria o cliente achar que nada aconteceu
+// - Uma nova tentativa do cliente poderia até duplicar a operação
+// - A falha fica no log de erro (com o ID do usuário) para ser investigada
+//
+// O autor vem do context (domain.ContextWithActor, preenchido pela autenticação)
+func (uc *userUseCase) recordAudit(ctx context.Context, operation domain.AuditOperation, userID string, changes domain.UserChanges) {
+	actor, _ := domain.ActorFromContext(ctx)
+	entry := &domain.AuditEntry{
+		UserID:     userID,
+		Operation:  operation,
+		Actor:      actor,
+		Changes:    changes,
+		OccurredAt: time.Now().UTC(),
+	}
+	if err := uc.audit.Record(ctx, entry); err != nil {
+		uc.logger.Error("failed to record audit entry", "operation", operation, "user_id", userID, "error", err)
+	}
+}
+
+// GetUserAudit retorna o histórico de alterações do usuário, da mais nova para a mais antiga
+// Não exige que o usuário exista: o histórico de um usuário removido continua disponível
+func (uc *userUseCase) GetUserAudit(ctx context.Context, id string, limit int) ([]*domain.AuditEntry, error) {
+	if limit < 1 {
+		return nil, ErrInvalidLimit
+	}
+	entries, err := uc.audit.ListByUser(ctx, id, limit)
+	if err != nil {
+		uc.logger.Error("failed to list audit entries", "user_id", id, "error", err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ============================================
+// VALIDAÇÕES
+// ============================================
+// validateName aplica as regras do nome (que já chega sem espaços nas pontas)
+// - Obrigatório, com 2 a 200 caracteres
+// - utf8.RuneCountInString conta CARACTERES, não bytes ("João" tem 4 caracteres e 5 bytes)
+// - Só letras, espaços, hífens, apóstrofos e pontos (ver isNameRune)
+func validateName(name string) error {
+	if name == "" {
+		return ErrNameRequired
+	}
+	length := utf8.RuneCountInString(name)
+	if length < minNameLength {
+		return ErrNameTooShort
+	}
+	if length > maxNameLength {
+		return ErrNameTooLong
+	}
+	for _, r := range name {
+		if !isNameRune(r) {
+			return ErrInvalidName
+		}
+	}
+	return nil
+}
+
+// isNameRune diz se o caractere pode aparecer em um nome
+//
+// POR QUE CATEGORIAS UNICODE E NÃO [A-Za-z]?
+// - unicode.IsLetter aceita letras de QUALQUER alfabeto: "José", "Zoë", "Дмитрий", "محمد", "田中"
+// - unicode.IsMark aceita os acentos combinados (ex: "e" + "◌́") e os sinais vocálicos de
+// escritas como o devanágari, sem os quais muitos nomes não podem ser escritos
+// - unicode.Zs são os espaços (inclui o espaço ideográfico dos nomes japoneses)
+// - Fora da lista ficam dígitos, emojis, símbolos e caracteres de controle/formatação
+// (ex: \n, \t e as marcas invisíveis de direção do texto U+200E/U+200F)
+//
+// Apóstrofos: o reto (') e o tipográfico (’), como em "D’Ávila"
+func isNameRune(r rune) bool {
+	switch r {
+	case '-', '\'', '’', '.':
+		return true
+	}
+	return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.Is(unicode.Zs, r)
+}
+
+// validateEmail verifica tamanho e formato básico do email
+// Em produção, use uma biblioteca de validação mais robusta (ex: validator)
+// Poderia validar: formato correto, domínio válido, não estar em blacklist, etc.
+func validateEmail(email string) error {
+	if len(email) > maxEmailLength {
+		return ErrEmailTooLong
+	}
+	if !strings.Contains(email, "@") {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+// e164Pattern descreve um telefone no formato internacional E.164:
+// "+", código do país (não começa com 0) e no máximo 15 dígitos no total
+// Ex: +5511987654321 (Brasil), +14155552671 (EUA)
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// validatePhone verifica se o telefone está no formato E.164
+// Espaços, traços e parênteses não são aceitos: o cliente deve normalizar antes
+func validatePhone(phone string) error {
+	if !e164Pattern.MatchString(phone) {
+		return ErrInvalidPhone
+	}
+	return nil
+}
+
+// validateMetadata limita o tamanho dos metadados e valida os nomes das chaves
+//
+// POR QUE PROIBIR "." E "$" NAS CHAVES?
+// - No MongoDB, "." separa campos aninhados: "a.b" em um update vira {a: {b: ...}}
+// - Nomes começando com "$" são lidos como operadores ($set, $gt...)
+// - Recusar essas chaves evita documentos que não podem ser consultados ou atualizados
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > maxMetadataKeys {
+		return ErrTooManyMetadataKeys
+	}
+	for key, value := range metadata {
+		if key == "" || utf8.RuneCountInString(key) > maxMetadataKeyLength || strings.ContainsAny(key, ".$") {
+			return ErrInvalidMetadataKey
+		}
+		if utf8.RuneCountInString(value) > maxMetadataValueLength {
+			return ErrMetadataValueTooLong
+		}
+	}
+	return nil
 }
@@ -0,0 +1,403 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"user-api/internal/domain"
+)
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{name: "simple", input: "Maria", want: nil},
+		{name: "accents", input: "José da Silva", want: nil},
+		{name: "hyphen and apostrophes", input: "Anne-Marie D'Ávila D’Souza", want: nil},
+		{name: "initial with dot", input: "John F. Kennedy", want: nil},
+		{name: "cyrillic", input: "Дмитрий", want: nil},
+		{name: "arabic (rtl)", input: "محمد علي", want: nil},
+		{name: "hebrew (rtl)", input: "דוד לוי", want: nil},
+		{name: "japanese with ideographic space", input: "田中　太郎", want: nil},
+		{name: "devanagari vowel signs", input: "देवनागरी", want: nil},
+		{name: "combining accent", input: "Jose\u0301", want: nil},
+		{name: "exactly the minimum", input: "Al", want: nil},
+		{name: "exactly the maximum", input: strings.Repeat("a", maxNameLength), want: nil},
+		{name: "empty", input: "", want: ErrNameRequired},
+		{name: "too short", input: "A", want: ErrNameTooShort},
+		{name: "too long", input: strings.Repeat("a", maxNameLength+1), want: ErrNameTooLong},
+		{name: "multibyte counts characters, not bytes", input: strings.Repeat("ã", maxNameLength), want: nil},
+		{name: "emoji", input: "Ana 😀", want: ErrInvalidName},
+		{name: "digits", input: "Ana 2", want: ErrInvalidName},
+		{name: "newline", input: "Ana\nMaria", want: ErrInvalidName},
+		{name: "tab", input: "Ana\tMaria", want: ErrInvalidName},
+		{name: "invisible rtl mark", input: "Ana\u200fMaria", want: ErrInvalidName},
+		{name: "symbols", input: "Ana <script>", want: ErrInvalidName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateName(tt.input); err != tt.want {
+				t.Errorf("validateName(%q) = %v, want %v", tt.input, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestNameRulesInCreateAndUpdate confere que CreateUser e UpdateUser aparam o nome e aplicam as regras
+func TestNameRulesInCreateAndUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantErr  error
+		wantName string
+	}{
+		{name: "trimmed", input: "  Maria  ", wantName: "Maria"},
+		{name: "only spaces", input: "   ", wantErr: ErrNameRequired},
+		{name: "short after trimming", input: " A ", wantErr: ErrNameTooShort},
+		{name: "emoji", input: "Maria 🎉", wantErr: ErrInvalidName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			tc := newTestUseCase(t)
+			created, err := tc.CreateUser(ctx, tt.input, "maria@example.com", "", nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateUser error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && created.Name != tt.wantName {
+				t.Errorf("CreateUser name = %q, want %q", created.Name, tt.wantName)
+			}
+
+			tc = newTestUseCase(t, newStoredUser(testUserID, "Ana", "ana@example.com"))
+			updated, _, err := tc.UpdateUser(ctx, testUserID, tt.input, "", "", nil, 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateUser error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && updated.Name != tt.wantName {
+				t.Errorf("UpdateUser name = %q, want %q", updated.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+// TestUpdateUserPrimaryEmail confere que o PUT não troca o email principal (a troca é pelo ChangeEmail)
+func TestUpdateUserPrimaryEmail(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   string
+		wantErr error
+	}{
+		{name: "omitted", email: ""},
+		{name: "same as the current one", email: "ana@example.com"},
+		{name: "same as the current one in other case", email: "Ana@Example.COM"},
+		{name: "another address", email: "other@example.com", wantErr: ErrEmailChangeRequiresVerification},
+		{name: "invalid address", email: "not-an-email", wantErr: ErrInvalidEmail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTestUseCase(t, newStoredUser(testUserID, "Ana", "ana@example.com"))
+
+			_, _, err := tc.UpdateUser(context.Background(), testUserID, "Ana Maria", tt.email, "", nil, 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateUser error = %v, want %v", err, tt.wantErr)
+			}
+
+			stored := tc.repo.users[testUserID]
+			if stored.Email != "ana@example.com" || len(stored.Emails) != 1 {
+				t.Errorf("primary email changed to %q (emails %v)", stored.Email, stored.Emails)
+			}
+			if tt.wantErr != nil && tc.repo.updates != 0 {
+				t.Errorf("Update called %d times for a rejected request", tc.repo.updates)
+			}
+		})
+	}
+}
+
+// TestUpdateUserWithoutChanges confere que um PUT com os valores atuais não grava nada:
+// a versão não sobe e nenhum evento ou entrada de auditoria é gerado
+func TestUpdateUserWithoutChanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		userName    string
+		email       string
+		phone       string
+		wantChanged bool
+	}{
+		{name: "nothing informed", wantChanged: false},
+		{name: "same name", userName: "Ana", wantChanged: false},
+		{name: "same name with spaces around", userName: "  Ana  ", wantChanged: false},
+		{name: "same name and email", userName: "Ana", email: "ana@example.com", wantChanged: false},
+		{name: "new name", userName: "Ana Maria", wantChanged: true},
+		{name: "new phone", phone: "+5511987654321", wantChanged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTestUseCase(t, newStoredUser(testUserID, "Ana", "ana@example.com"))
+
+			updated, changes, err := tc.UpdateUser(context.Background(), testUserID, tt.userName, tt.email, tt.phone, nil, 1)
+			if err != nil {
+				t.Fatalf("UpdateUser: %v", err)
+			}
+
+			wantVersion, wantCalls := 1, 0
+			if tt.wantChanged {
+				wantVersion, wantCalls = 2, 1
+			}
+			if updated.Version != wantVersion || tc.repo.users[testUserID].Version != wantVersion {
+				t.Errorf("Version = %d (stored %d), want %d", updated.Version, tc.repo.users[testUserID].Version, wantVersion)
+			}
+			if (len(changes) > 0) != tt.wantChanged {
+				t.Errorf("changes = %v, want changed: %v", changes, tt.wantChanged)
+			}
+			if tc.repo.updates != wantCalls {
+				t.Errorf("Update called %d times, want %d", tc.repo.updates, wantCalls)
+			}
+			if len(tc.publisher.events) != wantCalls {
+				t.Errorf("published %d events, want %d", len(tc.publisher.events), wantCalls)
+			}
+			if len(tc.audit.entries) != wantCalls {
+				t.Errorf("recorded %d audit entries, want %d", len(tc.audit.entries), wantCalls)
+			}
+		})
+	}
+}
+
+// TestPublishedEvents confere o evento de cada escrita e que a publicação é best-effort:
+// com o publisher falhando, a operação continua dando certo
+func TestPublishedEvents(t *testing.T) {
+	tests := []struct {
+		name      string
+		run       func(tc *testUseCase) (string, error) // Devolve o ID do usuário afetado
+		wantEvent domain.EventType
+	}{
+		{
+			name: "create",
+			run: func(tc *testUseCase) (string, error) {
+				user, err := tc.CreateUser(context.Background(), "Bruno", "bruno@example.com", "", nil)
+				if err != nil {
+					return "", err
+				}
+				return user.ID, nil
+			},
+			wantEvent: domain.UserCreated,
+		},
+		{
+			name: "update",
+			run: func(tc *testUseCase) (string, error) {
+				_, _, err := tc.UpdateUser(context.Background(), testUserID, "Ana Maria", "", "", nil, 0)
+				return testUserID, err
+			},
+			wantEvent: domain.UserUpdated,
+		},
+		{
+			name: "delete",
+			run: func(tc *testUseCase) (string, error) {
+				return testUserID, tc.DeleteUser(context.Background(), testUserID)
+			},
+			wantEvent: domain.UserDeleted,
+		},
+	}
+	for _, tt := range tests {
+		for _, publishErr := range []error{nil, errors.New("broker unavailable")} {
+			t.Run(fmt.Sprintf("%s, publish error %v", tt.name, publishErr), func(t *testing.T) {
+				tc := newTestUseCase(t, newStoredUser(testUserID, "Ana", "ana@example.com"))
+				tc.publisher.err = publishErr
+
+				id, err := tt.run(tc)
+				if err != nil {
+					t.Fatalf("%s error = %v, want success even if publishing fails", tt.name, err)
+				}
+
+				if len(tc.publisher.events) != 1 {
+					t.Fatalf("published %d events, want 1", len(tc.publisher.events))
+				}
+				event := tc.publisher.events[0]
+				if event.Type != tt.wantEvent || event.UserID != id {
+					t.Errorf("event = %s for %q, want %s for %q", event.Type, event.UserID, tt.wantEvent, id)
+				}
+				if event.OccurredAt.IsZero() {
+					t.Error("event.OccurredAt is zero")
+				}
+			})
+		}
+	}
+}
+
+// secondaryUserID é o usuário duplicado nos testes do MergeUsers
+const secondaryUserID = "65a1b2c3d4e5f6a7b8c9d0e2"
+
+func TestMergeUsers(t *testing.T) {
+	tests := []struct {
+		name                 string
+		primaryPhone         string
+		primaryMetadata      map[string]string
+		primaryLogins        int64
+		secondaryPhone       string
+		secondaryMetadata    map[string]string
+		secondaryLogins      int64
+		copyMetadata         bool
+		wantPhone            string
+		wantMetadata         map[string]string
+		wantLogins           int64
+		wantPrimaryRewritten bool // Update, evento UserUpdated e audit do principal
+	}{
+		{
+			name:           "phone comes from the secondary when the primary has none",
+			secondaryPhone: "+5511987654321",
+			wantPhone:      "+5511987654321", wantPrimaryRewritten: true,
+		},
+		{
+			name:         "the primary keeps its phone",
+			primaryPhone: "+5511911112222", secondaryPhone: "+5511987654321",
+			wantPhone: "+5511911112222",
+		},
+		{
+			name:            "the primary wins on shared metadata keys",
+			primaryMetadata: map[string]string{"plan": "pro"}, secondaryMetadata: map[string]string{"plan": "free", "source": "ads"},
+			copyMetadata: true,
+			wantMetadata: map[string]string{"plan": "pro", "source": "ads"}, wantPrimaryRewritten: true,
+		},
+		{
+			name:            "metadata is not copied without copyMetadata",
+			primaryMetadata: map[string]string{"plan": "pro"}, secondaryMetadata: map[string]string{"source": "ads"},
+			wantMetadata: map[string]string{"plan": "pro"},
+		},
+		{
+			name:          "login_count is summed",
+			primaryLogins: 3, secondaryLogins: 2,
+			wantLogins: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := newStoredUser(testUserID, "Ana", "ana@example.com")
+			primary.Phone, primary.Metadata, primary.LoginCount = tt.primaryPhone, tt.primaryMetadata, tt.primaryLogins
+			secondary := newStoredUser(secondaryUserID, "Ana Duplicada", "ana.dup@example.com")
+			secondary.Phone, secondary.Metadata, secondary.LoginCount = tt.secondaryPhone, tt.secondaryMetadata, tt.secondaryLogins
+			tc := newTestUseCase(t, primary, secondary)
+
+			merged, err := tc.MergeUsers(context.Background(), testUserID, secondaryUserID, tt.copyMetadata)
+			if err != nil {
+				t.Fatalf("MergeUsers: %v", err)
+			}
+
+			stored := tc.repo.users[testUserID]
+			for _, u := range []*domain.User{merged, stored} {
+				if u.Name != "Ana" || u.Email != "ana@example.com" {
+					t.Errorf("identity = %q <%s>, want the primary's", u.Name, u.Email)
+				}
+				if u.Phone != tt.wantPhone {
+					t.Errorf("Phone = %q, want %q", u.Phone, tt.wantPhone)
+				}
+				if !maps.Equal(u.Metadata, tt.wantMetadata) {
+					t.Errorf("Metadata = %v, want %v", u.Metadata, tt.wantMetadata)
+				}
+				if u.LoginCount != tt.wantLogins {
+					t.Errorf("LoginCount = %d, want %d", u.LoginCount, tt.wantLogins)
+				}
+			}
+
+			// O secundário é removido (soft delete)
+			if _, err := tc.repo.GetByID(context.Background(), secondaryUserID); !errors.Is(err, ErrGone) {
+				t.Errorf("GetByID(secondary) error = %v, want ErrGone", err)
+			}
+
+			// Sem alteração no principal: nenhuma escrita, nenhum UserUpdated, nenhuma entrada de audit dele
+			wantEvents := []domain.EventType{domain.UserDeleted}
+			wantUpdates, wantVersion := 0, 1
+			if tt.wantPrimaryRewritten {
+				wantEvents = []domain.EventType{domain.UserUpdated, domain.UserDeleted}
+				wantUpdates, wantVersion = 1, 2
+			}
+			var gotEvents []domain.EventType
+			for _, e := range tc.publisher.events {
+				gotEvents = append(gotEvents, e.Type)
+			}
+			if !slices.Equal(gotEvents, wantEvents) {
+				t.Errorf("events = %v, want %v", gotEvents, wantEvents)
+			}
+			if tc.repo.updates != wantUpdates || stored.Version != wantVersion {
+				t.Errorf("Update called %d times (version %d), want %d (version %d)", tc.repo.updates, stored.Version, wantUpdates, wantVersion)
+			}
+			if len(tc.audit.entries) != len(wantEvents) {
+				t.Errorf("recorded %d audit entries, want %d", len(tc.audit.entries), len(wantEvents))
+			}
+		})
+	}
+}
+
+// TestMergeUsersErrors confere que um merge recusado (ou que falha no meio) não deixa rastro nos dois usuários
+func TestMergeUsersErrors(t *testing.T) {
+	tooManyKeys := func(prefix string, n int) map[string]string {
+		m := map[string]string{}
+		for i := range n {
+			m[fmt.Sprintf("%s%d", prefix, i)] = "x"
+		}
+		return m
+	}
+	errConnection := errors.New("connection reset")
+
+	tests := []struct {
+		name              string
+		primaryID         string
+		secondaryID       string
+		secondaryDeleted  bool
+		primaryMetadata   map[string]string
+		secondaryMetadata map[string]string
+		deleteErr         error
+		wantErr           error
+	}{
+		{name: "same user", primaryID: testUserID, secondaryID: testUserID, wantErr: ErrMergeSameUser},
+		{name: "same user in other hex case", primaryID: testUserID, secondaryID: strings.ToUpper(testUserID), wantErr: ErrMergeSameUser},
+		{name: "unknown secondary", primaryID: testUserID, secondaryID: "65a1b2c3d4e5f6a7b8c9d0ff", wantErr: ErrNotFound},
+		{name: "deleted secondary", primaryID: testUserID, secondaryID: secondaryUserID, secondaryDeleted: true, wantErr: ErrGone},
+		{
+			name: "metadata key limit", primaryID: testUserID, secondaryID: secondaryUserID,
+			primaryMetadata: tooManyKeys("p", maxMetadataKeys-5), secondaryMetadata: tooManyKeys("s", 10),
+			wantErr: ErrTooManyMetadataKeys,
+		},
+		{
+			// O Update do principal já rodou quando o Delete falha: o rollback desfaz tudo
+			name: "failure in the middle of the transaction", primaryID: testUserID, secondaryID: secondaryUserID,
+			deleteErr: errConnection, wantErr: errConnection,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := newStoredUser(testUserID, "Ana", "ana@example.com")
+			primary.Metadata = tt.primaryMetadata
+			secondary := newStoredUser(secondaryUserID, "Ana Duplicada", "ana.dup@example.com")
+			secondary.Phone, secondary.Metadata, secondary.LoginCount = "+5511987654321", tt.secondaryMetadata, 4
+			tc := newTestUseCase(t, primary, secondary)
+			tc.repo.deleted[secondaryUserID] = tt.secondaryDeleted
+			tc.repo.deleteErr = tt.deleteErr
+
+			_, err := tc.MergeUsers(context.Background(), tt.primaryID, tt.secondaryID, true)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MergeUsers error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(tc.repo.users[testUserID], primary) {
+				t.Errorf("primary changed: %+v, want %+v", tc.repo.users[testUserID], primary)
+			}
+			if !reflect.DeepEqual(tc.repo.users[secondaryUserID], secondary) || tc.repo.deleted[secondaryUserID] != tt.secondaryDeleted {
+				t.Errorf("secondary changed: %+v (deleted %v)", tc.repo.users[secondaryUserID], tc.repo.deleted[secondaryUserID])
+			}
+			if tt.deleteErr == nil && tc.repo.updates != 0 {
+				t.Errorf("Update called %d times, want none", tc.repo.updates)
+			}
+			if len(tc.publisher.events) != 0 || len(tc.audit.entries) != 0 {
+				t.Errorf("events = %v, audit entries = %d; want none", tc.publisher.events, len(tc.audit.entries))
+			}
+		})
+	}
+}
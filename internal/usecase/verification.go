@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"user-api/internal/domain"
+)
+
+// ============================================
+// VERIFICAÇÃO DE EMAIL
+// ============================================
+// VerificationOptions agrupa as dependências da verificação de email
+//
+// POR QUE UMA STRUCT?
+// - São três valores que só fazem sentido juntos
+// - O zero value (VerificationOptions{}) desliga o envio, útil no seed e em ferramentas
+type VerificationOptions struct {
+	Tokens   domain.VerificationTokenStore // Onde os tokens ficam guardados (nil = sem verificação)
+	Mailer   domain.Mailer                 // Quem envia o email com o token
+	TokenTTL time.Duration                 // Validade de cada token
+}
+
+// tokenBytes é o tamanho do token em bytes (256 bits; 64 caracteres em hex)
+const tokenBytes = 32
+
+// sendVerification gera um token para o endereço email do usuário userID e o envia
+// O endereço é o principal (criação, PUT) ou o pendente (ChangeEmail)
+//
+// Falhas aqui NÃO desfazem a operação: o usuário já foi salvo
+// O erro vai para o log (como em publish) e o usuário segue não verificado
+func (uc *userUseCase) sendVerification(ctx context.Context, userID, email string) {
+	if uc.verification.Tokens == nil {
+		return
+	}
+
+	token, err := newToken()
+	if err != nil {
+		uc.logger.Error("failed to generate verification token", "user_id", userID, "error", err)
+		return
+	}
+
+	err = uc.verification.Tokens.Save(ctx, domain.VerificationToken{
+		Token:     token,
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().UTC().Add(uc.verification.TokenTTL),
+	})
+	if err != nil {
+		uc.logger.Error("failed to save verification token", "user_id", userID, "error", err)
+		return
+	}
+
+	if err := uc.verification.Mailer.SendVerification(ctx, email, token); err != nil {
+		uc.logger.Error("failed to send verification email", "user_id", userID, "error", err)
+	}
+}
+
+// ============================================
+// VERIFY EMAIL
+// ============================================
+// VerifyEmail consome o token e marca o usuário como verificado
+//
+// FLUXO:
+// 1. Consome o token (busca + remove): a partir daqui ele não vale mais, mesmo se algo falhar
+// 2. Token inexistente ou já usado → ErrInvalidToken
+// 3. Token expirado → ErrTokenExpired (o cliente sabe que precisa de um novo)
+// 4. Token do email pendente (ChangeEmail) → o pendente vira o principal, já verificado (confirmEmailChange)
+// 5. Senão, marca o usuário; se ele foi removido ou trocou o email principal → ErrInvalidToken
+func (uc *userUseCase) VerifyEmail(ctx context.Context, token string) (*domain.User, error) {
+	if token == "" || uc.verification.Tokens == nil {
+		return nil, ErrInvalidToken
+	}
+
+	stored, err := uc.verification.Tokens.Consume(ctx, token)
+	if err != nil {
+		uc.logger.Error("failed to consume verification token", "error", err)
+		return nil, err
+	}
+	if stored == nil {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	user, err := uc.repo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		if err == ErrNotFound || err == ErrGone {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if user.PendingEmail != "" && user.PendingEmail == stored.Email {
+		return uc.confirmEmailChange(ctx, user)
+	}
+
+	if err := uc.repo.MarkVerified(ctx, stored.UserID, stored.Email); err != nil {
+		if err == ErrNotFound {
+			return nil, ErrInvalidToken
+		}
+		uc.logger.Error("failed to mark user as verified", "user_id", stored.UserID, "error", err)
+		return nil, err
+	}
+
+	uc.publish(ctx, domain.UserUpdated, stored.UserID)
+	// Sem autor: quem prova a identidade aqui é o token, não um JWT
+	uc.recordAudit(ctx, domain.AuditUpdate, stored.UserID, domain.UserChanges{
+		"verified": {Old: false, New: true},
+	})
+	return uc.repo.GetByID(ctx, stored.UserID)
+}
+
+// confirmEmailChange promove o email pendente a principal, já verificado
+//
+// SOBRE O EMAIL ANTIGO:
+// - Como no PUT, o endereço novo substitui o principal na lista (um secundário é só promovido)
+// - Até este ponto o antigo seguiu valendo: é aqui, com o novo provado, que ele sai
+//
+// O token já foi consumido: uma escrita concorrente (ErrVersionConflict) ou um endereço que
+// outro usuário pegou nesse meio-tempo (ErrEmailTaken) exigem um novo pedido de troca
+func (uc *userUseCase) confirmEmailChange(ctx context.Context, user *domain.User) (*domain.User, error) {
+	before := user.Clone()
+	user.SetPrimaryEmail(user.PendingEmail)
+	user.PendingEmail = ""
+	user.Verified = true
+
+	if err := uc.repo.Update(ctx, user); err != nil {
+		if err != ErrVersionConflict && err != ErrEmailTaken {
+			uc.logger.Error("failed to confirm email change", "user_id", user.ID, "error", err)
+		}
+		return nil, err
+	}
+
+	uc.publish(ctx, domain.UserUpdated, user.ID)
+	// Sem autor: quem prova a identidade aqui é o token, não um JWT
+	uc.recordAudit(ctx, domain.AuditUpdate, user.ID, before.Diff(user))
+	return user, nil
+}
+
+// newToken gera um token aleatório com crypto/rand
+// math/rand NÃO serve: é previsível e permitiria adivinhar tokens
+func newToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}